@@ -3,10 +3,9 @@ package GoFlow
 import (
 	"context"
 	"net/http"
-	"regexp"
-	"strings"
+	"slices"
+	"sort"
 	"sync"
-	"unsafe"
 )
 
 // Common HTTP methods
@@ -58,95 +57,83 @@ var methodMap = map[string]uint16{
 var (
 	paramsPool = sync.Pool{
 		New: func() interface{} {
+			paramsPoolStats.allocations.Add(1)
 			return make(map[string]string, 8)
 		},
 	}
-
-	builderPool = sync.Pool{
-		New: func() interface{} {
-			return new(strings.Builder)
-		},
-	}
-
-	segmentsPool = sync.Pool{
-		New: func() interface{} {
-			return make([]string, 0, 8)
-		},
-	}
 )
 
-type (
-	contextKey      struct{}
-	paramContextKey struct{}
-)
+type paramContextKey struct{}
 
 // methodHandler manages HTTP method handling
 type methodHandler struct {
-	handlers    map[string]http.Handler
-	allowedSet  uint16
-	allowedList string
-}
-
-type routeNode struct {
-	get     http.Handler
-	methods *methodHandler
-}
-
-// routeTree represents a node in the routing tree
-type routeTree struct {
-	segment        string
-	methods        *methodHandler
-	children       map[string]*routeTree
-	paramChild     *routeTree
-	paramName      string
-	isWildcard     bool
-	rxPattern      *regexp.Regexp
-	staticHandlers map[string]routeNode
-}
-
-// Add after existing type definitions
-type routeCacheEntry struct {
-	methods *methodHandler
-	params  map[string]string
-}
+	handlers   map[string]http.Handler
+	allowedSet uint16
 
-type MiddlewareChain struct {
-	handlers []func(http.Handler) http.Handler
-	cached   http.Handler
+	// allowedList is the Allow header including the automatic OPTIONS,
+	// registeredList the registered methods alone
+	allowedList    string
+	registeredList string
 }
 
-// Update Mux struct
+// Mux is the router. Groups share its tree and route table.
 type Mux struct {
-	root             *routeTree
-	NotFound         http.Handler
+	root     *routeTree
+	NotFound http.Handler
+
+	// MethodNotAllowed answers requests for a path with no handler for
+	// their method. Groups may set their own for the routes they register.
 	MethodNotAllowed http.Handler
-	Options          http.Handler
-	middlewares      []func(http.Handler) http.Handler
-	middlewareChain  MiddlewareChain // Add this
-	rxCache          sync.Map
-	pathCache        sync.Map // Add this
-	optimized        bool
+
+	// ErrorHandler answers the errors passed to WriteError, such as those
+	// returned by a HandlerFunc. Groups may set their own for the routes
+	// they register. Defaults to problem details, see ProblemFor.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Options answers OPTIONS requests for paths without an OPTIONS
+	// handler. Set it to nil to answer them 405 and leave OPTIONS out of
+	// the Allow header.
+	Options     http.Handler
+	parent      *Mux
+	middlewares []func(http.Handler) http.Handler
+	routes      []*Route // registered on this mux, for Use
+	groups      []*Mux
+	meta        map[interface{}]interface{}
+	table       *routeTable
+	hooks       *muxHooks
+	prefix      string
+	rxCache     sync.Map
 }
 
 // New creates a new Mux instance
 func New() *Mux {
-	return &Mux{
+	m := &Mux{
 		root: &routeTree{
-			children:       make(map[string]*routeTree),
-			staticHandlers: make(map[string]routeNode),
+			staticHandlers: make(map[string]*methodHandler),
 		},
-		NotFound: http.NotFoundHandler(),
+		table: &routeTable{},
+		hooks: &muxHooks{},
+		NotFound: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if CurrentLocalizer(r.Context()) == nil {
+				http.NotFound(w, r)
+				return
+			}
+			http.Error(w, StatusText(r.Context(), http.StatusNotFound), http.StatusNotFound)
+		}),
 		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			http.Error(w, StatusText(r.Context(), http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
 		}),
 		Options: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusNoContent)
 		}),
 	}
+	registerStats(&registry.muxes, m)
+	return m
 }
 
-// Handle registers a new route with its handlers
-func (m *Mux) Handle(pattern string, handler http.Handler, methods ...string) {
+// Handle registers a new route with its handlers and returns the Route so
+// that route-level middleware and metadata can be attached
+func (m *Mux) Handle(pattern string, handler http.Handler, methods ...string) *Route {
 	if len(methods) == 0 {
 		methods = AllMethods
 	}
@@ -155,149 +142,196 @@ func (m *Mux) Handle(pattern string, handler http.Handler, methods ...string) {
 		methods = append(methods, MethodHead)
 	}
 
-	wrappedHandler := m.wrap(handler)
-	for _, method := range methods {
-		m.addRoute(pattern, strings.ToUpper(method), wrappedHandler)
+	pattern = joinPattern(m.prefix, pattern)
+	if err := ValidatePattern(pattern); err != nil {
+		panic(err.Error())
 	}
-
-	// Pre-compute static paths after adding new routes
-	if m.optimized {
-		m.precomputeStaticPaths()
+	route := newRoute(m, pattern, handler, methods)
+	m.routes = append(m.routes, route)
+	for _, method := range route.methods {
+		m.addRoute(pattern, method, route)
+	}
+	if m.table != nil {
+		m.table.add(route)
 	}
+	return route
 }
 
 // ServeHTTP implements the http.Handler interface
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.table != nil && m.table.debug != nil && m.table.debug.requested(r) {
+		m.serveDebug(w, r, m.table.debug)
+		return
+	}
+	m.dispatch(w, r)
+}
+
+func (m *Mux) dispatch(w http.ResponseWriter, r *http.Request) {
+	if m.table != nil && m.table.base != "" {
+		var ok bool
+		if r, ok = stripBasePath(r, m.table.base); !ok {
+			m.wrap(m.NotFound).ServeHTTP(w, r)
+			return
+		}
+	}
+	if m.hooks != nil {
+		if done := m.hooks.done.Load(); done != nil {
+			m.serveWithHooks(w, r, *done)
+			return
+		}
+	}
+	if m.intercept(w, r) {
+		return
+	}
+	m.serve(w, r)
+}
+
+func (m *Mux) serve(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
 	if path == "" {
 		path = "/"
 	}
 
-	// Fast path for GET requests
-	if r.Method == MethodGet {
-		if route, ok := m.root.staticHandlers[path[1:]]; ok && route.get != nil {
-			route.get.ServeHTTP(w, r)
+	// Fast path for routes without parameters, looked up by the exact path
+	if methods, ok := m.root.staticHandlers[path]; ok {
+		if handler, ok := methods.handlers[r.Method]; ok {
+			handler.ServeHTTP(w, r)
 			return
 		}
 	}
 
-	// Optimize struct pooling
-	sw := responseWriterPool.Get().(*statusWriter)
-	sw.ResponseWriter = w
-	sw.status = 0
-	sw.size = 0
-	clear(sw.headers)
-	defer responseWriterPool.Put(sw)
-
-	// Get segments from pool
-	segments := m.getPathSegments(path)
-	if segments == nil {
-		segments = segmentsPool.Get().([]string)[:0]
-	}
-	defer segmentsPool.Put(segments)
-
-	// Get params from pool
+	// The pooled map is only scratch space for matching. Handlers may keep
+	// the request context, so the parameters they see are a copy.
+	paramsPoolStats.gets.Add(1)
 	params := paramsPool.Get().(map[string]string)
-	defer func() {
-		clear(params)
-		paramsPool.Put(params)
-	}()
-
-	methods, foundParams, found := m.findHandler(m.root, segments, params)
+	methods, foundParams, found := m.findHandler(m.root, routePath(path), params)
+	if len(foundParams) > 0 {
+		foundParams = make(map[string]string, len(params))
+		for k, v := range params {
+			foundParams[k] = v
+		}
+	}
+	clear(params)
+	paramsPool.Put(params)
 
 	if found && methods != nil {
 		if handler, ok := methods.handlers[r.Method]; ok {
+			if rt, ok := handler.(*Route); ok {
+				rt.serve(w, r, foundParams)
+				return
+			}
 			if len(foundParams) > 0 {
 				ctx := context.WithValue(r.Context(), paramContextKey{}, foundParams)
-				handler.ServeHTTP(sw, r.WithContext(ctx))
+				handler.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
-			handler.ServeHTTP(sw, r)
+			handler.ServeHTTP(w, r)
 			return
 		}
-		sw.Header().Set("Allow", methods.allowedList)
-		if r.Method == MethodOptions {
-			m.wrap(m.Options).ServeHTTP(sw, r)
+		if m.Options == nil {
+			w.Header().Set("Allow", methods.registeredList)
+		} else {
+			w.Header().Set("Allow", methods.allowedList)
+		}
+		if r.Method == MethodOptions && m.Options != nil {
+			m.wrap(m.Options).ServeHTTP(w, r)
 		} else {
-			m.wrap(m.MethodNotAllowed).ServeHTTP(sw, r)
+			m.methodNotAllowed(methods).ServeHTTP(w, r)
 		}
 		return
 	}
 
-	m.wrap(m.NotFound).ServeHTTP(sw, r)
+	m.wrap(m.NotFound).ServeHTTP(w, r)
 }
 
-func (m *Mux) getPathSegments(path string) []string {
-	segments := segmentsPool.Get().([]string)
-	segments = segments[:0]
-
-	data := *(*[]byte)(unsafe.Pointer(&path))
-	start := 1
-
-	for i := 1; i < len(data); i++ {
-		if data[i] == '/' {
-			if i > start {
-				segments = append(segments, path[start:i])
-			}
-			start = i + 1
+// notFound returns the NotFound handler of m or its closest ancestor that
+// sets one, for handlers that reject a request within a route. Groups don't
+// copy NotFound, so theirs is usually nil.
+func (m *Mux) notFound() http.Handler {
+	for g := m; g != nil; g = g.parent {
+		if g.NotFound != nil {
+			return g.NotFound
 		}
 	}
-	if start < len(data) {
-		segments = append(segments, path[start:])
-	}
-	return segments
+	return http.NotFoundHandler()
 }
 
-func (m *Mux) getStaticHandler(path string, method string) http.Handler {
-	if route, ok := m.root.staticHandlers[strings.TrimPrefix(path, "/")]; ok {
-		if method == MethodGet {
-			return route.get
+// methodNotAllowed returns the MethodNotAllowed handler of the group that
+// registered the path, or of its closest ancestor that sets one, wrapped in
+// that group's middleware
+func (m *Mux) methodNotAllowed(methods *methodHandler) http.Handler {
+	registered := make([]string, 0, len(methods.handlers))
+	for method := range methods.handlers {
+		registered = append(registered, method)
+	}
+	sort.Strings(registered)
+	for _, method := range registered {
+		rt, ok := methods.handlers[method].(*Route)
+		if !ok {
+			continue
 		}
-		if route.methods != nil {
-			return route.methods.handlers[method]
+		for g := rt.mux; g != nil && g != m; g = g.parent {
+			if g.MethodNotAllowed != nil {
+				return g.wrap(g.MethodNotAllowed)
+			}
 		}
 	}
-	return nil
+	return m.wrap(m.MethodNotAllowed)
 }
 
-func (m *Mux) lookupStaticRoute(path string) *routeTree {
-	if !m.optimized {
-		return nil
-	}
-	if pathLen := len(path); pathLen > 1 && path[pathLen-1] == '/' {
-		path = path[:pathLen-1]
-	}
-	if route, ok := m.root.staticHandlers[path[1:]]; ok {
-		return &routeTree{methods: route.methods}
-	}
-	return nil
+// Use adds middleware to the router. Routes registered earlier on the mux
+// or its groups are recompiled so that they run it too, in the same
+// position as routes registered afterwards.
+func (m *Mux) Use(mw ...func(http.Handler) http.Handler) {
+	m.insertMiddleware(len(m.middlewares), mw)
 }
 
-// Use adds middleware to the router
-func (m *Mux) Use(mw ...func(http.Handler) http.Handler) {
-	m.middlewares = append(m.middlewares, mw...)
-	// Reset middleware chain cache
-	m.middlewareChain.cached = nil
+// insertMiddleware inserts mw at position at of the stacks of m, its routes
+// and its groups. Group stacks start with a copy of their parent's, so the
+// position is the same throughout.
+func (m *Mux) insertMiddleware(at int, mw []func(http.Handler) http.Handler) {
+	m.middlewares = slices.Insert(m.middlewares, at, mw...)
+	for _, rt := range m.routes {
+		rt.middlewares = slices.Insert(rt.middlewares, at, mw...)
+		rt.compile()
+	}
+	for _, g := range m.groups {
+		g.insertMiddleware(at, mw)
+	}
 }
 
 // Group creates a new route group
 func (m *Mux) Group(fn func(*Mux)) {
 	subMux := &Mux{
 		root:        m.root,
+		table:       m.table,
+		hooks:       m.hooks,
+		prefix:      m.prefix,
+		parent:      m,
 		middlewares: make([]func(http.Handler) http.Handler, len(m.middlewares)),
+		meta:        make(map[interface{}]interface{}, len(m.meta)),
 	}
 	copy(subMux.middlewares, m.middlewares)
+	m.groups = append(m.groups, subMux)
+	for k, v := range m.meta {
+		subMux.meta[k] = v
+	}
 	fn(subMux)
 }
 
-// Optimize applies performance optimizations
-func (m *Mux) Optimize() {
-	if !m.optimized {
-		m.precomputeStaticPaths()
-		m.optimized = true
+// Set stores a metadata default inherited by routes registered afterwards on
+// this mux or group. Route.Set takes precedence.
+func (m *Mux) Set(key, value interface{}) {
+	if m.meta == nil {
+		m.meta = make(map[interface{}]interface{})
 	}
+	m.meta[key] = value
 }
 
+// Optimize is kept for compatibility. Routes without parameters are
+// always served from a map keyed by their path, which Handle maintains.
+func (m *Mux) Optimize() {}
+
 // Param gets a route parameter from the context
 func Param(ctx context.Context, param string) string {
 	if params, ok := ctx.Value(paramContextKey{}).(map[string]string); ok {
@@ -305,3 +339,15 @@ func Param(ctx context.Context, param string) string {
 	}
 	return ""
 }
+
+// WithParams returns a shallow copy of r carrying params the way ServeHTTP
+// installs them, so handlers using Param can be unit-tested without a Mux
+func WithParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramContextKey{}, params))
+}
+
+// WithRoutePattern returns a shallow copy of r whose CurrentRoute reports
+// pattern, for testing handlers and middleware that inspect the route
+func WithRoutePattern(r *http.Request, pattern string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeContextKey{}, &Route{pattern: pattern}))
+}
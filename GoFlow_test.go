@@ -140,6 +140,40 @@ func TestMux(t *testing.T) {
 		}
 	})
 
+	t.Run("Wildcard Fallback", func(t *testing.T) {
+		mux := New()
+		var matched string
+
+		mux.Handle("/docs/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			matched = "api"
+		}), MethodGet)
+		mux.Handle("/docs/:section/index", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			matched = "section"
+		}), MethodGet)
+		mux.Handle("/docs/...", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			matched = "wildcard:" + Param(r.Context(), "...")
+		}), MethodGet)
+
+		tests := []struct {
+			path     string
+			expected string
+		}{
+			{"/docs/api", "api"},
+			{"/docs/guide/index", "section"},
+			// Static and parameter branches dead-end, so the wildcard matches
+			{"/docs/api/v2", "wildcard:api/v2"},
+			{"/docs/guide/intro", "wildcard:guide/intro"},
+		}
+
+		for _, tt := range tests {
+			matched = ""
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, tt.path, nil))
+			if matched != tt.expected {
+				t.Errorf("%s: expected %q, got %q", tt.path, tt.expected, matched)
+			}
+		}
+	})
+
 	t.Run("Method Not Allowed", func(t *testing.T) {
 		mux := New()
 
@@ -162,6 +196,65 @@ func TestMux(t *testing.T) {
 		}
 	})
 
+	t.Run("Allow Header", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		mux := New()
+		mux.Handle("/post", h, MethodPost)
+		mux.Handle("/options", h, MethodGet, MethodOptions)
+
+		tests := []struct {
+			path, allow string
+		}{
+			{"/post", "POST, OPTIONS"},
+			{"/options", "GET, HEAD, OPTIONS"},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodPut, tt.path, nil))
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Expected Allow %q for %s, got %q", tt.allow, tt.path, got)
+			}
+		}
+
+		mux.Options = nil
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodOptions, "/post", nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status code %d with automatic OPTIONS disabled, got %d", http.StatusMethodNotAllowed, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != "POST" {
+			t.Errorf("Expected Allow %q, got %q", "POST", got)
+		}
+	})
+
+	t.Run("Group Method Not Allowed", func(t *testing.T) {
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		mux := New()
+		mux.Handle("/page", h, MethodGet)
+		mux.Group(func(api *Mux) {
+			api.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				w.Write([]byte(`{"error":"method not allowed"}`))
+			})
+			api.Group(func(v1 *Mux) {
+				v1.Handle("/api/v1/users", h, MethodGet)
+			})
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodPost, "/api/v1/users", nil))
+		if w.Code != http.StatusMethodNotAllowed || w.Body.String() != `{"error":"method not allowed"}` {
+			t.Errorf("Expected the group's 405 response, got %d %q", w.Code, w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodPost, "/page", nil))
+		if w.Code != http.StatusMethodNotAllowed || strings.Contains(w.Body.String(), "{") {
+			t.Errorf("Expected the mux's 405 response, got %d %q", w.Code, w.Body.String())
+		}
+	})
+
 	t.Run("Concurrent Access", func(t *testing.T) {
 		mux := New()
 		var mu sync.Mutex
@@ -271,6 +364,47 @@ func TestMux(t *testing.T) {
 		}
 	})
 
+	t.Run("Use After Registration", func(t *testing.T) {
+		mux := New()
+		var calls []string
+		record := func(name string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		})
+
+		mux.Use(record("first"))
+		mux.Handle("/root", handler, MethodGet)
+		mux.Group(func(m *Mux) {
+			m.Use(record("group"))
+			m.Handle("/admin", handler, MethodGet)
+		})
+		mux.Handle("/other", handler, MethodGet)
+		mux.Use(record("late"))
+
+		tests := []struct {
+			path     string
+			expected []string
+		}{
+			{"/root", []string{"first", "late", "handler"}},
+			{"/admin", []string{"first", "late", "group", "handler"}},
+			{"/other", []string{"first", "late", "handler"}},
+		}
+		for _, tt := range tests {
+			calls = nil
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, tt.path, nil))
+			if !equalSlices(calls, tt.expected) {
+				t.Errorf("Expected calls %v for %s, got %v", tt.expected, tt.path, calls)
+			}
+		}
+	})
+
 	t.Run("Timeout Middleware", func(t *testing.T) {
 		mux := New()
 		mux.Use(Timeout(50 * time.Millisecond))
@@ -309,6 +443,21 @@ func TestMux(t *testing.T) {
 			t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
 		}
 	})
+
+	t.Run("Param Injection", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(CurrentRoute(r.Context()).Pattern() + " " + Param(r.Context(), "id")))
+		})
+
+		r := httptest.NewRequest(MethodGet, "/users/42", nil)
+		r = WithRoutePattern(WithParams(r, map[string]string{"id": "42"}), "/users/:id")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Body.String() != "/users/:id 42" {
+			t.Errorf("Expected '/users/:id 42', got '%s'", w.Body.String())
+		}
+	})
 }
 
 // Helper functions
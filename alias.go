@@ -0,0 +1,67 @@
+package GoFlow
+
+import (
+	"net/http"
+	"sort"
+)
+
+// Alias serves the route at further patterns, e.g. the paths of an API
+// before it was renamed. Parameters are passed to the handler by the names
+// the alias uses, so keep them the same as the route's. Aliases are left
+// out of Mux.Routes and generated documentation.
+func (rt *Route) Alias(patterns ...string) *Route {
+	m := rt.registeredMux("Alias")
+	for _, pattern := range patterns {
+		pattern = joinPattern(m.prefix, pattern)
+		if err := ValidatePattern(pattern); err != nil {
+			panic(err.Error())
+		}
+		for _, method := range rt.methods {
+			m.addRoute(pattern, method, rt)
+		}
+	}
+	return rt
+}
+
+// AliasRedirect redirects requests for further patterns to the route,
+// substituting their parameters into its pattern and keeping the query.
+// GET and HEAD requests get 301 Moved Permanently, others 308 Permanent
+// Redirect so clients repeat the method and body. The redirects appear in
+// Mux.Routes but are hidden from generated documentation.
+func (rt *Route) AliasRedirect(patterns ...string) *Route {
+	m := rt.registeredMux("AliasRedirect")
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params, _ := r.Context().Value(paramContextKey{}).(map[string]string)
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		pairs := make([]string, 0, 2*len(params))
+		for _, name := range names {
+			pairs = append(pairs, name, params[name])
+		}
+
+		target, err := rt.URL(pairs...)
+		if err != nil {
+			// The alias captured a value the route doesn't accept
+			m.notFound().ServeHTTP(w, r)
+			return
+		}
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		permanentRedirect(w, r, target)
+	})
+	for _, pattern := range patterns {
+		m.Handle(pattern, redirect, rt.methods...).Hidden()
+	}
+	return rt
+}
+
+func (rt *Route) registeredMux(method string) *Mux {
+	if rt.mux == nil {
+		panic("GoFlow: " + method + " requires a route registered with Handle")
+	}
+	return rt.mux
+}
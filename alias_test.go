@@ -0,0 +1,64 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAliases(t *testing.T) {
+	mux := New()
+	user := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + Param(r.Context(), "id") + " via " + CurrentRoute(r.Context()).Pattern()))
+	})
+	mux.Handle("/v2/users/:id|^\\d+$", user, MethodGet, MethodPut).
+		Alias("/people/:id").
+		AliasRedirect("/v1/users/:id", "/legacy/:id/profile")
+
+	t.Run("Serve", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/people/7", nil))
+		if w.Body.String() != "user 7 via /v2/users/:id|^\\d+$" {
+			t.Errorf("Expected the route served at its alias, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("Redirect", func(t *testing.T) {
+		tests := []struct {
+			method, path string
+			status       int
+			location     string
+		}{
+			{MethodGet, "/v1/users/42?fields=name", http.StatusMovedPermanently, "/v2/users/42?fields=name"},
+			{MethodPut, "/legacy/42/profile", http.StatusPermanentRedirect, "/v2/users/42"},
+			{MethodGet, "/v1/users/abc", http.StatusNotFound, ""},
+			{MethodDelete, "/v1/users/42", http.StatusMethodNotAllowed, ""},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status || w.Header().Get("Location") != tt.location {
+				t.Errorf("%s %s: expected %d %q, got %d %q", tt.method, tt.path, tt.status, tt.location, w.Code, w.Header().Get("Location"))
+			}
+		}
+	})
+
+	t.Run("Group", func(t *testing.T) {
+		mux := New()
+		mux.Group(func(g *Mux) {
+			g.Handle("/users/:id|^\\d+$", user, MethodGet).AliasRedirect("/members/:id")
+		})
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/members/abc", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Documentation", func(t *testing.T) {
+		routes := mux.Routes()
+		if len(routes) != 3 || routes[0].Doc().Hidden || !routes[1].Doc().Hidden {
+			t.Errorf("Expected the route plus two hidden redirects, got %d routes", len(routes))
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package GoFlow
+
+import "maps"
+
+// RouteDoc is the documentation attached to a route with Summary,
+// Description, Request, Response and friends. It is read by the openapi
+// package and shown by Mux.Print.
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+
+	// Hidden leaves the route out of generated documentation
+	Hidden bool
+
+	// Request is a value of the JSON request body type, e.g. CreateUser{}
+	Request any
+
+	// Responses maps status codes to values of the JSON response body
+	// types; a nil value documents a response without a body
+	Responses map[int]any
+
+	// Consumes and Produces are the media types declared with
+	// Route.Consumes and Route.Produces
+	Consumes []string
+	Produces []string
+}
+
+type routeDocKey struct{}
+
+// Doc returns the documentation attached to the route
+func (rt *Route) Doc() RouteDoc {
+	doc, _ := rt.Value(routeDocKey{}).(RouteDoc)
+	if _, ok := rt.Value(deprecationKey{}).(Deprecation); ok {
+		doc.Deprecated = true
+	}
+	if ct, ok := rt.Value(contentTypesKey{}).(contentTypes); ok {
+		doc.Consumes = ct.consumes
+		doc.Produces = ct.produces
+	}
+	return doc
+}
+
+// annotate updates a copy of the route's documentation, so that values
+// inherited through Mux.Set are never modified in place
+func (rt *Route) annotate(fn func(*RouteDoc)) *Route {
+	doc, _ := rt.Value(routeDocKey{}).(RouteDoc)
+	doc.Tags = append([]string(nil), doc.Tags...)
+	doc.Responses = maps.Clone(doc.Responses)
+	fn(&doc)
+	return rt.Set(routeDocKey{}, doc)
+}
+
+// Summary sets a one-line description of the route
+func (rt *Route) Summary(summary string) *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Summary = summary })
+}
+
+// Description sets a longer description of the route
+func (rt *Route) Description(description string) *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Description = description })
+}
+
+// Tags adds tags used to group the route in generated documentation
+func (rt *Route) Tags(tags ...string) *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Tags = append(d.Tags, tags...) })
+}
+
+// Request documents the JSON request body with a value of its type
+func (rt *Route) Request(body any) *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Request = body })
+}
+
+// Response documents a response status with a value of its JSON body type,
+// or nil for a response without a body
+func (rt *Route) Response(status int, body any) *Route {
+	return rt.annotate(func(d *RouteDoc) {
+		if d.Responses == nil {
+			d.Responses = make(map[int]any)
+		}
+		d.Responses[status] = body
+	})
+}
+
+// Deprecated marks the route as deprecated in generated documentation
+func (rt *Route) Deprecated() *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Deprecated = true })
+}
+
+// Hidden leaves the route out of generated documentation
+func (rt *Route) Hidden() *Route {
+	return rt.annotate(func(d *RouteDoc) { d.Hidden = true })
+}
@@ -0,0 +1,181 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"time"
+)
+
+// APIKey describes a key accepted by APIKeyAuth
+type APIKey struct {
+	// ID identifies the key without revealing it; it becomes the
+	// principal's subject and the rate limit key
+	ID string
+
+	// Tier selects the key's budget in TieredRateLimit, e.g. "free",
+	// "pro" or "enterprise"
+	Tier string
+
+	Scopes []string
+
+	// Secret signs requests verified by SignedRequestAuth, which looks the
+	// key up by its access key ID instead of a presented secret
+	Secret string
+}
+
+// KeyStore resolves the API keys presented by clients. LookupKey returns
+// nil without an error for unknown keys.
+type KeyStore interface {
+	LookupKey(ctx context.Context, key string) (*APIKey, error)
+}
+
+// KeyStoreFunc adapts a function to KeyStore
+type KeyStoreFunc func(ctx context.Context, key string) (*APIKey, error)
+
+// LookupKey calls f
+func (f KeyStoreFunc) LookupKey(ctx context.Context, key string) (*APIKey, error) {
+	return f(ctx, key)
+}
+
+// StaticKeys returns a KeyStore backed by a map of keys to their
+// descriptions
+func StaticKeys(keys map[string]APIKey) KeyStore {
+	// Keyed by hash so lookups don't compare the secrets themselves
+	hashed := make(map[[32]byte]*APIKey, len(keys))
+	for key, k := range keys {
+		hashed[sha256.Sum256([]byte(key))] = &k
+	}
+	return KeyStoreFunc(func(ctx context.Context, key string) (*APIKey, error) {
+		return hashed[sha256.Sum256([]byte(key))], nil
+	})
+}
+
+// APIKeyOptions configures the APIKeyAuth middleware
+type APIKeyOptions struct {
+	Store KeyStore
+
+	// Header carries the key. Defaults to "X-API-Key".
+	Header string
+
+	// Requirement decides whether requests without a key are admitted;
+	// Route.AuthLevel overrides it
+	Requirement AuthRequirement
+}
+
+type apiKeyContextKey struct{}
+
+// APIKeyAuth authenticates requests by the API key in a header, looked up
+// in the store. The key is available to handlers via GetAPIKey and as a
+// principal with the "apikey" scheme, its ID as the subject and its tier
+// as the "tier" claim.
+func APIKeyAuth(opts APIKeyOptions) func(http.Handler) http.Handler {
+	if opts.Store == nil {
+		panic("GoFlow: APIKeyOptions requires a Store")
+	}
+	if opts.Header == "" {
+		opts.Header = "X-API-Key"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := r.Header.Get(opts.Header)
+			if presented == "" {
+				if authRequirement(r, opts.Requirement) == AuthOptional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				authFailed(r, "apikey", "missing", "")
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			key, err := opts.Store.LookupKey(r.Context(), presented)
+			if err != nil {
+				debugNote(r.Context(), "api key: lookup failed: %v", err)
+				http.Error(w, StatusText(r.Context(), http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if key == nil {
+				authFailed(r, "apikey", "invalid", "")
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			authSucceeded(r, "apikey", key.ID)
+			r = WithPrincipal(r, &Principal{
+				Subject: key.ID,
+				Scheme:  "apikey",
+				Scopes:  key.Scopes,
+				Claims:  map[string]interface{}{"tier": key.Tier},
+			})
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+		})
+	}
+}
+
+// GetAPIKey returns the API key that authenticated the request, or nil
+func GetAPIKey(ctx context.Context) *APIKey {
+	k, _ := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return k
+}
+
+// RateLimitTier is the budget of the keys in one tier
+type RateLimitTier struct {
+	Requests  int
+	Duration  time.Duration
+	BurstSize int
+}
+
+// TieredRateLimitOptions configures the TieredRateLimit middleware
+type TieredRateLimitOptions struct {
+	// Tiers maps tier names to their budgets
+	Tiers map[string]RateLimitTier
+
+	// Default is the tier of keys whose tier isn't in Tiers
+	Default string
+}
+
+// TieredRateLimit limits each API key by the budget of its tier. Register it
+// after APIKeyAuth; requests without a key aren't limited here, so pair it
+// with RateLimit for anonymous traffic. Each tier's limiter appears in
+// Stats labeled with the tier.
+func TieredRateLimit(opts TieredRateLimitOptions) func(http.Handler) http.Handler {
+	if _, ok := opts.Tiers[opts.Default]; !ok {
+		panic("GoFlow: TieredRateLimitOptions.Default must name one of the Tiers")
+	}
+	limiters := make(map[string]*RateLimiter, len(opts.Tiers))
+	for name, tier := range opts.Tiers {
+		rl := NewRateLimiter(tier.Requests, tier.Duration, tier.BurstSize)
+		rl.tier = name
+		limiters[name] = rl
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := GetAPIKey(r.Context())
+			if key == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			tier := key.Tier
+			limiter, ok := limiters[tier]
+			if !ok {
+				tier, limiter = opts.Default, limiters[opts.Default]
+			}
+
+			w.Header().Set("X-RateLimit-Tier", tier)
+			if !limiter.Allow(tenantKey(r, key.ID)) {
+				debugNote(r.Context(), "rate limit: key %s limited in tier %s", key.ID, tier)
+				w.Header().Set("X-RateLimit-Limit", toString(int(limiter.requests)))
+				w.Header().Set("X-RateLimit-Burst", toString(int(limiter.burst)))
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", toString(int(limiter.interval/1e9)))
+				http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			debugNote(r.Context(), "rate limit: key %s allowed in tier %s", key.ID, tier)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
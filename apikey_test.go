@@ -0,0 +1,98 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	store := StaticKeys(map[string]APIKey{
+		"free-secret": {ID: "k1", Tier: "free"},
+		"pro-secret":  {ID: "k2", Tier: "pro", Scopes: []string{"reports"}},
+		"old-secret":  {ID: "k3", Tier: "legacy"},
+	})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(User(r.Context()) + " " + GetAPIKey(r.Context()).Tier))
+	})
+
+	t.Run("Authentication", func(t *testing.T) {
+		h := APIKeyAuth(APIKeyOptions{Store: store})(handler)
+		for _, tc := range []struct {
+			key    string
+			status int
+			body   string
+		}{
+			{"", http.StatusUnauthorized, ""},
+			{"wrong", http.StatusUnauthorized, ""},
+			{"pro-secret", http.StatusOK, "k2 pro"},
+		} {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			if tc.key != "" {
+				r.Header.Set("X-API-Key", tc.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tc.status || (tc.body != "" && w.Body.String() != tc.body) {
+				t.Errorf("Key %q: expected %d %q, got %d %q", tc.key, tc.status, tc.body, w.Code, w.Body.String())
+			}
+		}
+
+		failing := APIKeyAuth(APIKeyOptions{Store: KeyStoreFunc(func(ctx context.Context, key string) (*APIKey, error) {
+			return nil, errors.New("database down")
+		})})(handler)
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("X-API-Key", "pro-secret")
+		w := httptest.NewRecorder()
+		failing.ServeHTTP(w, r)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 when the store fails, got %d", w.Code)
+		}
+	})
+
+	t.Run("Tiers", func(t *testing.T) {
+		h := APIKeyAuth(APIKeyOptions{Store: store})(TieredRateLimit(TieredRateLimitOptions{
+			Tiers: map[string]RateLimitTier{
+				"free": {Requests: 1, Duration: time.Hour},
+				"pro":  {Requests: 3, Duration: time.Hour},
+			},
+			Default: "free",
+		})(handler))
+		allowed := func(key string, n int) int {
+			ok := 0
+			for i := 0; i < n; i++ {
+				r := httptest.NewRequest(MethodGet, "/", nil)
+				r.Header.Set("X-API-Key", key)
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, r)
+				if w.Code == http.StatusOK {
+					ok++
+				} else if w.Code != http.StatusTooManyRequests {
+					t.Fatalf("Expected 429 over the budget, got %d", w.Code)
+				}
+			}
+			return ok
+		}
+
+		if n := allowed("free-secret", 3); n != 1 {
+			t.Errorf("Expected 1 request for the free tier, got %d", n)
+		}
+		if n := allowed("pro-secret", 5); n != 3 {
+			t.Errorf("Expected 3 requests for the pro tier, got %d", n)
+		}
+		if n := allowed("old-secret", 2); n != 1 {
+			t.Errorf("Expected an unknown tier to get the default budget, got %d", n)
+		}
+
+		rejected := make(map[string]uint64)
+		for _, s := range Stats().RateLimiters {
+			rejected[s.Tier] += s.Rejected
+		}
+		if rejected["free"] != 3 || rejected["pro"] != 2 {
+			t.Errorf("Expected rejections labeled by tier, got %v", rejected)
+		}
+	})
+}
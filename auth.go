@@ -0,0 +1,103 @@
+package GoFlow
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strconv"
+)
+
+// Principal describes the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, e.g. a username or JWT "sub" claim
+	Subject string
+
+	// Scheme names the authentication method that produced the principal
+	Scheme string
+
+	Scopes []string
+	Roles  []string
+	Claims map[string]interface{}
+}
+
+type (
+	principalContextKey struct{}
+	logHolderKey        struct{}
+)
+
+// logHolder lets outer middleware (such as Logger) observe the principal,
+// tenant, experiment variants, trace and matched route established further
+// down the chain.
+type logHolder struct {
+	principal   *Principal
+	tenant      string
+	experiments []string // "experiment:variant"
+	route       *Route
+	params      map[string]string
+	trace       *TraceContext
+
+	// writeTimedOut is set when Route.WriteTimeout aborted the response
+	writeTimedOut bool
+}
+
+// GetPrincipal returns the authenticated principal stored in the context, or nil
+func GetPrincipal(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+// User returns the authenticated subject stored in the context
+func User(ctx context.Context) string {
+	if p := GetPrincipal(ctx); p != nil {
+		return p.Subject
+	}
+	return ""
+}
+
+// WithPrincipal returns a shallow copy of r carrying p in its context
+func WithPrincipal(r *http.Request, p *Principal) *http.Request {
+	ctx := r.Context()
+	if h, ok := ctx.Value(logHolderKey{}).(*logHolder); ok {
+		h.principal = p
+	}
+	return r.WithContext(context.WithValue(ctx, principalContextKey{}, p))
+}
+
+// BasicAuthValidator reports whether a username and password are valid
+type BasicAuthValidator func(username, password string) bool
+
+// BasicAuthUsers returns a validator backed by a static map of usernames to passwords
+func BasicAuthUsers(users map[string]string) BasicAuthValidator {
+	passwords := maps.Clone(users)
+
+	return func(username, password string) bool {
+		// Compared even when the user is unknown so lookups take the same time
+		expected, ok := passwords[username]
+		return secretsEqual(password, expected) && ok
+	}
+}
+
+// BasicAuth requires HTTP Basic credentials accepted by validator. The
+// authenticated user is available to handlers via User and GetPrincipal.
+func BasicAuth(realm string, validator BasicAuthValidator) func(http.Handler) http.Handler {
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if !ok || !validator(username, password) {
+				if ok {
+					authFailed(r, "basic", "invalid", username)
+				} else {
+					authFailed(r, "basic", "missing", "")
+				}
+				w.Header().Set("WWW-Authenticate", challenge)
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			authSucceeded(r, "basic", username)
+			next.ServeHTTP(w, WithPrincipal(r, &Principal{Subject: username, Scheme: "basic"}))
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Package auth implements OAuth2 authorization-code (with PKCE) and OpenID
+// Connect login flows on top of a GoFlow Mux.
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+// Config configures an Authenticator
+type Config struct {
+	// Providers available for login, addressed by Name
+	Providers []Provider
+
+	// Store persists established sessions. Defaults to a MemoryStore.
+	Store Store
+
+	// Secret signs the short-lived login state cookie
+	Secret []byte
+
+	// PreviousSecrets are still accepted while Secret is being rotated
+	PreviousSecrets [][]byte
+
+	// CookieName names the session cookie. Defaults to "goflow_session".
+	CookieName string
+
+	// CookieSecure marks cookies Secure; enable when served over HTTPS
+	CookieSecure bool
+
+	// SessionTTL is the lifetime of an established session. Defaults to 24 hours.
+	SessionTTL time.Duration
+
+	// RememberMe lets users stay logged in across sessions: logins started
+	// with ?remember=1 also set a long-lived, single-use token that is
+	// exchanged for a new session and a rotated token once the session
+	// expires. The Store must implement RememberStore.
+	RememberMe bool
+
+	// RememberTTL is the lifetime of a remember-me token. Defaults to 30 days.
+	RememberTTL time.Duration
+
+	// RememberCookieName names the remember-me cookie. Defaults to
+	// "goflow_remember".
+	RememberCookieName string
+
+	// AfterLogin and AfterLogout are the default redirect targets. Default to "/".
+	AfterLogin  string
+	AfterLogout string
+}
+
+// Authenticator serves login, callback and logout endpoints and establishes sessions
+type Authenticator struct {
+	config    Config
+	providers map[string]Provider
+	keys      *GoFlow.Keyring
+}
+
+// pendingLogin is carried in the signed state cookie between login and callback
+type pendingLogin struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	ReturnTo string `json:"r"`
+	Remember bool   `json:"m,omitempty"`
+}
+
+const stateCookieName = "goflow_oauth_state"
+
+// New creates an Authenticator from config
+func New(config Config) *Authenticator {
+	if len(config.Secret) == 0 {
+		panic("auth: Config.Secret is required")
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.CookieName == "" {
+		config.CookieName = "goflow_session"
+	}
+	if config.SessionTTL == 0 {
+		config.SessionTTL = 24 * time.Hour
+	}
+	if config.RememberMe {
+		if _, ok := config.Store.(RememberStore); !ok {
+			panic("auth: Config.RememberMe requires a Store implementing RememberStore")
+		}
+	}
+	if config.RememberTTL == 0 {
+		config.RememberTTL = 30 * 24 * time.Hour
+	}
+	if config.RememberCookieName == "" {
+		config.RememberCookieName = "goflow_remember"
+	}
+	if config.AfterLogin == "" {
+		config.AfterLogin = "/"
+	}
+	if config.AfterLogout == "" {
+		config.AfterLogout = "/"
+	}
+
+	providers := make(map[string]Provider, len(config.Providers))
+	for _, p := range config.Providers {
+		providers[p.Name()] = p
+	}
+	return &Authenticator{
+		config:    config,
+		providers: providers,
+		keys:      GoFlow.NewKeyring(config.Secret, config.PreviousSecrets...),
+	}
+}
+
+// Mount registers the login, callback and logout handlers under prefix:
+//
+//	GET  {prefix}/:provider/login
+//	GET  {prefix}/:provider/callback
+//	POST {prefix}/logout
+//	POST {prefix}/refresh (with Config.RememberMe)
+func (a *Authenticator) Mount(m *GoFlow.Mux, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	m.Handle(prefix+"/:provider/login", http.HandlerFunc(a.login), GoFlow.MethodGet)
+	m.Handle(prefix+"/:provider/callback", http.HandlerFunc(a.callback), GoFlow.MethodGet)
+	m.Handle(prefix+"/logout", http.HandlerFunc(a.logout), GoFlow.MethodPost)
+	if a.config.RememberMe {
+		m.Handle(prefix+"/refresh", http.HandlerFunc(a.refresh), GoFlow.MethodPost)
+	}
+}
+
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	provider, ok := a.providers[GoFlow.Param(r.Context(), "provider")]
+	if !ok {
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	pending := pendingLogin{
+		Provider: provider.Name(),
+		State:    randomString(24),
+		Nonce:    randomString(24),
+		Verifier: randomString(32),
+		ReturnTo: safeRedirect(r.URL.Query().Get("return_to"), a.config.AfterLogin),
+		Remember: a.config.RememberMe && r.URL.Query().Get("remember") == "1",
+	}
+	data, _ := json.Marshal(pending)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    a.keys.Sign(encodeCookie(data)),
+		Path:     "/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   a.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, provider.AuthCodeURL(pending.State, pending.Nonce, codeChallenge(pending.Verifier)), http.StatusFound)
+}
+
+func (a *Authenticator) callback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := a.providers[GoFlow.Param(r.Context(), "provider")]
+	if !ok {
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	pending, err := a.readPending(r)
+	a.clearCookie(w, stateCookieName)
+	if err != nil || pending.Provider != provider.Name() || pending.State != r.URL.Query().Get("state") {
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if e := r.URL.Query().Get("error"); e != "" {
+		log.Printf("auth: %s login failed: %s", provider.Name(), e)
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), pending.Verifier, pending.Nonce)
+	if err != nil {
+		log.Printf("auth: %s login failed: %v", provider.Name(), err)
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	sess, err := a.establish(w, r, &Session{
+		Provider: provider.Name(),
+		Subject:  identity.Subject,
+		Claims:   identity.Claims,
+		IDToken:  identity.IDToken,
+	})
+	if err == nil && pending.Remember {
+		err = a.remember(w, r, sess, "")
+	}
+	if err != nil {
+		log.Printf("auth: saving session: %v", err)
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, pending.ReturnTo, http.StatusFound)
+}
+
+// establish saves sess under a new ID and sets the session cookie
+func (a *Authenticator) establish(w http.ResponseWriter, r *http.Request, sess *Session) (*Session, error) {
+	sess.ID = randomString(32)
+	sess.Expires = time.Now().Add(a.config.SessionTTL)
+	if err := a.config.Store.Save(r.Context(), sess); err != nil {
+		return nil, err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.config.CookieName,
+		Value:    sess.ID,
+		Path:     "/",
+		Expires:  sess.Expires,
+		HttpOnly: true,
+		Secure:   a.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return sess, nil
+}
+
+func (a *Authenticator) logout(w http.ResponseWriter, r *http.Request) {
+	target := a.config.AfterLogout
+
+	if sess, err := a.session(r); err == nil {
+		a.config.Store.Delete(r.Context(), sess.ID)
+		if lp, ok := a.providers[sess.Provider].(LogoutProvider); ok && sess.IDToken != "" {
+			if u := lp.LogoutURL(sess.IDToken, ""); u != "" {
+				target = u
+			}
+		}
+	}
+
+	a.forget(w, r)
+	a.clearCookie(w, a.config.CookieName)
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// Middleware loads the session for each request and exposes it as the
+// GoFlow principal. Expired sessions are renewed with the remember-me
+// token if there is one. Requests without a session pass through
+// unauthenticated.
+func (a *Authenticator) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sess, err := a.session(r)
+			if err != nil {
+				sess, err = a.Refresh(w, r)
+				if err != nil && err != ErrSessionNotFound {
+					log.Printf("auth: refreshing session: %v", err)
+				}
+			}
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, GoFlow.WithPrincipal(r, &GoFlow.Principal{
+				Subject: sess.Subject,
+				Scheme:  "session",
+				Roles:   stringList(sess.Claims["roles"]),
+				Claims:  sess.Claims,
+			}))
+		})
+	}
+}
+
+// RequireLogin redirects requests without a session to the provider's login endpoint
+func (a *Authenticator) RequireLogin(loginURL string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if GoFlow.GetPrincipal(r.Context()) == nil {
+				if _, err := a.session(r); err != nil {
+					http.Redirect(w, r, loginURL+"?return_to="+url.QueryEscape(GoFlow.BasePath(r.Context())+r.URL.RequestURI()), http.StatusFound)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (a *Authenticator) session(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(a.config.CookieName)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	return a.config.Store.Get(r.Context(), c.Value)
+}
+
+func (a *Authenticator) readPending(r *http.Request) (*pendingLogin, error) {
+	c, err := r.Cookie(stateCookieName)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := a.keys.Verify(c.Value)
+	if !ok {
+		return nil, errors.New("auth: state cookie signature mismatch")
+	}
+	data, err := decodeCookie(value)
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingLogin
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func (a *Authenticator) clearCookie(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.config.CookieSecure,
+	})
+}
+
+// safeRedirect only accepts local paths to avoid open redirects
+func safeRedirect(target, fallback string) string {
+	if target == "" || !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return fallback
+	}
+	return target
+}
+
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+// fakeIssuer is a minimal OpenID Connect provider for exercising the login flow
+type fakeIssuer struct {
+	*httptest.Server
+	key       *ecdsa.PrivateKey
+	challenge string
+	nonce     string
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	f := &fakeIssuer{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+			"end_session_endpoint":   f.URL + "/logout",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "EC", "crv": "P-256", "kid": "k1",
+			"x": base64.RawURLEncoding.EncodeToString(key.X.Bytes()),
+			"y": base64.RawURLEncoding.EncodeToString(key.Y.Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || codeChallenge(r.Form.Get("code_verifier")) != f.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Token{
+			AccessToken: "at",
+			IDToken: f.sign(t, map[string]interface{}{
+				"iss": f.URL, "aud": "client", "sub": "user-42", "nonce": f.nonce,
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}),
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeIssuer) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "k1"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, f.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.Close()
+
+	provider, err := Discover(context.Background(), "test", issuer.URL, OAuth2Config{
+		ClientID:    "client",
+		RedirectURL: "http://app.local/auth/test/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := New(Config{Providers: []Provider{provider}, Secret: []byte("state-secret")})
+	mux := GoFlow.New()
+	a.Mount(mux, "/auth")
+	mux.Handle("/me", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GoFlow.User(r.Context())))
+	}), GoFlow.MethodGet).With(a.Middleware())
+
+	// Login redirects to the provider with PKCE parameters
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(GoFlow.MethodGet, "/auth/test/login?return_to=/me", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status code %d, got %d", http.StatusFound, w.Code)
+	}
+	location, _ := url.Parse(w.Header().Get("Location"))
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("state") == "" {
+		t.Fatalf("Missing PKCE or state parameters in %s", location)
+	}
+	issuer.challenge = query.Get("code_challenge")
+	issuer.nonce = query.Get("nonce")
+	stateCookie := w.Result().Cookies()[0]
+
+	t.Run("Rejects Mismatched State", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(GoFlow.MethodGet, "/auth/test/callback?code=good-code&state=forged", nil)
+		r.AddCookie(stateCookie)
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	// Callback exchanges the code and establishes a session
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(GoFlow.MethodGet, "/auth/test/callback?code=good-code&state="+query.Get("state"), nil)
+	r.AddCookie(stateCookie)
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/me" {
+		t.Fatalf("Expected redirect to /me, got %d %s: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+
+	var sessionCookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "goflow_session" {
+			sessionCookie = c
+		}
+	}
+	if sessionCookie == nil {
+		t.Fatal("Session cookie was not set")
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(GoFlow.MethodGet, "/me", nil)
+	r.AddCookie(sessionCookie)
+	mux.ServeHTTP(w, r)
+	if w.Body.String() != "user-42" {
+		t.Errorf("Expected user 'user-42', got '%s'", w.Body.String())
+	}
+
+	// Logout removes the session and redirects to the provider's end-session endpoint
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(GoFlow.MethodPost, "/auth/logout", nil)
+	r.AddCookie(sessionCookie)
+	mux.ServeHTTP(w, r)
+	if loc := w.Header().Get("Location"); len(loc) < len(issuer.URL) || loc[:len(issuer.URL)] != issuer.URL {
+		t.Errorf("Expected redirect to provider logout, got '%s'", loc)
+	}
+	if _, err := a.config.Store.Get(context.Background(), sessionCookie.Value); err != ErrSessionNotFound {
+		t.Error("Expected session to be deleted")
+	}
+}
+
+func TestSubjectClaim(t *testing.T) {
+	tests := []struct {
+		claims   map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"id": "octocat"}, "octocat"},
+		{map[string]interface{}{"id": float64(12345678)}, "12345678"},
+		{map[string]interface{}{"id": json.Number("98765432109")}, "98765432109"},
+		{map[string]interface{}{"id": ""}, ""},
+		{map[string]interface{}{"login": "octocat"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := subjectClaim(tt.claims, "id")
+		if got != tt.expected || (err == nil) != (tt.expected != "") {
+			t.Errorf("subjectClaim(%v) = %q, %v; want %q", tt.claims, got, err, tt.expected)
+		}
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := map[string]string{
+		"/dashboard":           "/dashboard",
+		"//evil.example":       "/",
+		"https://evil.example": "/",
+		"":                     "/",
+	}
+	for in, expected := range tests {
+		if got := safeRedirect(in, "/"); got != expected {
+			t.Errorf("safeRedirect(%q) = %q, want %q", in, got, expected)
+		}
+	}
+}
+
+func TestRememberMe(t *testing.T) {
+	a := New(Config{Secret: []byte("state-secret"), RememberMe: true})
+	store := a.config.Store.(*MemoryStore)
+	mux := GoFlow.New()
+	a.Mount(mux, "/auth")
+	mux.Handle("/me", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(GoFlow.User(r.Context())))
+	}), GoFlow.MethodGet).With(a.Middleware())
+
+	cookies := func(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+		out := map[string]*http.Cookie{}
+		for _, c := range w.Result().Cookies() {
+			out[c.Name] = c
+		}
+		return out
+	}
+
+	w := httptest.NewRecorder()
+	if err := a.remember(w, httptest.NewRequest(GoFlow.MethodGet, "/", nil), &Session{Provider: "test", Subject: "user-42"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	first := cookies(w)["goflow_remember"]
+	for hash := range store.remember {
+		if hash == first.Value {
+			t.Error("Expected the token stored hashed")
+		}
+	}
+
+	// An expired session is renewed with the token, which is rotated
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(GoFlow.MethodGet, "/me", nil)
+	r.AddCookie(&http.Cookie{Name: "goflow_session", Value: "expired"})
+	r.AddCookie(first)
+	mux.ServeHTTP(w, r)
+	if w.Body.String() != "user-42" {
+		t.Fatalf("Expected user 'user-42', got '%s'", w.Body.String())
+	}
+	second := cookies(w)["goflow_remember"]
+	if cookies(w)["goflow_session"] == nil || second == nil || second.Value == first.Value {
+		t.Fatal("Expected a new session and a rotated token")
+	}
+
+	t.Run("Refresh Endpoint", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(GoFlow.MethodPost, "/auth/refresh", nil)
+		r.AddCookie(second)
+		mux.ServeHTTP(w, r)
+		if w.Code != http.StatusNoContent || cookies(w)["goflow_remember"] == nil {
+			t.Fatalf("Expected status code %d with a rotated token, got %d", http.StatusNoContent, w.Code)
+		}
+		second = cookies(w)["goflow_remember"]
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(GoFlow.MethodPost, "/auth/refresh", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d without a token, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("Reuse Revokes Family", func(t *testing.T) {
+		// Within the grace period a used token is only refused
+		r := httptest.NewRequest(GoFlow.MethodPost, "/auth/refresh", nil)
+		r.AddCookie(first)
+		if _, err := a.Refresh(httptest.NewRecorder(), r); err != ErrSessionNotFound {
+			t.Errorf("Expected ErrSessionNotFound within the grace period, got %v", err)
+		}
+
+		store.remember[hashToken(first.Value)].Used = time.Now().Add(-time.Hour)
+		r = httptest.NewRequest(GoFlow.MethodPost, "/auth/refresh", nil)
+		r.AddCookie(first)
+		if _, err := a.Refresh(httptest.NewRecorder(), r); err != ErrRememberTokenReused {
+			t.Errorf("Expected ErrRememberTokenReused, got %v", err)
+		}
+
+		r = httptest.NewRequest(GoFlow.MethodPost, "/auth/refresh", nil)
+		r.AddCookie(second)
+		if _, err := a.Refresh(httptest.NewRecorder(), r); err != ErrSessionNotFound {
+			t.Errorf("Expected the latest token revoked, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+// Token is the response of an OAuth2 token endpoint
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Identity is the user returned by a provider after a successful login
+type Identity struct {
+	Subject string
+	Claims  map[string]interface{}
+	IDToken string
+}
+
+// Provider performs the provider-specific parts of the authorization-code flow
+type Provider interface {
+	// Name identifies the provider in login and callback URLs
+	Name() string
+
+	// AuthCodeURL returns the authorization endpoint URL for a login attempt
+	AuthCodeURL(state, nonce, codeChallenge string) string
+
+	// Exchange redeems an authorization code and resolves the user's identity
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// LogoutProvider is implemented by providers supporting RP-initiated logout
+type LogoutProvider interface {
+	LogoutURL(idToken, postLogoutRedirect string) string
+}
+
+// OAuth2Config holds the endpoints and client credentials of a provider
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	// SubjectClaim is the userinfo field identifying the user. Defaults to "sub".
+	SubjectClaim string
+
+	Client *http.Client
+}
+
+// OAuth2Provider is a plain OAuth2 provider that resolves identities from a userinfo endpoint
+type OAuth2Provider struct {
+	name   string
+	config OAuth2Config
+}
+
+// NewOAuth2Provider creates a Provider for a non-OIDC OAuth2 service
+func NewOAuth2Provider(name string, config OAuth2Config) *OAuth2Provider {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.SubjectClaim == "" {
+		config.SubjectClaim = "sub"
+	}
+	return &OAuth2Provider{name: name, config: config}
+}
+
+func (p *OAuth2Provider) Name() string {
+	return p.name
+}
+
+func (p *OAuth2Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	return authCodeURL(p.config, state, nonce, codeChallenge)
+}
+
+func (p *OAuth2Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := exchange(ctx, p.config, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.UserInfoURL == "" {
+		return nil, errors.New("auth: provider has no userinfo endpoint")
+	}
+
+	claims, err := userInfo(ctx, p.config, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := subjectClaim(claims, p.config.SubjectClaim)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Subject: subject, Claims: claims}, nil
+}
+
+// subjectClaim reads the claim identifying the user. Numeric IDs, as some
+// providers send them, are formatted without an exponent.
+func subjectClaim(claims map[string]interface{}, name string) (string, error) {
+	var subject string
+	switch v := claims[name].(type) {
+	case string:
+		subject = v
+	case json.Number:
+		subject = v.String()
+	case float64:
+		subject = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if subject == "" {
+		return "", fmt.Errorf("auth: userinfo has no %q claim", name)
+	}
+	return subject, nil
+}
+
+// OIDCProvider is an OpenID Connect provider whose identity comes from a verified ID token
+type OIDCProvider struct {
+	name          string
+	config        OAuth2Config
+	endSessionURL string
+	verifier      *GoFlow.JWTVerifier
+}
+
+// Discover configures an OIDCProvider from the issuer's
+// /.well-known/openid-configuration document
+func Discover(ctx context.Context, name, issuer string, config OAuth2Config) (*OIDCProvider, error) {
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: discovery: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: discovery: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+		EndSessionEndpoint    string `json:"end_session_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("auth: discovery: %w", err)
+	}
+	if doc.Issuer != issuer {
+		return nil, fmt.Errorf("auth: discovery: issuer mismatch %q", doc.Issuer)
+	}
+
+	config.AuthURL = doc.AuthorizationEndpoint
+	config.TokenURL = doc.TokenEndpoint
+	config.UserInfoURL = doc.UserInfoEndpoint
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &OIDCProvider{
+		name:          name,
+		config:        config,
+		endSessionURL: doc.EndSessionEndpoint,
+		verifier: GoFlow.NewJWTVerifier(GoFlow.JWTOptions{
+			JWKSURL:    doc.JWKSURI,
+			Issuer:     doc.Issuer,
+			Audience:   config.ClientID,
+			Algorithms: []string{"RS256", "ES256"},
+			Leeway:     time.Minute,
+			Client:     config.Client,
+		}),
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	return authCodeURL(p.config, state, nonce, codeChallenge)
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	token, err := exchange(ctx, p.config, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("auth: token response has no id_token")
+	}
+
+	claims, err := p.verifier.Verify(token.IDToken)
+	if err != nil {
+		return nil, fmt.Errorf("auth: id_token: %w", err)
+	}
+	if claims["nonce"] != nonce {
+		return nil, errors.New("auth: id_token nonce mismatch")
+	}
+
+	sub, _ := claims["sub"].(string)
+	return &Identity{Subject: sub, Claims: claims, IDToken: token.IDToken}, nil
+}
+
+func (p *OIDCProvider) LogoutURL(idToken, postLogoutRedirect string) string {
+	if p.endSessionURL == "" {
+		return ""
+	}
+	v := url.Values{"id_token_hint": {idToken}}
+	if postLogoutRedirect != "" {
+		v.Set("post_logout_redirect_uri", postLogoutRedirect)
+	}
+	return p.endSessionURL + "?" + v.Encode()
+}
+
+func authCodeURL(c OAuth2Config, state, nonce, codeChallenge string) string {
+	v := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {c.RedirectURL},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	if len(c.Scopes) > 0 {
+		v.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	if nonce != "" {
+		v.Set("nonce", nonce)
+	}
+
+	sep := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		sep = "&"
+	}
+	return c.AuthURL + sep + v.Encode()
+}
+
+func exchange(ctx context.Context, c OAuth2Config, code, codeVerifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.RedirectURL},
+		"client_id":     {c.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: token exchange: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: token exchange: unexpected status %d", resp.StatusCode)
+	}
+
+	var token Token
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("auth: token exchange: %w", err)
+	}
+	return &token, nil
+}
+
+func userInfo(ctx context.Context, c OAuth2Config, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: userinfo: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: userinfo: unexpected status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("auth: userinfo: %w", err)
+	}
+	return claims, nil
+}
+
+// codeChallenge derives the PKCE S256 challenge for a verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+// ErrRememberTokenReused is returned when a remember-me token is presented
+// again after it was exchanged, suggesting it was stolen. Every token of its
+// family is revoked.
+var ErrRememberTokenReused = errors.New("auth: remember-me token reused")
+
+// rememberGrace is how long a used token may be presented again without
+// revoking its family, covering parallel requests sent with the same cookie
+const rememberGrace = 30 * time.Second
+
+// RememberToken is a long-lived login kept by a RememberStore. Only the hash
+// of the token is stored; the token itself lives in the client's cookie.
+type RememberToken struct {
+	Hash string
+
+	// Family is shared by a token and the tokens it was rotated into
+	Family string
+
+	Provider string
+	Subject  string
+	Claims   map[string]interface{}
+	IDToken  string
+	Expires  time.Time
+
+	// Used is when the token was exchanged; zero while it's still valid
+	Used time.Time
+}
+
+// RememberStore persists remember-me tokens. A Store implementing it
+// enables Config.RememberMe.
+type RememberStore interface {
+	SaveRemember(ctx context.Context, t *RememberToken) error
+
+	// ConsumeRemember marks the token with the hash used and returns it as
+	// it was before, so a token with Used set had already been exchanged.
+	// It returns ErrSessionNotFound for unknown or expired tokens.
+	ConsumeRemember(ctx context.Context, hash string) (*RememberToken, error)
+
+	// RevokeRemember deletes every token of a family
+	RevokeRemember(ctx context.Context, family string) error
+}
+
+func (s *MemoryStore) SaveRemember(ctx context.Context, t *RememberToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		// Used tokens are kept until they expire to detect reuse
+		for hash, t := range s.remember {
+			if now.After(t.Expires) {
+				delete(s.remember, hash)
+			}
+		}
+		s.nextSweep = now.Add(time.Minute)
+	}
+	s.remember[t.Hash] = t
+	return nil
+}
+
+func (s *MemoryStore) ConsumeRemember(ctx context.Context, hash string) (*RememberToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.remember[hash]
+	if !ok || time.Now().After(t.Expires) {
+		return nil, ErrSessionNotFound
+	}
+	before := *t
+	if t.Used.IsZero() {
+		t.Used = time.Now()
+	}
+	return &before, nil
+}
+
+func (s *MemoryStore) RevokeRemember(ctx context.Context, family string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, t := range s.remember {
+		if t.Family == family {
+			delete(s.remember, hash)
+		}
+	}
+	return nil
+}
+
+// Refresh exchanges the request's remember-me cookie for a new session and
+// a rotated token, replacing the current session if there is one. Mount
+// serves it as POST {prefix}/refresh for single-page apps; Middleware calls
+// it when a request's session has expired.
+func (a *Authenticator) Refresh(w http.ResponseWriter, r *http.Request) (*Session, error) {
+	store, ok := a.config.Store.(RememberStore)
+	if !ok || !a.config.RememberMe {
+		return nil, ErrSessionNotFound
+	}
+	c, err := r.Cookie(a.config.RememberCookieName)
+	if err != nil {
+		return nil, ErrSessionNotFound
+	}
+	t, err := store.ConsumeRemember(r.Context(), hashToken(c.Value))
+	if err != nil {
+		return nil, err
+	}
+	if !t.Used.IsZero() {
+		if time.Since(t.Used) < rememberGrace {
+			return nil, ErrSessionNotFound
+		}
+		store.RevokeRemember(r.Context(), t.Family)
+		a.clearCookie(w, a.config.RememberCookieName)
+		return nil, ErrRememberTokenReused
+	}
+
+	if old, err := a.session(r); err == nil {
+		a.config.Store.Delete(r.Context(), old.ID)
+	}
+	sess, err := a.establish(w, r, &Session{
+		Provider: t.Provider,
+		Subject:  t.Subject,
+		Claims:   t.Claims,
+		IDToken:  t.IDToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := a.remember(w, r, sess, t.Family); err != nil {
+		return nil, err
+	}
+	return sess, nil
+}
+
+func (a *Authenticator) refresh(w http.ResponseWriter, r *http.Request) {
+	if _, err := a.Refresh(w, r); err != nil {
+		if err != ErrSessionNotFound {
+			log.Printf("auth: refreshing session: %v", err)
+		}
+		http.Error(w, GoFlow.StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// remember issues a remember-me token for sess, starting a new family when
+// family is empty
+func (a *Authenticator) remember(w http.ResponseWriter, r *http.Request, sess *Session, family string) error {
+	if family == "" {
+		family = randomString(16)
+	}
+	token := randomString(32)
+	t := &RememberToken{
+		Hash:     hashToken(token),
+		Family:   family,
+		Provider: sess.Provider,
+		Subject:  sess.Subject,
+		Claims:   sess.Claims,
+		IDToken:  sess.IDToken,
+		Expires:  time.Now().Add(a.config.RememberTTL),
+	}
+	if err := a.config.Store.(RememberStore).SaveRemember(r.Context(), t); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     a.config.RememberCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  t.Expires,
+		HttpOnly: true,
+		Secure:   a.config.CookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// forget revokes the family of the request's remember-me token
+func (a *Authenticator) forget(w http.ResponseWriter, r *http.Request) {
+	store, ok := a.config.Store.(RememberStore)
+	if !ok {
+		return
+	}
+	if c, err := r.Cookie(a.config.RememberCookieName); err == nil {
+		if t, err := store.ConsumeRemember(r.Context(), hashToken(c.Value)); err == nil {
+			store.RevokeRemember(r.Context(), t.Family)
+		}
+		a.clearCookie(w, a.config.RememberCookieName)
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned by a Store when no session exists for an ID
+var ErrSessionNotFound = errors.New("auth: session not found")
+
+// Session is an established login
+type Session struct {
+	ID       string
+	Provider string
+	Subject  string
+	Claims   map[string]interface{}
+	IDToken  string
+	Expires  time.Time
+}
+
+// Store persists sessions
+type Store interface {
+	Get(ctx context.Context, id string) (*Session, error)
+	Save(ctx context.Context, s *Session) error
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryStore is an in-process Store suitable for single-instance deployments
+type MemoryStore struct {
+	mu        sync.RWMutex
+	sessions  map[string]*Session
+	remember  map[string]*RememberToken
+	nextSweep time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*Session),
+		remember: make(map[string]*RememberToken),
+	}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.RLock()
+	sess, ok := s.sessions[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if time.Now().After(sess.Expires) {
+		s.Delete(ctx, id)
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, sess *Session) error {
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// randomString returns n random bytes encoded as unpadded base64url
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func encodeCookie(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCookie(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(value)
+}
@@ -0,0 +1,76 @@
+package GoFlow
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	var capturedUser string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedUser = User(r.Context())
+	})
+
+	t.Run("Static Users", func(t *testing.T) {
+		protected := BasicAuth("admin", BasicAuthUsers(map[string]string{"alice": "s3cret"}))(handler)
+
+		tests := []struct {
+			user, pass string
+			expected   int
+		}{
+			{"alice", "s3cret", http.StatusOK},
+			{"alice", "wrong", http.StatusUnauthorized},
+			{"bob", "s3cret", http.StatusUnauthorized},
+		}
+
+		for _, tt := range tests {
+			capturedUser = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.SetBasicAuth(tt.user, tt.pass)
+			protected.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s/%s: expected status code %d, got %d", tt.user, tt.pass, tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusOK && capturedUser != tt.user {
+				t.Errorf("Expected user '%s', got '%s'", tt.user, capturedUser)
+			}
+		}
+	})
+
+	t.Run("Missing Credentials", func(t *testing.T) {
+		protected := BasicAuth("api", func(u, p string) bool { return true })(handler)
+
+		w := httptest.NewRecorder()
+		protected.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if got := w.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, `Basic realm="api"`) {
+			t.Errorf("Unexpected WWW-Authenticate header '%s'", got)
+		}
+	})
+
+	t.Run("Logger Records User", func(t *testing.T) {
+		var buf bytes.Buffer
+		orig := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(orig)
+
+		protected := Logger()(BasicAuth("api", func(u, p string) bool { return true })(handler))
+
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.SetBasicAuth("carol", "x")
+		protected.ServeHTTP(httptest.NewRecorder(), r)
+
+		if !strings.Contains(buf.String(), " carol GET /") {
+			t.Errorf("Expected log line to contain user, got '%s'", buf.String())
+		}
+	})
+}
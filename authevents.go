@@ -0,0 +1,111 @@
+package GoFlow
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"math/rand/v2"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// AuthEvent describes an authentication attempt, passed to OnAuthEvent
+// hooks, e.g. to feed a SIEM pipeline
+type AuthEvent struct {
+	Time time.Time
+
+	// Scheme names the middleware: "basic", "jwt", "apikey", "signature",
+	// "webhook" or "signed_url"
+	Scheme  string
+	Success bool
+
+	// Reason says why a failed attempt was rejected: "missing", "invalid"
+	// or "expired"
+	Reason string
+
+	// Subject is the authenticated or claimed identity, when known, such
+	// as a Basic username or API key ID. Secrets are never included.
+	Subject string
+
+	// Route is the matched route's pattern
+	Route      string
+	RemoteAddr string
+	Request    *http.Request
+}
+
+// OnAuthEvent registers fn to run for every authentication attempt the
+// auth middleware on the mux's routes see, successful or not. Hooks run in
+// registration order on the request's goroutine.
+func (m *Mux) OnAuthEvent(fn func(event AuthEvent)) {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+	var auth []func(AuthEvent)
+	if current := m.hooks.auth.Load(); current != nil {
+		auth = append(auth, *current...)
+	}
+	auth = append(auth, fn)
+	m.hooks.auth.Store(&auth)
+}
+
+// authFailureDelay is the longest delay SetAuthFailureDelay added
+var authFailureDelay atomic.Int64
+
+// SetAuthFailureDelay delays every rejected authentication attempt by a
+// random duration between d/2 and d before it is answered, slowing down
+// credential guessing and blurring timing differences between failure
+// paths. Zero, the default, disables the delay.
+func SetAuthFailureDelay(d time.Duration) {
+	authFailureDelay.Store(int64(d))
+}
+
+// authSucceeded reports a successful attempt to the OnAuthEvent hooks
+func authSucceeded(r *http.Request, scheme, subject string) {
+	emitAuthEvent(r, AuthEvent{Scheme: scheme, Success: true, Subject: subject})
+}
+
+// authFailed counts a rejected attempt against the route, reports it to
+// the OnAuthEvent hooks and waits out the failure delay. The caller then
+// answers the request.
+func authFailed(r *http.Request, scheme, reason, subject string) {
+	if rt := CurrentRoute(r.Context()); rt != nil {
+		rt.authFailures.Add(1)
+	}
+	debugNote(r.Context(), "auth: %s %s", scheme, reason)
+	emitAuthEvent(r, AuthEvent{Scheme: scheme, Reason: reason, Subject: subject})
+
+	if max := time.Duration(authFailureDelay.Load()); max > 0 {
+		delay := max/2 + rand.N(max/2+1)
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+		}
+	}
+}
+
+func emitAuthEvent(r *http.Request, event AuthEvent) {
+	rt := CurrentRoute(r.Context())
+	if rt == nil || rt.mux == nil || rt.mux.hooks == nil {
+		return
+	}
+	hooks := rt.mux.hooks.auth.Load()
+	if hooks == nil {
+		return
+	}
+	event.Time = time.Now()
+	event.Route = rt.pattern
+	event.RemoteAddr = r.RemoteAddr
+	event.Request = r
+	for _, fn := range *hooks {
+		fn(event)
+	}
+}
+
+// secretsEqual compares a presented secret with the expected one in time
+// that depends on neither their contents nor their lengths
+func secretsEqual(given, expected string) bool {
+	g := sha256.Sum256([]byte(given))
+	e := sha256.Sum256([]byte(expected))
+	return subtle.ConstantTimeCompare(g[:], e[:]) == 1
+}
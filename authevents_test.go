@@ -0,0 +1,119 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuthEvents(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	var mu sync.Mutex
+	var events []AuthEvent
+	mux := New()
+	mux.OnAuthEvent(func(e AuthEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	})
+	mux.Group(func(m *Mux) {
+		m.Use(BasicAuth("admin", BasicAuthUsers(map[string]string{"alice": "s3cret"})))
+		m.Handle("/auth-events/basic", ok, MethodGet)
+	})
+	mux.Group(func(m *Mux) {
+		m.Use(APIKeyAuth(APIKeyOptions{Store: StaticKeys(map[string]APIKey{"k1": {ID: "ci"}})}))
+		m.Handle("/auth-events/key", ok, MethodGet)
+	})
+
+	serve := func(path string, setup func(r *http.Request)) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, path, nil)
+		setup(r)
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+	last := func() AuthEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(events) == 0 {
+			t.Fatal("Expected an auth event")
+		}
+		return events[len(events)-1]
+	}
+
+	t.Run("Basic Auth", func(t *testing.T) {
+		serve("/auth-events/basic", func(r *http.Request) { r.SetBasicAuth("alice", "wrong") })
+		e := last()
+		if e.Success || e.Scheme != "basic" || e.Reason != "invalid" || e.Subject != "alice" {
+			t.Errorf("Expected an invalid basic attempt by alice, got %+v", e)
+		}
+		if e.Route != "/auth-events/basic" || e.RemoteAddr == "" || e.Time.IsZero() {
+			t.Errorf("Expected the route, address and time set, got %+v", e)
+		}
+
+		serve("/auth-events/basic", func(r *http.Request) {})
+		if e := last(); e.Reason != "missing" || e.Subject != "" {
+			t.Errorf("Expected a missing attempt, got %+v", e)
+		}
+
+		serve("/auth-events/basic", func(r *http.Request) { r.SetBasicAuth("alice", "s3cret") })
+		if e := last(); !e.Success || e.Subject != "alice" {
+			t.Errorf("Expected a successful attempt by alice, got %+v", e)
+		}
+	})
+
+	t.Run("API Keys", func(t *testing.T) {
+		serve("/auth-events/key", func(r *http.Request) { r.Header.Set("X-API-Key", "nope") })
+		if e := last(); e.Success || e.Scheme != "apikey" || e.Reason != "invalid" {
+			t.Errorf("Expected an invalid apikey attempt, got %+v", e)
+		}
+
+		serve("/auth-events/key", func(r *http.Request) { r.Header.Set("X-API-Key", "k1") })
+		if e := last(); !e.Success || e.Subject != "ci" {
+			t.Errorf("Expected a successful attempt by key ci, got %+v", e)
+		}
+	})
+
+	t.Run("Failure Counters", func(t *testing.T) {
+		failures := map[string]uint64{}
+		for _, ms := range Stats().Muxes {
+			for _, f := range ms.AuthFailures {
+				failures[f.Pattern] = f.Failures
+			}
+		}
+		if failures["/auth-events/basic"] != 2 || failures["/auth-events/key"] != 1 {
+			t.Errorf("Expected 2 basic and 1 key failures, got %v", failures)
+		}
+	})
+
+	t.Run("Failure Delay", func(t *testing.T) {
+		SetAuthFailureDelay(40 * time.Millisecond)
+		defer SetAuthFailureDelay(0)
+
+		start := time.Now()
+		if code := serve("/auth-events/key", func(r *http.Request) {}); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("Expected the failure delayed by at least 20ms, took %v", elapsed)
+		}
+
+		start = time.Now()
+		serve("/auth-events/key", func(r *http.Request) { r.Header.Set("X-API-Key", "k1") })
+		if elapsed := time.Since(start); elapsed >= 20*time.Millisecond {
+			t.Errorf("Expected successful attempts not delayed, took %v", elapsed)
+		}
+	})
+}
+
+func TestSecretsEqual(t *testing.T) {
+	if !secretsEqual("s3cret", "s3cret") {
+		t.Error("Expected equal secrets to match")
+	}
+	if secretsEqual("s3cret", "s3cre") || secretsEqual("", "s3cret") {
+		t.Error("Expected different secrets not to match")
+	}
+}
@@ -0,0 +1,59 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AuthorizeFunc decides whether principal may perform the request. It is the
+// escape hatch for plugging in custom policy engines.
+type AuthorizeFunc func(r *http.Request, principal *Principal) bool
+
+// Authorize rejects requests that fn does not permit. Unauthenticated requests
+// get 401 and unauthorized ones 403, both as problem+json.
+func Authorize(fn AuthorizeFunc) func(http.Handler) http.Handler {
+	return authorize(fn, "")
+}
+
+// RequireScopes only admits principals holding every one of scopes
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return authorize(func(r *http.Request, p *Principal) bool {
+		for _, scope := range scopes {
+			if !contains(p.Scopes, scope) {
+				return false
+			}
+		}
+		return true
+	}, "Missing required scope: "+strings.Join(scopes, " "))
+}
+
+// RequireRole only admits principals holding at least one of roles
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return authorize(func(r *http.Request, p *Principal) bool {
+		for _, role := range roles {
+			if contains(p.Roles, role) {
+				return true
+			}
+		}
+		return false
+	}, "Requires role: "+strings.Join(roles, ", "))
+}
+
+func authorize(fn AuthorizeFunc, detail string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := GetPrincipal(r.Context())
+			if principal == nil {
+				WriteProblem(w, Problem{Status: http.StatusUnauthorized, Instance: r.URL.Path})
+				return
+			}
+
+			if !fn(r, principal) {
+				WriteProblem(w, Problem{Status: http.StatusForbidden, Detail: detail, Instance: r.URL.Path})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorization(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	withPrincipal := func(p *Principal, next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p != nil {
+				r = WithPrincipal(r, p)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	writer := &Principal{Subject: "svc", Scopes: []string{"orders:read", "orders:write"}}
+	reader := &Principal{Subject: "web", Scopes: []string{"orders:read"}, Roles: []string{"admin"}}
+
+	tests := []struct {
+		name      string
+		mw        func(http.Handler) http.Handler
+		principal *Principal
+		expected  int
+	}{
+		{"scopes granted", RequireScopes("orders:read", "orders:write"), writer, http.StatusOK},
+		{"scope missing", RequireScopes("orders:write"), reader, http.StatusForbidden},
+		{"anonymous", RequireScopes("orders:read"), nil, http.StatusUnauthorized},
+		{"role granted", RequireRole("admin", "ops"), reader, http.StatusOK},
+		{"role missing", RequireRole("admin"), writer, http.StatusForbidden},
+		{"custom policy", Authorize(func(r *http.Request, p *Principal) bool {
+			return p.Subject == "svc" && r.Method == MethodGet
+		}), writer, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			withPrincipal(tt.principal, tt.mw(ok)).ServeHTTP(w, httptest.NewRequest(MethodGet, "/orders", nil))
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+			}
+			if tt.expected == http.StatusOK {
+				return
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("Expected problem+json content type, got '%s'", ct)
+			}
+			var p Problem
+			if err := json.NewDecoder(w.Body).Decode(&p); err != nil || p.Status != tt.expected {
+				t.Errorf("Unexpected problem body: %+v (%v)", p, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type basePathKey struct{}
+
+// SetBasePath serves the mux under base, for deployments behind an ingress
+// that forwards /service-a/* unchanged. The base is stripped from request
+// paths before matching, so patterns don't repeat it, and requests outside
+// it are not found. Route.URL and GoFlow's redirects add it back.
+func (m *Mux) SetBasePath(base string) {
+	if base = "/" + strings.Trim(base, "/"); base == "/" {
+		base = ""
+	}
+	m.table.base = base
+}
+
+// BasePath returns the base path stripped from the request, or ""
+func BasePath(ctx context.Context) string {
+	base, _ := ctx.Value(basePathKey{}).(string)
+	return base
+}
+
+// stripBasePath returns a shallow copy of r without base in its URL, or
+// false if the path is outside base
+func stripBasePath(r *http.Request, base string) (*http.Request, bool) {
+	rest, ok := strings.CutPrefix(r.URL.Path, base)
+	if !ok || rest != "" && rest[0] != '/' {
+		return r, false
+	}
+	u := *r.URL
+	u.Path = rest
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if u.RawPath != "" {
+		if raw, ok := strings.CutPrefix(u.RawPath, base); ok && raw != "" {
+			u.RawPath = raw
+		} else {
+			u.RawPath = ""
+		}
+	}
+	r = r.WithContext(context.WithValue(r.Context(), basePathKey{}, base))
+	r.URL = &u
+	return r, true
+}
+
+// URL builds the path of the route from name/value pairs, e.g.
+// rt.URL("id", "42") for /users/:id, including the base path set with
+// SetBasePath. Values are escaped and must satisfy their constraints; the
+// wildcard is filled from "..." with its slashes kept.
+func (rt *Route) URL(pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", errors.New("GoFlow: URL takes name/value pairs")
+	}
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	var b strings.Builder
+	if rt.table != nil {
+		b.WriteString(rt.table.base)
+	}
+	for _, segment := range strings.Split(rt.pattern, "/") {
+		switch {
+		case segment == "":
+		case segment == "...":
+			for _, part := range strings.Split(values["..."], "/") {
+				if part != "" {
+					b.WriteString("/" + url.PathEscape(part))
+				}
+			}
+			delete(values, "...")
+		case strings.HasPrefix(segment, ":"):
+			name, rx, hasRx := strings.Cut(segment[1:], "|")
+			v, ok := values[name]
+			if !ok || v == "" {
+				return "", fmt.Errorf("GoFlow: %s: missing parameter :%s", rt.pattern, name)
+			}
+			if hasRx {
+				if m, err := compileMatcher(rx); err != nil || !m.MatchString(v) {
+					return "", fmt.Errorf("GoFlow: %s: %q doesn't match :%s", rt.pattern, v, segment[1:])
+				}
+			}
+			b.WriteString("/" + url.PathEscape(v))
+			delete(values, name)
+		default:
+			b.WriteString("/" + segment)
+		}
+	}
+	for name := range values {
+		return "", fmt.Errorf("GoFlow: %s: unknown parameter :%s", rt.pattern, name)
+	}
+	if b.Len() == 0 {
+		return "/", nil
+	}
+	return b.String(), nil
+}
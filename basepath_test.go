@@ -0,0 +1,71 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasePath(t *testing.T) {
+	mux := New()
+	mux.SetBasePath("/service-a/")
+	var users, files *Route
+	mux.Group(func(m *Mux) {
+		users = m.Handle("/users/:id|\\d+", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.URL.Path + " " + Param(r.Context(), "id") + " " + BasePath(r.Context())))
+		}), MethodGet)
+	})
+	files = mux.Handle("/files/...", http.NotFoundHandler(), MethodGet)
+	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("root " + r.URL.Path))
+	}), MethodGet)
+
+	t.Run("Stripping", func(t *testing.T) {
+		tests := []struct {
+			path string
+			code int
+			body string
+		}{
+			{"/service-a/users/42", http.StatusOK, "/users/42 42 /service-a"},
+			{"/service-a", http.StatusOK, "root /"},
+			{"/service-a/", http.StatusOK, "root /"},
+			{"/users/42", http.StatusNotFound, ""},
+			{"/service-ab/users/42", http.StatusNotFound, ""},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.path, nil))
+			if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("%s: expected %d '%s', got %d '%s'", tt.path, tt.code, tt.body, w.Code, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("URL", func(t *testing.T) {
+		if u, err := users.URL("id", "42"); err != nil || u != "/service-a/users/42" {
+			t.Errorf("Expected /service-a/users/42, got '%s' %v", u, err)
+		}
+		if u, err := files.URL("...", "a b/c.txt"); err != nil || u != "/service-a/files/a%20b/c.txt" {
+			t.Errorf("Expected an escaped wildcard, got '%s' %v", u, err)
+		}
+		for _, pairs := range [][]string{{"id", "x"}, {}, {"id", "1", "other", "2"}, {"id"}} {
+			if _, err := users.URL(pairs...); err == nil || !strings.HasPrefix(err.Error(), "GoFlow: ") {
+				t.Errorf("Expected an error for %v, got %v", pairs, err)
+			}
+		}
+	})
+
+	t.Run("Redirects", func(t *testing.T) {
+		loc := New()
+		loc.SetBasePath("/svc")
+		loc.Localized([]string{"en"}, func(m *Mux) {
+			m.Handle("/about", http.NotFoundHandler(), MethodGet)
+		})
+		w := httptest.NewRecorder()
+		loc.ServeHTTP(w, httptest.NewRequest(MethodGet, "/svc/about?x=1", nil))
+		if w.Header().Get("Location") != "/svc/en/about?x=1" {
+			t.Errorf("Expected redirect within the base path, got '%s'", w.Header().Get("Location"))
+		}
+	})
+}
@@ -3,7 +3,9 @@ package GoFlow
 import (
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func BenchmarkRouteMatch(b *testing.B) {
@@ -198,3 +200,108 @@ func BenchmarkNotFound(b *testing.B) {
 		mux.ServeHTTP(w, r)
 	}
 }
+
+func BenchmarkLargeRouteTable(b *testing.B) {
+	mux := New()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for i := 0; i < 1000; i++ {
+		n := toString(i)
+		mux.Handle("/api/v1/resource"+n, handler, "GET")
+		mux.Handle("/api/v1/resource"+n+"/:id", handler, "GET")
+		mux.Handle("/api/v1/resource"+n+"/:id/items/:item", handler, "GET")
+	}
+
+	for _, path := range []string{"/api/v1/resource999", "/api/v1/resource500/42", "/api/v1/resource123/42/items/7"} {
+		b.Run(path, func(b *testing.B) {
+			r := httptest.NewRequest("GET", path, nil)
+			w := httptest.NewRecorder()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				mux.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func BenchmarkServeAllocs(b *testing.B) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	plain := New()
+	plain.Handle("/users", handler, "GET")
+	plain.Handle("/users/:id", handler, "GET")
+
+	hooked := New()
+	hooked.OnRequestDone(func(RequestInfo) {})
+	hooked.Handle("/users", handler, "GET")
+
+	tests := []struct {
+		name string
+		mux  *Mux
+		path string
+	}{
+		{"Static", plain, "/users"},
+		{"StaticTrailingSlash", plain, "/users/"},
+		{"Parameter", plain, "/users/123"},
+		{"NotFound", plain, "/missing"},
+		{"RequestDoneHook", hooked, "/users"},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tt.mux.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func BenchmarkRateLimiter(b *testing.B) {
+	keys := make([]string, 1<<16)
+	for i := range keys {
+		keys[i] = "10.0." + toString(i>>8) + "." + toString(i&0xff)
+	}
+
+	b.Run("HotKey", func(b *testing.B) {
+		limiter := NewRateLimiter(1<<30, time.Hour, 0)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				limiter.Allow("10.0.0.1")
+			}
+		})
+	})
+
+	b.Run("HotKeyThrottled", func(b *testing.B) {
+		limiter := NewRateLimiter(1, time.Hour, 0)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				limiter.Allow("10.0.0.1")
+			}
+		})
+	})
+
+	b.Run("HighCardinality", func(b *testing.B) {
+		limiter := NewRateLimiter(100, time.Hour, 10)
+		var next atomic.Uint64
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				limiter.Allow(keys[next.Add(1)&uint64(len(keys)-1)])
+			}
+		})
+	})
+
+	b.Run("Shard", func(b *testing.B) {
+		limiter := NewRateLimiter(100, time.Hour, 10)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			limiter.getShard(keys[i&(len(keys)-1)])
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package GoFlow
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BodyLimitOptions configures the BodyLimit middleware
+type BodyLimitOptions struct {
+	// Limit is the maximum request body size, e.g. "10MB"
+	Limit string
+
+	// MultipartLimit applies to multipart/form-data uploads. Defaults to Limit.
+	MultipartLimit string
+
+	// Exceeded handles requests whose declared length is over the limit. Defaults to 413.
+	Exceeded http.Handler
+}
+
+type bodyLimitKey struct{}
+
+// BodyLimit caps request bodies at limit (e.g. "10MB"). Requests declaring a
+// larger Content-Length are rejected with 413 before the handler runs; streamed
+// bodies fail on read once the limit is crossed (see IsBodyTooLarge).
+func BodyLimit(limit string) func(http.Handler) http.Handler {
+	return BodyLimitWithOptions(BodyLimitOptions{Limit: limit})
+}
+
+// BodyLimitWithOptions is BodyLimit with a separate multipart limit and rejection handler
+func BodyLimitWithOptions(opts BodyLimitOptions) func(http.Handler) http.Handler {
+	limit := mustParseSize(opts.Limit)
+	multipartLimit := limit
+	if opts.MultipartLimit != "" {
+		multipartLimit = mustParseSize(opts.MultipartLimit)
+	}
+
+	exceeded := opts.Exceeded
+	if exceeded == nil {
+		exceeded = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, StatusText(r.Context(), http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			max := limit
+			if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+				max = multipartLimit
+			}
+			if rt := CurrentRoute(r.Context()); rt != nil {
+				if override, ok := rt.Value(bodyLimitKey{}).(int64); ok {
+					max = override
+				}
+			}
+
+			if r.ContentLength > max {
+				w.Header().Set("Connection", "close")
+				exceeded.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, max)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit overrides the BodyLimit middleware's maximum for this route
+func (rt *Route) BodyLimit(limit string) *Route {
+	return rt.Set(bodyLimitKey{}, mustParseSize(limit))
+}
+
+// IsBodyTooLarge reports whether err was caused by reading past the body limit.
+// Handlers should answer such errors with 413.
+func IsBodyTooLarge(err error) bool {
+	var maxErr *http.MaxBytesError
+	return errors.As(err, &maxErr)
+}
+
+// ParseSize parses human-readable byte sizes such as "512", "64KB" or "1.5GB".
+// Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		size   float64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+		{"B", 1},
+	}
+
+	multiplier := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(str, u.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u.suffix))
+			multiplier = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(str, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("GoFlow: invalid size %q", s)
+	}
+	return int64(n * multiplier), nil
+}
+
+func mustParseSize(s string) int64 {
+	n, err := ParseSize(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+package GoFlow
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	readBody := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			if IsBodyTooLarge(err) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Parse Size", func(t *testing.T) {
+		tests := map[string]int64{
+			"512":   512,
+			"64KB":  64 << 10,
+			"10MB":  10 << 20,
+			"1.5gb": 3 << 29,
+			"2 MiB": 2 << 20,
+		}
+		for in, expected := range tests {
+			if got, err := ParseSize(in); err != nil || got != expected {
+				t.Errorf("ParseSize(%q) = %d, %v; want %d", in, got, err, expected)
+			}
+		}
+		if _, err := ParseSize("ten"); err == nil {
+			t.Error("Expected error for invalid size")
+		}
+	})
+
+	t.Run("Declared And Streamed Bodies", func(t *testing.T) {
+		handler := BodyLimit("16B")(readBody)
+
+		tests := []struct {
+			name     string
+			body     string
+			chunked  bool
+			expected int
+		}{
+			{"within limit", "small", false, http.StatusOK},
+			{"declared too large", strings.Repeat("x", 32), false, http.StatusRequestEntityTooLarge},
+			{"streamed too large", strings.Repeat("x", 32), true, http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(MethodPost, "/", strings.NewReader(tt.body))
+				if tt.chunked {
+					r.ContentLength = -1
+				}
+				handler.ServeHTTP(w, r)
+
+				if w.Code != tt.expected {
+					t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+				}
+			})
+		}
+	})
+
+	t.Run("Multipart And Route Overrides", func(t *testing.T) {
+		mux := New()
+		mux.Use(BodyLimitWithOptions(BodyLimitOptions{Limit: "16B", MultipartLimit: "1KB"}))
+		mux.Handle("/form", readBody, MethodPost)
+		mux.Handle("/upload", readBody, MethodPost).BodyLimit("64B")
+
+		tests := []struct {
+			path        string
+			contentType string
+			size        int
+			expected    int
+		}{
+			{"/form", "application/json", 100, http.StatusRequestEntityTooLarge},
+			{"/form", "multipart/form-data; boundary=x", 100, http.StatusOK},
+			{"/upload", "application/octet-stream", 50, http.StatusOK},
+			{"/upload", "application/octet-stream", 100, http.StatusRequestEntityTooLarge},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+			r.Header.Set("Content-Type", tt.contentType)
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s %s (%d bytes): expected status code %d, got %d", tt.path, tt.contentType, tt.size, tt.expected, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorBudgetOptions configures the ErrorBudget middleware
+type ErrorBudgetOptions struct {
+	// Name identifies the budget in alerts and Stats, e.g. the group's
+	// path prefix
+	Name string
+
+	// Threshold is the share of requests that may fail with a 5xx status
+	// or a panic within Window, e.g. 0.05. Defaults to 0.1.
+	Threshold float64
+
+	// Window is the sliding period the error rate is measured over.
+	// Defaults to 1m.
+	Window time.Duration
+
+	// MinRequests is the number of requests in the window below which the
+	// rate isn't judged, so a single failure can't exhaust the budget.
+	// Defaults to 20.
+	MinRequests int
+
+	// Trip opens the circuit when the budget is exhausted: requests are
+	// answered by Rejected without reaching the handlers for Cooldown,
+	// giving a failing dependency room to recover. Cooldown defaults to
+	// Window.
+	Trip     bool
+	Cooldown time.Duration
+
+	// OnExceeded is called once each time the budget is exhausted, e.g. to
+	// alert operators
+	OnExceeded func(alert ErrorBudgetAlert)
+
+	// Rejected answers requests while the circuit is open. Defaults to 503
+	// Service Unavailable with Retry-After.
+	Rejected http.Handler
+}
+
+// ErrorBudgetAlert describes an exhausted error budget
+type ErrorBudgetAlert struct {
+	Name     string
+	Requests int
+	Failures int
+	Rate     float64
+	// Tripped is set when the circuit was opened
+	Tripped bool
+}
+
+// ErrorBudgetStats reports an ErrorBudget's current window
+type ErrorBudgetStats struct {
+	Name     string
+	Requests int
+	Failures int
+	Rate     float64
+	Open     bool
+	// Trips counts the times the circuit was opened
+	Trips uint64
+}
+
+// budgetBuckets split the window, so old requests expire a bucket at a
+// time
+const budgetBuckets = 10
+
+type errorBudget struct {
+	opts  ErrorBudgetOptions
+	width time.Duration
+	trips atomic.Uint64
+
+	mu        sync.Mutex
+	buckets   [budgetBuckets]budgetBucket
+	exceeded  bool
+	openUntil time.Time
+}
+
+type budgetBucket struct {
+	start              time.Time
+	requests, failures int
+}
+
+// ErrorBudget tracks the share of requests that fail with a 5xx status or
+// panic across the routes it wraps. Register it on a group so the group's
+// routes share one budget:
+//
+//	mux.Group(func(admin *GoFlow.Mux) {
+//		admin.Use(GoFlow.ErrorBudget(GoFlow.ErrorBudgetOptions{Name: "admin", Trip: true}))
+//		...
+//	})
+//
+// When the budget is exhausted it calls OnExceeded and, with Trip, answers
+// 503 for the cooldown before letting requests through again. Stats reports
+// each budget's window.
+func ErrorBudget(opts ErrorBudgetOptions) func(http.Handler) http.Handler {
+	if opts.Threshold == 0 {
+		opts.Threshold = 0.1
+	}
+	if opts.Window == 0 {
+		opts.Window = time.Minute
+	}
+	if opts.MinRequests == 0 {
+		opts.MinRequests = 20
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = opts.Window
+	}
+	b := &errorBudget{opts: opts, width: opts.Window / budgetBuckets}
+	registerStats(&registry.budgets, b)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if wait := b.open(time.Now()); wait > 0 {
+				debugNote(r.Context(), "error budget: %s circuit open", opts.Name)
+				if opts.Rejected != nil {
+					opts.Rejected.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				http.Error(w, StatusText(r.Context(), http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			panicked := true
+			defer func() {
+				if alert, ok := b.record(time.Now(), panicked || sw.status >= 500); ok && opts.OnExceeded != nil {
+					opts.OnExceeded(alert)
+				}
+			}()
+			next.ServeHTTP(wrapWriter(sw), r)
+			panicked = false
+		})
+	}
+}
+
+// open returns how long the circuit stays open, closing it with a fresh
+// window once the cooldown is over
+func (b *errorBudget) open(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return 0
+	}
+	if wait := b.openUntil.Sub(now); wait > 0 {
+		return wait
+	}
+	b.openUntil = time.Time{}
+	b.exceeded = false
+	b.buckets = [budgetBuckets]budgetBucket{}
+	return 0
+}
+
+// record counts a request and reports an alert if it exhausted the budget
+func (b *errorBudget) record(now time.Time, failed bool) (ErrorBudgetAlert, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := now.Truncate(b.width)
+	bucket := &b.buckets[start.UnixNano()/int64(b.width)%budgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+	bucket.requests++
+	if failed {
+		bucket.failures++
+	}
+
+	requests, failures := b.window(now)
+	rate := float64(failures) / float64(requests)
+	if requests < b.opts.MinRequests || rate <= b.opts.Threshold {
+		b.exceeded = false
+		return ErrorBudgetAlert{}, false
+	}
+	if b.exceeded {
+		return ErrorBudgetAlert{}, false
+	}
+	b.exceeded = true
+	if b.opts.Trip {
+		b.openUntil = now.Add(b.opts.Cooldown)
+		b.trips.Add(1)
+	}
+	return ErrorBudgetAlert{Name: b.opts.Name, Requests: requests, Failures: failures, Rate: rate, Tripped: b.opts.Trip}, true
+}
+
+// window sums the buckets within the window, with b.mu held
+func (b *errorBudget) window(now time.Time) (requests, failures int) {
+	for _, bucket := range b.buckets {
+		if now.Sub(bucket.start) < b.opts.Window {
+			requests += bucket.requests
+			failures += bucket.failures
+		}
+	}
+	return requests, failures
+}
+
+func (b *errorBudget) stats() ErrorBudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	s := ErrorBudgetStats{Name: b.opts.Name, Open: now.Before(b.openUntil), Trips: b.trips.Load()}
+	s.Requests, s.Failures = b.window(now)
+	if s.Requests > 0 {
+		s.Rate = float64(s.Failures) / float64(s.Requests)
+	}
+	return s
+}
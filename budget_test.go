@@ -0,0 +1,79 @@
+package GoFlow
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestErrorBudget(t *testing.T) {
+	orig := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(orig)
+
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	failing := false
+	var alerts []ErrorBudgetAlert
+	mux := New()
+	mux.Use(Recovery())
+	mux.Group(func(m *Mux) {
+		m.Use(ErrorBudget(ErrorBudgetOptions{
+			Name:        "reports",
+			Threshold:   0.5,
+			MinRequests: 4,
+			Trip:        true,
+			Cooldown:    30 * time.Millisecond,
+			OnExceeded:  func(alert ErrorBudgetAlert) { alerts = append(alerts, alert) },
+		}))
+		m.Handle("/reports", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing {
+				panic("report generator down")
+			}
+		}), MethodGet)
+		m.Handle("/reports/export", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if failing {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}), MethodGet)
+	})
+	mux.Handle("/health", noop, MethodGet)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w
+	}
+
+	get("/reports")
+	get("/reports/export")
+	failing = true
+	get("/reports")
+	if len(alerts) != 0 {
+		t.Fatalf("Expected no alert below MinRequests, got %+v", alerts)
+	}
+	get("/reports/export")
+	get("/reports/export")
+	if len(alerts) != 1 || alerts[0].Name != "reports" || alerts[0].Failures != 3 || !alerts[0].Tripped {
+		t.Fatalf("Expected one alert once 3 of 5 requests failed, got %+v", alerts)
+	}
+
+	failing = false
+	if w := get("/reports/export"); w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected the group's circuit open, got %d", w.Code)
+	}
+	if w := get("/health"); w.Code != http.StatusOK {
+		t.Errorf("Expected routes outside the group unaffected, got %d", w.Code)
+	}
+	stats := Stats().ErrorBudgets
+	if s := stats[len(stats)-1]; s.Name != "reports" || !s.Open || s.Trips != 1 {
+		t.Errorf("Expected the open circuit in Stats, got %+v", s)
+	}
+
+	time.Sleep(35 * time.Millisecond)
+	if w := get("/reports"); w.Code != http.StatusOK {
+		t.Errorf("Expected the circuit closed after the cooldown, got %d", w.Code)
+	}
+}
@@ -0,0 +1,60 @@
+package GoFlow
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrBufferBudget is returned by writes to a buffered response when the
+// buffer budget set with SetBufferBudget is exhausted
+var ErrBufferBudget = errors.New("GoFlow: buffer budget exhausted")
+
+// bufferBudget caps the bytes held at once by middleware that buffer
+// responses: Cache, Coalesce, Transform, Retry and Timeout
+type bufferBudget struct {
+	limit    atomic.Int64
+	inUse    atomic.Int64
+	exceeded atomic.Uint64
+}
+
+var buffers bufferBudget
+
+// SetBufferBudget caps the response bytes that Cache, Coalesce, Transform,
+// Retry and Timeout buffer across all requests at once, so bursts of large
+// responses can't exhaust a small container's memory. Past the cap, Cache
+// and Transform stream the response without caching or transforming it,
+// Coalesce serves waiting requests separately, Retry sends the current
+// attempt without retrying, and Timeout answers 503 Service Unavailable.
+// Zero, the default, removes the cap.
+func SetBufferBudget(limit int64) {
+	buffers.limit.Store(limit)
+}
+
+func (b *bufferBudget) reserve(n int64) bool {
+	if limit := b.limit.Load(); b.inUse.Add(n) > limit && limit > 0 {
+		b.inUse.Add(-n)
+		b.exceeded.Add(1)
+		return false
+	}
+	return true
+}
+
+// bufferReservation tracks the bytes one writer holds against the budget
+type bufferReservation struct {
+	held int64
+}
+
+// grow reserves n more bytes, reporting false if the budget is exhausted
+func (r *bufferReservation) grow(n int) bool {
+	if !buffers.reserve(int64(n)) {
+		return false
+	}
+	r.held += int64(n)
+	return true
+}
+
+// free returns the reserved bytes to the budget
+func (r *bufferReservation) free() {
+	buffers.inUse.Add(-r.held)
+	r.held = 0
+}
@@ -0,0 +1,96 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBufferBudget(t *testing.T) {
+	SetBufferBudget(16)
+	defer SetBufferBudget(0)
+
+	large := strings.Repeat("x", 32)
+	handler := func(body string, status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		})
+	}
+
+	t.Run("Cache Skips Storage", func(t *testing.T) {
+		calls := 0
+		h := Cache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Write([]byte(large))
+		}))
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			if rec.Body.String() != large {
+				t.Errorf("Expected full body, got %q", rec.Body.String())
+			}
+		}
+		if calls != 2 {
+			t.Errorf("Expected the response not to be cached, got %d handler calls", calls)
+		}
+	})
+
+	t.Run("Transform Streams Through", func(t *testing.T) {
+		h := Transform(TransformOptions{
+			Transformers: map[string][]ResponseTransformer{"application/json": {JSONEnvelope("data")}},
+		})(handler(`"`+large+`"`, http.StatusOK))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Body.String() != `"`+large+`"` {
+			t.Errorf("Expected untransformed body, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("Retry Sends Attempt", func(t *testing.T) {
+		calls := 0
+		h := Retry(RetryOptions{BaseDelay: time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(large))
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+		if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != large {
+			t.Errorf("Expected the streamed 503, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("Timeout Answers 503", func(t *testing.T) {
+		h := Timeout(time.Second)(handler(large, http.StatusOK))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", rec.Code)
+		}
+	})
+
+	t.Run("Small Responses Buffered", func(t *testing.T) {
+		h := Transform(TransformOptions{
+			Transformers: map[string][]ResponseTransformer{"application/json": {JSONEnvelope("data")}},
+		})(handler(`[1]`, http.StatusOK))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Body.String() != `{"data":[1]}` {
+			t.Errorf("Expected transformed body, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		s := Stats().Buffers
+		if s.Limit != 16 || s.InUse != 0 || s.Exceeded == 0 {
+			t.Errorf("Expected limit 16, nothing in use and exceeded writes, got %+v", s)
+		}
+	})
+}
@@ -0,0 +1,249 @@
+package GoFlow
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errFetchPanicked is returned to callers waiting on a fetch that panicked
+var errFetchPanicked = errors.New("GoFlow: Cached fetch panicked")
+
+// defaultCachedEntries bounds the default store of Cached
+const defaultCachedEntries = 10000
+
+// CacheStore holds the values cached with Cached; see SetCacheStore.
+// Implementations must be safe for concurrent use.
+type CacheStore interface {
+	// Get returns the item stored under key
+	Get(key string) (CacheItem, bool)
+	// Set stores item under key, replacing any previous one
+	Set(key string, item CacheItem)
+	// Purge removes the items whose Key starts with prefix and returns
+	// the number removed
+	Purge(prefix string) int
+	// Len returns the number of items stored
+	Len() int
+}
+
+// CacheItem is a value cached with Cached
+type CacheItem struct {
+	// Key is as passed to Cached, without the tenant, for PurgeCache
+	Key   string
+	Value any
+	// Expires is when the value turns stale, and Stale when it may be
+	// dropped
+	Expires time.Time
+	Stale   time.Time
+}
+
+// dataCache holds the values cached with Cached
+type dataCache struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	mu    sync.Mutex
+	store CacheStore
+	calls map[string]*dataCall
+}
+
+// dataCall is a fetch in flight that callers for the same key wait for
+type dataCall struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+var readThrough = dataCache{store: NewMemoryCacheStore(defaultCachedEntries), calls: make(map[string]*dataCall)}
+
+// SetCacheStore replaces the store Cached keeps its values in, e.g. with
+// a larger NewMemoryCacheStore or a shared one. Values cached so far are
+// dropped. Nil restores the default, a NewMemoryCacheStore of 10000
+// entries.
+func SetCacheStore(store CacheStore) {
+	if store == nil {
+		store = NewMemoryCacheStore(defaultCachedEntries)
+	}
+	readThrough.mu.Lock()
+	readThrough.store = store
+	readThrough.mu.Unlock()
+}
+
+// Cached returns the value cached under key, calling fetch to compute it
+// when there is none. Values are kept for ttl and then served stale for up
+// to another ttl while fetch refreshes them in the background, so callers
+// rarely wait. Concurrent calls for a missing key share one fetch, and
+// errors aren't cached. Keys are scoped to the context's tenant, and
+// PurgeCache removes the entries whose key starts with its prefix, as for
+// the Cache middleware:
+//
+//	user, err := GoFlow.Cached(r.Context(), "/users/"+id, time.Minute, func(ctx context.Context) (*User, error) {
+//		return db.LoadUser(ctx, id)
+//	})
+func Cached[T any](ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (T, error)) (T, error) {
+	c := &readThrough
+	scoped := key
+	if tenant := GetTenant(ctx); tenant != "" {
+		scoped = tenant + "\x00" + key
+	}
+	load := func(ctx context.Context) (any, error) {
+		return fetch(ctx)
+	}
+
+	if item, ok := c.current().Get(scoped); ok {
+		if value, ok := item.Value.(T); ok {
+			now := time.Now()
+			if now.Before(item.Expires) {
+				debugNote(ctx, "cached: hit %s", key)
+				c.hits.Add(1)
+				return value, nil
+			}
+			if now.Before(item.Stale) {
+				debugNote(ctx, "cached: stale %s, revalidating", key)
+				c.hits.Add(1)
+				c.refresh(context.WithoutCancel(ctx), key, scoped, ttl, load)
+				return value, nil
+			}
+		}
+	}
+
+	debugNote(ctx, "cached: miss %s", key)
+	c.misses.Add(1)
+	v, err := c.do(ctx, key, scoped, ttl, load)
+	value, _ := v.(T)
+	return value, err
+}
+
+func (c *dataCache) current() CacheStore {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store
+}
+
+// refresh fetches a stale value again in the background, unless a fetch
+// for it is already in flight
+func (c *dataCache) refresh(ctx context.Context, key, scoped string, ttl time.Duration, fetch func(context.Context) (any, error)) {
+	c.mu.Lock()
+	_, inFlight := c.calls[scoped]
+	c.mu.Unlock()
+	if !inFlight {
+		go c.do(ctx, key, scoped, ttl, fetch)
+	}
+}
+
+// do fetches the value for scoped and stores it, or waits for the fetch
+// already in flight
+func (c *dataCache) do(ctx context.Context, key, scoped string, ttl time.Duration, fetch func(context.Context) (any, error)) (any, error) {
+	c.mu.Lock()
+	if call, ok := c.calls[scoped]; ok {
+		c.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &dataCall{done: make(chan struct{}), err: errFetchPanicked}
+	c.calls[scoped] = call
+	store := c.store
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, scoped)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = fetch(ctx)
+	if call.err == nil {
+		now := time.Now()
+		store.Set(scoped, CacheItem{Key: key, Value: call.value, Expires: now.Add(ttl), Stale: now.Add(2 * ttl)})
+	}
+	return call.value, call.err
+}
+
+// memoryCacheStore is a CacheStore that evicts the least recently used
+// items past its capacity
+type memoryCacheStore struct {
+	capacity int
+
+	mu    sync.Mutex
+	items map[string]*list.Element // of *memoryCacheEntry
+	lru   list.List                // most recently used first
+}
+
+type memoryCacheEntry struct {
+	key  string
+	item CacheItem
+}
+
+// NewMemoryCacheStore returns an in-memory CacheStore holding up to
+// capacity items. Past it, the least recently used items are evicted;
+// items are also dropped once they are past their Stale time.
+func NewMemoryCacheStore(capacity int) CacheStore {
+	if capacity <= 0 {
+		panic("GoFlow: NewMemoryCacheStore capacity must be positive")
+	}
+	return &memoryCacheStore{capacity: capacity, items: make(map[string]*list.Element)}
+}
+
+func (s *memoryCacheStore) Get(key string) (CacheItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return CacheItem{}, false
+	}
+	entry := el.Value.(*memoryCacheEntry)
+	if time.Now().After(entry.item.Stale) {
+		s.remove(el)
+		return CacheItem{}, false
+	}
+	s.lru.MoveToFront(el)
+	return entry.item, true
+}
+
+func (s *memoryCacheStore) Set(key string, item CacheItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.items[key]; ok {
+		el.Value.(*memoryCacheEntry).item = item
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.lru.PushFront(&memoryCacheEntry{key: key, item: item})
+	for s.lru.Len() > s.capacity {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *memoryCacheStore) Purge(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		if strings.HasPrefix(el.Value.(*memoryCacheEntry).item.Key, prefix) {
+			s.remove(el)
+			purged++
+		}
+		el = next
+	}
+	return purged
+}
+
+func (s *memoryCacheStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lru.Len()
+}
+
+func (s *memoryCacheStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.items, el.Value.(*memoryCacheEntry).key)
+}
@@ -0,0 +1,127 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	ctx := context.Background()
+	defer PurgeCache("test/")
+
+	t.Run("Hit", func(t *testing.T) {
+		var calls atomic.Int32
+		fetch := func(ctx context.Context) (int, error) {
+			return int(calls.Add(1)), nil
+		}
+		a, _ := Cached(ctx, "test/hit", time.Minute, fetch)
+		b, err := Cached(ctx, "test/hit", time.Minute, fetch)
+		if err != nil || a != 1 || b != 1 {
+			t.Errorf("Expected the cached value, got %d %d %v", a, b, err)
+		}
+	})
+
+	t.Run("Shared Fetch", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := Cached(ctx, "test/shared", time.Minute, func(ctx context.Context) (string, error) {
+					calls.Add(1)
+					<-release
+					return "report", nil
+				})
+				if v != "report" || err != nil {
+					t.Errorf("Expected the shared value, got %q %v", v, err)
+				}
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if calls.Load() != 1 {
+			t.Errorf("Expected one fetch, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Stale While Revalidate", func(t *testing.T) {
+		var calls atomic.Int32
+		refreshed := make(chan struct{})
+		fetch := func(ctx context.Context) (int32, error) {
+			n := calls.Add(1)
+			if n == 2 {
+				defer close(refreshed)
+			}
+			return n, nil
+		}
+		Cached(ctx, "test/stale", 20*time.Millisecond, fetch)
+		time.Sleep(25 * time.Millisecond)
+		if v, _ := Cached(ctx, "test/stale", 20*time.Millisecond, fetch); v != 1 {
+			t.Errorf("Expected the stale value served, got %d", v)
+		}
+		<-refreshed
+		time.Sleep(time.Millisecond)
+		if v, _ := Cached(ctx, "test/stale", 20*time.Millisecond, fetch); v != 2 {
+			t.Errorf("Expected the refreshed value, got %d", v)
+		}
+	})
+
+	t.Run("Errors And Purge", func(t *testing.T) {
+		fail := true
+		fetch := func(ctx context.Context) (string, error) {
+			if fail {
+				return "", errors.New("database down")
+			}
+			return "ok", nil
+		}
+		if _, err := Cached(ctx, "test/posts/1", time.Minute, fetch); err == nil {
+			t.Fatal("Expected the fetch error")
+		}
+		fail = false
+		if v, err := Cached(ctx, "test/posts/1", time.Minute, fetch); v != "ok" || err != nil {
+			t.Errorf("Expected errors not cached, got %q %v", v, err)
+		}
+
+		r := WithTenant(httptest.NewRequest(MethodGet, "/", nil), "acme")
+		if v, _ := Cached(r.Context(), "test/posts/1", time.Minute, func(ctx context.Context) (string, error) { return "acme", nil }); v != "acme" {
+			t.Errorf("Expected keys scoped to the tenant, got %q", v)
+		}
+		if n := PurgeCache("test/posts/"); n != 2 {
+			t.Errorf("Expected both tenants' entries purged, got %d", n)
+		}
+	})
+	t.Run("Bounded Store", func(t *testing.T) {
+		store := NewMemoryCacheStore(2)
+		SetCacheStore(store)
+		defer SetCacheStore(nil)
+
+		value := func(v string) func(context.Context) (string, error) {
+			return func(context.Context) (string, error) { return v, nil }
+		}
+		Cached(ctx, "test/a", time.Minute, value("a"))
+		Cached(ctx, "test/b", time.Minute, value("b"))
+		Cached(ctx, "test/a", time.Minute, value("a2"))
+		Cached(ctx, "test/c", time.Minute, value("c"))
+
+		if store.Len() != 2 {
+			t.Errorf("Expected 2 entries, got %d", store.Len())
+		}
+		if v, _ := Cached(ctx, "test/a", time.Minute, value("a3")); v != "a" {
+			t.Errorf("Expected the recently used entry kept, got %q", v)
+		}
+		if v, _ := Cached(ctx, "test/b", time.Minute, value("b2")); v != "b2" {
+			t.Errorf("Expected the least recently used entry evicted, got %q", v)
+		}
+		if Stats().Cached.Entries != 2 {
+			t.Errorf("Expected Stats to report the store's entries, got %d", Stats().Cached.Entries)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package GoFlow
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// CanaryOptions configures the Canary middleware
+type CanaryOptions struct {
+	// Handler serves canary traffic, e.g. NewProxy to the new version
+	Handler http.Handler
+
+	// Percent of remaining traffic sent to the canary, from 0 to 100
+	Percent float64
+
+	// Header forces the canary for requests carrying it. When HeaderValue is
+	// set the header must have that value, e.g. X-Canary: always.
+	Header      string
+	HeaderValue string
+
+	// Match forces the canary for requests it returns true for
+	Match func(r *http.Request) bool
+
+	// Sticky keeps clients on the variant they were first assigned through
+	// a cookie, so a user doesn't bounce between versions
+	Sticky bool
+
+	// CookieName defaults to "goflow_canary" and CookieMaxAge to 24 hours
+	CookieName   string
+	CookieMaxAge time.Duration
+}
+
+// Canary sends a share of traffic, or requests matching a header or rule, to
+// an alternate handler for incremental rollouts. Apply it to a route with
+// Route.With or to a group with Use.
+func Canary(opts CanaryOptions) func(http.Handler) http.Handler {
+	if opts.Handler == nil {
+		panic("GoFlow: Canary requires a Handler")
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "goflow_canary"
+	}
+	if opts.CookieMaxAge == 0 {
+		opts.CookieMaxAge = 24 * time.Hour
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if canaryForced(r, opts) {
+				opts.Handler.ServeHTTP(w, r)
+				return
+			}
+
+			if opts.Sticky {
+				if c, err := r.Cookie(opts.CookieName); err == nil {
+					switch c.Value {
+					case "canary":
+						opts.Handler.ServeHTTP(w, r)
+						return
+					case "stable":
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			canary := opts.Percent > 0 && rand.Float64()*100 < opts.Percent
+			if opts.Sticky {
+				value := "stable"
+				if canary {
+					value = "canary"
+				}
+				http.SetCookie(w, &http.Cookie{
+					Name:     opts.CookieName,
+					Value:    value,
+					Path:     "/",
+					MaxAge:   int(opts.CookieMaxAge / time.Second),
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			if canary {
+				opts.Handler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func canaryForced(r *http.Request, opts CanaryOptions) bool {
+	if opts.Header != "" {
+		if v := r.Header.Get(opts.Header); v != "" && (opts.HeaderValue == "" || v == opts.HeaderValue) {
+			return true
+		}
+	}
+	return opts.Match != nil && opts.Match(r)
+}
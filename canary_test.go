@@ -0,0 +1,76 @@
+package GoFlow
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanary(t *testing.T) {
+	stable := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "stable") })
+	canary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "canary") })
+
+	serve := func(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Percentage Split", func(t *testing.T) {
+		handler := Canary(CanaryOptions{Handler: canary, Percent: 25})(stable)
+
+		counts := map[string]int{}
+		for i := 0; i < 4000; i++ {
+			counts[serve(handler, httptest.NewRequest(MethodGet, "/", nil)).Body.String()]++
+		}
+		if counts["canary"] < 800 || counts["canary"] > 1200 {
+			t.Errorf("Expected about 25%% canary traffic, got %v", counts)
+		}
+	})
+
+	t.Run("Header And Rule Overrides", func(t *testing.T) {
+		handler := Canary(CanaryOptions{
+			Handler:     canary,
+			Header:      "X-Canary",
+			HeaderValue: "always",
+			Match: func(r *http.Request) bool {
+				return r.URL.Query().Get("beta") == "1"
+			},
+		})(stable)
+
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("X-Canary", "always")
+		if body := serve(handler, r).Body.String(); body != "canary" {
+			t.Errorf("Expected header to force canary, got '%s'", body)
+		}
+
+		r = httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("X-Canary", "no")
+		if body := serve(handler, r).Body.String(); body != "stable" {
+			t.Errorf("Expected other header values to stay stable, got '%s'", body)
+		}
+
+		if body := serve(handler, httptest.NewRequest(MethodGet, "/?beta=1", nil)).Body.String(); body != "canary" {
+			t.Errorf("Expected rule to force canary, got '%s'", body)
+		}
+	})
+
+	t.Run("Sticky Assignment", func(t *testing.T) {
+		handler := Canary(CanaryOptions{Handler: canary, Percent: 50, Sticky: true})(stable)
+
+		w := serve(handler, httptest.NewRequest(MethodGet, "/", nil))
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "goflow_canary" {
+			t.Fatalf("Expected assignment cookie, got %v", cookies)
+		}
+
+		for i := 0; i < 20; i++ {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.AddCookie(cookies[0])
+			if body := serve(handler, r).Body.String(); body != w.Body.String() {
+				t.Fatalf("Expected sticky variant '%s', got '%s'", w.Body.String(), body)
+			}
+		}
+	})
+}
@@ -0,0 +1,347 @@
+// Package client is an HTTP client for calls from GoFlow handlers to other
+// services. It applies the server's resilience primitives to outbound
+// calls: retries through GoFlow.RetryTransport, per-host circuit breaking,
+// trace and deadline propagation, per-host connection limits and request
+// metrics.
+//
+//	users := client.New(client.Options{Timeout: 5 * time.Second})
+//	resp, err := users.Get(r.Context(), "http://users-service/v1/42")
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+// ErrCircuitOpen is returned for calls to a host whose circuit is open
+var ErrCircuitOpen = errors.New("client: circuit open")
+
+// Options configures a Client
+type Options struct {
+	// Timeout limits each call, retries included. The deadline of the
+	// request's context applies as well. Defaults to 30 seconds.
+	Timeout time.Duration
+
+	// Retry configures retries of idempotent requests on connection errors
+	// and retryable statuses, as for GoFlow.RetryTransport. Set
+	// MaxAttempts to 1 to disable retries.
+	Retry GoFlow.RetryOptions
+
+	// FailureThreshold consecutive failed calls to a host, connection
+	// errors or 5xx responses, open its circuit for Cooldown: calls fail
+	// with ErrCircuitOpen without being sent. A single call then probes the
+	// host, closing the circuit on success. Default to 5 and 30 seconds; a
+	// negative threshold disables circuit breaking.
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	// MaxConnsPerHost limits the connections to each host; further calls
+	// wait for one to be free. Zero means no limit. It is applied to a
+	// copy of Transport when that is an *http.Transport.
+	MaxConnsPerHost int
+
+	// Transport sends the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// OnRequestDone is called after every call, e.g. to record metrics
+	OnRequestDone func(info RequestInfo)
+}
+
+// RequestInfo describes a completed call passed to OnRequestDone
+type RequestInfo struct {
+	Method string
+	Host   string
+	Path   string
+	// Status is 0 when the call failed without a response
+	Status int
+	// Attempts counts the requests sent, 0 when the circuit was open
+	Attempts int
+	// Duration is the time until the response headers arrived
+	Duration time.Duration
+	Err      error
+	Request  *http.Request
+}
+
+// HostStats reports the calls a Client made to one host
+type HostStats struct {
+	Host     string
+	Requests uint64
+	// Failures counts connection errors and 5xx responses
+	Failures uint64
+	Retries  uint64
+	// Rejected counts the calls failed fast by an open circuit
+	Rejected uint64
+	InFlight int64
+	Open     bool
+	// Trips counts the times the circuit was opened
+	Trips uint64
+}
+
+// Client sends requests with the configured resilience. It is safe for
+// concurrent use; create one per upstream service, or share one, and
+// reuse it.
+type Client struct {
+	opts   Options
+	client *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// New creates a Client from opts
+func New(opts Options) *Client {
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	base := opts.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t, ok := base.(*http.Transport); ok && opts.MaxConnsPerHost > 0 {
+		t = t.Clone()
+		t.MaxConnsPerHost = opts.MaxConnsPerHost
+		base = t
+	}
+
+	c := &Client{opts: opts, hosts: make(map[string]*host)}
+	// Retries re-inject the deadline, so each attempt sees the time left
+	attempts := &countingTransport{next: GoFlow.DeadlineTransport(base)}
+	c.client = &http.Client{
+		Transport: &transport{c: c, next: GoFlow.RetryTransport(attempts, opts.Retry)},
+		Timeout:   opts.Timeout,
+	}
+	return c
+}
+
+// Do sends req. Use http.NewRequestWithContext with the handler's request
+// context so the trace and deadline are propagated.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// Get issues a GET to url
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, GoFlow.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Post issues a POST to url. Bodies from bytes.Reader, bytes.Buffer and
+// strings.Reader can be replayed, so they are retried when the request
+// carries an Idempotency-Key header.
+func (c *Client) Post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, GoFlow.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+// HTTPClient returns the underlying *http.Client, for libraries that take
+// one. Calls through it share the Client's circuits and stats.
+func (c *Client) HTTPClient() *http.Client {
+	return c.client
+}
+
+// Stats reports the calls made to each host, ordered by host
+func (c *Client) Stats() []HostStats {
+	c.mu.Lock()
+	hosts := make([]*host, 0, len(c.hosts))
+	for _, h := range c.hosts {
+		hosts = append(hosts, h)
+	}
+	c.mu.Unlock()
+
+	stats := make([]HostStats, 0, len(hosts))
+	for _, h := range hosts {
+		stats = append(stats, h.stats())
+	}
+	slices.SortFunc(stats, func(a, b HostStats) int { return strings.Compare(a.Host, b.Host) })
+	return stats
+}
+
+func (c *Client) host(name string) *host {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h, ok := c.hosts[name]
+	if !ok {
+		h = &host{name: name}
+		c.hosts[name] = h
+	}
+	return h
+}
+
+type attemptsKey struct{}
+
+// transport applies the circuit breaker, propagates the trace and records
+// each call around the retries
+type transport struct {
+	c    *Client
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.c
+	h := c.host(req.URL.Host)
+	h.requests.Add(1)
+	start := time.Now()
+	breaking := c.opts.FailureThreshold > 0
+
+	if breaking && !h.allow(start) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		h.rejected.Add(1)
+		err := fmt.Errorf("%w: %s", ErrCircuitOpen, req.URL.Host)
+		c.done(req, nil, err, 0, start)
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	attempts := new(atomic.Int64)
+	req = req.Clone(context.WithValue(req.Context(), attemptsKey{}, attempts))
+	if tc, ok := GoFlow.GetTrace(req.Context()); ok {
+		tc.Inject(req.Header)
+	}
+
+	h.inFlight.Add(1)
+	resp, err := t.next.RoundTrip(req)
+	h.inFlight.Add(-1)
+
+	n := attempts.Load()
+	if n > 1 {
+		h.retries.Add(uint64(n - 1))
+	}
+	failed := err != nil || resp.StatusCode >= 500
+	if failed {
+		h.failures.Add(1)
+	}
+	if breaking {
+		// A call the caller canceled says nothing about the host
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			h.release()
+		} else {
+			h.record(failed, c.opts.FailureThreshold, c.opts.Cooldown)
+		}
+	}
+	c.done(req, resp, err, int(n), start)
+	return resp, err
+}
+
+func (c *Client) done(req *http.Request, resp *http.Response, err error, attempts int, start time.Time) {
+	if c.opts.OnRequestDone == nil {
+		return
+	}
+	info := RequestInfo{
+		Method:   req.Method,
+		Host:     req.URL.Host,
+		Path:     req.URL.Path,
+		Attempts: attempts,
+		Duration: time.Since(start),
+		Err:      err,
+		Request:  req,
+	}
+	if resp != nil {
+		info.Status = resp.StatusCode
+	}
+	c.opts.OnRequestDone(info)
+}
+
+// countingTransport counts the attempts of a call
+type countingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if n, ok := req.Context().Value(attemptsKey{}).(*atomic.Int64); ok {
+		n.Add(1)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// host tracks the calls to one host and its circuit
+type host struct {
+	name                                  string
+	requests, failures, retries, rejected atomic.Uint64
+	trips                                 atomic.Uint64
+	inFlight                              atomic.Int64
+
+	mu          sync.Mutex
+	consecutive int
+	openUntil   time.Time
+	probing     bool
+}
+
+// allow reports whether a call may be sent. Once the cooldown is over a
+// single call is let through to probe the host.
+func (h *host) allow(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(h.openUntil) || h.probing {
+		return false
+	}
+	h.probing = true
+	return true
+}
+
+// record counts a call's outcome, opening the circuit after threshold
+// consecutive failures or a failed probe
+func (h *host) record(failed bool, threshold int, cooldown time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !failed {
+		h.consecutive, h.openUntil, h.probing = 0, time.Time{}, false
+		return
+	}
+	h.consecutive++
+	if h.probing || h.consecutive >= threshold {
+		h.openUntil = time.Now().Add(cooldown)
+		h.consecutive, h.probing = 0, false
+		h.trips.Add(1)
+	}
+}
+
+// release lets another call probe the host when the probe was canceled
+func (h *host) release() {
+	h.mu.Lock()
+	h.probing = false
+	h.mu.Unlock()
+}
+
+func (h *host) stats() HostStats {
+	h.mu.Lock()
+	open := time.Now().Before(h.openUntil)
+	h.mu.Unlock()
+	return HostStats{
+		Host:     h.name,
+		Requests: h.requests.Load(),
+		Failures: h.failures.Load(),
+		Retries:  h.retries.Load(),
+		Rejected: h.rejected.Load(),
+		InFlight: h.inFlight.Load(),
+		Open:     open,
+		Trips:    h.trips.Load(),
+	}
+}
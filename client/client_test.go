@@ -0,0 +1,161 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jie10/GoFlow"
+	"github.com/jie10/GoFlow/client"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("Retries", func(t *testing.T) {
+		var calls atomic.Int32
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("ok"))
+		}))
+		defer upstream.Close()
+
+		var info client.RequestInfo
+		c := client.New(client.Options{
+			Retry:         GoFlow.RetryOptions{BaseDelay: time.Millisecond},
+			OnRequestDone: func(i client.RequestInfo) { info = i },
+		})
+		resp, err := c.Get(context.Background(), upstream.URL+"/users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "ok" || info.Attempts != 2 || info.Status != http.StatusOK || info.Path != "/users" {
+			t.Errorf("Expected success on the second attempt, got %q after %d attempts", body, info.Attempts)
+		}
+		if stats := c.Stats(); len(stats) != 1 || stats[0].Requests != 1 || stats[0].Retries != 1 || stats[0].Failures != 0 {
+			t.Errorf("Expected one request with one retry, got %+v", stats)
+		}
+	})
+
+	t.Run("Circuit Breaker", func(t *testing.T) {
+		var healthy atomic.Bool
+		var calls atomic.Int32
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if !healthy.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer upstream.Close()
+
+		c := client.New(client.Options{
+			Retry:            GoFlow.RetryOptions{MaxAttempts: 1},
+			FailureThreshold: 3,
+			Cooldown:         50 * time.Millisecond,
+		})
+		get := func() error {
+			resp, err := c.Get(context.Background(), upstream.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		}
+
+		for i := 0; i < 3; i++ {
+			get()
+		}
+		if err := get(); !errors.Is(err, client.ErrCircuitOpen) || calls.Load() != 3 {
+			t.Errorf("Expected ErrCircuitOpen without calling the host, got %v after %d calls", err, calls.Load())
+		}
+		if s := c.Stats()[0]; !s.Open || s.Trips != 1 || s.Rejected != 1 || s.Failures != 3 {
+			t.Errorf("Expected an open circuit, got %+v", s)
+		}
+
+		// A failed probe opens the circuit again right away
+		time.Sleep(60 * time.Millisecond)
+		get()
+		if err := get(); !errors.Is(err, client.ErrCircuitOpen) || calls.Load() != 4 {
+			t.Errorf("Expected the circuit reopened after one probe, got %v after %d calls", err, calls.Load())
+		}
+
+		healthy.Store(true)
+		time.Sleep(60 * time.Millisecond)
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+		if err := get(); err != nil || c.Stats()[0].Open {
+			t.Errorf("Expected the circuit closed after a successful probe, got %v", err)
+		}
+	})
+
+	t.Run("Propagation", func(t *testing.T) {
+		var got http.Header
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+		}))
+		defer upstream.Close()
+
+		c := client.New(client.Options{})
+		mux := GoFlow.New()
+		mux.Use(GoFlow.Tracing(), GoFlow.Timeout(2*time.Second))
+		mux.Handle("/orders", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resp, err := c.Get(r.Context(), upstream.URL)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			resp.Body.Close()
+		}), GoFlow.MethodGet)
+
+		r := httptest.NewRequest(GoFlow.MethodGet, "/orders", nil)
+		r.Header.Set("X-Request-ID", "order-42")
+		r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		if got.Get("X-Request-Id") != "order-42" || !strings.HasPrefix(got.Get("Traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+			t.Errorf("Expected the trace and request ID propagated, got %v", got)
+		}
+		if got.Get(GoFlow.RequestTimeoutHeader) == "" {
+			t.Error("Expected the deadline propagated")
+		}
+	})
+
+	t.Run("Connection Limit", func(t *testing.T) {
+		var active, peak atomic.Int32
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+			}
+			time.Sleep(20 * time.Millisecond)
+		}))
+		defer upstream.Close()
+
+		c := client.New(client.Options{MaxConnsPerHost: 1})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if resp, err := c.Get(context.Background(), upstream.URL); err == nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}()
+		}
+		wg.Wait()
+		if peak.Load() != 1 {
+			t.Errorf("Expected one request at a time, got %d", peak.Load())
+		}
+	})
+}
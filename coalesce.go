@@ -0,0 +1,124 @@
+package GoFlow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CoalesceOptions configures the Coalesce middleware
+type CoalesceOptions struct {
+	// Key returns more of what identifies identical requests besides the
+	// method and URL, e.g. a header the response depends on
+	Key func(r *http.Request) string
+
+	// SessionCookies name the cookies that identify a user, as for
+	// CacheOptions. When empty, requests only share responses with
+	// requests sending the same Cookie header.
+	SessionCookies []string
+}
+
+// coalesceCall is a request in flight that identical requests wait for
+type coalesceCall struct {
+	done chan struct{}
+
+	// ok is set when the response can be shared
+	ok     bool
+	status int
+	header http.Header
+	data   []byte
+}
+
+// Coalesce collapses concurrent identical GET and HEAD requests into one
+// execution of the handler, whether or not responses are cached. The first
+// request is served as usual while the others wait and receive a copy of
+// its response, e.g. for expensive reports. Requests are identical when
+// their method, URL and tenant match; authenticated requests only share
+// responses with requests carrying the same credentials and cookies.
+// Responses that set cookies or are marked no-store or private are never
+// shared.
+func Coalesce() func(http.Handler) http.Handler {
+	return CoalesceWithOptions(CoalesceOptions{})
+}
+
+// CoalesceWithOptions is Coalesce with a custom key. If the first request
+// panics, is canceled, its response exceeds the buffer budget or can't be
+// shared, the waiting requests are served separately.
+func CoalesceWithOptions(opts CoalesceOptions) func(http.Handler) http.Handler {
+	var mu sync.Mutex
+	calls := make(map[string]*coalesceCall)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method != http.MethodGet && r.Method != http.MethodHead) || isWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Method + " " + tenantKey(r, r.URL.RequestURI())
+			if cacheAuthenticated(r, opts.SessionCookies) {
+				key += "\x00user=" + defaultCacheUserKey(r, opts.SessionCookies)
+			}
+			if cookies := r.Header.Values("Cookie"); len(opts.SessionCookies) == 0 && len(cookies) > 0 {
+				sum := sha256.Sum256([]byte(strings.Join(cookies, "; ")))
+				key += "\x00cookie=" + hex.EncodeToString(sum[:])
+			}
+			if opts.Key != nil {
+				key += "\x00" + opts.Key(r)
+			}
+
+			mu.Lock()
+			if call, ok := calls[key]; ok {
+				mu.Unlock()
+				select {
+				case <-call.done:
+				case <-r.Context().Done():
+					return
+				}
+				if !call.ok {
+					debugNote(r.Context(), "coalesce: identical request failed, serving separately")
+					next.ServeHTTP(w, r)
+					return
+				}
+				debugNote(r.Context(), "coalesce: shared the response of an identical request")
+				copyHeaders(w.Header(), call.header)
+				w.WriteHeader(call.status)
+				w.Write(call.data)
+				copyTrailers(w.Header(), call.header)
+				return
+			}
+			call := &coalesceCall{done: make(chan struct{})}
+			calls[key] = call
+			mu.Unlock()
+
+			cw := &cacheWriter{
+				ResponseWriter: w,
+				headers:        make(http.Header),
+			}
+			defer func() {
+				mu.Lock()
+				delete(calls, key)
+				mu.Unlock()
+				close(call.done)
+				cw.reserved.free()
+			}()
+			next.ServeHTTP(wrapWriter(cw), r)
+			if !cw.wroteHeader {
+				cw.WriteHeader(http.StatusOK)
+			}
+			copyTrailers(w.Header(), cw.headers)
+
+			if cw.overBudget || cw.status == http.StatusSwitchingProtocols || r.Context().Err() != nil {
+				return
+			}
+			if reason := uncacheable(cw.headers, true); reason != "" {
+				debugNote(r.Context(), "coalesce: not sharing response: %s", reason)
+				return
+			}
+			call.ok = true
+			call.status, call.header, call.data = cw.status, cw.headers.Clone(), cw.data.Bytes()
+		})
+	}
+}
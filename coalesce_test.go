@@ -0,0 +1,152 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesce(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	h := Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("X-Report", r.URL.Query().Get("month"))
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("report"))
+	}))
+
+	// run sends a concurrent request per credential and releases the
+	// handler once they are all in flight
+	run := func(target string, auths ...string) []*httptest.ResponseRecorder {
+		recorders := make([]*httptest.ResponseRecorder, len(auths))
+		var wg sync.WaitGroup
+		for i, auth := range auths {
+			recorders[i] = httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, target, nil)
+			if auth != "" {
+				r.Header.Set("Authorization", auth)
+			}
+			wg.Add(1)
+			go func(w *httptest.ResponseRecorder) {
+				defer wg.Done()
+				h.ServeHTTP(w, r)
+			}(recorders[i])
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		release = make(chan struct{})
+		return recorders
+	}
+
+	t.Run("Identical Requests", func(t *testing.T) {
+		calls.Store(0)
+		for _, w := range run("/reports?month=5", "", "", "", "", "") {
+			if w.Code != http.StatusAccepted || w.Body.String() != "report" || w.Header().Get("X-Report") != "5" {
+				t.Errorf("Expected every request to get the report, got %d %q %v", w.Code, w.Body.String(), w.Header())
+			}
+		}
+		if calls.Load() != 1 {
+			t.Errorf("Expected one execution, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Separate Credentials", func(t *testing.T) {
+		calls.Store(0)
+		run("/reports", "Bearer alice", "Bearer bob", "Bearer bob")
+		if calls.Load() != 2 {
+			t.Errorf("Expected one execution per user, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Separate Cookies", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		h := CoalesceWithOptions(CoalesceOptions{SessionCookies: []string{"session"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			<-release
+			c, _ := r.Cookie("session")
+			w.Write([]byte("account of " + c.Value))
+		}))
+
+		var wg sync.WaitGroup
+		recorders := map[string]*httptest.ResponseRecorder{"alice": httptest.NewRecorder(), "bob": httptest.NewRecorder()}
+		for user, w := range recorders {
+			r := httptest.NewRequest(MethodGet, "/account", nil)
+			r.AddCookie(&http.Cookie{Name: "session", Value: user})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(w, r)
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		for user, w := range recorders {
+			if w.Body.String() != "account of "+user {
+				t.Errorf("Expected %s's own response, got %q", user, w.Body.String())
+			}
+		}
+		if calls.Load() != 2 {
+			t.Errorf("Expected one execution per session, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Private Responses", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		h := Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			if n == 1 {
+				<-release
+			}
+			http.SetCookie(w, &http.Cookie{Name: "visitor", Value: strconv.Itoa(int(n))})
+			w.Write([]byte("hello"))
+		}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+			}()
+		}
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if calls.Load() != 3 {
+			t.Errorf("Expected responses setting cookies served separately, got %d executions", calls.Load())
+		}
+	})
+
+	t.Run("Failed Leader", func(t *testing.T) {
+		var calls atomic.Int32
+		started := make(chan struct{})
+		h := Coalesce()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				close(started)
+				time.Sleep(20 * time.Millisecond)
+				panic("report failed")
+			}
+			w.Write([]byte("report"))
+		}))
+		go func() {
+			defer func() { recover() }()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/reports", nil))
+		}()
+		<-started
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(MethodGet, "/reports", nil))
+		if w.Body.String() != "report" || calls.Load() != 2 {
+			t.Errorf("Expected the waiting request served separately, got %q after %d calls", w.Body.String(), calls.Load())
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package GoFlow
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConcurrencyOptions configures the ConcurrencyLimit middleware
+type ConcurrencyOptions struct {
+	// PerIP caps the requests in flight per client IP. Zero disables it.
+	PerIP int
+
+	// PerKey caps the requests in flight per key, such as an API key, that
+	// Key returns. Requests without a key are only capped by PerIP.
+	PerKey int
+	Key    func(r *http.Request) string
+
+	// TrustedProxies may set X-Forwarded-For, as in SecurityOptions
+	TrustedProxies []string
+
+	// Rejected answers requests over a cap. Defaults to 429 Too Many
+	// Requests with Retry-After: 1.
+	Rejected http.Handler
+}
+
+// inFlight counts requests in flight per key
+type inFlight struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// acquire counts a request for key unless limit are in flight already
+func (f *inFlight) acquire(key string, limit int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[key] >= limit {
+		return false
+	}
+	f.counts[key]++
+	return true
+}
+
+func (f *inFlight) release(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.counts[key]--; f.counts[key] <= 0 {
+		delete(f.counts, key)
+	}
+}
+
+// ConcurrencyLimit caps the requests a client has in flight at once. Token
+// buckets limit the rate of requests; this limits slow clients and
+// expensive requests that tie up the server without a high rate.
+func ConcurrencyLimit(opts ConcurrencyOptions) func(http.Handler) http.Handler {
+	if opts.PerKey > 0 && opts.Key == nil {
+		panic("GoFlow: ConcurrencyOptions.PerKey requires a Key function")
+	}
+	if opts.Rejected == nil {
+		opts.Rejected = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
+		})
+	}
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
+	byIP := &inFlight{counts: make(map[string]int)}
+	byKey := &inFlight{counts: make(map[string]int)}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.PerIP > 0 {
+				ip := getRealIP(r, trustedProxies)
+				if !byIP.acquire(ip, opts.PerIP) {
+					debugNote(r.Context(), "concurrency: %s at its limit of %d", ip, opts.PerIP)
+					opts.Rejected.ServeHTTP(w, r)
+					return
+				}
+				defer byIP.release(ip)
+			}
+			if opts.PerKey > 0 {
+				if key := opts.Key(r); key != "" {
+					if !byKey.acquire(key, opts.PerKey) {
+						debugNote(r.Context(), "concurrency: key at its limit of %d", opts.PerKey)
+						opts.Rejected.ServeHTTP(w, r)
+						return
+					}
+					defer byKey.release(key)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitListener closes connections from remote IPs that already have max
+// connections open
+type limitListener struct {
+	net.Listener
+	max    int
+	conns  inFlight
+	logger *slog.Logger
+}
+
+func newLimitListener(ln net.Listener, max int, logger *slog.Logger) *limitListener {
+	return &limitListener{Listener: ln, max: max, conns: inFlight{counts: make(map[string]int)}, logger: logger}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ip := stripPort(c.RemoteAddr().String())
+		if l.conns.acquire(ip, l.max) {
+			return &limitConn{Conn: c, release: func() { l.conns.release(ip) }}, nil
+		}
+		l.logger.Warn("connection limit reached", slog.String("ip", ip), slog.Int("max", l.max))
+		c.Close()
+	}
+}
+
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// ReadFrom keeps sendfile working for TCP connections
+func (c *limitConn) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := c.Conn.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(struct{ io.Writer }{c.Conn}, src)
+}
@@ -0,0 +1,108 @@
+package GoFlow
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	handler := ConcurrencyLimit(ConcurrencyOptions{
+		PerIP:  2,
+		PerKey: 1,
+		Key:    func(r *http.Request) string { return r.Header.Get("X-API-Key") },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	serve := func(ip, key string) int {
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.RemoteAddr = ip + ":1234"
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// Hold two requests from 10.0.0.1, one with key k1
+	var wg sync.WaitGroup
+	for _, key := range []string{"k1", ""} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve("10.0.0.1", key)
+		}()
+		<-started
+	}
+
+	t.Run("Per IP", func(t *testing.T) {
+		if code := serve("10.0.0.1", ""); code != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 for a third request from the IP, got %d", code)
+		}
+	})
+
+	t.Run("Per Key", func(t *testing.T) {
+		if code := serve("10.0.0.2", "k1"); code != http.StatusTooManyRequests {
+			t.Errorf("Expected 429 for a second request with the key, got %d", code)
+		}
+	})
+
+	t.Run("Released", func(t *testing.T) {
+		close(release)
+		wg.Wait()
+		if code := serve("10.0.0.1", "k1"); code != http.StatusOK {
+			t.Errorf("Expected requests allowed once others finish, got %d", code)
+		}
+	})
+}
+
+func TestMaxConnsPerIP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited := newLimitListener(ln, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	defer limited.Close()
+
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			c, err := limited.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	first, _ := net.Dial("tcp", ln.Addr().String())
+	defer first.Close()
+	server := <-accepted
+
+	second, _ := net.Dial("tcp", ln.Addr().String())
+	defer second.Close()
+	second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := second.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Expected the second connection closed, got %v", err)
+	}
+
+	server.Close()
+	third, _ := net.Dial("tcp", ln.Addr().String())
+	defer third.Close()
+	select {
+	case c := <-accepted:
+		c.Close()
+	case <-time.After(time.Second):
+		t.Error("Expected a connection accepted once the first closed")
+	}
+}
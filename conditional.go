@@ -0,0 +1,114 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckPreconditions evaluates the RFC 7232 conditional headers of r
+// against the current version of a resource, identified by its entity tag
+// and modification time. Either may be empty or zero. It sets the ETag and
+// Last-Modified headers, and writes 304 Not Modified or 412 Precondition
+// Failed and returns true when the handler should not continue:
+//
+//	if GoFlow.CheckPreconditions(w, r, doc.ETag, doc.Updated) {
+//		return
+//	}
+//
+// For PUT, PATCH and DELETE an If-Match that doesn't match the stored
+// version fails with 412, giving clients optimistic concurrency.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	etag = quoteETag(etag)
+	modified = modified.Truncate(time.Second)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	switch evaluatePreconditions(r, etag, modified) {
+	case http.StatusNotModified:
+		h := w.Header()
+		delete(h, "Content-Type")
+		delete(h, "Content-Length")
+		delete(h, "Content-Encoding")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	case http.StatusPreconditionFailed:
+		http.Error(w, StatusText(r.Context(), http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return true
+	}
+	return false
+}
+
+// evaluatePreconditions returns the status the conditional headers call
+// for, in the order of RFC 7232 section 6, or 0 to serve the request
+func evaluatePreconditions(r *http.Request, etag string, modified time.Time) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !etagListMatch(im, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius, ok := headerTime(r, "If-Unmodified-Since"); ok && !modified.IsZero() {
+		if modified.After(ius) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagListMatch(inm, etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims, ok := headerTime(r, "If-Modified-Since"); ok && safe && !modified.IsZero() {
+		if !modified.After(ims) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// quoteETag quotes a bare entity tag such as v42
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// etagListMatch reports whether the comma-separated entity tags of an
+// If-Match or If-None-Match header include etag. "*" matches any current
+// version. Strong comparison fails for weak tags.
+func etagListMatch(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || etagMatch(candidate, etag, strong) {
+			return true
+		}
+	}
+	return false
+}
+
+func etagMatch(a, b string, strong bool) bool {
+	if strong {
+		return a == b && !strings.HasPrefix(a, "W/")
+	}
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+func headerTime(r *http.Request, name string) (time.Time, bool) {
+	v := r.Header.Get(name)
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(v)
+	return t, err == nil
+}
@@ -0,0 +1,64 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name     string
+		method   string
+		headers  map[string]string
+		etag     string
+		expected int
+	}{
+		{"Unconditional", MethodGet, nil, "v2", http.StatusOK},
+		{"If-None-Match Hit", MethodGet, map[string]string{"If-None-Match": `"v1", "v2"`}, "v2", http.StatusNotModified},
+		{"If-None-Match Weak", MethodGet, map[string]string{"If-None-Match": `W/"v2"`}, "v2", http.StatusNotModified},
+		{"If-None-Match Miss", MethodGet, map[string]string{"If-None-Match": `"v1"`}, "v2", http.StatusOK},
+		{"If-None-Match Star On PUT", MethodPut, map[string]string{"If-None-Match": "*"}, "v2", http.StatusPreconditionFailed},
+		{"If-None-Match Star On Create", MethodPut, map[string]string{"If-None-Match": "*"}, "", http.StatusOK},
+		{"If-Match Hit", MethodPut, map[string]string{"If-Match": `"v2"`}, "v2", http.StatusOK},
+		{"If-Match Stale", MethodPut, map[string]string{"If-Match": `"v1"`}, "v2", http.StatusPreconditionFailed},
+		{"If-Match Weak", MethodDelete, map[string]string{"If-Match": `W/"v2"`}, "v2", http.StatusPreconditionFailed},
+		{"If-Modified-Since", MethodGet, map[string]string{"If-Modified-Since": after}, "", http.StatusNotModified},
+		{"If-Modified-Since Older", MethodGet, map[string]string{"If-Modified-Since": before}, "", http.StatusOK},
+		{"If-None-Match Wins", MethodGet, map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": after}, "v2", http.StatusOK},
+		{"If-Unmodified-Since", MethodPatch, map[string]string{"If-Unmodified-Since": before}, "", http.StatusPreconditionFailed},
+		{"If-Match Wins", MethodPatch, map[string]string{"If-Match": `"v2"`, "If-Unmodified-Since": before}, "v2", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/doc", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			if !CheckPreconditions(w, r, tt.etag, modified) {
+				w.WriteHeader(http.StatusOK)
+			}
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+
+	t.Run("Validator Headers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		CheckPreconditions(w, httptest.NewRequest(MethodGet, "/doc", nil), "v2", modified.Add(time.Millisecond))
+		if w.Header().Get("ETag") != `"v2"` {
+			t.Errorf("Expected a quoted ETag, got '%s'", w.Header().Get("ETag"))
+		}
+		if w.Header().Get("Last-Modified") != "Fri, 01 Mar 2024 12:00:00 GMT" {
+			t.Errorf("Expected Last-Modified, got '%s'", w.Header().Get("Last-Modified"))
+		}
+	})
+}
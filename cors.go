@@ -0,0 +1,184 @@
+package GoFlow
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins lists origins such as https://example.com, "*" for
+	// any, or patterns such as https://*.example.com for any subdomain
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+
+	// ExposedHeaders lists response headers scripts may read besides the
+	// CORS-safelisted ones, e.g. X-Request-ID
+	ExposedHeaders []string
+
+	// AllowCredentials lets requests with cookies or HTTP authentication
+	// read the response. It can't be combined with a "*" origin.
+	AllowCredentials bool
+
+	// AllowPrivateNetwork answers Chrome's Private Network Access
+	// preflights, letting public sites call services on the intranet
+	AllowPrivateNetwork bool
+
+	// AllowOriginFunc is asked about origins AllowedOrigins doesn't allow,
+	// e.g. to look up a customer's custom domain
+	AllowOriginFunc func(origin string) bool
+
+	// IgnorePort matches origins regardless of their port, so
+	// http://localhost matches http://localhost:3000
+	IgnorePort bool
+}
+
+// CORS middleware adds Cross-Origin Resource Sharing headers
+func CORS(allowedOrigins []string, allowedMethods []string, allowedHeaders []string) func(http.Handler) http.Handler {
+	return CORSWithOptions(CORSOptions{
+		AllowedOrigins: allowedOrigins,
+		AllowedMethods: allowedMethods,
+		AllowedHeaders: allowedHeaders,
+	})
+}
+
+// CORSWithOptions is CORS configured with a CORSOptions, e.g. one loaded
+// with CORSOptionsFromEnv
+func CORSWithOptions(opts CORSOptions) func(http.Handler) http.Handler {
+	origins := newOriginMatcher(opts.AllowedOrigins, opts.IgnorePort)
+	allowFunc := opts.AllowOriginFunc
+
+	if origins.any && opts.AllowCredentials {
+		panic(`GoFlow: CORSOptions.AllowCredentials can't be combined with a "*" origin; list the origins instead`)
+	}
+
+	allowedMethodsStr := strings.Join(opts.AllowedMethods, ", ")
+	allowedHeadersStr := strings.Join(opts.AllowedHeaders, ", ")
+	exposedHeadersStr := strings.Join(opts.ExposedHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+
+			// Check if origin is allowed. Unless every origin is, the
+			// response depends on Origin even when it is absent or denied.
+			if !origins.any {
+				AddVary(w.Header(), "Origin")
+			}
+			allowed := false
+			if origin != "" {
+				if origins.any {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+					allowed = true
+				} else if origins.match(origin) || allowFunc != nil && allowFunc(origin) {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					allowed = true
+				}
+			}
+			if allowed {
+				if opts.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if exposedHeadersStr != "" && !isPreflight(r) {
+					w.Header().Set("Access-Control-Expose-Headers", exposedHeadersStr)
+				}
+			}
+
+			// Handle preflight requests. Other OPTIONS requests reach the
+			// route's handler.
+			if isPreflight(r) {
+				w.Header().Set("Access-Control-Allow-Methods", allowedMethodsStr)
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeadersStr)
+				w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+				if allowed && opts.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+					w.Header().Set("Access-Control-Allow-Private-Network", "true")
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isPreflight reports whether r is a CORS preflight: an OPTIONS request
+// naming the method of the request it precedes
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// originMatcher matches origins against exact origins and subdomain
+// patterns, case-insensitively
+type originMatcher struct {
+	any        bool
+	exact      map[string]bool
+	suffixes   []originSuffix
+	ignorePort bool
+}
+
+// originSuffix is a pattern such as https://*.example.com, split into
+// "https://" and ".example.com"
+type originSuffix struct {
+	scheme, suffix string
+}
+
+func newOriginMatcher(origins []string, ignorePort bool) *originMatcher {
+	m := &originMatcher{exact: make(map[string]bool), ignorePort: ignorePort}
+	for _, origin := range origins {
+		origin = strings.ToLower(origin)
+		if ignorePort {
+			origin = stripOriginPort(origin)
+		}
+		switch scheme, host, _ := strings.Cut(origin, "://"); {
+		case origin == "*":
+			m.any = true
+		case strings.HasPrefix(host, "*."):
+			m.suffixes = append(m.suffixes, originSuffix{scheme + "://", host[1:]})
+		default:
+			m.exact[origin] = true
+		}
+	}
+	return m
+}
+
+func (m *originMatcher) match(origin string) bool {
+	return m.any || m.matchListed(origin)
+}
+
+// matchListed matches origin against the listed origins and patterns,
+// ignoring "*"
+func (m *originMatcher) matchListed(origin string) bool {
+	origin = strings.ToLower(origin)
+	if m.ignorePort {
+		origin = stripOriginPort(origin)
+	}
+	if m.exact[origin] {
+		return true
+	}
+	for _, s := range m.suffixes {
+		// The subdomain must be non-empty: *.example.com doesn't match
+		// example.com itself
+		if strings.HasPrefix(origin, s.scheme) && strings.HasSuffix(origin, s.suffix) &&
+			len(origin) > len(s.scheme)+len(s.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripOriginPort removes the port from an origin such as
+// http://localhost:3000
+func stripOriginPort(origin string) string {
+	scheme, hostport, ok := strings.Cut(origin, "://")
+	if !ok {
+		return origin
+	}
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return scheme + "://" + host
+	}
+	return origin
+}
@@ -0,0 +1,180 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	allowOrigin := func(h http.Handler, origin string) string {
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	t.Run("Wildcard Subdomain", func(t *testing.T) {
+		h := CORSWithOptions(CORSOptions{AllowedOrigins: []string{"https://*.example.com"}})(ok)
+		for origin, allowed := range map[string]bool{
+			"https://acme.example.com":    true,
+			"https://a.b.example.com":     true,
+			"https://ACME.Example.com":    true,
+			"https://example.com":         false,
+			"http://acme.example.com":     false,
+			"https://acme.example.com.io": false,
+			"https://evilexample.com":     false,
+		} {
+			if got := allowOrigin(h, origin) == origin; got != allowed {
+				t.Errorf("Expected %s allowed to be %v, got %v", origin, allowed, got)
+			}
+		}
+	})
+
+	t.Run("Origin Func", func(t *testing.T) {
+		h := CORSWithOptions(CORSOptions{
+			AllowedOrigins:  []string{"https://app.example.com"},
+			AllowOriginFunc: func(origin string) bool { return origin == "https://shop.customer.io" },
+		})(ok)
+		if got := allowOrigin(h, "https://shop.customer.io"); got != "https://shop.customer.io" {
+			t.Errorf("Expected the func to allow the custom domain, got '%s'", got)
+		}
+		if got := allowOrigin(h, "https://other.io"); got != "" {
+			t.Errorf("Expected other origins denied, got '%s'", got)
+		}
+		if got := allowOrigin(h, "https://app.example.com"); got != "https://app.example.com" {
+			t.Errorf("Expected listed origins allowed, got '%s'", got)
+		}
+	})
+
+	t.Run("Ignore Port", func(t *testing.T) {
+		strict := CORS([]string{"http://localhost"}, nil, nil)(ok)
+		if got := allowOrigin(strict, "http://localhost:3000"); got != "" {
+			t.Errorf("Expected ports to matter by default, got '%s'", got)
+		}
+		loose := CORSWithOptions(CORSOptions{AllowedOrigins: []string{"http://localhost:8080", "https://*.dev.test"}, IgnorePort: true})(ok)
+		for _, origin := range []string{"http://localhost:3000", "http://localhost", "https://api.dev.test:8443"} {
+			if got := allowOrigin(loose, origin); got != origin {
+				t.Errorf("Expected %s allowed regardless of port, got '%s'", origin, got)
+			}
+		}
+	})
+
+	t.Run("Validate Patterns", func(t *testing.T) {
+		if err := (CORSOptions{AllowedOrigins: []string{"https://*.example.com"}}).Validate(); err != nil {
+			t.Errorf("Expected a subdomain pattern to validate, got %v", err)
+		}
+		if err := (CORSOptions{AllowedOrigins: []string{"https://foo.*.com"}}).Validate(); err == nil {
+			t.Error("Expected an error for a wildcard inside the host")
+		}
+	})
+}
+
+func TestCORSResponseHeaders(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := CORSWithOptions(CORSOptions{
+		AllowedOrigins:      []string{"https://app.example.com"},
+		AllowedMethods:      []string{MethodGet},
+		ExposedHeaders:      []string{"X-Request-ID", "X-Total-Count"},
+		AllowCredentials:    true,
+		AllowPrivateNetwork: true,
+	})(ok)
+	serve := func(method, origin string, headers map[string]string) http.Header {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("Origin", origin)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header()
+	}
+
+	t.Run("Actual Request", func(t *testing.T) {
+		got := serve(MethodGet, "https://app.example.com", nil)
+		if got.Get("Access-Control-Expose-Headers") != "X-Request-ID, X-Total-Count" {
+			t.Errorf("Expected exposed headers, got '%s'", got.Get("Access-Control-Expose-Headers"))
+		}
+		if got.Get("Access-Control-Allow-Credentials") != "true" {
+			t.Error("Expected credentials allowed")
+		}
+	})
+
+	t.Run("Denied Origin", func(t *testing.T) {
+		got := serve(MethodGet, "https://evil.example", nil)
+		if got.Get("Access-Control-Allow-Credentials") != "" || got.Get("Access-Control-Expose-Headers") != "" {
+			t.Errorf("Expected no CORS headers for a denied origin, got %v", got)
+		}
+	})
+
+	t.Run("Private Network Preflight", func(t *testing.T) {
+		got := serve(MethodOptions, "https://app.example.com", map[string]string{
+			"Access-Control-Request-Method":          MethodGet,
+			"Access-Control-Request-Private-Network": "true",
+		})
+		if got.Get("Access-Control-Allow-Private-Network") != "true" {
+			t.Errorf("Expected private network access allowed, got %v", got)
+		}
+		got = serve(MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": MethodGet})
+		if got.Get("Access-Control-Allow-Private-Network") != "" {
+			t.Error("Expected no private network header without the request header")
+		}
+	})
+
+	t.Run("Credentials With Any Origin", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected a panic for credentials with a \"*\" origin")
+			}
+		}()
+		CORSWithOptions(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	})
+}
+
+func TestCORSPreflight(t *testing.T) {
+	mux := New()
+	mux.Use(CORS([]string{"https://app.example.com"}, []string{MethodGet, "PROPFIND"}, []string{"Depth"}))
+	mux.Handle("/dav", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("DAV", "1, 2")
+		w.WriteHeader(http.StatusOK)
+	}), MethodOptions, MethodGet)
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(MethodOptions, "/dav", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Preflight", func(t *testing.T) {
+		w := serve(map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PROPFIND"})
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "GET, PROPFIND" {
+			t.Errorf("Expected a 204 preflight response, got %d %v", w.Code, w.Header())
+		}
+		if w.Header().Get("DAV") != "" {
+			t.Error("Expected the preflight not to reach the handler")
+		}
+	})
+
+	t.Run("Plain OPTIONS", func(t *testing.T) {
+		w := serve(nil)
+		if w.Code != http.StatusOK || w.Header().Get("DAV") != "1, 2" {
+			t.Errorf("Expected OPTIONS to reach the handler, got %d %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("Cross-Origin OPTIONS", func(t *testing.T) {
+		w := serve(map[string]string{"Origin": "https://app.example.com"})
+		if w.Code != http.StatusOK || w.Header().Get("DAV") != "1, 2" {
+			t.Errorf("Expected OPTIONS without Access-Control-Request-Method to reach the handler, got %d", w.Code)
+		}
+		if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Error("Expected the actual request to get CORS headers")
+		}
+	})
+}
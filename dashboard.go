@@ -0,0 +1,245 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"html/template"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DashboardPath is where HandleDashboard serves the dashboard by default
+const DashboardPath = "/debug/goflow"
+
+// Dashboard keeps request metrics per route in memory and serves them, with
+// the cache and rate limiter counters from Stats, as an HTML page or JSON.
+// It suits small deployments without an observability stack.
+type Dashboard struct {
+	// Samples is the number of recent durations kept per route for the
+	// percentiles. Defaults to 1000.
+	Samples int
+
+	mu      sync.Mutex
+	routes  map[string]*routeMetrics
+	started time.Time
+}
+
+// DashboardSnapshot is the data the dashboard shows
+type DashboardSnapshot struct {
+	Uptime       time.Duration      `json:"uptime"`
+	Routes       []RouteMetrics     `json:"routes"`
+	Caches       []CacheStats       `json:"caches,omitempty"`
+	RateLimiters []RateLimiterStats `json:"rate_limiters,omitempty"`
+}
+
+// RouteMetrics summarizes the requests for one method and route
+type RouteMetrics struct {
+	// Method is OTHER for requests with a nonstandard method that matched
+	// no route, so clients can't add entries at will
+	Method string `json:"method"`
+	// Route is the pattern, empty for requests that matched no route
+	Route    string `json:"route"`
+	Requests uint64 `json:"requests"`
+	// Rate is the requests per second over the last minute
+	Rate     float64        `json:"rate"`
+	P50      time.Duration  `json:"p50"`
+	P95      time.Duration  `json:"p95"`
+	P99      time.Duration  `json:"p99"`
+	Statuses map[int]uint64 `json:"statuses"`
+}
+
+// routeMetrics counts requests in per-second buckets for the rate, and
+// keeps a ring of recent durations for the percentiles
+type routeMetrics struct {
+	requests  uint64
+	statuses  map[int]uint64
+	durations []time.Duration
+	next      int
+	seconds   [60]int64
+	counts    [60]uint64
+}
+
+// NewDashboard creates an empty Dashboard. Pass its Record method to
+// Mux.OnRequestDone, or use HandleDashboard.
+func NewDashboard() *Dashboard {
+	return &Dashboard{Samples: 1000, routes: make(map[string]*routeMetrics), started: time.Now()}
+}
+
+// HandleDashboard records the requests the mux serves on a new Dashboard
+// and serves it at pattern, DashboardPath when empty. The page reveals
+// routes and traffic, so protect the route:
+//
+//	mux.HandleDashboard("").With(GoFlow.BasicAuth("ops", check))
+func (m *Mux) HandleDashboard(pattern string) *Route {
+	if pattern == "" {
+		pattern = DashboardPath
+	}
+	d := NewDashboard()
+	m.OnRequestDone(d.Record)
+	return m.Handle(pattern, d, MethodGet, MethodHead)
+}
+
+// Record adds a completed request to the metrics
+func (d *Dashboard) Record(info RequestInfo) {
+	method := info.Method
+	if info.Route == "" && !slices.Contains(standardMethods, method) {
+		method = "OTHER"
+	}
+	key := method + " " + info.Route
+	now := time.Now().Unix()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	rm, ok := d.routes[key]
+	if !ok {
+		rm = &routeMetrics{statuses: make(map[int]uint64)}
+		d.routes[key] = rm
+	}
+	rm.requests++
+	rm.statuses[info.Status]++
+
+	samples := d.Samples
+	if samples <= 0 {
+		samples = 1000
+	}
+	if len(rm.durations) < samples {
+		rm.durations = append(rm.durations, info.Duration)
+	} else {
+		rm.durations[rm.next] = info.Duration
+		rm.next = (rm.next + 1) % len(rm.durations)
+	}
+
+	i := now % int64(len(rm.seconds))
+	if rm.seconds[i] != now {
+		rm.seconds[i], rm.counts[i] = now, 0
+	}
+	rm.counts[i]++
+}
+
+// Snapshot returns the current metrics, with routes sorted by pattern
+func (d *Dashboard) Snapshot() DashboardSnapshot {
+	stats := Stats()
+	snap := DashboardSnapshot{
+		Uptime:       time.Since(d.started).Round(time.Second),
+		Caches:       stats.Caches,
+		RateLimiters: stats.RateLimiters,
+	}
+	now := time.Now().Unix()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, key := range slices.Sorted(maps.Keys(d.routes)) {
+		rm := d.routes[key]
+		method, route, _ := strings.Cut(key, " ")
+		metrics := RouteMetrics{
+			Method:   method,
+			Route:    route,
+			Requests: rm.requests,
+			Statuses: maps.Clone(rm.statuses),
+		}
+
+		var recent uint64
+		for i, sec := range rm.seconds {
+			if now-sec < int64(len(rm.seconds)) {
+				recent += rm.counts[i]
+			}
+		}
+		window := time.Since(d.started).Seconds()
+		if window > 60 {
+			window = 60
+		}
+		metrics.Rate = float64(recent) / max(window, 1)
+
+		sorted := slices.Clone(rm.durations)
+		slices.Sort(sorted)
+		metrics.P50 = percentile(sorted, 0.50)
+		metrics.P95 = percentile(sorted, 0.95)
+		metrics.P99 = percentile(sorted, 0.99)
+		snap.Routes = append(snap.Routes, metrics)
+	}
+	return snap
+}
+
+// standardMethods are recorded as is even for requests that matched no route
+var standardMethods = []string{
+	MethodGet, MethodHead, MethodPost, MethodPut, MethodPatch,
+	MethodDelete, MethodOptions, MethodConnect, MethodTrace,
+}
+
+// percentile returns the p-th quantile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p*float64(len(sorted)-1)+0.5)]
+}
+
+// ServeHTTP renders the dashboard, as JSON when the request asks for
+// application/json or has ?format=json
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	snap := d.Snapshot()
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snap)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboardTemplate.Execute(w, snap)
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string {
+		return d.Round(10 * time.Microsecond).String()
+	},
+	"statuses": func(m map[int]uint64) string {
+		var parts []string
+		for _, code := range slices.Sorted(maps.Keys(m)) {
+			parts = append(parts, toString(code)+": "+strconv.FormatUint(m[code], 10))
+		}
+		return strings.Join(parts, ", ")
+	},
+	"hitRate": func(c CacheStats) string {
+		if c.Hits+c.Misses == 0 {
+			return "-"
+		}
+		return strconv.FormatUint(c.Hits*100/(c.Hits+c.Misses), 10) + "%"
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="5">
+<title>GoFlow</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child, td.text { text-align: left; }
+</style>
+</head>
+<body>
+<h1>GoFlow</h1>
+<p>Up {{.Uptime}}</p>
+<h2>Routes</h2>
+<table>
+<tr><th>Route</th><th>Requests</th><th>Req/s</th><th>p50</th><th>p95</th><th>p99</th><th>Statuses</th></tr>
+{{range .Routes}}<tr><td>{{.Method}} {{if .Route}}{{.Route}}{{else}}(no route){{end}}</td><td>{{.Requests}}</td><td>{{printf "%.2f" .Rate}}</td><td>{{ms .P50}}</td><td>{{ms .P95}}</td><td>{{ms .P99}}</td><td class="text">{{statuses .Statuses}}</td></tr>
+{{end}}</table>
+{{if .Caches}}<h2>Caches</h2>
+<table>
+<tr><th>Cache</th><th>Entries</th><th>Bytes</th><th>Hits</th><th>Misses</th><th>Hit rate</th></tr>
+{{range $i, $c := .Caches}}<tr><td>#{{$i}}</td><td>{{$c.Entries}}</td><td>{{$c.Bytes}}</td><td>{{$c.Hits}}</td><td>{{$c.Misses}}</td><td>{{hitRate $c}}</td></tr>
+{{end}}</table>{{end}}
+{{if .RateLimiters}}<h2>Rate Limiters</h2>
+<table>
+<tr><th>Limiter</th><th>Buckets</th><th>Rejected</th></tr>
+{{range $i, $l := .RateLimiters}}<tr><td>#{{$i}}</td><td>{{$l.Buckets}}</td><td>{{$l.Rejected}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,103 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashboard(t *testing.T) {
+	mux := New()
+	mux.HandleDashboard("")
+	mux.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if Param(r.Context(), "id") == "0" {
+			http.NotFound(w, r)
+		}
+	}), MethodGet)
+	mux.Handle("/limited", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MethodGet).
+		With(RateLimit(1, time.Minute, 0))
+	mux.Handle("/cached", Cache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cached"))
+	})), MethodGet)
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/0", "/missing", "/limited", "/limited", "/cached", "/cached"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, path, nil))
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, DashboardPath+"?format=json", nil))
+		var snap DashboardSnapshot
+		if err := json.Unmarshal(w.Body.Bytes(), &snap); err != nil {
+			t.Fatal(err)
+		}
+
+		routes := make(map[string]RouteMetrics)
+		for _, rm := range snap.Routes {
+			routes[rm.Method+" "+rm.Route] = rm
+		}
+		users := routes["GET /users/:id"]
+		if users.Requests != 3 || users.Statuses[200] != 2 || users.Statuses[404] != 1 {
+			t.Errorf("Expected 3 requests with 2 200s and a 404, got %+v", users)
+		}
+		if users.Rate <= 0 || users.P99 < users.P50 {
+			t.Errorf("Expected a rate and ordered percentiles, got %+v", users)
+		}
+		if routes["GET "].Statuses[404] != 1 {
+			t.Errorf("Expected the unmatched request recorded, got %+v", routes["GET "])
+		}
+
+		var rejected, hits uint64
+		for _, l := range snap.RateLimiters {
+			rejected += l.Rejected
+		}
+		for _, c := range snap.Caches {
+			hits += c.Hits
+		}
+		if rejected == 0 || hits == 0 {
+			t.Errorf("Expected limiter rejections and cache hits, got %d and %d", rejected, hits)
+		}
+	})
+
+	t.Run("HTML", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, DashboardPath, nil))
+		body := w.Body.String()
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+			t.Errorf("Expected an HTML page, got %s", ct)
+		}
+		for _, want := range []string{"GET /users/:id", "(no route)", "Rate Limiters", "Hit rate"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected %q on the page", want)
+			}
+		}
+	})
+
+	t.Run("Bounded Keys", func(t *testing.T) {
+		d := NewDashboard()
+		for i := 0; i < 100; i++ {
+			d.Record(RequestInfo{Method: "X" + strconv.Itoa(i), Path: "/" + strconv.Itoa(i), Status: 405})
+		}
+		d.Record(RequestInfo{Method: "PROPFIND", Route: "/dav/...", Status: 207})
+
+		routes := d.Snapshot().Routes
+		if len(routes) != 2 || routes[0].Method != "OTHER" || routes[0].Requests != 100 || routes[1].Method != "PROPFIND" {
+			t.Errorf("Expected unmatched methods in one OTHER entry, got %+v", routes)
+		}
+	})
+
+	t.Run("Percentiles", func(t *testing.T) {
+		d := NewDashboard()
+		for i := 1; i <= 100; i++ {
+			d.Record(RequestInfo{Method: MethodGet, Route: "/", Status: 200, Duration: time.Duration(i) * time.Millisecond})
+		}
+		rm := d.Snapshot().Routes[0]
+		if rm.P50 != 51*time.Millisecond || rm.P95 != 95*time.Millisecond || rm.P99 != 99*time.Millisecond {
+			t.Errorf("Expected p50=51ms p95=95ms p99=99ms, got %s %s %s", rm.P50, rm.P95, rm.P99)
+		}
+	})
+}
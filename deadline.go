@@ -0,0 +1,152 @@
+package GoFlow
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RequestTimeoutHeader tells an upstream how many milliseconds the caller
+// is still willing to wait
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// InjectDeadline sets the X-Request-Timeout header of an outgoing request,
+// and grpc-timeout for gRPC requests, to the time left until ctx's
+// deadline, so the next service stops once the caller has given up.
+// Without a deadline the headers are left alone. Proxy and
+// DeadlineTransport call it for every request.
+func InjectDeadline(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	left := max(time.Until(deadline), 0)
+	h.Set(RequestTimeoutHeader, strconv.FormatInt(left.Milliseconds(), 10))
+	if isStreamingRPC(&http.Request{Header: h}) {
+		h.Set("Grpc-Timeout", formatGRPCTimeout(left))
+	}
+}
+
+// DeadlineTransport wraps base (http.DefaultTransport if nil) to inject
+// the deadline of each request's context into its headers. Requests whose
+// deadline has already passed fail with context.DeadlineExceeded without
+// being sent. Use it for clients calling other services from handlers:
+//
+//	client := &http.Client{Transport: GoFlow.DeadlineTransport(nil)}
+//	req, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+func DeadlineTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &deadlineTransport{base: base}
+}
+
+type deadlineTransport struct {
+	base http.RoundTripper
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	deadline, ok := req.Context().Deadline()
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	if time.Until(deadline) <= 0 {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, context.DeadlineExceeded
+	}
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	InjectDeadline(req.Context(), req.Header)
+	return t.base.RoundTrip(req)
+}
+
+// RequestDeadline applies the deadline a caller sent in X-Request-Timeout
+// or grpc-timeout to the request context, capped at limit when it is
+// non-zero, so work stops when the caller gives up. Requests whose caller
+// has no time left are answered with 504 right away.
+func RequestDeadline(limit time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, ok := incomingTimeout(r.Header)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if timeout <= 0 {
+				debugNote(r.Context(), "deadline: caller has no time left")
+				http.Error(w, StatusText(r.Context(), http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+				return
+			}
+			if limit > 0 && timeout > limit {
+				timeout = limit
+			}
+			debugNote(r.Context(), "deadline: %v from caller", timeout)
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// incomingTimeout reads the caller's timeout from grpc-timeout or
+// X-Request-Timeout
+func incomingTimeout(h http.Header) (time.Duration, bool) {
+	if v := h.Get("Grpc-Timeout"); v != "" {
+		return parseGRPCTimeout(v)
+	}
+	if v := h.Get(RequestTimeoutHeader); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	return 0, false
+}
+
+// grpcTimeoutUnits are the grpc-timeout units, which allow at most 8 digits
+var grpcTimeoutUnits = []struct {
+	unit   time.Duration
+	suffix byte
+}{
+	{time.Nanosecond, 'n'},
+	{time.Microsecond, 'u'},
+	{time.Millisecond, 'm'},
+	{time.Second, 'S'},
+	{time.Minute, 'M'},
+	{time.Hour, 'H'},
+}
+
+// formatGRPCTimeout uses milliseconds unless d is shorter or needs more
+// than 8 digits
+func formatGRPCTimeout(d time.Duration) string {
+	if d < time.Millisecond {
+		return strconv.FormatInt(max(d.Microseconds(), 0), 10) + "u"
+	}
+	for _, u := range grpcTimeoutUnits[2:5] {
+		if v := d / u.unit; v < 1e8 {
+			return strconv.FormatInt(int64(v), 10) + string(u.suffix)
+		}
+	}
+	// A time.Duration never exceeds 8 digits of hours
+	return strconv.FormatInt(int64(d/time.Hour), 10) + "H"
+}
+
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	for _, u := range grpcTimeoutUnits {
+		if v[len(v)-1] == u.suffix {
+			return time.Duration(n) * u.unit, true
+		}
+	}
+	return 0, false
+}
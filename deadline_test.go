@@ -0,0 +1,116 @@
+package GoFlow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeadlinePropagation(t *testing.T) {
+	t.Run("Proxy Headers", func(t *testing.T) {
+		var got http.Header
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+		}))
+		defer upstream.Close()
+
+		mux := New()
+		mux.Use(Timeout(2 * time.Second))
+		mux.Proxy("/api/...", upstream.URL, ProxyOptions{})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/api/users", nil))
+		ms, _ := strconv.Atoi(got.Get(RequestTimeoutHeader))
+		if ms <= 1500 || ms > 2000 {
+			t.Errorf("Expected about 2000ms left, got %q", got.Get(RequestTimeoutHeader))
+		}
+
+		r := httptest.NewRequest(MethodPost, "/api/Greeter/Hello", nil)
+		r.Header.Set("Content-Type", "application/grpc")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+		if v := got.Get("Grpc-Timeout"); len(v) < 2 || v[len(v)-1] != 'm' {
+			t.Errorf("Expected a grpc-timeout in milliseconds, got %q", v)
+		}
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		var got string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get(RequestTimeoutHeader)
+		}))
+		defer upstream.Close()
+		client := &http.Client{Transport: DeadlineTransport(nil)}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, MethodGet, upstream.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got == "" || req.Header.Get(RequestTimeoutHeader) != "" {
+			t.Errorf("Expected the header sent without changing the request, got %q", got)
+		}
+
+		expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		got = ""
+		req, _ = http.NewRequestWithContext(expired, MethodGet, upstream.URL, nil)
+		if _, err := client.Do(req); err == nil || got != "" {
+			t.Error("Expected an expired request to fail without being sent")
+		}
+	})
+
+	t.Run("Incoming Deadline", func(t *testing.T) {
+		var left time.Duration
+		handler := RequestDeadline(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, _ := r.Context().Deadline()
+			left = time.Until(deadline)
+		}))
+
+		tests := []struct {
+			header, value string
+			min, max      time.Duration
+		}{
+			{RequestTimeoutHeader, "250", 200 * time.Millisecond, 250 * time.Millisecond},
+			{"Grpc-Timeout", "300m", 250 * time.Millisecond, 300 * time.Millisecond},
+			{"Grpc-Timeout", "1H", 900 * time.Millisecond, time.Second},
+		}
+		for _, tt := range tests {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.Header.Set(tt.header, tt.value)
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if left <= tt.min || left > tt.max {
+				t.Errorf("%s %s: expected between %v and %v left, got %v", tt.header, tt.value, tt.min, tt.max, left)
+			}
+		}
+
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set(RequestTimeoutHeader, "0")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status code %d, got %d", http.StatusGatewayTimeout, w.Code)
+		}
+	})
+
+	t.Run("gRPC Timeout Format", func(t *testing.T) {
+		tests := map[time.Duration]string{
+			500 * time.Microsecond:  "500u",
+			1500 * time.Millisecond: "1500m",
+			48 * time.Hour:          "172800S",
+		}
+		for d, expected := range tests {
+			if got := formatGRPCTimeout(d); got != expected {
+				t.Errorf("formatGRPCTimeout(%v) = %q, want %q", d, got, expected)
+			}
+			if back, ok := parseGRPCTimeout(expected); !ok || back != d {
+				t.Errorf("parseGRPCTimeout(%q) = %v, want %v", expected, back, d)
+			}
+		}
+	})
+}
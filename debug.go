@@ -0,0 +1,209 @@
+package GoFlow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugOptions configures the debug mode enabled with Mux.Debug
+type DebugOptions struct {
+	// Header and Query name the request header and query parameter that
+	// ask for a trace. They default to X-GoFlow-Debug and goflow_debug.
+	Header string
+	Query  string
+
+	// Authorize decides who may trace requests, e.g. by checking the
+	// principal or the client's address. It is required. It runs once the
+	// request is served, with the request as the innermost middleware that
+	// ran received it, so principals and other values the route's auth
+	// middleware put in the context are available. Traces it rejects are
+	// discarded.
+	Authorize func(r *http.Request) bool
+
+	// Logger receives one record per trace. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// DebugTrace describes how a request was routed and served
+type DebugTrace struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Pattern string            `json:"pattern,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+
+	// Query and Header are the request's, with the values the route's
+	// redaction policy lists replaced
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Middleware lists the route's middleware from the outermost in, with
+	// the handler last
+	Middleware []MiddlewareTiming `json:"middleware,omitempty"`
+
+	// Events are decisions such as cache hits and rate limits
+	Events []string `json:"events,omitempty"`
+
+	Status   int           `json:"status"`
+	Duration time.Duration `json:"duration"`
+
+	route *Route
+	// request is the request as the innermost middleware that ran saw it
+	request *http.Request
+	mu      sync.Mutex
+}
+
+// MiddlewareTiming is the time spent in one middleware. Duration includes
+// the middleware and handler it called; Self excludes them.
+type MiddlewareTiming struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Self     time.Duration `json:"self"`
+	Ran      bool          `json:"ran"`
+}
+
+// DebugTraceHeader is the trailer a trace is returned in, as JSON
+const DebugTraceHeader = "X-GoFlow-Debug-Trace"
+
+type debugTraceKey struct{}
+
+// Debug enables tracing of requests carrying the debug header or query
+// parameter that opts.Authorize accepts. Their trace is logged and
+// returned in the DebugTraceHeader trailer. Other requests are served as
+// usual.
+func (m *Mux) Debug(opts DebugOptions) {
+	if opts.Authorize == nil {
+		panic("GoFlow: DebugOptions.Authorize is required")
+	}
+	if opts.Header == "" {
+		opts.Header = "X-GoFlow-Debug"
+	}
+	if opts.Query == "" {
+		opts.Query = "goflow_debug"
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	m.table.debug = &opts
+}
+
+func (o *DebugOptions) requested(r *http.Request) bool {
+	return r.Header.Get(o.Header) != "" || r.URL.Query().Has(o.Query)
+}
+
+func (m *Mux) serveDebug(w http.ResponseWriter, r *http.Request, opts *DebugOptions) {
+	trace := &DebugTrace{Method: r.Method, Path: r.URL.Path}
+	start := time.Now()
+
+	// Announced up front so HTTP/1.1 responses are chunked; the trailer is
+	// only filled in if Authorize accepts the served request
+	w.Header().Add("Trailer", DebugTraceHeader)
+	sw := &statusWriter{ResponseWriter: w}
+	m.dispatch(wrapWriter(sw), r.WithContext(context.WithValue(r.Context(), debugTraceKey{}, trace)))
+
+	trace.mu.Lock()
+	served := trace.request
+	trace.mu.Unlock()
+	if served == nil {
+		served = r
+	}
+	if !opts.Authorize(served) {
+		return
+	}
+
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.Duration = time.Since(start)
+	trace.Status = sw.status
+	redaction := redactionFor(trace.route)
+	trace.Query = redaction.RedactQuery(r.URL.RawQuery)
+	trace.Header = redaction.RedactHeader(r.Header)
+	if trace.Pattern == "" {
+		trace.Events = append(trace.Events, "no route matched")
+	}
+	for i := range trace.Middleware {
+		trace.Middleware[i].Self = trace.Middleware[i].Duration
+		if i+1 < len(trace.Middleware) {
+			trace.Middleware[i].Self -= trace.Middleware[i+1].Duration
+		}
+	}
+
+	data, _ := json.Marshal(trace)
+	w.Header().Set(DebugTraceHeader, string(data))
+	opts.Logger.LogAttrs(r.Context(), slog.LevelInfo, "request trace",
+		slog.String("method", trace.Method),
+		slog.String("path", trace.Path),
+		slog.String("pattern", trace.Pattern),
+		slog.Int("status", trace.Status),
+		slog.Duration("duration", trace.Duration),
+		slog.String("trace", string(data)),
+	)
+}
+
+// debugNote adds an event to the request's trace, if it is being traced
+func debugNote(ctx context.Context, format string, args ...any) {
+	if trace, ok := ctx.Value(debugTraceKey{}).(*DebugTrace); ok {
+		trace.mu.Lock()
+		trace.Events = append(trace.Events, fmt.Sprintf(format, args...))
+		trace.mu.Unlock()
+	}
+}
+
+// traced builds the route's chain with every middleware timed, and records
+// the match in trace
+func (rt *Route) traced(trace *DebugTrace, params map[string]string) http.Handler {
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.Pattern = rt.pattern
+	trace.Params = params
+	trace.route = rt
+
+	var timings []MiddlewareTiming
+	timed := func(name string, h http.Handler) http.Handler {
+		i := len(timings)
+		timings = append(timings, MiddlewareTiming{Name: name})
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trace.mu.Lock()
+			trace.request = r
+			trace.mu.Unlock()
+			start := time.Now()
+			h.ServeHTTP(w, r)
+			trace.mu.Lock()
+			// timings is reversed once built, so entry i moves
+			t := &trace.Middleware[len(trace.Middleware)-1-i]
+			t.Duration, t.Ran = time.Since(start), true
+			trace.mu.Unlock()
+		})
+	}
+	h := rt.build(timed)
+	slices.Reverse(timings)
+	trace.Middleware = timings
+	return h
+}
+
+// funcName names a middleware by the function that created it, e.g.
+// "GoFlow.Logger" for the closure Logger returns. Closures are suffixed
+// ".func1", or ".1" when the function creating them was inlined.
+func funcName(fn any) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = name[strings.LastIndexByte(name, '/')+1:]
+	for {
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return name
+		}
+		suffix := strings.TrimPrefix(name[i+1:], "func")
+		if suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+			return name
+		}
+		name = name[:i]
+	}
+}
@@ -0,0 +1,138 @@
+package GoFlow
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugTrace(t *testing.T) {
+	var logs bytes.Buffer
+	mux := New()
+	mux.Debug(DebugOptions{
+		Authorize: func(r *http.Request) bool { return r.Header.Get("X-Admin") == "yes" },
+		Logger:    slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	mux.Use(RateLimit(100, time.Minute, 10), Cache(time.Minute))
+	mux.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("user " + Param(r.Context(), "id")))
+	}), MethodGet).With(SetHeaders(map[string]string{"X-API-Version": "2"}))
+
+	trace := func(path string, headers map[string]string) (*httptest.ResponseRecorder, *DebugTrace) {
+		r := httptest.NewRequest(MethodGet, path, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		data := w.Result().Trailer.Get(DebugTraceHeader)
+		if data == "" {
+			return w, nil
+		}
+		var tr DebugTrace
+		if err := json.Unmarshal([]byte(data), &tr); err != nil {
+			t.Fatalf("Expected a JSON trace, got %v", err)
+		}
+		return w, &tr
+	}
+
+	t.Run("Routing Decision", func(t *testing.T) {
+		w, tr := trace("/users/42", map[string]string{"X-GoFlow-Debug": "1", "X-Admin": "yes"})
+		if tr == nil {
+			t.Fatal("Expected a trace trailer")
+		}
+		if w.Body.String() != "user 42" || tr.Status != http.StatusOK {
+			t.Errorf("Expected the request served as usual, got %d '%s'", tr.Status, w.Body.String())
+		}
+		if tr.Pattern != "/users/:id" || tr.Params["id"] != "42" {
+			t.Errorf("Expected the matched pattern and params, got %s %v", tr.Pattern, tr.Params)
+		}
+		var names []string
+		for _, m := range tr.Middleware {
+			names = append(names, m.Name)
+			if !m.Ran {
+				t.Errorf("Expected %s to have run", m.Name)
+			}
+		}
+		expected := []string{"GoFlow.RateLimitWithOptions", "GoFlow.CacheWithOptions", "GoFlow.SetHeaders", "handler"}
+		if !equalSlices(names, expected) {
+			t.Errorf("Expected middleware %v, got %v", expected, names)
+		}
+		events := strings.Join(tr.Events, "; ")
+		if !strings.Contains(events, "rate limit: ") || !strings.Contains(events, "cache: miss") {
+			t.Errorf("Expected rate limit and cache decisions, got %q", events)
+		}
+		if !strings.Contains(logs.String(), "pattern=/users/:id") {
+			t.Errorf("Expected the trace logged, got '%s'", logs.String())
+		}
+	})
+
+	t.Run("Cache Hit", func(t *testing.T) {
+		_, tr := trace("/users/42?goflow_debug", map[string]string{"X-Admin": "yes"})
+		if tr == nil {
+			t.Fatal("Expected the query flag to enable tracing")
+		}
+		_, tr = trace("/users/42", map[string]string{"X-GoFlow-Debug": "1", "X-Admin": "yes"})
+		if !strings.Contains(strings.Join(tr.Events, "; "), "cache: hit") {
+			t.Errorf("Expected a cache hit, got %q", tr.Events)
+		}
+		if tr.Middleware[len(tr.Middleware)-1].Ran {
+			t.Error("Expected the handler not to run on a cache hit")
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		if _, tr := trace("/users/1", map[string]string{"X-GoFlow-Debug": "1"}); tr != nil {
+			t.Error("Expected no trace without authorization")
+		}
+	})
+
+	t.Run("Authenticated Principal", func(t *testing.T) {
+		mux := New()
+		mux.Debug(DebugOptions{
+			Authorize: func(r *http.Request) bool {
+				p := GetPrincipal(r.Context())
+				return p != nil && p.Subject == "admin"
+			},
+			Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		mux.Use(BasicAuth("api", BasicAuthUsers(map[string]string{"admin": "secret", "bob": "hunter2"})))
+		mux.Handle("/reports", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("report"))
+		}), MethodGet)
+
+		for _, tt := range []struct {
+			user, password string
+			traced         bool
+		}{
+			{"admin", "secret", true},
+			{"bob", "hunter2", false},
+		} {
+			r := httptest.NewRequest(MethodGet, "/reports", nil)
+			r.Header.Set("X-GoFlow-Debug", "1")
+			r.SetBasicAuth(tt.user, tt.password)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if traced := w.Result().Trailer.Get(DebugTraceHeader) != ""; traced != tt.traced {
+				t.Errorf("Expected %s traced: %v, got %v", tt.user, tt.traced, traced)
+			}
+			if w.Body.String() != "report" {
+				t.Errorf("Expected the request served as usual, got '%s'", w.Body.String())
+			}
+		}
+	})
+
+	t.Run("No Route", func(t *testing.T) {
+		w, tr := trace("/nowhere", map[string]string{"X-GoFlow-Debug": "1", "X-Admin": "yes"})
+		if w.Code != http.StatusNotFound || tr == nil || !contains(tr.Events, "no route matched") {
+			t.Errorf("Expected a 404 trace, got %d %+v", w.Code, tr)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Deprecation describes the retirement of a route, see Route.Deprecate
+type Deprecation struct {
+	// Since is when the route was deprecated, sent in the Deprecation
+	// header (RFC 9745). When zero the header is "true".
+	Since time.Time
+
+	// Sunset is when the route will stop responding, sent in the Sunset
+	// header (RFC 8594). Optional.
+	Sunset time.Time
+
+	// Successor is the URL of the route replacing this one, and Policy a
+	// page documenting the deprecation. Both are sent as Link headers.
+	Successor string
+	Policy    string
+}
+
+type deprecationKey struct{}
+
+// Deprecate marks the route as deprecated: responses carry Deprecation,
+// Sunset and Link headers, the route is flagged in generated documentation,
+// and its requests are counted in Stats
+func (rt *Route) Deprecate(d Deprecation) *Route {
+	rt.Set(deprecationKey{}, d)
+	rt.compile()
+	return rt
+}
+
+// Deprecate sets Route.Deprecate for routes registered afterwards on this
+// mux or group
+func (m *Mux) Deprecate(d Deprecation) {
+	m.Set(deprecationKey{}, d)
+}
+
+// deprecate adds the deprecation headers to every response of the route,
+// including those answered by its middleware, and counts its requests
+func deprecate(d Deprecation, calls *atomic.Uint64, next http.Handler) http.Handler {
+	deprecation := "true"
+	if !d.Since.IsZero() {
+		deprecation = "@" + strconv.FormatInt(d.Since.Unix(), 10)
+	}
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	var links []string
+	if d.Successor != "" {
+		links = append(links, "<"+d.Successor+`>; rel="successor-version"`)
+	}
+	if d.Policy != "" {
+		links = append(links, "<"+d.Policy+`>; rel="deprecation"; type="text/html"`)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		h := w.Header()
+		h.Set("Deprecation", deprecation)
+		if sunset != "" {
+			h.Set("Sunset", sunset)
+		}
+		for _, link := range links {
+			h.Add("Link", link)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
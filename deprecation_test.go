@@ -0,0 +1,75 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecation(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mux := New()
+	mux.Handle("/v1/users", ok, MethodGet).Deprecate(Deprecation{
+		Since:     since,
+		Sunset:    sunset,
+		Successor: "/v2/users",
+		Policy:    "https://example.com/deprecations",
+	})
+	mux.Handle("/v2/users", ok, MethodGet)
+	mux.Group(func(m *Mux) {
+		m.Deprecate(Deprecation{})
+		m.Use(BasicAuth("api", func(u, p string) bool { return false }))
+		m.Handle("/v1/orders", ok, MethodGet)
+	})
+
+	t.Run("Headers", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/v1/users", nil))
+		h := w.Header()
+		if h.Get("Deprecation") != "@1767225600" {
+			t.Errorf("Expected Deprecation @1767225600, got %q", h.Get("Deprecation"))
+		}
+		if h.Get("Sunset") != "Thu, 31 Dec 2026 23:59:59 GMT" {
+			t.Errorf("Expected the sunset date, got %q", h.Get("Sunset"))
+		}
+		links := h.Values("Link")
+		if len(links) != 2 || links[0] != `</v2/users>; rel="successor-version"` {
+			t.Errorf("Expected successor and policy links, got %v", links)
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/v2/users", nil))
+		if w.Header().Get("Deprecation") != "" {
+			t.Error("Expected no Deprecation header on the successor")
+		}
+	})
+
+	t.Run("Group And Middleware Responses", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/v1/orders", nil))
+		if w.Code != http.StatusUnauthorized || w.Header().Get("Deprecation") != "true" {
+			t.Errorf("Expected a 401 still marked deprecated, got %d %q", w.Code, w.Header().Get("Deprecation"))
+		}
+		if !mux.Routes()[2].Doc().Deprecated {
+			t.Error("Expected the group route documented as deprecated")
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		var found *DeprecatedRouteStats
+		for _, ms := range Stats().Muxes {
+			for i, d := range ms.Deprecated {
+				if d.Pattern == "/v1/users" {
+					found = &ms.Deprecated[i]
+				}
+			}
+		}
+		if found == nil || found.Requests != 1 || !found.Sunset.Equal(sunset) {
+			t.Errorf("Expected one counted request to /v1/users, got %+v", found)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package GoFlow
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// DocsUI selects the API reference renderer served by MountAPIDocs
+type DocsUI int
+
+const (
+	// SwaggerUI renders an interactive console that can send requests
+	SwaggerUI DocsUI = iota
+	// Redoc renders a three-panel reference page
+	Redoc
+)
+
+// APIDocsOptions configures MountAPIDocsWithOptions
+type APIDocsOptions struct {
+	// UI selects the renderer. Defaults to SwaggerUI.
+	UI DocsUI
+
+	// SpecURL is where the OpenAPI document is served. Defaults to
+	// "/openapi.json", as mounted by the openapi package.
+	SpecURL string
+
+	// Title is the page title. Defaults to "API Reference".
+	Title string
+
+	// AssetsURL is the base URL of the swagger-ui-dist or redoc package.
+	// Defaults to the jsDelivr CDN; point it at a self-hosted copy for
+	// offline deployments or a strict Content-Security-Policy.
+	AssetsURL string
+}
+
+var apiDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+{{- if .Redoc}}
+<script src="{{.AssetsURL}}/bundles/redoc.standalone.js"></script>
+{{- else}}
+<link rel="stylesheet" href="{{.AssetsURL}}/swagger-ui.css">
+<script src="{{.AssetsURL}}/swagger-ui-bundle.js"></script>
+{{- end}}
+</head>
+<body>
+{{- if .Redoc}}
+<redoc spec-url="{{.SpecURL}}"></redoc>
+{{- else}}
+<div id="swagger-ui"></div>
+<script>
+window.onload = function () {
+	SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui", deepLinking: true});
+};
+</script>
+{{- end}}
+</body>
+</html>
+`))
+
+// MountAPIDocs serves a Swagger UI API reference at prefix for the OpenAPI
+// document at /openapi.json. The page itself is hidden from the document.
+// The returned route can be protected like any other:
+//
+//	mux.MountAPIDocs("/docs").With(GoFlow.BasicAuth("docs", GoFlow.BasicAuthUsers(users)))
+func (m *Mux) MountAPIDocs(prefix string) *Route {
+	return m.MountAPIDocsWithOptions(prefix, APIDocsOptions{})
+}
+
+// MountAPIDocsWithOptions is MountAPIDocs with a choice of renderer, spec
+// location and asset source
+func (m *Mux) MountAPIDocsWithOptions(prefix string, opts APIDocsOptions) *Route {
+	if opts.SpecURL == "" {
+		opts.SpecURL = "/openapi.json"
+	}
+	if opts.Title == "" {
+		opts.Title = "API Reference"
+	}
+	if opts.AssetsURL == "" {
+		opts.AssetsURL = "https://cdn.jsdelivr.net/npm/swagger-ui-dist@5"
+		if opts.UI == Redoc {
+			opts.AssetsURL = "https://cdn.jsdelivr.net/npm/redoc@2"
+		}
+	}
+
+	var buf bytes.Buffer
+	err := apiDocsTemplate.Execute(&buf, struct {
+		APIDocsOptions
+		AssetsURL string
+		Redoc     bool
+	}{opts, strings.TrimSuffix(opts.AssetsURL, "/"), opts.UI == Redoc})
+	if err != nil {
+		panic("GoFlow: " + err.Error())
+	}
+	page := buf.Bytes()
+
+	return m.Handle(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}), MethodGet).Hidden()
+}
@@ -0,0 +1,54 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIDocs(t *testing.T) {
+	t.Run("Swagger UI", func(t *testing.T) {
+		mux := New()
+		mux.MountAPIDocs("/docs").With(BasicAuth("docs", BasicAuthUsers(map[string]string{"dev": "pw"})))
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/docs", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+
+		r := httptest.NewRequest(MethodGet, "/docs/", nil)
+		r.SetBasicAuth("dev", "pw")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("Expected HTML page, got %d '%s'", w.Code, w.Header().Get("Content-Type"))
+		}
+		for _, want := range []string{"swagger-ui-dist@5/swagger-ui-bundle.js", `url: "/openapi.json"`, "<title>API Reference</title>"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected page to contain '%s', got:\n%s", want, body)
+			}
+		}
+	})
+
+	t.Run("Redoc", func(t *testing.T) {
+		mux := New()
+		mux.MountAPIDocsWithOptions("/reference", APIDocsOptions{
+			UI:        Redoc,
+			SpecURL:   "/v1/openapi.yaml",
+			Title:     "Orders <API>",
+			AssetsURL: "/assets/redoc/",
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/reference", nil))
+		body := w.Body.String()
+		for _, want := range []string{`src="/assets/redoc/bundles/redoc.standalone.js"`, `spec-url="/v1/openapi.yaml"`, "Orders &lt;API&gt;"} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected page to contain '%s', got:\n%s", want, body)
+			}
+		}
+	})
+}
@@ -0,0 +1,398 @@
+package GoFlow
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MiddlewareConfig groups middleware settings so operators can tune them
+// from a file or the environment
+type MiddlewareConfig struct {
+	Security    SecurityOptions
+	RateLimit   RateLimitOptions
+	CORS        CORSOptions
+	Compression CompressionOptions
+}
+
+// LoadMiddlewareConfig reads a YAML or JSON file at path, if path is not
+// empty, then applies environment overrides under envPrefix, if not empty,
+// and validates the result. Keys are the snake_case field names:
+//
+//	security:
+//	  hsts: true
+//	  csp: "default-src 'self'"
+//	  rate_limit:
+//	    requests: 100
+//	    duration: 1m
+//	compression:
+//	  level: 6
+//
+// and the matching variables are e.g. APP_SECURITY_HSTS and
+// APP_COMPRESSION_LEVEL. Lists are comma-separated in the environment.
+func LoadMiddlewareConfig(path, envPrefix string) (MiddlewareConfig, error) {
+	var config MiddlewareConfig
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return config, err
+		}
+		doc, err := parseConfigDocument(data)
+		if err != nil {
+			return config, fmt.Errorf("%s: %w", path, err)
+		}
+		if doc != nil {
+			if err := loadMap(reflect.ValueOf(&config).Elem(), doc, ""); err != nil {
+				return config, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	if envPrefix != "" {
+		if err := loadEnv(reflect.ValueOf(&config).Elem(), envPrefix); err != nil {
+			return config, err
+		}
+	}
+	return config, config.Validate()
+}
+
+// Validate checks every section of the config
+func (c MiddlewareConfig) Validate() error {
+	return errors.Join(c.Security.Validate(), c.RateLimit.Validate(), c.CORS.Validate(), c.Compression.Validate())
+}
+
+// SecurityOptionsFromEnv reads SecurityOptions from variables named after
+// its fields under prefix, e.g. APP_ALLOWED_ORIGINS, APP_HSTS_MAX_AGE and
+// APP_RATE_LIMIT_REQUESTS for prefix "APP", and validates them
+func SecurityOptionsFromEnv(prefix string) (SecurityOptions, error) {
+	var opts SecurityOptions
+	if err := loadEnv(reflect.ValueOf(&opts).Elem(), prefix); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// RateLimitOptionsFromEnv reads RateLimitOptions from variables such as
+// APP_REQUESTS, APP_DURATION and APP_BURST_SIZE for prefix "APP"
+func RateLimitOptionsFromEnv(prefix string) (RateLimitOptions, error) {
+	var opts RateLimitOptions
+	if err := loadEnv(reflect.ValueOf(&opts).Elem(), prefix); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// CORSOptionsFromEnv reads CORSOptions from variables such as
+// APP_ALLOWED_ORIGINS for prefix "APP"
+func CORSOptionsFromEnv(prefix string) (CORSOptions, error) {
+	var opts CORSOptions
+	if err := loadEnv(reflect.ValueOf(&opts).Elem(), prefix); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// CompressionOptionsFromEnv reads CompressionOptions from variables such as
+// APP_LEVEL for prefix "APP"
+func CompressionOptionsFromEnv(prefix string) (CompressionOptions, error) {
+	var opts CompressionOptions
+	if err := loadEnv(reflect.ValueOf(&opts).Elem(), prefix); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
+
+// Validate reports settings the Security middleware can't honor
+func (o SecurityOptions) Validate() error {
+	var errs []error
+	errs = append(errs, validateOrigins("SecurityOptions.AllowedOrigins", o.AllowedOrigins))
+	errs = append(errs, validateMethods("SecurityOptions.AllowedMethods", o.AllowedMethods))
+	if o.AllowCredentials && contains(o.AllowedOrigins, "*") {
+		errs = append(errs, errors.New(`GoFlow: SecurityOptions.AllowCredentials can't be combined with a "*" origin; list the origins instead`))
+	}
+	if o.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("GoFlow: SecurityOptions.MaxAge must not be negative, got %d", o.MaxAge))
+	}
+	if o.HSTSMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("GoFlow: SecurityOptions.HSTSMaxAge must not be negative, got %d", o.HSTSMaxAge))
+	}
+	if o.HSTSPreload && o.HSTS && o.HSTSMaxAge != 0 && o.HSTSMaxAge < 31536000 {
+		errs = append(errs, errors.New("GoFlow: SecurityOptions.HSTSPreload requires an HSTSMaxAge of at least one year"))
+	}
+	if o.CSRFEnabled && o.CSRFKey == "" {
+		errs = append(errs, errors.New("GoFlow: SecurityOptions.CSRFKey is required when CSRFEnabled is set"))
+	}
+	errs = append(errs, validateProxies("SecurityOptions.TrustedProxies", o.TrustedProxies))
+	if err := o.RateLimit.Validate(); err != nil {
+		errs = append(errs, errors.New(strings.ReplaceAll(err.Error(), "RateLimitOptions.", "SecurityOptions.RateLimit.")))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports inconsistent rate limit settings
+func (o RateLimitOptions) Validate() error {
+	var errs []error
+	if o.Requests < 0 || o.BurstSize < 0 || o.Duration < 0 {
+		errs = append(errs, errors.New("GoFlow: RateLimitOptions.Requests, BurstSize and Duration must not be negative"))
+	}
+	if o.Requests > 0 && o.Duration == 0 {
+		errs = append(errs, fmt.Errorf("GoFlow: RateLimitOptions.Duration is required with Requests, e.g. %d per minute", o.Requests))
+	}
+	errs = append(errs, validateIPs("RateLimitOptions.TrustedIPs", o.TrustedIPs))
+	errs = append(errs, validateProxies("RateLimitOptions.TrustedProxies", o.TrustedProxies))
+	return errors.Join(errs...)
+}
+
+// Validate reports malformed origins, unknown methods and credentials
+// allowed for any origin
+func (o CORSOptions) Validate() error {
+	var errs []error
+	errs = append(errs, validateOrigins("CORSOptions.AllowedOrigins", o.AllowedOrigins))
+	errs = append(errs, validateMethods("CORSOptions.AllowedMethods", o.AllowedMethods))
+	if o.AllowCredentials && contains(o.AllowedOrigins, "*") {
+		errs = append(errs, errors.New(`GoFlow: CORSOptions.AllowCredentials can't be combined with a "*" origin; list the origins instead`))
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports an out of range gzip level
+func (o CompressionOptions) Validate() error {
+	if o.Level < gzip.HuffmanOnly || o.Level > gzip.BestCompression {
+		return fmt.Errorf("GoFlow: CompressionOptions.Level must be between %d and %d, got %d", gzip.HuffmanOnly, gzip.BestCompression, o.Level)
+	}
+	return nil
+}
+
+func validateOrigins(field string, origins []string) error {
+	var errs []error
+	for _, origin := range origins {
+		// Patterns such as https://*.example.com validate like any subdomain
+		if origin != "*" && !originRegex.MatchString(strings.Replace(origin, "://*.", "://x.", 1)) {
+			errs = append(errs, fmt.Errorf("GoFlow: %s: %q is not an origin such as https://example.com or https://*.example.com", field, origin))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateMethods(field string, methods []string) error {
+	var errs []error
+	for _, method := range methods {
+		if _, ok := methodMap[strings.ToUpper(method)]; !ok {
+			errs = append(errs, fmt.Errorf("GoFlow: %s: unknown method %q", field, method))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateIPs(field string, ips []string) error {
+	var errs []error
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("GoFlow: %s: %q is not an IP address", field, ip))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateProxies is validateIPs for lists that also take CIDR ranges
+func validateProxies(field string, proxies []string) error {
+	var errs []error
+	for _, proxy := range proxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("GoFlow: %s: %q is not an IP address or CIDR range", field, proxy))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// parseConfigDocument decodes a YAML or JSON document; JSON is detected by
+// a leading '{'
+func parseConfigDocument(data []byte) (any, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc any
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("GoFlow: %w", err)
+		}
+		return doc, nil
+	}
+	return parseYAML(data)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// loadEnv sets the fields of the struct v from variables named
+// PREFIX_FIELD_NAME, recursing into nested structs
+func loadEnv(v reflect.Value, prefix string) error {
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(snakeCase(field.Name))
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, loadEnv(v.Field(i), name))
+			continue
+		}
+		if raw, ok := os.LookupEnv(name); ok {
+			if err := setOption(v.Field(i), raw); err != nil {
+				errs = append(errs, fmt.Errorf("GoFlow: %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadMap sets the fields of the struct v from a decoded document keyed by
+// snake_case field names
+func loadMap(v reflect.Value, doc any, path string) error {
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return fmt.Errorf("GoFlow: %s: expected a mapping", strings.TrimSuffix(path, "."))
+	}
+	fields := make(map[string]int, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Type().Field(i); f.IsExported() {
+			fields[snakeCase(f.Name)] = i
+		}
+	}
+
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		i, ok := fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("GoFlow: %s%s: unknown setting", path, key))
+			continue
+		}
+		if m[key] == nil {
+			continue
+		}
+		if v.Field(i).Kind() == reflect.Struct {
+			errs = append(errs, loadMap(v.Field(i), m[key], path+key+"."))
+			continue
+		}
+		if err := setOption(v.Field(i), m[key]); err != nil {
+			errs = append(errs, fmt.Errorf("GoFlow: %s%s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// setOption converts an environment string or decoded document value to
+// the field's type
+func setOption(f reflect.Value, raw any) error {
+	switch {
+	case f.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf(`expected a duration such as "30s", got %v`, raw)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf(`expected a duration such as "30s", got %q`, s)
+		}
+		f.SetInt(int64(d))
+
+	case f.Kind() == reflect.String:
+		f.SetString(scalarString(raw))
+
+	case f.Kind() == reflect.Bool:
+		switch b := raw.(type) {
+		case bool:
+			f.SetBool(b)
+		case string:
+			v, err := strconv.ParseBool(strings.TrimSpace(b))
+			if err != nil {
+				return fmt.Errorf("expected true or false, got %q", b)
+			}
+			f.SetBool(v)
+		default:
+			return fmt.Errorf("expected true or false, got %v", raw)
+		}
+
+	case f.Kind() == reflect.Int:
+		var n int64
+		switch x := raw.(type) {
+		case int64:
+			n = x
+		case float64:
+			if x != float64(int64(x)) {
+				return fmt.Errorf("expected an integer, got %v", x)
+			}
+			n = int64(x)
+		case string:
+			v, err := strconv.Atoi(strings.TrimSpace(x))
+			if err != nil {
+				return fmt.Errorf("expected an integer, got %q", x)
+			}
+			n = int64(v)
+		default:
+			return fmt.Errorf("expected an integer, got %v", raw)
+		}
+		f.SetInt(n)
+
+	case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.String:
+		var list []string
+		switch x := raw.(type) {
+		case string:
+			for _, item := range strings.Split(x, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+		case []any:
+			for _, item := range x {
+				list = append(list, scalarString(item))
+			}
+		default:
+			list = []string{scalarString(raw)}
+		}
+		f.Set(reflect.ValueOf(list))
+
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.Type())
+	}
+	return nil
+}
+
+func scalarString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// snakeCase converts a field name such as HSTSMaxAge or TrustedIPs to
+// hsts_max_age or trusted_ips
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// A trailing "s" pluralizes an acronym rather than starting a word
+			plural := nextLower && runes[i+1] == 's' && (i+2 == len(runes) || unicode.IsUpper(runes[i+2]))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || nextLower && !plural {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package GoFlow
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Run("Security", func(t *testing.T) {
+		t.Setenv("APP_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+		t.Setenv("APP_ALLOW_CREDENTIALS", "true")
+		t.Setenv("APP_HSTS", "1")
+		t.Setenv("APP_HSTS_MAX_AGE", "63072000")
+		t.Setenv("APP_CSP", "default-src 'self'")
+		t.Setenv("APP_TRUSTED_PROXIES", "10.0.0.1")
+		t.Setenv("APP_RATE_LIMIT_REQUESTS", "100")
+		t.Setenv("APP_RATE_LIMIT_DURATION", "1m")
+
+		opts, err := SecurityOptionsFromEnv("APP")
+		if err != nil {
+			t.Fatalf("Expected options to load, got %v", err)
+		}
+		if !equalSlices(opts.AllowedOrigins, []string{"https://a.example", "https://b.example"}) || !opts.AllowCredentials {
+			t.Errorf("Expected origins with credentials, got %v %v", opts.AllowedOrigins, opts.AllowCredentials)
+		}
+		if !opts.HSTS || opts.HSTSMaxAge != 63072000 || opts.CSP != "default-src 'self'" {
+			t.Errorf("Expected HSTS and CSP, got %v %d '%s'", opts.HSTS, opts.HSTSMaxAge, opts.CSP)
+		}
+		if opts.RateLimit.Requests != 100 || opts.RateLimit.Duration != time.Minute {
+			t.Errorf("Expected 100 requests per minute, got %+v", opts.RateLimit)
+		}
+	})
+
+	t.Run("Parse Errors", func(t *testing.T) {
+		t.Setenv("BAD_HSTS_MAX_AGE", "a year")
+		t.Setenv("BAD_HSTS", "maybe")
+		t.Setenv("BAD_RATE_LIMIT_DURATION", "60")
+
+		_, err := SecurityOptionsFromEnv("BAD")
+		for _, want := range []string{
+			`BAD_HSTS_MAX_AGE: expected an integer, got "a year"`,
+			`BAD_HSTS: expected true or false, got "maybe"`,
+			`BAD_RATE_LIMIT_DURATION: expected a duration such as "30s", got "60"`,
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error '%s', got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		t.Setenv("V_ALLOWED_ORIGINS", "*,example.com")
+		t.Setenv("V_ALLOW_CREDENTIALS", "true")
+		t.Setenv("V_CSRF_ENABLED", "true")
+		t.Setenv("V_RATE_LIMIT_REQUESTS", "10")
+
+		_, err := SecurityOptionsFromEnv("V")
+		for _, want := range []string{
+			`SecurityOptions.AllowedOrigins: "example.com" is not an origin`,
+			`AllowCredentials can't be combined with a "*" origin`,
+			"CSRFKey is required when CSRFEnabled is set",
+			"SecurityOptions.RateLimit.Duration is required with Requests",
+		} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error '%s', got %v", want, err)
+			}
+		}
+
+		t.Setenv("C_LEVEL", "11")
+		if _, err := CompressionOptionsFromEnv("C"); err == nil || !strings.Contains(err.Error(), "between -2 and 9") {
+			t.Errorf("Expected level range error, got %v", err)
+		}
+	})
+
+	t.Run("Config File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "middleware.yaml")
+		os.WriteFile(path, []byte(`
+security:
+  hsts: true
+  rate_limit:
+    requests: 50
+    duration: 1s
+    trusted_ips: [127.0.0.1]
+cors:
+  allowed_origins:
+    - https://app.example
+  allowed_methods: [GET, POST]
+compression:
+  level: 1
+`), 0o600)
+		t.Setenv("OPS_COMPRESSION_LEVEL", "9")
+
+		config, err := LoadMiddlewareConfig(path, "OPS")
+		if err != nil {
+			t.Fatalf("Expected config to load, got %v", err)
+		}
+		if !config.Security.HSTS || config.Security.RateLimit.Requests != 50 || !equalSlices(config.Security.RateLimit.TrustedIPs, []string{"127.0.0.1"}) {
+			t.Errorf("Expected security settings from file, got %+v", config.Security)
+		}
+		if !equalSlices(config.CORS.AllowedMethods, []string{MethodGet, MethodPost}) {
+			t.Errorf("Expected CORS methods, got %v", config.CORS.AllowedMethods)
+		}
+		if config.Compression.Level != 9 {
+			t.Errorf("Expected environment to override the file, got level %d", config.Compression.Level)
+		}
+
+		os.WriteFile(path, []byte("security:\n  hstss: true\ncompression:\n  level: fast\n"), 0o600)
+		_, err = LoadMiddlewareConfig(path, "")
+		for _, want := range []string{"security.hstss: unknown setting", `compression.level: expected an integer, got "fast"`} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error '%s', got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("Middleware", func(t *testing.T) {
+		handler := CompressionWithOptions(CompressionOptions{Level: gzip.BestSpeed})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("a", 100)))
+		}))
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		gz, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Expected gzip body, got %v", err)
+		}
+		if body, _ := io.ReadAll(gz); len(body) != 100 {
+			t.Errorf("Expected 100 bytes, got %d", len(body))
+		}
+
+		limited := RateLimitWithOptions(RateLimitOptions{Requests: 1, Duration: time.Hour, BurstSize: 1, TrustedIPs: []string{"10.0.0.9"}})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		for i := 0; i < 3; i++ {
+			req := httptest.NewRequest(MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.9:1234"
+			w := httptest.NewRecorder()
+			limited.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected trusted IP to bypass the limit, got %d", w.Code)
+			}
+		}
+	})
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"HSTSMaxAge":       "hsts_max_age",
+		"TrustedIPs":       "trusted_ips",
+		"CSRFPreviousKeys": "csrf_previous_keys",
+		"XSSProtection":    "xss_protection",
+		"CORS":             "cors",
+		"BurstSize":        "burst_size",
+	} {
+		if got := snakeCase(name); got != want {
+			t.Errorf("Expected %s to be '%s', got '%s'", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotFoundError reports that a resource doesn't exist: 404 Not Found
+type NotFoundError struct {
+	// Resource and ID name what was looked up, e.g. "user" and "42"
+	Resource string
+	ID       string
+	Err      error
+}
+
+func (e *NotFoundError) Error() string {
+	switch {
+	case e.Resource != "" && e.ID != "":
+		return e.Resource + " " + e.ID + " not found"
+	case e.Resource != "":
+		return e.Resource + " not found"
+	}
+	return "not found"
+}
+
+func (e *NotFoundError) Unwrap() error   { return e.Err }
+func (e *NotFoundError) StatusCode() int { return http.StatusNotFound }
+
+// ValidationError reports invalid input: 422 Unprocessable Entity. Fields
+// maps each invalid field to what is wrong with it and is rendered as the
+// problem's "errors" member.
+type ValidationError struct {
+	Fields map[string]string
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 0 {
+		return "validation failed"
+	}
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e.Fields[name]
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() error   { return e.Err }
+func (e *ValidationError) StatusCode() int { return http.StatusUnprocessableEntity }
+
+// ConflictError reports that a request conflicts with the resource's
+// current state, e.g. a duplicate or a stale version: 409 Conflict
+type ConflictError struct {
+	Detail string
+	Err    error
+}
+
+func (e *ConflictError) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	return "conflict"
+}
+
+func (e *ConflictError) Unwrap() error   { return e.Err }
+func (e *ConflictError) StatusCode() int { return http.StatusConflict }
+
+// TooManyRequestsError reports that the caller is over a quota: 429 Too
+// Many Requests, with Retry-After when RetryAfter is set
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *TooManyRequestsError) Error() string   { return "too many requests" }
+func (e *TooManyRequestsError) Unwrap() error   { return e.Err }
+func (e *TooManyRequestsError) StatusCode() int { return http.StatusTooManyRequests }
+
+// UpstreamError reports that a service the handler depends on failed: 502
+// Bad Gateway, or 504 Gateway Timeout when Err is a timeout. Err isn't
+// shown to clients.
+type UpstreamError struct {
+	Service string
+	Err     error
+}
+
+func (e *UpstreamError) Error() string {
+	msg := "upstream"
+	if e.Service != "" {
+		msg += " " + e.Service
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+func (e *UpstreamError) StatusCode() int {
+	var netErr interface{ Timeout() bool }
+	if errors.Is(e.Err, context.DeadlineExceeded) || (errors.As(e.Err, &netErr) && netErr.Timeout()) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// ErrorStatus returns the status an error is answered with: that of the
+// first error in its chain with a StatusCode method, such as the error
+// types above, 413 for bodies over BodyLimit, and 500 otherwise
+func ErrorStatus(err error) int {
+	var coded interface{ StatusCode() int }
+	switch {
+	case errors.As(err, &coded):
+		return coded.StatusCode()
+	case IsBodyTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrBufferBudget):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// ProblemFor describes err as problem details. Client errors carry the
+// error's message as the detail; server errors don't, so internal details
+// aren't leaked.
+func ProblemFor(err error) Problem {
+	return problemFor(context.Background(), err)
+}
+
+// problemFor is ProblemFor with the details translated for the request's
+// locale, see localize
+func problemFor(ctx context.Context, err error) Problem {
+	p := Problem{Status: ErrorStatus(err), Title: StatusText(ctx, ErrorStatus(err))}
+	if p.Status < 500 {
+		p.Detail = err.Error()
+	}
+	var (
+		notFound  *NotFoundError
+		invalid   *ValidationError
+		conflict  *ConflictError
+		throttled *TooManyRequestsError
+		upstream  *UpstreamError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		p.Detail = localize(ctx, "errors.not_found", p.Detail, notFound.Resource, notFound.ID)
+	case errors.As(err, &invalid):
+		p.Detail = localize(ctx, "errors.validation", "validation failed")
+		if len(invalid.Fields) > 0 {
+			p.Errors = make(map[string]string, len(invalid.Fields))
+			for field, msg := range invalid.Fields {
+				p.Errors[field] = localize(ctx, msg, msg)
+			}
+		}
+	case errors.As(err, &conflict):
+		p.Detail = localize(ctx, conflict.Detail, p.Detail)
+	case errors.As(err, &throttled):
+		p.Detail = localize(ctx, "errors.too_many_requests", p.Detail)
+	case errors.As(err, &upstream) && upstream.Service != "":
+		p.Detail = localize(ctx, "errors.upstream", upstream.Service+" is unavailable", upstream.Service)
+	}
+	return p
+}
+
+// localize returns the message for key in the request's locale, or its
+// fallback locale, formatted with args, and otherwise the English
+// fallback. Error messages are looked up under "errors.not_found"
+// (formatted with the resource and ID), "errors.validation",
+// "errors.too_many_requests" and "errors.upstream" (with the service),
+// and the messages of ValidationError fields and ConflictError details
+// under themselves, so they can be catalog keys.
+func localize(ctx context.Context, key, fallback string, args ...any) string {
+	l := CurrentLocalizer(ctx)
+	if l == nil || key == "" {
+		return fallback
+	}
+	msg, ok := l.lookup(key)
+	if !ok {
+		return fallback
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// HandlerFunc is a handler that returns its errors for WriteError to
+// answer, so handlers can share error semantics:
+//
+//	mux.Handle("/users/:id", GoFlow.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+//		user, err := db.User(r.Context(), GoFlow.Param(r.Context(), "id"))
+//		if errors.Is(err, sql.ErrNoRows) {
+//			return &GoFlow.NotFoundError{Resource: "user", Err: err}
+//		}
+//		...
+//	}), "GET")
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ServeHTTP calls f and passes its error to WriteError
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// WriteError answers a request that failed with err. It calls the
+// ErrorHandler of the group that registered the route, or of its closest
+// ancestor that sets one, and otherwise writes ProblemFor(err) as
+// application/problem+json. Server errors are logged.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if rt := CurrentRoute(r.Context()); rt != nil {
+		for g := rt.mux; g != nil; g = g.parent {
+			if g.ErrorHandler != nil {
+				g.ErrorHandler(w, r, err)
+				return
+			}
+		}
+	}
+
+	p := problemFor(r.Context(), err)
+	debugNote(r.Context(), "error: %d %v", p.Status, err)
+	if p.Status >= 500 && !errors.Is(err, context.Canceled) {
+		slog.Error("request failed", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	}
+	var throttled *TooManyRequestsError
+	if errors.As(err, &throttled) && throttled.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int((throttled.RetryAfter+time.Second-1)/time.Second)))
+	}
+	p.Instance = r.URL.Path
+	WriteProblem(w, p)
+}
@@ -0,0 +1,148 @@
+package GoFlow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestErrors(t *testing.T) {
+	t.Run("Status Mapping", func(t *testing.T) {
+		tests := []struct {
+			err      error
+			expected int
+		}{
+			{&NotFoundError{Resource: "user", ID: "42"}, http.StatusNotFound},
+			{fmt.Errorf("loading profile: %w", &NotFoundError{Resource: "user"}), http.StatusNotFound},
+			{&ValidationError{Fields: map[string]string{"email": "is required"}}, http.StatusUnprocessableEntity},
+			{&ConflictError{Detail: "email already registered"}, http.StatusConflict},
+			{&TooManyRequestsError{RetryAfter: time.Second}, http.StatusTooManyRequests},
+			{&UpstreamError{Service: "payments", Err: errors.New("connection refused")}, http.StatusBadGateway},
+			{&UpstreamError{Service: "payments", Err: context.DeadlineExceeded}, http.StatusGatewayTimeout},
+			{&http.MaxBytesError{Limit: 10}, http.StatusRequestEntityTooLarge},
+			{errors.New("boom"), http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			if got := ErrorStatus(tt.err); got != tt.expected {
+				t.Errorf("ErrorStatus(%v) = %d, want %d", tt.err, got, tt.expected)
+			}
+		}
+
+		cause := errors.New("duplicate key")
+		if err := fmt.Errorf("signup: %w", &ConflictError{Err: cause}); !errors.Is(err, cause) {
+			t.Error("Expected the cause to be reachable through the chain")
+		}
+	})
+
+	t.Run("Problem Details", func(t *testing.T) {
+		orig := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+		defer slog.SetDefault(orig)
+
+		fail := map[string]error{
+			"/signup":   &ValidationError{Fields: map[string]string{"email": "is required"}},
+			"/export":   &TooManyRequestsError{RetryAfter: 1500 * time.Millisecond},
+			"/checkout": &UpstreamError{Service: "payments", Err: errors.New("dial tcp 10.0.0.7:443: connection refused")},
+		}
+		mux := New()
+		for path := range fail {
+			mux.Handle(path, HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				return fail[r.URL.Path]
+			}), MethodPost)
+		}
+		var handled error
+		mux.Group(func(admin *Mux) {
+			admin.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+				handled = err
+				http.Error(w, "admin error", ErrorStatus(err))
+			}
+			admin.Handle("/admin/users/:id", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				return &NotFoundError{Resource: "user", ID: Param(r.Context(), "id")}
+			}), MethodGet)
+		})
+
+		serve := func(method, path string) (*httptest.ResponseRecorder, Problem) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+			var p Problem
+			json.Unmarshal(w.Body.Bytes(), &p)
+			return w, p
+		}
+
+		w, p := serve(MethodPost, "/signup")
+		if w.Code != http.StatusUnprocessableEntity || w.Header().Get("Content-Type") != "application/problem+json" || p.Errors["email"] != "is required" || p.Instance != "/signup" {
+			t.Errorf("Expected a validation problem, got %d %s", w.Code, w.Body.String())
+		}
+		if w, _ := serve(MethodPost, "/export"); w.Header().Get("Retry-After") != "2" {
+			t.Errorf("Expected Retry-After rounded up, got %q", w.Header().Get("Retry-After"))
+		}
+		if w, p := serve(MethodPost, "/checkout"); w.Code != http.StatusBadGateway || p.Detail != "payments is unavailable" {
+			t.Errorf("Expected the upstream failure without its internals, got %d %s", w.Code, w.Body.String())
+		}
+		if w, _ := serve(MethodGet, "/admin/users/7"); w.Code != http.StatusNotFound || handled == nil || handled.Error() != "user 7 not found" {
+			t.Errorf("Expected the group's ErrorHandler, got %d %v", w.Code, handled)
+		}
+	})
+
+	t.Run("Localized", func(t *testing.T) {
+		catalog := NewCatalog()
+		catalog.Add("de", map[string]string{
+			"status.404":          "Nicht gefunden",
+			"status.422":          "Ungültige Eingabe",
+			"status.429":          "Zu viele Anfragen",
+			"status.504":          "Zeitüberschreitung",
+			"errors.not_found":    "%[1]s %[2]s wurde nicht gefunden",
+			"errors.validation":   "Validierung fehlgeschlagen",
+			"validation.required": "ist erforderlich",
+		})
+		mux := New()
+		mux.Use(I18n(catalog, "en"))
+		mux.Handle("/users/:id", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return &NotFoundError{Resource: "user", ID: Param(r.Context(), "id")}
+		}), MethodGet)
+		mux.Handle("/signup", HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+			return &ValidationError{Fields: map[string]string{"email": "validation.required"}}
+		}), MethodPost)
+		mux.Handle("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}), MethodGet).With(Timeout(10 * time.Millisecond))
+		mux.Handle("/search", RateLimit(1, time.Hour, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})), MethodGet)
+
+		serve := func(method, path, lang string) (*httptest.ResponseRecorder, Problem) {
+			r := httptest.NewRequest(method, path, nil)
+			r.Header.Set("Accept-Language", lang)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			var p Problem
+			json.Unmarshal(w.Body.Bytes(), &p)
+			return w, p
+		}
+
+		if _, p := serve(MethodGet, "/users/7", "de"); p.Title != "Nicht gefunden" || p.Detail != "user 7 wurde nicht gefunden" {
+			t.Errorf("Expected a German problem, got %+v", p)
+		}
+		if _, p := serve(MethodPost, "/signup", "de"); p.Detail != "Validierung fehlgeschlagen" || p.Errors["email"] != "ist erforderlich" {
+			t.Errorf("Expected German validation messages, got %+v", p)
+		}
+		if _, p := serve(MethodPost, "/signup", "fr"); p.Title != "Unprocessable Entity" || p.Errors["email"] != "validation.required" {
+			t.Errorf("Expected the English fallback, got %+v", p)
+		}
+		if w, _ := serve(MethodGet, "/missing", "de"); w.Body.String() != "Nicht gefunden\n" {
+			t.Errorf("Expected a German 404 body, got %q", w.Body.String())
+		}
+		serve(MethodGet, "/search", "de")
+		if w, _ := serve(MethodGet, "/search", "de"); w.Code != http.StatusTooManyRequests || w.Body.String() != "Zu viele Anfragen\n" {
+			t.Errorf("Expected a German 429 body, got %d %q", w.Code, w.Body.String())
+		}
+		if w, _ := serve(MethodGet, "/slow", "de"); w.Code != http.StatusGatewayTimeout || w.Body.String() != "Zeitüberschreitung\n" {
+			t.Errorf("Expected a German 504 body, got %d %q", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name string
+
+	// Weight is the variant's share of traffic relative to the other
+	// variants. Zero counts as 1.
+	Weight float64
+
+	// Handler serves the variant's requests. Without it the request
+	// continues down the chain, and the handler can check
+	// ExperimentVariant.
+	Handler http.Handler
+}
+
+// ExperimentOptions configures the Experiment middleware
+type ExperimentOptions struct {
+	// Name identifies the experiment in the context, cookie and logs
+	Name     string
+	Variants []Variant
+
+	// Key returns the unit requests are bucketed by. The same key always
+	// gets the same variant. Defaults to the authenticated user, falling
+	// back to the client IP.
+	Key func(r *http.Request) string
+
+	// CookieName defaults to "goflow_exp_" plus Name, CookieMaxAge to 30
+	// days. The cookie keeps the assignment when the key changes, e.g.
+	// after signing in.
+	CookieName   string
+	CookieMaxAge time.Duration
+}
+
+type experimentsKey struct{}
+
+// Experiment assigns each request to a variant, deterministically by the
+// hash of its key. The assignment is stored in a cookie, reported by
+// ExperimentVariant and logged by Logger. Variants with a Handler serve
+// their requests with it, so a pattern can route to a handler per variant.
+func Experiment(opts ExperimentOptions) func(http.Handler) http.Handler {
+	if opts.Name == "" || len(opts.Variants) == 0 {
+		panic("GoFlow: Experiment requires a Name and Variants")
+	}
+	if opts.Key == nil {
+		opts.Key = experimentKey
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "goflow_exp_" + opts.Name
+	}
+	if opts.CookieMaxAge == 0 {
+		opts.CookieMaxAge = 30 * 24 * time.Hour
+	}
+	opts.Variants = slices.Clone(opts.Variants)
+	variants := make(map[string]*Variant, len(opts.Variants))
+	var total float64
+	for i := range opts.Variants {
+		v := &opts.Variants[i]
+		if v.Weight == 0 {
+			v.Weight = 1
+		}
+		total += v.Weight
+		variants[v.Name] = v
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var variant *Variant
+			if c, err := r.Cookie(opts.CookieName); err == nil {
+				variant = variants[c.Value]
+			}
+			if variant == nil {
+				variant = pickVariant(opts.Variants, total, bucketOf(opts.Name, opts.Key(r)))
+				http.SetCookie(w, &http.Cookie{
+					Name:     opts.CookieName,
+					Value:    variant.Name,
+					Path:     "/",
+					MaxAge:   int(opts.CookieMaxAge / time.Second),
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			r = withExperiment(r, opts.Name, variant.Name)
+			if variant.Handler != nil {
+				variant.Handler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ExperimentVariant returns the variant of the experiment the request was
+// assigned to, or ""
+func ExperimentVariant(ctx context.Context, experiment string) string {
+	experiments, _ := ctx.Value(experimentsKey{}).(map[string]string)
+	return experiments[experiment]
+}
+
+func withExperiment(r *http.Request, experiment, variant string) *http.Request {
+	ctx := r.Context()
+	experiments, _ := ctx.Value(experimentsKey{}).(map[string]string)
+	experiments = maps.Clone(experiments)
+	if experiments == nil {
+		experiments = make(map[string]string, 1)
+	}
+	experiments[experiment] = variant
+	if h, ok := ctx.Value(logHolderKey{}).(*logHolder); ok {
+		h.experiments = append(h.experiments, experiment+":"+variant)
+	}
+	return r.WithContext(context.WithValue(ctx, experimentsKey{}, experiments))
+}
+
+func experimentKey(r *http.Request) string {
+	if user := User(r.Context()); user != "" {
+		return user
+	}
+	return stripPort(r.RemoteAddr)
+}
+
+// bucketOf maps key to [0, 1), independently per experiment. The hash is
+// stable across processes so every instance assigns the same variant.
+func bucketOf(experiment, key string) float64 {
+	sum := sha256.Sum256([]byte(experiment + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+func pickVariant(variants []Variant, total, bucket float64) *Variant {
+	point := bucket * total
+	for i := range variants {
+		if point < variants[i].Weight {
+			return &variants[i]
+		}
+		point -= variants[i].Weight
+	}
+	return &variants[len(variants)-1]
+}
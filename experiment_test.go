@@ -0,0 +1,90 @@
+package GoFlow
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExperiment(t *testing.T) {
+	variantB := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("B"))
+	})
+	mux := New()
+	mux.Handle("/checkout", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ExperimentVariant(r.Context(), "checkout")))
+	}), MethodGet).With(Experiment(ExperimentOptions{
+		Name:     "checkout",
+		Variants: []Variant{{Name: "A", Weight: 3}, {Name: "B", Handler: variantB}},
+		Key:      func(r *http.Request) string { return r.Header.Get("X-Session") },
+	}))
+
+	serve := func(session string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(MethodGet, "/checkout", nil)
+		r.Header.Set("X-Session", session)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			session := fmt.Sprint("session-", i)
+			if first, again := serve(session, nil).Body.String(), serve(session, nil).Body.String(); first != again {
+				t.Errorf("Expected %s to keep its variant, got %s then %s", session, first, again)
+			}
+		}
+	})
+
+	t.Run("Weights", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := 0; i < 4000; i++ {
+			counts[serve(fmt.Sprint("user-", i), nil).Body.String()]++
+		}
+		if share := float64(counts["A"]) / 4000; math.Abs(share-0.75) > 0.05 {
+			t.Errorf("Expected about 75%% in A, got %v", counts)
+		}
+	})
+
+	t.Run("Cookie", func(t *testing.T) {
+		w := serve("session-1", nil)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "goflow_exp_checkout" || cookies[0].Value != w.Body.String() {
+			t.Fatalf("Expected the assignment in a cookie, got %v", cookies)
+		}
+		other := "A"
+		if cookies[0].Value == "A" {
+			other = "B"
+		}
+		w = serve("session-1", &http.Cookie{Name: "goflow_exp_checkout", Value: other})
+		if w.Body.String() != other || len(w.Result().Cookies()) != 0 {
+			t.Errorf("Expected the cookie's variant %s to win, got %s", other, w.Body.String())
+		}
+		if w = serve("session-1", &http.Cookie{Name: "goflow_exp_checkout", Value: "Z"}); w.Body.String() == "Z" {
+			t.Error("Expected unknown variants in the cookie to be reassigned")
+		}
+	})
+
+	t.Run("Logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		orig := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(orig)
+
+		handler := Logger()(mux)
+		r := httptest.NewRequest(MethodGet, "/checkout", nil)
+		r.AddCookie(&http.Cookie{Name: "goflow_exp_checkout", Value: "B"})
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if !strings.Contains(buf.String(), "experiment=checkout:B") {
+			t.Errorf("Expected the variant in the log line, got '%s'", buf.String())
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package GoFlow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FlagProvider decides whether a feature flag is on for a request. The
+// request carries the principal and tenant, for per-user rollouts.
+type FlagProvider interface {
+	Enabled(r *http.Request, flag string) bool
+}
+
+// FlagFunc adapts a function, such as a call into a LaunchDarkly-style
+// SDK, to a FlagProvider
+type FlagFunc func(r *http.Request, flag string) bool
+
+// Enabled calls f(r, flag)
+func (f FlagFunc) Enabled(r *http.Request, flag string) bool {
+	return f(r, flag)
+}
+
+// StaticFlags is a FlagProvider with fixed flags; unknown flags are off
+type StaticFlags map[string]bool
+
+// Enabled reports s[flag]
+func (s StaticFlags) Enabled(r *http.Request, flag string) bool {
+	return s[flag]
+}
+
+// FileFlags is a FlagProvider reading a JSON or YAML file of flag names
+// to true or false, and rereading it when it changes
+type FileFlags struct {
+	path string
+
+	// CheckInterval limits how often the file is stat'ed. Defaults to 10s.
+	CheckInterval time.Duration
+
+	mu        sync.RWMutex
+	flags     map[string]bool
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewFileFlags loads the flags in path
+func NewFileFlags(path string) (*FileFlags, error) {
+	f := &FileFlags{path: path, CheckInterval: 10 * time.Second}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload reads the flags file from disk
+func (f *FileFlags) Reload() error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		return fmt.Errorf("GoFlow: loading flags: %w", err)
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("GoFlow: loading flags: %w", err)
+	}
+	flags, err := parseFlags(data)
+	if err != nil {
+		return fmt.Errorf("GoFlow: loading flags from %s: %w", f.path, err)
+	}
+
+	f.mu.Lock()
+	f.flags = flags
+	f.modTime = fi.ModTime()
+	f.checkedAt = time.Now()
+	f.mu.Unlock()
+	return nil
+}
+
+// Enabled reports the flag from the file, rereading it if it changed. A
+// file that fails to parse keeps the previous flags in effect.
+func (f *FileFlags) Enabled(r *http.Request, flag string) bool {
+	f.mu.RLock()
+	modTime, due := f.modTime, time.Since(f.checkedAt) > f.CheckInterval
+	f.mu.RUnlock()
+
+	if due {
+		f.mu.Lock()
+		f.checkedAt = time.Now()
+		f.mu.Unlock()
+
+		if fi, err := os.Stat(f.path); err == nil && fi.ModTime().After(modTime) {
+			f.Reload()
+		}
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flags[flag]
+}
+
+func parseFlags(data []byte) (map[string]bool, error) {
+	doc, err := parseConfigDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a map of flag names to true or false")
+	}
+	flags := make(map[string]bool, len(m))
+	for name, v := range m {
+		switch b := v.(type) {
+		case bool:
+			flags[name] = b
+		case string:
+			on, err := strconv.ParseBool(strings.TrimSpace(b))
+			if err != nil {
+				return nil, fmt.Errorf("flag %s: expected true or false, got %q", name, b)
+			}
+			flags[name] = on
+		default:
+			return nil, fmt.Errorf("flag %s: expected true or false, got %v", name, v)
+		}
+	}
+	return flags, nil
+}
+
+// flagState evaluates flags for one request, each at most once so a flag
+// doesn't change halfway through
+type flagState struct {
+	provider FlagProvider
+	r        *http.Request
+
+	mu     sync.Mutex
+	values map[string]bool
+}
+
+type (
+	flagStateKey     struct{}
+	requiredFlagsKey struct{}
+)
+
+// FeatureFlags makes provider's flags available to Flag and RequireFlag
+// for the rest of the chain
+func FeatureFlags(provider FlagProvider) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			state := &flagState{provider: provider, r: r}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), flagStateKey{}, state)))
+		})
+	}
+}
+
+// Flag reports whether flag is on for the request. It is off without the
+// FeatureFlags middleware.
+func Flag(ctx context.Context, flag string) bool {
+	state, ok := ctx.Value(flagStateKey{}).(*flagState)
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if on, ok := state.values[flag]; ok {
+		return on
+	}
+	if state.values == nil {
+		state.values = make(map[string]bool)
+	}
+	// Evaluate with the current context, which may carry a principal set
+	// after FeatureFlags ran
+	on := state.provider.Enabled(state.r.WithContext(ctx), flag)
+	state.values[flag] = on
+	return on
+}
+
+// RequireFlag answers requests with the mux's NotFound handler unless every
+// flag is on, so the route doesn't appear to exist while it is off
+func (rt *Route) RequireFlag(flags ...string) *Route {
+	required, _ := rt.Value(requiredFlagsKey{}).([]string)
+	rt.Set(requiredFlagsKey{}, append(required[:len(required):len(required)], flags...))
+	rt.compile()
+	return rt
+}
+
+// RequireFlag sets Route.RequireFlag for routes registered afterwards on
+// this mux or group
+func (m *Mux) RequireFlag(flags ...string) {
+	required, _ := m.meta[requiredFlagsKey{}].([]string)
+	m.Set(requiredFlagsKey{}, append(required[:len(required):len(required)], flags...))
+}
+
+// requireFlags answers with m's NotFound handler, like a request for a
+// path that isn't registered
+func requireFlags(flags []string, m *Mux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, flag := range flags {
+			if !Flag(r.Context(), flag) {
+				m.notFound().ServeHTTP(w, r)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,107 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	t.Run("Route Gating", func(t *testing.T) {
+		mux := New()
+		mux.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("custom"))
+		})
+		mux.Use(FeatureFlags(StaticFlags{"beta-api": true}))
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		mux.Handle("/beta", ok, MethodGet).RequireFlag("beta-api")
+		mux.Handle("/next", ok, MethodGet).RequireFlag("beta-api", "next-api")
+		mux.Group(func(m *Mux) {
+			m.RequireFlag("next-api")
+			m.Handle("/next/users", ok, MethodGet)
+		})
+
+		for path, expected := range map[string]int{
+			"/beta":       http.StatusOK,
+			"/next":       http.StatusNotFound,
+			"/next/users": http.StatusNotFound,
+		} {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+			if w.Code != expected {
+				t.Errorf("Expected %d for %s, got %d", expected, path, w.Code)
+			}
+			if expected == http.StatusNotFound && w.Body.String() != "custom" {
+				t.Errorf("Expected the mux's NotFound handler for %s, got %q", path, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("Per Request", func(t *testing.T) {
+		calls := 0
+		provider := FlagFunc(func(r *http.Request, flag string) bool {
+			calls++
+			return flag == "new-checkout" && User(r.Context()) == "alice"
+		})
+		handler := FeatureFlags(provider)(BasicAuth("shop", func(u, p string) bool { return true })(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if Flag(r.Context(), "new-checkout") && Flag(r.Context(), "new-checkout") {
+					w.Write([]byte("new"))
+				}
+			})))
+
+		for user, expected := range map[string]string{"alice": "new", "bob": ""} {
+			calls = 0
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.SetBasicAuth(user, "secret")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Body.String() != expected {
+				t.Errorf("Expected '%s' for %s, got '%s'", expected, user, w.Body.String())
+			}
+			if calls != 1 {
+				t.Errorf("Expected the flag evaluated once per request, got %d", calls)
+			}
+		}
+	})
+
+	t.Run("Without Middleware", func(t *testing.T) {
+		if Flag(httptest.NewRequest(MethodGet, "/", nil).Context(), "beta-api") {
+			t.Error("Expected flags off without FeatureFlags")
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "flags.yaml")
+		if err := os.WriteFile(path, []byte("beta-api: true\nnew-checkout: false\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		flags, err := NewFileFlags(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flags.CheckInterval = 0
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		if !flags.Enabled(r, "beta-api") || flags.Enabled(r, "new-checkout") {
+			t.Error("Expected the flags from the file")
+		}
+
+		os.WriteFile(path, []byte(`{"beta-api": false, "new-checkout": true}`), 0o600)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(path, later, later)
+		if flags.Enabled(r, "beta-api") || !flags.Enabled(r, "new-checkout") {
+			t.Error("Expected the flags reloaded after the file changed")
+		}
+
+		os.WriteFile(path, []byte(`{"beta-api": maybe}`), 0o600)
+		later = later.Add(time.Minute)
+		os.Chtimes(path, later, later)
+		if !flags.Enabled(r, "new-checkout") {
+			t.Error("Expected the previous flags kept after a bad edit")
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package GoFlow
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// GeoInfo is the location and network of a client address
+type GeoInfo struct {
+	Country      string // ISO 3166-1 alpha-2 code, e.g. "DE"
+	ASN          uint
+	Organization string
+}
+
+// GeoResolver maps client addresses to GeoInfo. A MaxMind GeoIP2/GeoLite2
+// reader can be adapted with GeoResolverFunc:
+//
+//	GoFlow.GeoResolverFunc(func(ip net.IP) (GoFlow.GeoInfo, error) {
+//		c, err := countryDB.Country(ip)
+//		if err != nil {
+//			return GoFlow.GeoInfo{}, err
+//		}
+//		a, _ := asnDB.ASN(ip)
+//		return GoFlow.GeoInfo{
+//			Country:      c.Country.IsoCode,
+//			ASN:          a.AutonomousSystemNumber,
+//			Organization: a.AutonomousSystemOrganization,
+//		}, nil
+//	})
+type GeoResolver interface {
+	Lookup(ip net.IP) (GeoInfo, error)
+}
+
+// GeoResolverFunc adapts a function to the GeoResolver interface
+type GeoResolverFunc func(ip net.IP) (GeoInfo, error)
+
+// Lookup calls f(ip)
+func (f GeoResolverFunc) Lookup(ip net.IP) (GeoInfo, error) {
+	return f(ip)
+}
+
+// GeoOptions configures the GeoFilter middleware
+type GeoOptions struct {
+	Resolver GeoResolver
+
+	// Country codes to admit or reject. Deny takes precedence, and an
+	// empty allow list admits every country that isn't denied.
+	AllowCountries []string
+	DenyCountries  []string
+
+	// Autonomous system numbers to reject
+	DenyASNs []uint
+
+	// BlockUnknown rejects clients whose country can't be resolved
+	BlockUnknown bool
+
+	// RateLimit applies a per-client limit, scaled for individual countries
+	// by CountryRateMultipliers (e.g. {"US": 2, "XX": 0.1})
+	RateLimit              RateLimitOptions
+	CountryRateMultipliers map[string]float64
+
+	// Trusted proxies whose X-Forwarded-For header is honored
+	TrustedProxies []string
+
+	// Rejected handles blocked requests. Defaults to 403.
+	Rejected http.Handler
+}
+
+type geoContextKey struct{}
+
+// GetGeo returns the GeoInfo resolved by GeoFilter
+func GetGeo(ctx context.Context) (GeoInfo, bool) {
+	info, ok := ctx.Value(geoContextKey{}).(GeoInfo)
+	return info, ok
+}
+
+// GeoFilter resolves the client's country and ASN, rejects requests from
+// denied locations and networks, and optionally applies country-scaled rate
+// limits. The resolved GeoInfo is available to handlers through GetGeo.
+func GeoFilter(opts GeoOptions) func(http.Handler) http.Handler {
+	if opts.Resolver == nil {
+		panic("GoFlow: GeoFilter requires a Resolver")
+	}
+
+	rejected := opts.Rejected
+	if rejected == nil {
+		rejected = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+
+	allow := countrySet(opts.AllowCountries)
+	deny := countrySet(opts.DenyCountries)
+	denyASN := make(map[uint]struct{}, len(opts.DenyASNs))
+	for _, asn := range opts.DenyASNs {
+		denyASN[asn] = struct{}{}
+	}
+
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
+
+	// One limiter per country with a multiplier, plus the default
+	var limiter *RateLimiter
+	countryLimiters := make(map[string]*RateLimiter)
+	if opts.RateLimit.Requests > 0 {
+		limiter = NewRateLimiter(opts.RateLimit.Requests, opts.RateLimit.Duration, opts.RateLimit.BurstSize)
+		for country, mult := range opts.CountryRateMultipliers {
+			requests := int(float64(opts.RateLimit.Requests) * mult)
+			if requests < 1 {
+				requests = 1
+			}
+			burst := int(float64(opts.RateLimit.BurstSize) * mult)
+			countryLimiters[strings.ToUpper(country)] = NewRateLimiter(requests, opts.RateLimit.Duration, burst)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clientIP := getRealIP(r, trustedProxies)
+			info, err := opts.Resolver.Lookup(net.ParseIP(clientIP))
+			if err != nil {
+				info = GeoInfo{}
+			}
+			country := strings.ToUpper(info.Country)
+
+			if country == "" && opts.BlockUnknown {
+				rejected.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := deny[country]; ok && country != "" {
+				rejected.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := allow[country]; len(allow) > 0 && !ok {
+				rejected.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := denyASN[info.ASN]; ok && info.ASN != 0 {
+				rejected.ServeHTTP(w, r)
+				return
+			}
+
+			if limiter != nil {
+				l := limiter
+				if cl, ok := countryLimiters[country]; ok {
+					l = cl
+				}
+				if !l.Allow(clientIP) {
+					http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), geoContextKey{}, info)))
+		})
+	}
+}
+
+func countrySet(codes []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(codes))
+	for _, c := range codes {
+		set[strings.ToUpper(strings.TrimSpace(c))] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,96 @@
+package GoFlow
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGeoFilter(t *testing.T) {
+	resolver := GeoResolverFunc(func(ip net.IP) (GeoInfo, error) {
+		switch ip.String() {
+		case "192.0.2.1":
+			return GeoInfo{Country: "DE", ASN: 3320}, nil
+		case "192.0.2.2":
+			return GeoInfo{Country: "KP"}, nil
+		case "192.0.2.3":
+			return GeoInfo{Country: "US", ASN: 64512}, nil
+		case "192.0.2.4":
+			return GeoInfo{Country: "FR"}, nil
+		}
+		return GeoInfo{}, errors.New("not found")
+	})
+
+	var seen GeoInfo
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = GetGeo(r.Context())
+	})
+
+	t.Run("Country And ASN Lists", func(t *testing.T) {
+		handler := GeoFilter(GeoOptions{
+			Resolver:       resolver,
+			AllowCountries: []string{"de", "us", "kp"},
+			DenyCountries:  []string{"KP"},
+			DenyASNs:       []uint{64512},
+			BlockUnknown:   true,
+		})(ok)
+
+		tests := []struct {
+			addr     string
+			expected int
+		}{
+			{"192.0.2.1:1234", http.StatusOK},
+			{"192.0.2.2:1234", http.StatusForbidden},
+			{"192.0.2.3:1234", http.StatusForbidden},
+			{"192.0.2.4:1234", http.StatusForbidden},
+			{"198.51.100.1:1234", http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.RemoteAddr = tt.addr
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.addr, tt.expected, w.Code)
+			}
+		}
+
+		if seen.Country != "DE" || seen.ASN != 3320 {
+			t.Errorf("Expected GeoInfo in context, got %+v", seen)
+		}
+	})
+
+	t.Run("Country Rate Multipliers", func(t *testing.T) {
+		handler := GeoFilter(GeoOptions{
+			Resolver:               resolver,
+			RateLimit:              RateLimitOptions{Requests: 4, Duration: time.Minute},
+			CountryRateMultipliers: map[string]float64{"FR": 0.25},
+		})(ok)
+
+		allowed := func(addr string) int {
+			n := 0
+			for i := 0; i < 10; i++ {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(MethodGet, "/", nil)
+				r.RemoteAddr = addr
+				handler.ServeHTTP(w, r)
+				if w.Code == http.StatusOK {
+					n++
+				}
+			}
+			return n
+		}
+
+		if n := allowed("192.0.2.1:1234"); n != 4 {
+			t.Errorf("Expected 4 requests allowed for DE, got %d", n)
+		}
+		if n := allowed("192.0.2.4:1234"); n != 1 {
+			t.Errorf("Expected 1 request allowed for FR, got %d", n)
+		}
+	})
+}
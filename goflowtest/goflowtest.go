@@ -0,0 +1,370 @@
+// Package goflowtest provides a fluent client for testing GoFlow handlers
+// without a network listener:
+//
+//	tc := goflowtest.New(mux)
+//	tc.Post("/users").WithJSON(user).Expect(t).Status(201).JSONPath("$.id", 1)
+package goflowtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Client sends requests directly to a handler. Cookies set by responses are
+// kept and sent with later requests, so session and CSRF flows work.
+type Client struct {
+	handler http.Handler
+	header  http.Header
+
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie
+}
+
+// New creates a Client for handler, usually a *GoFlow.Mux
+func New(handler http.Handler) *Client {
+	return &Client{
+		handler: handler,
+		header:  make(http.Header),
+		cookies: make(map[string]*http.Cookie),
+	}
+}
+
+// WithHeader sets a header sent with every request from the client
+func (c *Client) WithHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Get starts a GET request
+func (c *Client) Get(path string) *Request { return c.Request(http.MethodGet, path) }
+
+// Head starts a HEAD request
+func (c *Client) Head(path string) *Request { return c.Request(http.MethodHead, path) }
+
+// Post starts a POST request
+func (c *Client) Post(path string) *Request { return c.Request(http.MethodPost, path) }
+
+// Put starts a PUT request
+func (c *Client) Put(path string) *Request { return c.Request(http.MethodPut, path) }
+
+// Patch starts a PATCH request
+func (c *Client) Patch(path string) *Request { return c.Request(http.MethodPatch, path) }
+
+// Delete starts a DELETE request
+func (c *Client) Delete(path string) *Request { return c.Request(http.MethodDelete, path) }
+
+// Options starts an OPTIONS request
+func (c *Client) Options(path string) *Request { return c.Request(http.MethodOptions, path) }
+
+// Request starts a request with any method
+func (c *Client) Request(method, path string) *Request {
+	return &Request{
+		client: c,
+		method: method,
+		path:   path,
+		header: c.header.Clone(),
+		query:  make(url.Values),
+		ctx:    context.Background(),
+	}
+}
+
+// ClearCookies forgets the cookies collected so far
+func (c *Client) ClearCookies() {
+	c.mu.Lock()
+	clear(c.cookies)
+	c.mu.Unlock()
+}
+
+// Request is a request being built
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	body    []byte
+	cookies []*http.Cookie
+	ctx     context.Context
+	err     error
+}
+
+// WithHeader sets a request header
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithQuery adds a query parameter
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithBody sets a raw request body
+func (r *Request) WithBody(body string) *Request {
+	r.body = []byte(body)
+	return r
+}
+
+// WithJSON encodes v as the request body
+func (r *Request) WithJSON(v any) *Request {
+	r.body, r.err = json.Marshal(v)
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// WithForm sets a URL-encoded form body
+func (r *Request) WithForm(form url.Values) *Request {
+	r.body = []byte(form.Encode())
+	r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// WithCookie adds a cookie to the request
+func (r *Request) WithCookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+// WithBearer sets a bearer token Authorization header
+func (r *Request) WithBearer(token string) *Request {
+	return r.WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithBasicAuth sets a basic Authorization header
+func (r *Request) WithBasicAuth(user, password string) *Request {
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(user, password)
+	return r.WithHeader("Authorization", req.Header.Get("Authorization"))
+}
+
+// WithContext sets the request context
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// Build returns the *http.Request that Do would send
+func (r *Request) Build() (*http.Request, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequestWithContext(r.ctx, r.method, target, bytes.NewReader(r.body))
+	req.Header = r.header.Clone()
+
+	r.client.mu.Lock()
+	for _, cookie := range r.client.cookies {
+		req.AddCookie(cookie)
+	}
+	r.client.mu.Unlock()
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+	return req, nil
+}
+
+// Do sends the request and records the response
+func (r *Request) Do() (*httptest.ResponseRecorder, error) {
+	req, err := r.Build()
+	if err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(rec, req)
+
+	r.client.mu.Lock()
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(r.client.cookies, cookie.Name)
+		} else {
+			r.client.cookies[cookie.Name] = cookie
+		}
+	}
+	r.client.mu.Unlock()
+	return rec, nil
+}
+
+// Expect sends the request and returns its response for assertions, which
+// report failures on t
+func (r *Request) Expect(t testing.TB) *Response {
+	t.Helper()
+	rec, err := r.Do()
+	if err != nil {
+		t.Fatalf("Expected request to be built, got %v", err)
+	}
+	return &Response{t: t, Recorder: rec}
+}
+
+// Response holds a recorded response and chains assertions on it
+type Response struct {
+	t        testing.TB
+	Recorder *httptest.ResponseRecorder
+}
+
+// Status asserts the status code
+func (res *Response) Status(code int) *Response {
+	res.t.Helper()
+	if res.Recorder.Code != code {
+		res.t.Errorf("Expected status code %d, got %d: %s", code, res.Recorder.Code, snippet(res.Recorder.Body.String()))
+	}
+	return res
+}
+
+// Header asserts a response header value
+func (res *Response) Header(key, value string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Header().Get(key); got != value {
+		res.t.Errorf("Expected header %s '%s', got '%s'", key, value, got)
+	}
+	return res
+}
+
+// Body asserts the exact response body
+func (res *Response) Body(body string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); got != body {
+		res.t.Errorf("Expected body '%s', got '%s'", body, got)
+	}
+	return res
+}
+
+// BodyContains asserts that the response body contains substr
+func (res *Response) BodyContains(substr string) *Response {
+	res.t.Helper()
+	if got := res.Recorder.Body.String(); !strings.Contains(got, substr) {
+		res.t.Errorf("Expected body to contain '%s', got '%s'", substr, snippet(got))
+	}
+	return res
+}
+
+// JSON asserts that the body is JSON equal to want, ignoring formatting and
+// key order
+func (res *Response) JSON(want any) *Response {
+	res.t.Helper()
+	var got any
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), &got); err != nil {
+		res.t.Errorf("Expected JSON body, got %v: %s", err, snippet(res.Recorder.Body.String()))
+		return res
+	}
+	if w := normalize(want); !reflect.DeepEqual(got, w) {
+		res.t.Errorf("Expected JSON %s, got %s", encode(w), encode(got))
+	}
+	return res
+}
+
+// JSONPath asserts the value at a simple JSONPath such as "$.items[0].id".
+// Numbers compare by value, so JSONPath("$.id", 1) matches {"id": 1}.
+func (res *Response) JSONPath(path string, want any) *Response {
+	res.t.Helper()
+	var doc any
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), &doc); err != nil {
+		res.t.Errorf("Expected JSON body, got %v: %s", err, snippet(res.Recorder.Body.String()))
+		return res
+	}
+	got, err := lookup(doc, path)
+	if err != nil {
+		res.t.Errorf("Expected %s to exist, got %v", path, err)
+		return res
+	}
+	if w := normalize(want); !reflect.DeepEqual(got, w) {
+		res.t.Errorf("Expected %s to be %s, got %s", path, encode(w), encode(got))
+	}
+	return res
+}
+
+// Decode unmarshals the JSON body into v for further checks
+func (res *Response) Decode(v any) *Response {
+	res.t.Helper()
+	if err := json.Unmarshal(res.Recorder.Body.Bytes(), v); err != nil {
+		res.t.Errorf("Expected JSON body, got %v: %s", err, snippet(res.Recorder.Body.String()))
+	}
+	return res
+}
+
+// lookup evaluates a dotted JSONPath with array indexes against doc
+func lookup(doc any, path string) (any, error) {
+	rest, ok := strings.CutPrefix(path, "$")
+	if !ok {
+		return nil, fmt.Errorf("path must start with $")
+	}
+
+	current := doc
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			rest = rest[end+1:]
+			obj, ok := current.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%q is not an object", key)
+			}
+			if current, ok = obj[key]; !ok {
+				return nil, fmt.Errorf("no key %q", key)
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [")
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q", rest[1:end])
+			}
+			rest = rest[end+1:]
+			arr, ok := current.([]any)
+			if !ok || i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("no index %d", i)
+			}
+			current = arr[i]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+	return current, nil
+}
+
+// normalize converts v to the types produced by decoding JSON into any
+func normalize(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	json.Unmarshal(b, &out)
+	return out
+}
+
+func encode(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func snippet(s string) string {
+	if len(s) > 200 {
+		return s[:200] + "..."
+	}
+	return s
+}
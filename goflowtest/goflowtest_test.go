@@ -0,0 +1,101 @@
+package goflowtest_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jie10/GoFlow"
+	"github.com/jie10/GoFlow/goflowtest"
+)
+
+// recordingT captures assertion failures instead of failing the test
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...any) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func newMux() *GoFlow.Mux {
+	mux := GoFlow.New()
+	mux.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"id":    GoFlow.Param(r.Context(), "id"),
+			"roles": []string{"admin", "dev"},
+			"query": r.URL.Query().Get("expand"),
+			"token": r.Header.Get("Authorization"),
+		})
+	}), GoFlow.MethodGet)
+	mux.Handle("/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"id": 1, "name": body.Name})
+	}), GoFlow.MethodPost)
+	mux.Handle("/login", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+	}), GoFlow.MethodPost)
+	mux.Handle("/me", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			w.Write([]byte(c.Value))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}), GoFlow.MethodGet)
+	return mux
+}
+
+func TestClient(t *testing.T) {
+	tc := goflowtest.New(newMux())
+
+	t.Run("Request Builder", func(t *testing.T) {
+		tc.Get("/users/7").
+			WithQuery("expand", "roles").
+			WithBearer("secret").
+			Expect(t).
+			Status(http.StatusOK).
+			Header("Content-Type", "application/json").
+			JSONPath("$.id", "7").
+			JSONPath("$.roles[1]", "dev").
+			JSONPath("$.query", "roles").
+			JSONPath("$.token", "Bearer secret")
+
+		tc.Post("/users").
+			WithJSON(map[string]string{"name": "alice"}).
+			Expect(t).
+			Status(http.StatusCreated).
+			JSONPath("$.id", 1).
+			JSON(map[string]any{"name": "alice", "id": 1})
+	})
+
+	t.Run("Cookies Persist", func(t *testing.T) {
+		tc.Get("/me").Expect(t).Status(http.StatusUnauthorized)
+		tc.Post("/login").Expect(t).Status(http.StatusOK)
+		tc.Get("/me").Expect(t).Status(http.StatusOK).Body("abc")
+
+		tc.ClearCookies()
+		tc.Get("/me").Expect(t).Status(http.StatusUnauthorized)
+	})
+
+	t.Run("Failures Are Reported", func(t *testing.T) {
+		rt := &recordingT{TB: t}
+		tc.Get("/users/7").Expect(rt).
+			Status(http.StatusNotFound).
+			JSONPath("$.roles[0]", "dev").
+			JSONPath("$.missing", 1).
+			BodyContains("nope")
+
+		if len(rt.failures) != 4 {
+			t.Errorf("Expected 4 failures, got %d: %v", len(rt.failures), rt.failures)
+		}
+	})
+}
@@ -0,0 +1,21 @@
+// Package goflowvars publishes GoFlow's runtime statistics with expvar.
+// It is separate from GoFlow because importing expvar registers
+// /debug/vars on http.DefaultServeMux.
+//
+//	goflowvars.Publish("goflow")
+//	mux.Handle("/debug/vars", expvar.Handler(), GoFlow.MethodGet)
+package goflowvars
+
+import (
+	"expvar"
+
+	"github.com/jie10/GoFlow"
+)
+
+// Publish exposes GoFlow.Stats as the expvar variable name. Like
+// expvar.Publish it panics if the name is already in use.
+func Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return GoFlow.Stats()
+	}))
+}
@@ -0,0 +1,42 @@
+package goflowvars_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jie10/GoFlow"
+	"github.com/jie10/GoFlow/goflowvars"
+)
+
+// runs makes the published names unique across go test -count runs, as
+// expvar names can't be reused
+var runs atomic.Int32
+
+func TestPublish(t *testing.T) {
+	mux := GoFlow.New()
+	mux.Handle("/users/:id", GoFlow.Cache(time.Minute)(nil), GoFlow.MethodGet)
+	limiter := GoFlow.NewRateLimiter(10, time.Minute, 0)
+	limiter.Allow("a")
+
+	name := t.Name() + strconv.Itoa(int(runs.Add(1)))
+	goflowvars.Publish(name)
+	v := expvar.Get(name)
+	if v == nil {
+		t.Fatal("Expected the goflow variable to be published")
+	}
+
+	var stats GoFlow.RuntimeStats
+	if err := json.Unmarshal([]byte(v.String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Muxes) == 0 || stats.Muxes[len(stats.Muxes)-1].Routes != 1 {
+		t.Errorf("Expected the mux in the published stats, got %+v", stats.Muxes)
+	}
+	if len(stats.RateLimiters) == 0 || len(stats.Caches) == 0 {
+		t.Errorf("Expected the limiter and cache in the published stats, got %+v", stats)
+	}
+}
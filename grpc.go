@@ -0,0 +1,14 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+)
+
+// isStreamingRPC reports whether r is a gRPC, gRPC-Web or Connect streaming
+// call. Such responses carry trailers and must be streamed, so middleware
+// that buffers or rewrites the body passes them through untouched.
+func isStreamingRPC(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	return strings.HasPrefix(ct, "application/grpc") || strings.HasPrefix(ct, "application/connect+")
+}
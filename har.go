@@ -0,0 +1,412 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// CapturePath is where HandleCapture serves the HAR file by default
+const CapturePath = "/debug/goflow/har"
+
+// CaptureOptions configures a TrafficCapture
+type CaptureOptions struct {
+	// Entries is the number of recent requests kept. Defaults to 100.
+	Entries int
+
+	// MaxBodySize is the number of bytes kept of each request and response
+	// body; the rest is dropped. Defaults to 64KB.
+	MaxBodySize int64
+
+	// Authorize decides who may download the capture, e.g. by checking for
+	// an admin principal. It is required: captures hold real traffic.
+	Authorize func(r *http.Request) bool
+
+	// Filter selects the requests to capture. Defaults to all.
+	Filter func(r *http.Request) bool
+}
+
+// TrafficCapture keeps recent requests and responses in memory and exports
+// them as a HAR 1.2 file, which browser devtools and most HTTP tools can
+// open. Headers, query parameters and JSON and form bodies go through the
+// route's redaction policy before they are stored.
+type TrafficCapture struct {
+	opts    CaptureOptions
+	mu      sync.Mutex
+	entries []harEntry
+	next    int
+	export  *Route
+}
+
+// NewTrafficCapture creates an empty capture. Add requests to it with the
+// Capture middleware and serve it as a handler, or use HandleCapture.
+func NewTrafficCapture(opts CaptureOptions) *TrafficCapture {
+	if opts.Authorize == nil {
+		panic("GoFlow: CaptureOptions.Authorize is required")
+	}
+	if opts.Entries <= 0 {
+		opts.Entries = 100
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	return &TrafficCapture{opts: opts}
+}
+
+// HandleCapture captures the requests the mux serves and serves the HAR
+// file at pattern, CapturePath when empty. Requests for the file itself
+// aren't captured.
+func (m *Mux) HandleCapture(pattern string, opts CaptureOptions) *Route {
+	if pattern == "" {
+		pattern = CapturePath
+	}
+	c := NewTrafficCapture(opts)
+	c.export = m.Handle(pattern, c, MethodGet, MethodHead)
+	m.Use(Capture(c))
+	return c.export
+}
+
+// Capture records the requests it serves in c
+func Capture(c *TrafficCapture) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (c.export != nil && CurrentRoute(r.Context()) == c.export) ||
+				(c.opts.Filter != nil && !c.opts.Filter(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Outside a route's chain, learn the route and its redaction
+			// policy through the holder an outer Logger or hook shares
+			if _, ok := r.Context().Value(logHolderKey{}).(*logHolder); !ok {
+				r = r.WithContext(context.WithValue(r.Context(), logHolderKey{}, &logHolder{}))
+			}
+
+			var reqBody *captureBody
+			if r.Body != nil && r.Body != http.NoBody {
+				reqBody = &captureBody{ReadCloser: r.Body, max: c.opts.MaxBodySize}
+				r.Body = reqBody
+			}
+			cw := &captureWriter{ResponseWriter: w}
+			cw.body.max = c.opts.MaxBodySize
+			start := time.Now()
+			next.ServeHTTP(wrapWriter(cw), r)
+			elapsed := time.Since(start)
+			if reqBody != nil {
+				reqBody.readRest()
+			}
+
+			c.add(newHAREntry(r, reqBody, cw, start, elapsed, CurrentRedaction(r.Context())))
+		})
+	}
+}
+
+func (c *TrafficCapture) add(e harEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) < c.opts.Entries {
+		c.entries = append(c.entries, e)
+		return
+	}
+	c.entries[c.next] = e
+	c.next = (c.next + 1) % len(c.entries)
+}
+
+// WriteHAR writes the captured requests, oldest first, as a HAR 1.2 file
+func (c *TrafficCapture) WriteHAR(w io.Writer) error {
+	var har struct {
+		Log harLog `json:"log"`
+	}
+	har.Log = harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "GoFlow", Version: "1"},
+		Entries: c.snapshot(),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(har)
+}
+
+// snapshot returns the entries oldest first
+func (c *TrafficCapture) snapshot() []harEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entries := make([]harEntry, 0, len(c.entries))
+	entries = append(entries, c.entries[c.next:]...)
+	return append(entries, c.entries[:c.next]...)
+}
+
+// ServeHTTP downloads the capture as a HAR file, if Authorize accepts the
+// request
+func (c *TrafficCapture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !c.opts.Authorize(r) {
+		http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="goflow-`+time.Now().UTC().Format("20060102T150405Z")+`.har"`)
+	w.Header().Set("Cache-Control", "no-store")
+	c.WriteHAR(w)
+}
+
+// captureBody keeps the first max bytes of a request body as the handler
+// reads it
+type captureBody struct {
+	io.ReadCloser
+	buf       bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+func (b *captureBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.keep(p[:n])
+	return n, err
+}
+
+// readRest captures the part of the body the handler didn't read, e.g.
+// when it rejected the request, so it can be replayed
+func (b *captureBody) readRest() {
+	if room := b.max - int64(b.buf.Len()); room >= 0 && !b.truncated {
+		// One byte more tells whether the body was truncated
+		io.Copy(io.Discard, io.LimitReader(b, room+1))
+	}
+}
+
+func (b *captureBody) keep(p []byte) {
+	if room := b.max - int64(b.buf.Len()); int64(len(p)) > room {
+		p, b.truncated = p[:room], true
+	}
+	b.buf.Write(p)
+}
+
+// captureWriter keeps the status, headers and first max bytes of a
+// response
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	body   captureBody
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 || w.status < http.StatusOK {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	w.body.keep(b[:n])
+	return n, err
+}
+
+func (w *captureWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ReadFrom goes through Write so the body is captured
+func (w *captureWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, src)
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// HAR 1.2 structures, see http://www.softwareishard.com/blog/har-12-spec/
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+const harTruncated = "body truncated by GoFlow"
+
+func newHAREntry(r *http.Request, reqBody *captureBody, cw *captureWriter, start time.Time, elapsed time.Duration, p Redaction) harEntry {
+	ms := float64(elapsed) / float64(time.Millisecond)
+	u := *r.URL
+	u.Host = r.Host
+	u.Scheme = "http"
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	req := harRequest{
+		Method:      r.Method,
+		URL:         p.RedactURL(&u),
+		HTTPVersion: r.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(p.RedactHeader(r.Header)),
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if q, err := url.ParseQuery(p.RedactQuery(r.URL.RawQuery)); err == nil {
+		req.QueryString = harValues(q)
+	}
+	if reqBody != nil {
+		req.BodySize = int64(reqBody.buf.Len())
+		mimeType := r.Header.Get("Content-Type")
+		req.PostData = &harPostData{MimeType: mimeType, Text: redactBody(p, mimeType, reqBody.buf.Bytes())}
+		if reqBody.truncated {
+			req.Comment = harTruncated
+		}
+	}
+
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	mimeType := cw.Header().Get("Content-Type")
+	resp := harResponse{
+		Status:      status,
+		StatusText:  http.StatusText(status),
+		HTTPVersion: r.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(p.RedactHeader(cw.Header())),
+		Content:     harContent{Size: cw.size, MimeType: mimeType},
+		RedirectURL: cw.Header().Get("Location"),
+		HeadersSize: -1,
+		BodySize:    cw.size,
+	}
+	body := cw.body.buf.Bytes()
+	if utf8.Valid(body) && cw.Header().Get("Content-Encoding") == "" {
+		resp.Content.Text = redactBody(p, mimeType, body)
+	} else {
+		resp.Content.Text = base64.StdEncoding.EncodeToString(body)
+		resp.Content.Encoding = "base64"
+	}
+	if cw.body.truncated {
+		resp.Comment = harTruncated
+	}
+
+	return harEntry{
+		StartedDateTime: start.UTC().Format(time.RFC3339Nano),
+		Time:            ms,
+		Request:         req,
+		Response:        resp,
+		Timings:         harTimings{Wait: ms},
+	}
+}
+
+// redactBody applies p to JSON and form bodies. JSON that can't be parsed,
+// e.g. because it was truncated, is left out.
+func redactBody(p Redaction, contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if !json.Valid(body) {
+			return ""
+		}
+		return string(p.RedactJSON(body))
+	case mediaType == "application/x-www-form-urlencoded":
+		return p.RedactQuery(string(body))
+	}
+	return string(body)
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	for _, name := range slices.Sorted(maps.Keys(h)) {
+		for _, v := range h[name] {
+			out = append(out, harNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
+
+func harValues(v url.Values) []harNameValue {
+	out := []harNameValue{}
+	for _, name := range slices.Sorted(maps.Keys(v)) {
+		for _, value := range v[name] {
+			out = append(out, harNameValue{Name: name, Value: value})
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTrafficCapture(t *testing.T) {
+	mux := New()
+	mux.Redact(Redaction{Headers: []string{"X-Session"}})
+	mux.HandleCapture("", CaptureOptions{
+		Entries:     2,
+		MaxBodySize: 64,
+		Authorize:   func(r *http.Request) bool { return r.Header.Get("X-Admin") == "yes" },
+	})
+	mux.Handle("/login", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"user":"` + r.PostForm.Get("user") + `","token":"t0k3n"}`))
+	}), MethodPost)
+	mux.Handle("/big", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}), MethodGet)
+
+	export := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(MethodGet, CapturePath, nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) []map[string]any {
+		var har struct {
+			Log struct {
+				Version string           `json:"version"`
+				Entries []map[string]any `json:"entries"`
+			} `json:"log"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &har); err != nil {
+			t.Fatalf("Expected a JSON HAR file, got %v", err)
+		}
+		if har.Log.Version != "1.2" {
+			t.Errorf("Expected HAR version 1.2, got %q", har.Log.Version)
+		}
+		return har.Log.Entries
+	}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		if w := export(nil); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("Redacted Entries", func(t *testing.T) {
+		r := httptest.NewRequest(MethodPost, "/login?token=abc&next=/home", strings.NewReader("user=bob&password=hunter2"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Authorization", "Bearer abc")
+		r.Header.Set("X-Session", "s1")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		w := export(map[string]string{"X-Admin": "yes"})
+		if !strings.Contains(w.Header().Get("Content-Disposition"), ".har") {
+			t.Errorf("Expected a HAR attachment, got %q", w.Header().Get("Content-Disposition"))
+		}
+		entries := decode(t, w)
+		if len(entries) != 1 {
+			t.Fatalf("Expected 1 entry without the export request, got %d", len(entries))
+		}
+		data, _ := json.Marshal(entries[0])
+		out := string(data)
+		for _, secret := range []string{"abc", "s1", "hunter2", "t0k3n"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("Expected %q redacted, got %s", secret, out)
+			}
+		}
+		for _, kept := range []string{"user=bob", `\"user\":\"bob\"`, "next", "http://example.com/login"} {
+			if !strings.Contains(out, kept) {
+				t.Errorf("Expected %q in the entry, got %s", kept, out)
+			}
+		}
+	})
+
+	t.Run("Truncated And Bounded", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/big", nil))
+		}
+		entries := decode(t, export(map[string]string{"X-Admin": "yes"}))
+		if len(entries) != 2 {
+			t.Fatalf("Expected the 2 most recent entries, got %d", len(entries))
+		}
+		resp := entries[1]["response"].(map[string]any)
+		content := resp["content"].(map[string]any)
+		if len(content["text"].(string)) != 64 || content["size"] != float64(100) || resp["comment"] == nil {
+			t.Errorf("Expected the body truncated to 64 of 100 bytes, got %v", resp)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HeaderLimitOptions configures the HeaderLimit middleware. Zero fields
+// aren't limited.
+type HeaderLimitOptions struct {
+	// MaxHeaders caps the number of header fields, counting each value of
+	// a repeated header
+	MaxHeaders int
+
+	// MaxHeaderSize caps the bytes of a single header field, name and
+	// value
+	MaxHeaderSize int
+
+	// MaxCookies caps the number of cookies across Cookie headers
+	MaxCookies int
+
+	// MaxURLLength caps the bytes of the request target, path and query
+	MaxURLLength int
+}
+
+type headerLimitKey struct{}
+
+// HeaderLimit rejects requests with too many or too large headers with 431
+// Request Header Fields Too Large, and requests with too long a URL with
+// 414 URI Too Long, before the handler runs. Server.MaxHeaderBytes only
+// caps the header block as a whole; this limits its parts, and
+// Route.HeaderLimit tightens the limits for routes such as webhooks.
+func HeaderLimit(opts HeaderLimitOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limits := opts
+			if rt := CurrentRoute(r.Context()); rt != nil {
+				if override, ok := rt.Value(headerLimitKey{}).(HeaderLimitOptions); ok {
+					limits = limits.merge(override)
+				}
+			}
+
+			if status, reason := limits.check(r); status != 0 {
+				debugNote(r.Context(), "header limit: %s", reason)
+				http.Error(w, StatusText(r.Context(), status), status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HeaderLimit overrides the HeaderLimit middleware's non-zero limits for
+// this route
+func (rt *Route) HeaderLimit(opts HeaderLimitOptions) *Route {
+	return rt.Set(headerLimitKey{}, opts)
+}
+
+func (o HeaderLimitOptions) merge(override HeaderLimitOptions) HeaderLimitOptions {
+	if override.MaxHeaders != 0 {
+		o.MaxHeaders = override.MaxHeaders
+	}
+	if override.MaxHeaderSize != 0 {
+		o.MaxHeaderSize = override.MaxHeaderSize
+	}
+	if override.MaxCookies != 0 {
+		o.MaxCookies = override.MaxCookies
+	}
+	if override.MaxURLLength != 0 {
+		o.MaxURLLength = override.MaxURLLength
+	}
+	return o
+}
+
+// check returns the status to reject r with and why, or 0
+func (o HeaderLimitOptions) check(r *http.Request) (int, string) {
+	if o.MaxURLLength > 0 {
+		target := r.RequestURI
+		if target == "" {
+			target = r.URL.RequestURI()
+		}
+		if len(target) > o.MaxURLLength {
+			return http.StatusRequestURITooLong, "URL longer than " + toString(o.MaxURLLength) + " bytes"
+		}
+	}
+
+	fields, cookies := 0, 0
+	for name, values := range r.Header {
+		fields += len(values)
+		for _, v := range values {
+			if o.MaxHeaderSize > 0 && len(name)+len(v) > o.MaxHeaderSize {
+				return http.StatusRequestHeaderFieldsTooLarge, name + " header larger than " + toString(o.MaxHeaderSize) + " bytes"
+			}
+			if name == "Cookie" {
+				for _, part := range strings.Split(v, ";") {
+					if strings.TrimSpace(part) != "" {
+						cookies++
+					}
+				}
+			}
+		}
+	}
+	if o.MaxHeaders > 0 && fields > o.MaxHeaders {
+		return http.StatusRequestHeaderFieldsTooLarge, "more than " + toString(o.MaxHeaders) + " header fields"
+	}
+	if o.MaxCookies > 0 && cookies > o.MaxCookies {
+		return http.StatusRequestHeaderFieldsTooLarge, "more than " + toString(o.MaxCookies) + " cookies"
+	}
+	return 0, ""
+}
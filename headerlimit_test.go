@@ -0,0 +1,45 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeaderLimit(t *testing.T) {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux := New()
+	mux.Use(HeaderLimit(HeaderLimitOptions{MaxHeaders: 10, MaxHeaderSize: 64, MaxCookies: 3, MaxURLLength: 40}))
+	mux.Handle("/api/...", noop, MethodGet)
+	mux.Handle("/webhooks/github", noop, MethodPost).HeaderLimit(HeaderLimitOptions{MaxHeaders: 3})
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		header   map[string][]string
+		expected int
+	}{
+		{"within limits", MethodGet, "/api/users", map[string][]string{"Cookie": {"a=1; b=2"}}, http.StatusOK},
+		{"long URL", MethodGet, "/api/search?q=" + strings.Repeat("x", 40), nil, http.StatusRequestURITooLong},
+		{"large header", MethodGet, "/api/users", map[string][]string{"X-Trace": {strings.Repeat("x", 64)}}, http.StatusRequestHeaderFieldsTooLarge},
+		{"repeated header", MethodGet, "/api/users", map[string][]string{"X-Tag": {"1", "2", "3", "4", "5", "6", "7", "8", "9", "10", "11"}}, http.StatusRequestHeaderFieldsTooLarge},
+		{"too many cookies", MethodGet, "/api/users", map[string][]string{"Cookie": {"a=1; b=2", "c=3; d=4"}}, http.StatusRequestHeaderFieldsTooLarge},
+		{"stricter route", MethodPost, "/webhooks/github", map[string][]string{"A": {"1"}, "B": {"1"}, "C": {"1"}, "D": {"1"}}, http.StatusRequestHeaderFieldsTooLarge},
+		{"stricter route keeps other limits", MethodPost, "/webhooks/github", map[string][]string{"A": {strings.Repeat("x", 64)}}, http.StatusRequestHeaderFieldsTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
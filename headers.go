@@ -0,0 +1,159 @@
+package GoFlow
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// SetHeaders sets response headers, replacing any value the handler set,
+// e.g. {"X-API-Version": "2"}. Install it on a group to scope the policy.
+func SetHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	headers = canonicalHeaders(headers)
+	return NamedMiddleware("GoFlow.SetHeaders", rewriteHeaders(func(h http.Header) {
+		for k, v := range headers {
+			h[k] = []string{v}
+		}
+	}))
+}
+
+// DefaultHeaders sets response headers the handler didn't set, e.g.
+// {"Cache-Control": "no-store"} as a default individual handlers override
+func DefaultHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	headers = canonicalHeaders(headers)
+	return NamedMiddleware("GoFlow.DefaultHeaders", rewriteHeaders(func(h http.Header) {
+		for k, v := range headers {
+			if _, ok := h[k]; !ok {
+				h[k] = []string{v}
+			}
+		}
+	}))
+}
+
+// RemoveHeaders removes response headers before they are sent, such as
+// X-Powered-By from proxied upstreams
+func RemoveHeaders(names ...string) func(http.Handler) http.Handler {
+	canonical := make([]string, len(names))
+	for i, name := range names {
+		canonical[i] = http.CanonicalHeaderKey(name)
+	}
+	return NamedMiddleware("GoFlow.RemoveHeaders", rewriteHeaders(func(h http.Header) {
+		for _, name := range canonical {
+			delete(h, name)
+		}
+	}))
+}
+
+func canonicalHeaders(headers map[string]string) map[string]string {
+	canonical := make(map[string]string, len(headers))
+	for k, v := range headers {
+		canonical[http.CanonicalHeaderKey(k)] = v
+	}
+	return canonical
+}
+
+// rewriteHeaders runs fn on the response headers just before they are sent
+func rewriteHeaders(fn func(http.Header)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hw := &headerWriter{ResponseWriter: w, rewrite: fn}
+			next.ServeHTTP(wrapWriter(hw), r)
+			if !hw.rewritten && !hw.hijacked {
+				// The handler wrote nothing; the server sends an empty 200
+				hw.apply()
+			}
+		})
+	}
+}
+
+// headerWriter applies a header rewrite once, before the header is written
+type headerWriter struct {
+	http.ResponseWriter
+	rewrite   func(http.Header)
+	rewritten bool
+	hijacked  bool
+}
+
+func (w *headerWriter) apply() {
+	if !w.rewritten {
+		w.rewritten = true
+		w.rewrite(w.Header())
+	}
+}
+
+func (w *headerWriter) WriteHeader(status int) {
+	if status >= http.StatusOK {
+		w.apply()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *headerWriter) Flush() {
+	w.apply()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headerWriter) ReadFrom(src io.Reader) (int64, error) {
+	w.apply()
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(src)
+	}
+	return io.Copy(writerOnly{w.ResponseWriter}, src)
+}
+
+func (w *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *headerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AddVary adds names to the Vary header of h, skipping names it already
+// lists. Middleware that picks a response by a request header calls it
+// rather than setting Vary, which would drop what others added.
+func AddVary(h http.Header, names ...string) {
+	listed := h.Values("Vary")
+	for _, name := range names {
+		if !varies(listed, name) {
+			h.Add("Vary", http.CanonicalHeaderKey(name))
+			listed = h.Values("Vary")
+		}
+	}
+}
+
+// varies reports whether the Vary values list name or "*"
+func varies(values []string, name string) bool {
+	for _, v := range values {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// varyNames returns the header names the Vary values list, canonicalized
+func varyNames(values []string) []string {
+	var names []string
+	for _, v := range values {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				names = append(names, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+	return names
+}
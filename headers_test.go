@@ -0,0 +1,113 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderMiddleware(t *testing.T) {
+	mux := New()
+	mux.Use(DefaultHeaders(map[string]string{"cache-control": "no-store", "X-Frame": "DENY"}))
+	mux.Group(func(m *Mux) {
+		m.Use(SetHeaders(map[string]string{"X-API-Version": "2"}), RemoveHeaders("x-powered-by"))
+		m.Handle("/api", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-API-Version", "1")
+			w.Header().Set("X-Powered-By", "PHP/5.6")
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte("ok"))
+		}), MethodGet)
+		m.Handle("/empty", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MethodGet)
+	})
+	mux.Handle("/plain", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}), MethodGet)
+
+	serve := func(path string) http.Header {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w.Header()
+	}
+
+	t.Run("Group Policy", func(t *testing.T) {
+		h := serve("/api")
+		if h.Get("X-API-Version") != "2" || h.Get("X-Powered-By") != "" {
+			t.Errorf("Expected X-API-Version 2 without X-Powered-By, got %v", h)
+		}
+		if h.Get("Cache-Control") != "max-age=60" || h.Get("X-Frame") != "DENY" {
+			t.Errorf("Expected defaults not to override the handler, got %v", h)
+		}
+	})
+
+	t.Run("Empty Response", func(t *testing.T) {
+		if h := serve("/empty"); h.Get("X-API-Version") != "2" || h.Get("Cache-Control") != "no-store" {
+			t.Errorf("Expected headers on an empty response, got %v", h)
+		}
+	})
+
+	t.Run("Outside Group", func(t *testing.T) {
+		h := serve("/plain")
+		if h.Get("X-API-Version") != "" || h.Get("Cache-Control") != "no-store" {
+			t.Errorf("Expected only the defaults outside the group, got %v", h)
+		}
+	})
+
+	t.Run("Proxied Upstream", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Powered-By", "Express")
+			w.Write([]byte("upstream"))
+		}))
+		defer upstream.Close()
+
+		gw := New()
+		gw.Use(RemoveHeaders("X-Powered-By"))
+		gw.Proxy("/...", upstream.URL, ProxyOptions{})
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, httptest.NewRequest(MethodGet, "/x", nil))
+		if w.Header().Get("X-Powered-By") != "" || !strings.Contains(w.Body.String(), "upstream") {
+			t.Errorf("Expected X-Powered-By stripped from the upstream response, got %v", w.Header())
+		}
+	})
+}
+
+func TestVary(t *testing.T) {
+	t.Run("AddVary", func(t *testing.T) {
+		h := http.Header{"Vary": {"accept-encoding, Origin"}}
+		AddVary(h, "Origin", "Accept-Language", "Accept-Encoding")
+		if got := h.Values("Vary"); !equalSlices(got, []string{"accept-encoding, Origin", "Accept-Language"}) {
+			t.Errorf("Expected Accept-Language added once, got %q", got)
+		}
+		h = http.Header{"Vary": {"*"}}
+		AddVary(h, "Origin")
+		if got := h.Values("Vary"); !equalSlices(got, []string{"*"}) {
+			t.Errorf("Expected Vary * untouched, got %q", got)
+		}
+	})
+
+	t.Run("Stacked Middleware", func(t *testing.T) {
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			AddVary(w.Header(), "Accept-Language")
+			w.Write([]byte(r.Header.Get("Accept-Language")))
+		})
+		stack := CORS([]string{"https://app.example.com"}, []string{MethodGet}, nil)(
+			Compression()(Cache(time.Minute)(handler)))
+
+		for _, lang := range []string{"en", "de", "en"} {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.Header.Set("Origin", "https://app.example.com")
+			r.Header.Set("Accept-Language", lang)
+			w := httptest.NewRecorder()
+			stack.ServeHTTP(w, r)
+
+			names := varyNames(w.Header().Values("Vary"))
+			if !equalSlices(names, []string{"Origin", "Accept-Encoding", "Accept-Language"}) {
+				t.Errorf("Expected Vary from every middleware, got %q", names)
+			}
+			if w.Body.String() != lang {
+				t.Errorf("Expected the cached %s variant, got %q", lang, w.Body.String())
+			}
+		}
+	})
+}
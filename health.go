@@ -0,0 +1,105 @@
+package GoFlow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck reports an error when the checked dependency is unhealthy
+type HealthCheck func(ctx context.Context) error
+
+// Health is a registry of named health checks served as a JSON report
+type Health struct {
+	// Timeout bounds each run of the checks. Defaults to 5s.
+	Timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]HealthCheck
+}
+
+// HealthReport is the result of running all checks
+type HealthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// NewHealth creates an empty Health registry
+func NewHealth() *Health {
+	return &Health{Timeout: 5 * time.Second, checks: make(map[string]HealthCheck)}
+}
+
+// Register adds or replaces the check called name
+func (h *Health) Register(name string, check HealthCheck) {
+	h.mu.Lock()
+	h.checks[name] = check
+	h.mu.Unlock()
+}
+
+// Unregister removes the check called name
+func (h *Health) Unregister(name string) {
+	h.mu.Lock()
+	delete(h.checks, name)
+	h.mu.Unlock()
+}
+
+// Check runs all checks concurrently. The report's status is "ok" when every
+// check passes and "fail" otherwise.
+func (h *Health) Check(ctx context.Context) HealthReport {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	h.mu.RLock()
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]HealthCheck, len(names))
+	for i, name := range names {
+		checks[i] = h.checks[name]
+	}
+	h.mu.RUnlock()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := HealthReport{Status: "ok", Checks: make(map[string]string, len(names))}
+	for i, name := range names {
+		if results[i] != nil {
+			report.Status = "fail"
+			report.Checks[name] = results[i].Error()
+		} else {
+			report.Checks[name] = "ok"
+		}
+	}
+	return report
+}
+
+// Handler serves the report as JSON with 200, or 503 if any check fails
+func (h *Health) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if report.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,93 @@
+package GoFlow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	t.Run("Report", func(t *testing.T) {
+		health := NewHealth()
+		health.Register("db", func(ctx context.Context) error { return nil })
+		health.Register("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+		w := httptest.NewRecorder()
+		health.Handler().ServeHTTP(w, httptest.NewRequest(MethodGet, "/healthz", nil))
+
+		var report HealthReport
+		json.NewDecoder(w.Body).Decode(&report)
+		if w.Code != http.StatusServiceUnavailable || report.Status != "fail" {
+			t.Errorf("Expected failing report, got %d %+v", w.Code, report)
+		}
+		if report.Checks["db"] != "ok" || report.Checks["cache"] != "connection refused" {
+			t.Errorf("Unexpected checks %v", report.Checks)
+		}
+
+		health.Unregister("cache")
+		w = httptest.NewRecorder()
+		health.Handler().ServeHTTP(w, httptest.NewRequest(MethodGet, "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("Upstream Ejection And Recovery", func(t *testing.T) {
+		var failing atomic.Bool
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/healthz" || failing.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			io.WriteString(w, "ok")
+		}))
+		defer backend.Close()
+
+		pool, _ := NewUpstreamPool(RoundRobin, backend.URL)
+		health := NewHealth()
+		health.Register("upstreams", pool.Check)
+
+		stop := pool.StartHealthChecks(HealthCheckOptions{
+			Path:           "/healthz",
+			Interval:       5 * time.Millisecond,
+			ExpectedStatus: http.StatusOK,
+			Rise:           2,
+			Fall:           2,
+			Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		defer stop()
+
+		waitFor := func(healthy bool) {
+			t.Helper()
+			deadline := time.Now().Add(time.Second)
+			for pool.Upstreams()[0].Healthy() != healthy {
+				if time.Now().After(deadline) {
+					t.Fatalf("Upstream did not become healthy=%v", healthy)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		failing.Store(true)
+		waitFor(false)
+		if pool.acquire() != nil {
+			t.Error("Expected ejected upstream not to be picked")
+		}
+		if report := health.Check(context.Background()); report.Status != "fail" {
+			t.Errorf("Expected pool state in health report, got %+v", report)
+		}
+
+		failing.Store(false)
+		waitFor(true)
+		if report := health.Check(context.Background()); report.Status != "ok" {
+			t.Errorf("Expected recovered pool, got %+v", report)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package GoFlow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HealthCheckOptions configures active health checks for an UpstreamPool
+type HealthCheckOptions struct {
+	// Path is requested on every upstream. Defaults to "/".
+	Path string
+
+	// Interval between probes and Timeout of each probe. Default to 10s and 2s.
+	Interval time.Duration
+	Timeout  time.Duration
+
+	// ExpectedStatus is the status a healthy upstream answers with.
+	// Defaults to any 2xx or 3xx status.
+	ExpectedStatus int
+
+	// Rise consecutive successes reinstate an ejected upstream, and Fall
+	// consecutive failures eject a healthy one. Default to 2 and 3.
+	Rise int
+	Fall int
+
+	// Client sends the probes. Defaults to a client without redirects.
+	Client *http.Client
+
+	// Logger receives state changes. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// StartHealthChecks probes every upstream periodically, ejecting upstreams
+// that fail and reinstating them once they recover. It returns a function
+// that stops the checks.
+func (p *UpstreamPool) StartHealthChecks(opts HealthCheckOptions) (stop func()) {
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	if opts.Rise == 0 {
+		opts.Rise = 2
+	}
+	if opts.Fall == 0 {
+		opts.Fall = 3
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			p.probeAll(ctx, opts)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
+
+// Check is a HealthCheck that fails when no upstream in the pool is healthy.
+// Register it with Health to expose the pool's state:
+//
+//	health.Register("upstreams", pool.Check)
+func (p *UpstreamPool) Check(ctx context.Context) error {
+	var down []string
+	upstreams := p.Upstreams()
+	for _, up := range upstreams {
+		if !up.Healthy() {
+			down = append(down, up.url.Host)
+		}
+	}
+	if len(upstreams) > 0 && len(down) == len(upstreams) {
+		return fmt.Errorf("all upstreams down: %s", strings.Join(down, ", "))
+	}
+	return nil
+}
+
+func (p *UpstreamPool) probeAll(ctx context.Context, opts HealthCheckOptions) {
+	var wg sync.WaitGroup
+	for _, up := range p.Upstreams() {
+		wg.Add(1)
+		go func(up *Upstream) {
+			defer wg.Done()
+			up.record(probe(ctx, up, opts), opts)
+		}(up)
+	}
+	wg.Wait()
+}
+
+func probe(ctx context.Context, up *Upstream, opts HealthCheckOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	target := *up.url
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(opts.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, MethodGet, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	if opts.ExpectedStatus != 0 && resp.StatusCode != opts.ExpectedStatus {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if opts.ExpectedStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode >= 400) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// record applies a probe result to the rise/fall counters
+func (up *Upstream) record(err error, opts HealthCheckOptions) {
+	if err == nil {
+		up.failures = 0
+		up.successes++
+		if !up.healthy.Load() && up.successes >= opts.Rise {
+			up.healthy.Store(true)
+			opts.Logger.Info("upstream healthy", slog.String("upstream", up.url.String()))
+		}
+		return
+	}
+
+	up.successes = 0
+	up.failures++
+	if up.healthy.Load() && up.failures >= opts.Fall {
+		up.healthy.Store(false)
+		opts.Logger.Warn("upstream unhealthy",
+			slog.String("upstream", up.url.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+}
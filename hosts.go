@@ -0,0 +1,49 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts rejects requests whose Host header doesn't match one of hosts,
+// preventing host-header injection into generated links and cache keys.
+// Entries may start with "*." to match any subdomain, e.g. "*.example.com"
+// matches "api.example.com" but not "example.com". Ports are ignored.
+func AllowedHosts(hosts ...string) func(http.Handler) http.Handler {
+	exact := make(map[string]struct{}, len(hosts))
+	var suffixes []string
+	for _, h := range hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if strings.HasPrefix(h, "*.") {
+			suffixes = append(suffixes, h[1:])
+			continue
+		}
+		exact[stripPort(h)] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := strings.TrimSuffix(strings.ToLower(stripPort(r.Host)), ".")
+			if hostAllowed(host, exact, suffixes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, StatusText(r.Context(), http.StatusBadRequest), http.StatusBadRequest)
+		})
+	}
+}
+
+func hostAllowed(host string, exact map[string]struct{}, suffixes []string) bool {
+	if host == "" {
+		return false
+	}
+	if _, ok := exact[host]; ok {
+		return true
+	}
+	for _, suffix := range suffixes {
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedHosts(t *testing.T) {
+	handler := AllowedHosts("example.com", "*.example.com", "localhost:8080")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	tests := []struct {
+		host     string
+		expected int
+	}{
+		{"example.com", http.StatusOK},
+		{"EXAMPLE.com:443", http.StatusOK},
+		{"api.example.com", http.StatusOK},
+		{"a.b.example.com", http.StatusOK},
+		{"example.com.", http.StatusOK},
+		{"localhost", http.StatusOK},
+		{"evil.com", http.StatusBadRequest},
+		{"evilexample.com", http.StatusBadRequest},
+		{"example.com.evil.com", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, "/reset-password", nil)
+		r.Host = tt.host
+		handler.ServeHTTP(w, r)
+
+		if w.Code != tt.expected {
+			t.Errorf("%q: expected status code %d, got %d", tt.host, tt.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+//go:build http3
+
+package GoFlow
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Building with -tags http3 enables Server.HTTP3
+func init() {
+	newHTTP3Server = func(addr string, handler http.Handler, config *tls.Config) http3Server {
+		return &http3.Server{
+			Addr:      addr,
+			Handler:   handler,
+			TLSConfig: http3.ConfigureTLSConfig(config),
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//go:build http3
+
+package GoFlow
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3(t *testing.T) {
+	cert, key := writeTestCert(t, t.TempDir(), "localhost")
+	addr := freeAddr(t)
+	s := NewServer(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	s.HTTP3 = true
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	done := make(chan error, 1)
+	go func() { done <- s.RunTLS(cert, key) }()
+	waitForServer(t, addr)
+
+	transport := &http3.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	defer transport.Close()
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	var body []byte
+	for i := 0; i < 50; i++ {
+		// QUIC may come up just after the TCP listener
+		resp, err := client.Get("https://" + addr + "/")
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if string(body) != "HTTP/3.0" {
+		t.Errorf("Expected a response over HTTP/3, got %q", body)
+	}
+
+	s.Shutdown(context.Background())
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean stop, got %v", err)
+	}
+}
@@ -0,0 +1,467 @@
+package GoFlow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Catalog holds translated messages by locale. Keys of nested JSON objects
+// and TOML tables are joined with dots, e.g. "errors.not_found".
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{messages: make(map[string]map[string]string)}
+}
+
+// Add merges messages into locale
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	locale = canonicalLocale(locale)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string, len(messages))
+	}
+	for k, v := range messages {
+		c.messages[locale][k] = v
+	}
+}
+
+// LoadFile adds the messages of a JSON or TOML file named after its locale,
+// e.g. "locales/de-AT.toml"
+func (c *Catalog) LoadFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return c.load(name, data)
+}
+
+// LoadFS adds every catalog file in fsys matching pattern, e.g.
+// "locales/*.json" in an embed.FS
+func (c *Catalog) LoadFS(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("GoFlow: no catalog files match %q", pattern)
+	}
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if err := c.load(name, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Catalog) load(name string, data []byte) error {
+	ext := path.Ext(name)
+	locale := strings.TrimSuffix(path.Base(name), ext)
+
+	messages := make(map[string]string)
+	switch ext {
+	case ".json":
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("GoFlow: %s: %w", name, err)
+		}
+		if err := flattenMessages(messages, "", doc); err != nil {
+			return fmt.Errorf("GoFlow: %s: %w", name, err)
+		}
+	case ".toml":
+		if err := parseTOMLMessages(messages, string(data)); err != nil {
+			return fmt.Errorf("GoFlow: %s: %w", name, err)
+		}
+	default:
+		return fmt.Errorf("GoFlow: %s: unsupported catalog format %q", name, ext)
+	}
+	c.Add(locale, messages)
+	return nil
+}
+
+// Locales returns the locales with messages, sorted
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Message looks up key in locale, then in its base language, e.g. "de-AT"
+// then "de"
+func (c *Catalog) Message(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for locale != "" {
+		if msg, ok := c.messages[locale][key]; ok {
+			return msg, true
+		}
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return "", false
+}
+
+// Localizer translates messages for one locale
+type Localizer struct {
+	locale   string
+	fallback string
+	catalog  *Catalog
+}
+
+// Localizer returns a localizer for locale that falls back to fallback
+func (c *Catalog) Localizer(locale, fallback string) *Localizer {
+	return &Localizer{locale: canonicalLocale(locale), fallback: canonicalLocale(fallback), catalog: c}
+}
+
+// Locale returns the localizer's locale
+func (l *Localizer) Locale() string {
+	return l.locale
+}
+
+// T returns the message for key formatted with args as by fmt.Sprintf. If
+// neither the locale nor the fallback has the message, key is returned.
+func (l *Localizer) T(key string, args ...any) string {
+	msg, ok := l.lookup(key)
+	if !ok {
+		msg = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (l *Localizer) lookup(key string) (string, bool) {
+	if msg, ok := l.catalog.Message(l.locale, key); ok {
+		return msg, true
+	}
+	return l.catalog.Message(l.fallback, key)
+}
+
+// I18nOptions configures the I18n middleware
+type I18nOptions struct {
+	Catalog *Catalog
+
+	// Default is used when the request asks for no supported locale
+	Default string
+
+	// QueryParam and Cookie name where an explicit choice is read from,
+	// checked before Accept-Language. Default to "lang"; "-" disables.
+	QueryParam string
+	Cookie     string
+}
+
+type localizerKey struct{}
+
+// I18n detects the request's locale from the lang query parameter, the
+// lang cookie or Accept-Language, in that order, among the catalog's
+// locales, and stores a Localizer for T in the request context
+func I18n(catalog *Catalog, defaultLocale string) func(http.Handler) http.Handler {
+	return I18nWithOptions(I18nOptions{Catalog: catalog, Default: defaultLocale})
+}
+
+// I18nWithOptions is I18n with configurable detection sources
+func I18nWithOptions(opts I18nOptions) func(http.Handler) http.Handler {
+	if opts.Catalog == nil {
+		panic("GoFlow: I18n requires a Catalog")
+	}
+	if opts.QueryParam == "" {
+		opts.QueryParam = "lang"
+	}
+	if opts.Cookie == "" {
+		opts.Cookie = "lang"
+	}
+	opts.Default = canonicalLocale(opts.Default)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := detectLocale(r, opts)
+			AddVary(w.Header(), "Accept-Language")
+			w.Header().Set("Content-Language", locale)
+			ctx := context.WithValue(r.Context(), localizerKey{}, opts.Catalog.Localizer(locale, opts.Default))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func detectLocale(r *http.Request, opts I18nOptions) string {
+	supported := opts.Catalog.Locales()
+	if opts.QueryParam != "-" {
+		if v := r.URL.Query().Get(opts.QueryParam); v != "" {
+			if locale := matchLocale(supported, v); locale != "" {
+				return locale
+			}
+		}
+	}
+	if opts.Cookie != "-" {
+		if c, err := r.Cookie(opts.Cookie); err == nil {
+			if locale := matchLocale(supported, c.Value); locale != "" {
+				return locale
+			}
+		}
+	}
+	if locale := NegotiateLocale(r, supported...); locale != "" {
+		return locale
+	}
+	return opts.Default
+}
+
+// NegotiateLocale returns the supported locale the Accept-Language header of
+// r prefers, or "" if it accepts none of them
+func NegotiateLocale(r *http.Request, supported ...string) string {
+	type languageRange struct {
+		tag string
+		q   float64
+	}
+	var ranges []languageRange
+	for _, part := range strings.Split(strings.Join(r.Header.Values("Accept-Language"), ","), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, languageRange{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, lr := range ranges {
+		if locale := matchLocale(supported, lr.tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// matchLocale finds tag among supported, then its base language, then a
+// regional variant of it, e.g. "de-CH" matches "de" and "de" matches "de-DE"
+func matchLocale(supported []string, tag string) string {
+	tag = canonicalLocale(tag)
+	for t := tag; t != ""; {
+		for _, s := range supported {
+			if s == t {
+				return s
+			}
+		}
+		i := strings.LastIndexByte(t, '-')
+		if i < 0 {
+			break
+		}
+		t = t[:i]
+	}
+	base, _, _ := strings.Cut(tag, "-")
+	for _, s := range supported {
+		if strings.HasPrefix(s, base+"-") {
+			return s
+		}
+	}
+	return ""
+}
+
+// canonicalLocale normalizes "en_us" to "en-US" and "zh-hant" to "zh-Hant"
+func canonicalLocale(tag string) string {
+	parts := strings.FieldsFunc(strings.TrimSpace(tag), func(r rune) bool { return r == '-' || r == '_' })
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4:
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// CurrentLocalizer returns the localizer installed by I18n, or nil
+func CurrentLocalizer(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(localizerKey{}).(*Localizer)
+	return l
+}
+
+// Locale returns the locale chosen by I18n, or ""
+func Locale(ctx context.Context) string {
+	if l := CurrentLocalizer(ctx); l != nil {
+		return l.locale
+	}
+	return ""
+}
+
+// T translates key for the request's locale; see Localizer.T. Without the
+// I18n middleware key itself is formatted.
+func T(ctx context.Context, key string, args ...any) string {
+	l := CurrentLocalizer(ctx)
+	if l == nil {
+		l = &Localizer{catalog: NewCatalog()}
+	}
+	return l.T(key, args...)
+}
+
+// StatusText returns the message for "status.<code>" in the request's
+// locale, falling back to http.StatusText. The mux's default error
+// responses use it.
+func StatusText(ctx context.Context, code int) string {
+	if l := CurrentLocalizer(ctx); l != nil {
+		if msg, ok := l.lookup("status." + strconv.Itoa(code)); ok {
+			return msg
+		}
+	}
+	return http.StatusText(code)
+}
+
+func flattenMessages(dst map[string]string, prefix string, doc map[string]any) error {
+	for k, v := range doc {
+		key := prefix + k
+		switch v := v.(type) {
+		case string:
+			dst[key] = v
+		case map[string]any:
+			if err := flattenMessages(dst, key+".", v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q must be a string", key)
+		}
+	}
+	return nil
+}
+
+// parseTOMLMessages reads the TOML subset message catalogs use: [tables],
+// dotted or quoted keys and basic or literal string values
+func parseTOMLMessages(dst map[string]string, src string) error {
+	table := ""
+	for i, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return fmt.Errorf("line %d: unsupported table header %s", i+1, line)
+			}
+			key, err := tomlKey(line[1 : len(line)-1])
+			if err != nil {
+				return fmt.Errorf("line %d: %w", i+1, err)
+			}
+			table = key + "."
+			continue
+		}
+
+		k, v, ok := cutTOMLAssignment(line)
+		if !ok {
+			return fmt.Errorf("line %d: expected key = \"value\"", i+1)
+		}
+		key, err := tomlKey(k)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		value, err := tomlString(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", i+1, key, err)
+		}
+		dst[table+key] = value
+	}
+	return nil
+}
+
+// cutTOMLAssignment splits at the first '=' outside a quoted key
+func cutTOMLAssignment(line string) (string, string, bool) {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// tomlKey joins the parts of a dotted key, unquoting quoted parts
+func tomlKey(s string) (string, error) {
+	var parts []string
+	for s = strings.TrimSpace(s); s != ""; {
+		var part string
+		if s[0] == '"' || s[0] == '\'' {
+			end, err := quotedEnd(s)
+			if err != nil {
+				return "", err
+			}
+			if part, err = tomlString(s[:end]); err != nil {
+				return "", err
+			}
+			s = strings.TrimSpace(s[end:])
+		} else {
+			i := strings.IndexByte(s, '.')
+			if i < 0 {
+				i = len(s)
+			}
+			part, s = strings.TrimSpace(s[:i]), s[i:]
+			if part == "" {
+				return "", fmt.Errorf("empty key")
+			}
+		}
+		parts = append(parts, part)
+		if s != "" {
+			if s[0] != '.' {
+				return "", fmt.Errorf("unexpected %q in key", s)
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("empty key")
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func tomlString(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		return strconv.Unquote(s)
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	}
+	return "", fmt.Errorf("expected a quoted string, got %s", s)
+}
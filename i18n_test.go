@@ -0,0 +1,102 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestI18n(t *testing.T) {
+	catalog := NewCatalog()
+	err := catalog.LoadFS(fstest.MapFS{
+		"locales/en.json": {Data: []byte(`{"greeting": "Hello, %s!", "status": {"404": "Nothing here"}}`)},
+		"locales/de.toml": {Data: []byte(`
+greeting = "Hallo, %s!" # informal
+
+[status]
+404 = "Nicht gefunden"
+"405" = 'Methode nicht erlaubt'
+`)},
+		"locales/de-AT.toml": {Data: []byte(`greeting = "Servus, %s!"`)},
+	}, "locales/*")
+	if err != nil {
+		t.Fatalf("Expected catalog to load, got %v", err)
+	}
+	if got := catalog.Locales(); !equalSlices(got, []string{"de", "de-AT", "en"}) {
+		t.Errorf("Expected de, de-AT, en, got %v", got)
+	}
+
+	mux := New()
+	mux.Use(I18n(catalog, "en"))
+	mux.Handle("/hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Locale(r.Context()) + " " + T(r.Context(), "greeting", "Ada") + " " + T(r.Context(), "missing.key")))
+	}), MethodGet)
+
+	tests := []struct {
+		name   string
+		url    string
+		cookie string
+		accept string
+		want   string
+	}{
+		{"Default", "/hello", "", "", "en Hello, Ada! missing.key"},
+		{"Accept-Language", "/hello", "", "fr;q=0.9, de;q=0.8, en;q=0.1", "de Hallo, Ada! missing.key"},
+		{"Regional Variant", "/hello", "", "de-at", "de-AT Servus, Ada! missing.key"},
+		{"Base Language", "/hello", "", "de-CH", "de Hallo, Ada! missing.key"},
+		{"Cookie", "/hello", "de-AT", "en", "de-AT Servus, Ada! missing.key"},
+		{"Query", "/hello?lang=de", "en", "en", "de Hallo, Ada! missing.key"},
+		{"Unsupported Query", "/hello?lang=xx", "", "de", "de Hallo, Ada! missing.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(MethodGet, tt.url, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "lang", Value: tt.cookie})
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept-Language", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Body.String() != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, w.Body.String())
+			}
+			if w.Header().Get("Content-Language") != strings.Fields(tt.want)[0] {
+				t.Errorf("Expected Content-Language %s, got '%s'", strings.Fields(tt.want)[0], w.Header().Get("Content-Language"))
+			}
+		})
+	}
+
+	t.Run("Error Responses", func(t *testing.T) {
+		req := httptest.NewRequest(MethodGet, "/nowhere", nil)
+		req.Header.Set("Accept-Language", "de-AT")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound || strings.TrimSpace(w.Body.String()) != "Nicht gefunden" {
+			t.Errorf("Expected localized 404, got %d '%s'", w.Code, w.Body.String())
+		}
+
+		req = httptest.NewRequest(MethodPost, "/hello?lang=de", nil)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if strings.TrimSpace(w.Body.String()) != "Methode nicht erlaubt" {
+			t.Errorf("Expected localized 405, got '%s'", w.Body.String())
+		}
+
+		req = httptest.NewRequest(MethodPost, "/hello?lang=en", nil)
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if strings.TrimSpace(w.Body.String()) != "Method Not Allowed" {
+			t.Errorf("Expected status text fallback, got '%s'", w.Body.String())
+		}
+	})
+
+	t.Run("Bad Catalog", func(t *testing.T) {
+		err := NewCatalog().LoadFS(fstest.MapFS{"en.toml": {Data: []byte("greeting = Hello")}}, "*.toml")
+		if err == nil || !strings.Contains(err.Error(), "en.toml: line 1: greeting: expected a quoted string") {
+			t.Errorf("Expected TOML error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package GoFlow
+
+import (
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// InspectMode selects whether rule matches are only logged or also rejected
+type InspectMode int
+
+const (
+	// InspectDetect logs matches and lets the request through
+	InspectDetect InspectMode = iota
+	// InspectBlock logs matches and rejects the request
+	InspectBlock
+)
+
+// InspectTarget selects the parts of a request a rule examines
+type InspectTarget uint8
+
+const (
+	TargetPath InspectTarget = 1 << iota
+	TargetQuery
+	// TargetForm is the field values of url-encoded and multipart bodies.
+	// Uploaded files are not inspected.
+	TargetForm
+	TargetHeaders
+)
+
+// inspectMaxMemory is the part of a multipart body kept in memory while
+// parsing it, as for http.Request.FormValue; the rest goes to temporary files
+const inspectMaxMemory = 32 << 20
+
+// InspectRule examines a request and reports the offending value when it matches
+type InspectRule struct {
+	Name  string
+	Check func(r *http.Request) (value string, matched bool)
+}
+
+// InspectOptions configures the Inspect middleware
+type InspectOptions struct {
+	Mode InspectMode
+
+	// Rules to evaluate. Defaults to DefaultInspectRules().
+	Rules []InspectRule
+
+	// Logger receives one warning per match. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Blocked handles rejected requests in InspectBlock mode. Defaults to 403.
+	Blocked http.Handler
+}
+
+var (
+	sqlInjectionRegex = regexp.MustCompile(`(?i)(\bunion\b[\s\S]+\bselect\b|'\s*(or|and)\s+['\d]|\b(or|and)\s+\d+\s*=\s*\d+|;\s*(drop|delete|insert|update|shutdown)\b|\b(sleep|benchmark|pg_sleep)\s*\(|\bwaitfor\s+delay\b|--\s*$|/\*[\s\S]*\*/)`)
+	xssRegex          = regexp.MustCompile(`(?i)(<\s*script\b|<\s*/\s*script\s*>|javascript\s*:|\bon(error|load|click|mouseover|focus|submit)\s*=|<\s*(iframe|object|embed)\b|\bsrcdoc\s*=)`)
+)
+
+// DefaultInspectRules returns the built-in rule set: path traversal, null
+// bytes, SQL injection and XSS patterns in path, query and form fields, and
+// at most 100 headers
+func DefaultInspectRules() []InspectRule {
+	return []InspectRule{
+		PathTraversalRule(),
+		NullByteRule(),
+		PatternRule("sql-injection", sqlInjectionRegex, TargetQuery|TargetForm),
+		PatternRule("xss", xssRegex, TargetPath|TargetQuery|TargetForm),
+		HeaderCountRule(100),
+	}
+}
+
+// PathTraversalRule matches ../ sequences in the path, including encoded forms
+func PathTraversalRule() InspectRule {
+	return InspectRule{
+		Name: "path-traversal",
+		Check: func(r *http.Request) (string, bool) {
+			raw := strings.ToLower(r.URL.EscapedPath() + "?" + r.URL.RawQuery)
+			for _, seq := range []string{"../", "..\\", "%2e%2e", "..%2f", "..%5c", "%252e"} {
+				if strings.Contains(raw, seq) {
+					return r.URL.RequestURI(), true
+				}
+			}
+			if strings.HasSuffix(r.URL.Path, "/..") {
+				return r.URL.Path, true
+			}
+			return "", false
+		},
+	}
+}
+
+// NullByteRule matches NUL characters in the path or query
+func NullByteRule() InspectRule {
+	return InspectRule{
+		Name: "null-byte",
+		Check: func(r *http.Request) (string, bool) {
+			raw := r.URL.Path + r.URL.RawQuery
+			if strings.Contains(raw, "\x00") || strings.Contains(strings.ToLower(raw), "%00") {
+				return strconv.Quote(r.URL.RequestURI()), true
+			}
+			for _, values := range r.URL.Query() {
+				for _, v := range values {
+					if strings.Contains(v, "\x00") {
+						return strconv.Quote(v), true
+					}
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+// PatternRule matches rx against the selected parts of the request. Form
+// bodies of POST, PUT and PATCH requests are parsed before the handler runs;
+// the handler finds them in r.PostForm and r.MultipartForm.
+func PatternRule(name string, rx *regexp.Regexp, targets InspectTarget) InspectRule {
+	return InspectRule{
+		Name: name,
+		Check: func(r *http.Request) (string, bool) {
+			if targets&TargetPath != 0 && rx.MatchString(r.URL.Path) {
+				return r.URL.Path, true
+			}
+			if targets&TargetQuery != 0 {
+				if v, ok := matchValues(rx, r.URL.Query()); ok {
+					return v, true
+				}
+			}
+			if targets&TargetForm != 0 {
+				if v, ok := matchValues(rx, formValues(r)); ok {
+					return v, true
+				}
+			}
+			if targets&TargetHeaders != 0 {
+				if v, ok := matchValues(rx, r.Header); ok {
+					return v, true
+				}
+			}
+			return "", false
+		},
+	}
+}
+
+// HeaderCountRule matches requests carrying more than max header fields
+func HeaderCountRule(max int) InspectRule {
+	return InspectRule{
+		Name: "header-count",
+		Check: func(r *http.Request) (string, bool) {
+			count := 0
+			for _, values := range r.Header {
+				count += len(values)
+			}
+			if count > max {
+				return strconv.Itoa(count), true
+			}
+			return "", false
+		},
+	}
+}
+
+// Inspect evaluates request-inspection rules and, in InspectBlock mode,
+// rejects requests matching any of them
+func Inspect(opts InspectOptions) func(http.Handler) http.Handler {
+	if opts.Rules == nil {
+		opts.Rules = DefaultInspectRules()
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Blocked == nil {
+		opts.Blocked = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+
+	mode := "detect"
+	if opts.Mode == InspectBlock {
+		mode = "block"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			matched := false
+			for _, rule := range opts.Rules {
+				value, ok := rule.Check(r)
+				if !ok {
+					continue
+				}
+				matched = true
+
+				if len(value) > 256 {
+					value = value[:256]
+				}
+				opts.Logger.LogAttrs(r.Context(), slog.LevelWarn, "request inspection match",
+					slog.String("rule", rule.Name),
+					slog.String("mode", mode),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.String("remote_addr", r.RemoteAddr),
+					slog.String("value", value),
+				)
+
+				if opts.Mode == InspectBlock {
+					break
+				}
+			}
+
+			if matched && opts.Mode == InspectBlock {
+				opts.Blocked.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchValues(rx *regexp.Regexp, values map[string][]string) (string, bool) {
+	for _, vs := range values {
+		for _, v := range vs {
+			if rx.MatchString(v) {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// formValues parses and returns the field values of url-encoded and
+// multipart bodies, or nil for other requests and unparsable bodies
+func formValues(r *http.Request) map[string][]string {
+	if r.Method != MethodPost && r.Method != MethodPut && r.Method != MethodPatch {
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mt {
+	case "application/x-www-form-urlencoded":
+		if r.ParseForm() == nil {
+			return r.PostForm
+		}
+	case "multipart/form-data":
+		if r.ParseMultipartForm(inspectMaxMemory) == nil {
+			return r.MultipartForm.Value
+		}
+	}
+	return nil
+}
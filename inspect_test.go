@@ -0,0 +1,138 @@
+package GoFlow
+
+import (
+	"bytes"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("Block Mode", func(t *testing.T) {
+		handler := Inspect(InspectOptions{
+			Mode:   InspectBlock,
+			Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		})(ok)
+
+		tests := []struct {
+			name     string
+			target   string
+			expected int
+		}{
+			{"clean", "/search?q=golang+routers", http.StatusOK},
+			{"apostrophe in name", "/search?q=O'Brien", http.StatusOK},
+			{"encoded traversal", "/files/%2e%2e/%2e%2e/etc/passwd", http.StatusForbidden},
+			{"null byte", "/download?file=report.pdf%00.exe", http.StatusForbidden},
+			{"sql injection", "/search?q=" + url.QueryEscape("1' OR '1'='1"), http.StatusForbidden},
+			{"union select", "/items?id=" + url.QueryEscape("1 UNION ALL SELECT password FROM users"), http.StatusForbidden},
+			{"xss", "/search?q=" + url.QueryEscape("<script>alert(1)</script>"), http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.target, nil))
+
+				if w.Code != tt.expected {
+					t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+				}
+			})
+		}
+
+		t.Run("form field", func(t *testing.T) {
+			var received string
+			handler := Inspect(InspectOptions{
+				Mode:   InspectBlock,
+				Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r.FormValue("q")
+			}))
+
+			for _, tt := range []struct {
+				value    string
+				expected int
+			}{
+				{"1' OR '1'='1", http.StatusForbidden},
+				{"golang routers", http.StatusOK},
+			} {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(MethodPost, "/search", strings.NewReader("q="+url.QueryEscape(tt.value)))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				handler.ServeHTTP(w, r)
+				if w.Code != tt.expected {
+					t.Errorf("%q: expected status code %d, got %d", tt.value, tt.expected, w.Code)
+				}
+			}
+			if received != "golang routers" {
+				t.Errorf("Expected the handler to still read the parsed form, got %q", received)
+			}
+		})
+	})
+
+	t.Run("Detect Mode Logs Only", func(t *testing.T) {
+		var logs bytes.Buffer
+		handler := Inspect(InspectOptions{
+			Mode:   InspectDetect,
+			Logger: slog.New(slog.NewJSONHandler(&logs, nil)),
+		})(ok)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/search?q="+url.QueryEscape("<script>"), nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+		if !strings.Contains(logs.String(), `"rule":"xss"`) || !strings.Contains(logs.String(), `"mode":"detect"`) {
+			t.Errorf("Expected structured log of the match, got '%s'", logs.String())
+		}
+	})
+
+	t.Run("Form And Header Rules", func(t *testing.T) {
+		handler := Inspect(InspectOptions{
+			Mode: InspectBlock,
+			Rules: []InspectRule{
+				PatternRule("xss", xssRegex, TargetForm),
+				HeaderCountRule(5),
+			},
+			Logger: slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		})(ok)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodPost, "/comments", strings.NewReader("body="+url.QueryEscape(`<img src=x onerror=alert(1)>`)))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected form XSS to be blocked, got %d", w.Code)
+		}
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("title", "hello")
+		mw.WriteField("body", `<script>alert(1)</script>`)
+		mw.Close()
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(MethodPost, "/comments", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected multipart XSS to be blocked, got %d", w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(MethodGet, "/", nil)
+		for i := 0; i < 6; i++ {
+			r.Header.Add("X-Custom-"+strconv.Itoa(i), "v")
+		}
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected oversized header count to be blocked, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Introspection errors
+var (
+	ErrTokenInactive = errors.New("GoFlow: token inactive")
+
+	// ErrIntrospectionUnavailable is returned when the introspection
+	// endpoint fails or its circuit is open; the JWT middleware answers 503
+	ErrIntrospectionUnavailable = errors.New("GoFlow: token introspection unavailable")
+)
+
+// IntrospectionOptions configures RFC 7662 token introspection
+type IntrospectionOptions struct {
+	// URL is the authorization server's introspection endpoint
+	URL string
+
+	// ClientID and ClientSecret authenticate to the endpoint with Basic auth
+	ClientID     string
+	ClientSecret string
+
+	// CacheTTL is how long responses are cached through Cached, keyed by a
+	// hash of the token. A revoked token may be accepted for up to twice
+	// as long. Defaults to 30 seconds.
+	CacheTTL time.Duration
+
+	// FailureThreshold consecutive endpoint failures open the circuit for
+	// Cooldown, failing requests fast instead of waiting on a struggling
+	// server. Default to 5 and 30 seconds.
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	// Client calls the endpoint. Defaults to a client with a 5s timeout.
+	Client *http.Client
+}
+
+// Introspector validates opaque access tokens by asking the authorization
+// server that issued them
+type Introspector struct {
+	opts IntrospectionOptions
+
+	failures  atomic.Int64
+	openUntil atomic.Int64
+}
+
+// NewIntrospector creates an Introspector from opts
+func NewIntrospector(opts IntrospectionOptions) *Introspector {
+	if opts.URL == "" {
+		panic("GoFlow: IntrospectionOptions requires a URL")
+	}
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = 30 * time.Second
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &Introspector{opts: opts}
+}
+
+// Introspect returns the claims of an active token, ErrTokenInactive for
+// tokens the server doesn't accept and ErrIntrospectionUnavailable when the
+// server can't be asked. The claims' expiry isn't checked here.
+func (in *Introspector) Introspect(ctx context.Context, token string) (map[string]interface{}, error) {
+	sum := sha256.Sum256([]byte(token))
+	key := "GoFlow/introspect:" + in.opts.URL + ":" + hex.EncodeToString(sum[:])
+	claims, err := Cached(ctx, key, in.opts.CacheTTL, func(ctx context.Context) (map[string]interface{}, error) {
+		return in.fetch(ctx, token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, ErrTokenInactive
+	}
+	return claims, nil
+}
+
+func (in *Introspector) fetch(ctx context.Context, token string) (map[string]interface{}, error) {
+	if time.Now().UnixNano() < in.openUntil.Load() {
+		return nil, fmt.Errorf("%w: circuit open", ErrIntrospectionUnavailable)
+	}
+
+	claims, err := in.post(ctx, token)
+	if err != nil {
+		if in.failures.Add(1) >= int64(in.opts.FailureThreshold) {
+			in.openUntil.Store(time.Now().Add(in.opts.Cooldown).UnixNano())
+			in.failures.Store(0)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrIntrospectionUnavailable, err)
+	}
+	in.failures.Store(0)
+	return claims, nil
+}
+
+func (in *Introspector) post(ctx context.Context, token string) (map[string]interface{}, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, MethodPost, in.opts.URL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if in.opts.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(in.opts.ClientID), url.QueryEscape(in.opts.ClientSecret))
+	}
+
+	resp, err := in.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var claims map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,102 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestIntrospection(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "api" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PostFormValue("token") {
+		case "opaque-good":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"active": true, "sub": "user-42", "scope": "orders:read", "aud": "orders-api",
+				"exp": time.Now().Add(time.Hour).Unix(),
+			})
+		case "opaque-expired":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"active": true, "sub": "user-42", "exp": time.Now().Add(-time.Hour).Unix(),
+			})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+		}
+	}))
+	defer server.Close()
+	defer PurgeCache("GoFlow/introspect:")
+
+	var subject string
+	var scopes []string
+	handler := JWT(JWTOptions{
+		Introspection: &IntrospectionOptions{
+			URL:              server.URL,
+			ClientID:         "api",
+			ClientSecret:     "secret",
+			FailureThreshold: 2,
+			Cooldown:         time.Hour,
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := GetPrincipal(r.Context())
+		subject, scopes = p.Subject, p.Scopes
+	}))
+
+	serve := func(token string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Active Token", func(t *testing.T) {
+		if code := serve("opaque-good"); code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+		}
+		if subject != "user-42" || len(scopes) != 1 || scopes[0] != "orders:read" {
+			t.Errorf("Expected user-42 with orders:read, got %q %v", subject, scopes)
+		}
+	})
+
+	t.Run("Cached Responses", func(t *testing.T) {
+		before := calls.Load()
+		serve("opaque-good")
+		if calls.Load() != before {
+			t.Errorf("Expected the cached response reused, got %d more calls", calls.Load()-before)
+		}
+	})
+
+	t.Run("Inactive And Expired Tokens", func(t *testing.T) {
+		for _, token := range []string{"opaque-revoked", "opaque-expired"} {
+			if code := serve(token); code != http.StatusUnauthorized {
+				t.Errorf("%s: expected status code %d, got %d", token, http.StatusUnauthorized, code)
+			}
+		}
+	})
+
+	t.Run("Circuit Breaking", func(t *testing.T) {
+		failing.Store(true)
+		for _, token := range []string{"opaque-a", "opaque-b"} {
+			if code := serve(token); code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, code)
+			}
+		}
+		before := calls.Load()
+		if code := serve("opaque-c"); code != http.StatusServiceUnavailable || calls.Load() != before {
+			t.Errorf("Expected the open circuit to fail fast, got %d after %d calls", code, calls.Load()-before)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package GoFlow
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// IPFilterOptions configures the IPFilter middleware
+type IPFilterOptions struct {
+	// List holds the allow and deny rules. It can be updated at runtime.
+	List *IPList
+
+	// Trusted proxies whose X-Forwarded-For header is honored
+	TrustedProxies []string
+
+	// Rejected handles requests from filtered addresses. Defaults to 403.
+	Rejected http.Handler
+}
+
+// IPList is a hot-reloadable set of allow and deny networks
+type IPList struct {
+	rules atomic.Pointer[ipRules]
+}
+
+type ipRules struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// NewIPList creates an IPList from CIDR ranges or single addresses
+func NewIPList(allow, deny []string) (*IPList, error) {
+	l := &IPList{}
+	if err := l.Update(allow, deny); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update atomically replaces the allow and deny rules
+func (l *IPList) Update(allow, deny []string) error {
+	allowNets, err := parseNetworks(allow)
+	if err != nil {
+		return err
+	}
+	denyNets, err := parseNetworks(deny)
+	if err != nil {
+		return err
+	}
+	l.rules.Store(&ipRules{allow: allowNets, deny: denyNets})
+	return nil
+}
+
+// Allowed reports whether ip passes the filter. Deny rules take precedence,
+// and an empty allow list admits every address that isn't denied.
+func (l *IPList) Allowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	rules := l.rules.Load()
+	for _, n := range rules.deny {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(rules.allow) == 0 {
+		return true
+	}
+	for _, n := range rules.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IPFilter rejects requests whose client address is not permitted by the list.
+// Apply it inside a Group to scope it to a subset of routes.
+func IPFilter(opts IPFilterOptions) func(http.Handler) http.Handler {
+	if opts.List == nil {
+		panic("GoFlow: IPFilter requires a List")
+	}
+
+	rejected := opts.Rejected
+	if rejected == nil {
+		rejected = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.List.Allowed(net.ParseIP(getRealIP(r, trustedProxies))) {
+				rejected.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("GoFlow: invalid IP address %q", entry)
+			}
+			bits := 128
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("GoFlow: invalid CIDR %q: %w", entry, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
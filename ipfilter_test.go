@@ -0,0 +1,135 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("Allow And Deny Lists", func(t *testing.T) {
+		list, err := NewIPList([]string{"10.0.0.0/8", "192.168.1.10"}, []string{"10.0.0.5"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := IPFilter(IPFilterOptions{List: list})(ok)
+
+		tests := []struct {
+			addr     string
+			expected int
+		}{
+			{"10.1.2.3:1234", http.StatusOK},
+			{"192.168.1.10:1234", http.StatusOK},
+			{"10.0.0.5:1234", http.StatusForbidden},
+			{"172.16.0.1:1234", http.StatusForbidden},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.RemoteAddr = tt.addr
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.addr, tt.expected, w.Code)
+			}
+		}
+	})
+
+	t.Run("Behind Proxies", func(t *testing.T) {
+		list, err := NewIPList([]string{"192.168.1.10", "2001:db8::1"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := IPFilter(IPFilterOptions{List: list, TrustedProxies: []string{"10.0.0.0/8"}})(ok)
+
+		tests := []struct {
+			name, addr, forwardedFor string
+			expected                 int
+		}{
+			{"forwarded client", "10.0.0.1:1234", "192.168.1.10", http.StatusOK},
+			{"through two proxies", "10.0.0.1:1234", "192.168.1.10, 10.2.0.1", http.StatusOK},
+			{"prepended address", "10.0.0.1:1234", "192.168.1.10, 172.16.0.1", http.StatusForbidden},
+			{"untrusted peer", "172.16.0.1:1234", "192.168.1.10", http.StatusForbidden},
+			{"ipv6 client", "10.0.0.1:1234", "2001:db8::1", http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.RemoteAddr = tt.addr
+			r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.name, tt.expected, w.Code)
+			}
+		}
+	})
+
+	t.Run("Hot Reload", func(t *testing.T) {
+		list, _ := NewIPList(nil, nil)
+		handler := IPFilter(IPFilterOptions{List: list})(ok)
+
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.RemoteAddr = "203.0.113.7:80"
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+		}
+
+		if err := list.Update(nil, []string{"203.0.113.0/24"}); err != nil {
+			t.Fatal(err)
+		}
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+
+		if err := list.Update([]string{"not-an-ip"}, nil); err == nil {
+			t.Error("Expected error for invalid entry")
+		}
+	})
+
+	t.Run("Group Scoping", func(t *testing.T) {
+		mux := New()
+		list, _ := NewIPList([]string{"10.8.0.0/16"}, nil)
+
+		mux.Handle("/public", ok, MethodGet)
+		mux.Group(func(m *Mux) {
+			m.Use(IPFilter(IPFilterOptions{
+				List: list,
+				Rejected: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+				}),
+			}))
+			m.Handle("/admin", ok, MethodGet)
+		})
+
+		tests := []struct {
+			path     string
+			addr     string
+			expected int
+		}{
+			{"/public", "198.51.100.1:80", http.StatusOK},
+			{"/admin", "198.51.100.1:80", http.StatusNotFound},
+			{"/admin", "10.8.3.4:80", http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, tt.path, nil)
+			r.RemoteAddr = tt.addr
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s from %s: expected status code %d, got %d", tt.path, tt.addr, tt.expected, w.Code)
+			}
+		}
+	})
+}
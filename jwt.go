@@ -0,0 +1,498 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthRequirement controls whether a route demands credentials
+type AuthRequirement int
+
+const (
+	// AuthRequired rejects requests without valid credentials
+	AuthRequired AuthRequirement = iota
+	// AuthOptional validates credentials when present but admits anonymous requests
+	AuthOptional
+)
+
+type authRequirementKey struct{}
+
+// AuthLevel overrides the authentication requirement of auth middleware for this route
+func (rt *Route) AuthLevel(level AuthRequirement) *Route {
+	return rt.Set(authRequirementKey{}, level)
+}
+
+func authRequirement(r *http.Request, fallback AuthRequirement) AuthRequirement {
+	if rt := CurrentRoute(r.Context()); rt != nil {
+		if level, ok := rt.Value(authRequirementKey{}).(AuthRequirement); ok {
+			return level
+		}
+	}
+	return fallback
+}
+
+// JWT errors
+var (
+	ErrTokenMalformed   = errors.New("GoFlow: malformed token")
+	ErrTokenSignature   = errors.New("GoFlow: invalid token signature")
+	ErrTokenExpired     = errors.New("GoFlow: token expired")
+	ErrTokenNotYetValid = errors.New("GoFlow: token not yet valid")
+	ErrTokenClaims      = errors.New("GoFlow: invalid token claims")
+	ErrUnknownKey       = errors.New("GoFlow: unknown signing key")
+)
+
+// JWTOptions configures JWT bearer token validation
+type JWTOptions struct {
+	// Secret verifies HS256 tokens
+	Secret []byte
+
+	// Keys maps key IDs to *rsa.PublicKey, *ecdsa.PublicKey or []byte secrets.
+	// The empty key ID matches tokens without a "kid" header.
+	Keys map[string]interface{}
+
+	// JWKSURL fetches verification keys from a JSON Web Key Set endpoint
+	JWKSURL string
+
+	// JWKSRefresh is how long fetched keys are cached. Defaults to 1 hour.
+	JWKSRefresh time.Duration
+
+	// Algorithms restricts accepted "alg" values. Defaults to HS256, RS256 and ES256.
+	Algorithms []string
+
+	// Expected "iss" and "aud" claims; empty disables the check
+	Issuer   string
+	Audience string
+
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+
+	// Requirement is the default for routes without an AuthLevel override
+	Requirement AuthRequirement
+
+	// Client is used to fetch the JWKS. Defaults to a client with a 10s timeout.
+	Client *http.Client
+
+	// Introspection, if set, validates opaque tokens, those that aren't
+	// JWTs, with the authorization server. Without Secret, Keys or JWKSURL
+	// every token is introspected. Issuer, Audience and Leeway apply to the
+	// introspected claims too.
+	Introspection *IntrospectionOptions
+}
+
+// JWTVerifier validates signed JSON Web Tokens
+type JWTVerifier struct {
+	opts         JWTOptions
+	algorithms   map[string]bool
+	introspector *Introspector
+
+	mu        sync.RWMutex
+	jwks      map[string]interface{}
+	fetchedAt time.Time
+	fetching  *jwksFetch
+}
+
+// jwksFetch is a key set fetch in progress that concurrent refreshes wait on
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWTVerifier creates a verifier from opts
+func NewJWTVerifier(opts JWTOptions) *JWTVerifier {
+	if opts.JWKSRefresh == 0 {
+		opts.JWKSRefresh = time.Hour
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(opts.Algorithms) == 0 {
+		opts.Algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+
+	algorithms := make(map[string]bool, len(opts.Algorithms))
+	for _, alg := range opts.Algorithms {
+		algorithms[alg] = true
+	}
+
+	v := &JWTVerifier{opts: opts, algorithms: algorithms}
+	if opts.Introspection != nil {
+		v.introspector = NewIntrospector(*opts.Introspection)
+	}
+	return v
+}
+
+// Verify checks the token's signature and registered claims and returns its claims
+func (v *JWTVerifier) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if !v.algorithms[header.Alg] {
+		return nil, fmt.Errorf("%w: algorithm %q not allowed", ErrTokenSignature, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	key, err := v.key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenMalformed
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerifyContext is Verify, introspecting opaque tokens when
+// JWTOptions.Introspection is set
+func (v *JWTVerifier) VerifyContext(ctx context.Context, token string) (map[string]interface{}, error) {
+	local := v.opts.Secret != nil || len(v.opts.Keys) > 0 || v.opts.JWKSURL != ""
+	if v.introspector == nil || (local && strings.Count(token, ".") == 2) {
+		return v.Verify(token)
+	}
+	claims, err := v.introspector.Introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *JWTVerifier) validateClaims(claims map[string]interface{}) error {
+	now := time.Now()
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if now.After(time.Unix(int64(exp), 0).Add(v.opts.Leeway)) {
+			return ErrTokenExpired
+		}
+	}
+	if nbf, ok := claims["nbf"].(float64); ok {
+		if now.Add(v.opts.Leeway).Before(time.Unix(int64(nbf), 0)) {
+			return ErrTokenNotYetValid
+		}
+	}
+	if v.opts.Issuer != "" && claims["iss"] != v.opts.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrTokenClaims)
+	}
+	if v.opts.Audience != "" && !contains(stringList(claims["aud"]), v.opts.Audience) {
+		return fmt.Errorf("%w: unexpected audience", ErrTokenClaims)
+	}
+	return nil
+}
+
+func (v *JWTVerifier) key(kid, alg string) (interface{}, error) {
+	if alg == "HS256" && v.opts.Secret != nil {
+		return v.opts.Secret, nil
+	}
+	if key, ok := v.opts.Keys[kid]; ok {
+		return key, nil
+	}
+	if v.opts.JWKSURL == "" {
+		return nil, ErrUnknownKey
+	}
+
+	v.mu.RLock()
+	key, ok := v.jwks[kid]
+	stale := time.Since(v.fetchedAt) > v.opts.JWKSRefresh
+	// Refetch at most once a minute when an unknown kid suggests a rotation
+	rotated := !ok && time.Since(v.fetchedAt) > time.Minute
+	v.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || rotated {
+		if err := v.refreshJWKS(); err != nil && !ok {
+			return nil, err
+		}
+		v.mu.RLock()
+		key, ok = v.jwks[kid]
+		v.mu.RUnlock()
+	}
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+// refreshJWKS refetches the key set. Concurrent callers share a single fetch,
+// and the lock is held only to swap the keys, so verifications with cached
+// keys aren't blocked by a slow endpoint.
+func (v *JWTVerifier) refreshJWKS() error {
+	v.mu.Lock()
+	// Another goroutine may have refreshed moments ago
+	if time.Since(v.fetchedAt) < time.Second {
+		v.mu.Unlock()
+		return nil
+	}
+	if fetch := v.fetching; fetch != nil {
+		v.mu.Unlock()
+		<-fetch.done
+		return fetch.err
+	}
+	fetch := &jwksFetch{done: make(chan struct{})}
+	v.fetching = fetch
+	v.mu.Unlock()
+
+	keys, err := v.fetchJWKS()
+
+	v.mu.Lock()
+	if err == nil {
+		v.jwks = keys
+		v.fetchedAt = time.Now()
+	}
+	v.fetching = nil
+	v.mu.Unlock()
+
+	fetch.err = err
+	close(fetch.done)
+	return err
+}
+
+func (v *JWTVerifier) fetchJWKS() (map[string]interface{}, error) {
+	resp, err := v.opts.Client.Get(v.opts.JWKSURL)
+	if err != nil {
+		return nil, fmt.Errorf("GoFlow: fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GoFlow: fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+	return ParseJWKS(resp.Body)
+}
+
+// JWT authenticates requests carrying an "Authorization: Bearer" token. The
+// token's claims are stored in the context and available via Claims and GetPrincipal.
+// Opaque tokens are accepted when JWTOptions.Introspection is set.
+func JWT(opts JWTOptions) func(http.Handler) http.Handler {
+	verifier := NewJWTVerifier(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requirement := authRequirement(r, verifier.opts.Requirement)
+
+			token, ok := bearerToken(r)
+			if !ok {
+				if requirement == AuthOptional {
+					next.ServeHTTP(w, r)
+					return
+				}
+				authFailed(r, "jwt", "missing", "")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := verifier.VerifyContext(r.Context(), token)
+			if errors.Is(err, ErrIntrospectionUnavailable) {
+				debugNote(r.Context(), "jwt: %v", err)
+				http.Error(w, StatusText(r.Context(), http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			if err != nil {
+				reason := "invalid"
+				if errors.Is(err, ErrTokenExpired) {
+					reason = "expired"
+				}
+				authFailed(r, "jwt", reason, "")
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			p := principalFromClaims(claims, "jwt")
+			authSucceeded(r, "jwt", p.Subject)
+			next.ServeHTTP(w, WithPrincipal(r, p))
+		})
+	}
+}
+
+// Claims returns the verified token claims stored in the context
+func Claims(ctx context.Context) map[string]interface{} {
+	if p := GetPrincipal(ctx); p != nil {
+		return p.Claims
+	}
+	return nil
+}
+
+func principalFromClaims(claims map[string]interface{}, scheme string) *Principal {
+	p := &Principal{Scheme: scheme, Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = stringList(claims["scp"])
+	}
+	p.Roles = stringList(claims["roles"])
+	return p
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:]), true
+	}
+	return "", false
+}
+
+// stringList converts a claim that is either a string or an array of strings
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func verifySignature(alg string, key interface{}, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnknownKey
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrTokenSignature
+		}
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnknownKey
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) != nil {
+			return ErrTokenSignature
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return ErrTokenSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenSignature
+	}
+	return nil
+}
+
+// ParseJWKS decodes a JSON Web Key Set into a map of key IDs to public keys.
+// RSA, P-256 EC and symmetric ("oct") keys are supported; others are skipped.
+func ParseJWKS(r io.Reader) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("GoFlow: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				continue
+			}
+			keys[k.Kid] = secret
+		}
+	}
+	return keys, nil
+}
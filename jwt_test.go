@@ -0,0 +1,217 @@
+package GoFlow
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func signHS256(t *testing.T, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("test-secret")
+	var capturedSub string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedSub = User(r.Context())
+	})
+
+	t.Run("HS256 Claims Validation", func(t *testing.T) {
+		protected := JWT(JWTOptions{Secret: secret, Issuer: "goflow", Audience: "api"})(handler)
+		future := float64(time.Now().Add(time.Hour).Unix())
+		past := float64(time.Now().Add(-time.Hour).Unix())
+
+		tests := []struct {
+			name     string
+			token    string
+			expected int
+		}{
+			{"valid", signHS256(t, secret, map[string]interface{}{"sub": "u1", "iss": "goflow", "aud": "api", "exp": future}), http.StatusOK},
+			{"audience list", signHS256(t, secret, map[string]interface{}{"sub": "u1", "iss": "goflow", "aud": []string{"web", "api"}}), http.StatusOK},
+			{"expired", signHS256(t, secret, map[string]interface{}{"iss": "goflow", "aud": "api", "exp": past}), http.StatusUnauthorized},
+			{"wrong issuer", signHS256(t, secret, map[string]interface{}{"iss": "other", "aud": "api"}), http.StatusUnauthorized},
+			{"wrong secret", signHS256(t, []byte("nope"), map[string]interface{}{"iss": "goflow", "aud": "api"}), http.StatusUnauthorized},
+			{"malformed", "abc.def", http.StatusUnauthorized},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				capturedSub = ""
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(MethodGet, "/", nil)
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+				protected.ServeHTTP(w, r)
+
+				if w.Code != tt.expected {
+					t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+				}
+				if tt.expected == http.StatusOK && capturedSub != "u1" {
+					t.Errorf("Expected subject 'u1', got '%s'", capturedSub)
+				}
+			})
+		}
+	})
+
+	t.Run("JWKS Rotation", func(t *testing.T) {
+		key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		key2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		current := map[string]*ecdsa.PrivateKey{"k1": key1}
+		fetches := 0
+
+		jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches++
+			var keys []map[string]string
+			for kid, k := range current {
+				keys = append(keys, map[string]string{
+					"kty": "EC", "crv": "P-256", "kid": kid,
+					"x": base64.RawURLEncoding.EncodeToString(k.X.Bytes()),
+					"y": base64.RawURLEncoding.EncodeToString(k.Y.Bytes()),
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+		}))
+		defer jwks.Close()
+
+		verifier := NewJWTVerifier(JWTOptions{JWKSURL: jwks.URL})
+
+		if _, err := verifier.Verify(signES256(t, key1, "k1", map[string]interface{}{"sub": "a"})); err != nil {
+			t.Fatalf("Expected token to verify, got %v", err)
+		}
+		if _, err := verifier.Verify(signES256(t, key1, "k1", map[string]interface{}{"sub": "a"})); err != nil {
+			t.Fatalf("Expected cached key to verify, got %v", err)
+		}
+		if fetches != 1 {
+			t.Errorf("Expected 1 JWKS fetch, got %d", fetches)
+		}
+
+		// A new kid triggers a refetch once the rotation interval has passed
+		current["k2"] = key2
+		verifier.fetchedAt = time.Now().Add(-2 * time.Minute)
+		if _, err := verifier.Verify(signES256(t, key2, "k2", map[string]interface{}{"sub": "b"})); err != nil {
+			t.Fatalf("Expected rotated key to verify, got %v", err)
+		}
+		if fetches != 2 {
+			t.Errorf("Expected 2 JWKS fetches, got %d", fetches)
+		}
+	})
+
+	t.Run("Concurrent JWKS Refresh", func(t *testing.T) {
+		key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		key2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		var fetches atomic.Int32
+		started, release := make(chan struct{}, 1), make(chan struct{})
+
+		jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			started <- struct{}{}
+			<-release
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+				"kty": "EC", "crv": "P-256", "kid": "k2",
+				"x": base64.RawURLEncoding.EncodeToString(key2.X.Bytes()),
+				"y": base64.RawURLEncoding.EncodeToString(key2.Y.Bytes()),
+			}}})
+		}))
+		defer jwks.Close()
+
+		verifier := NewJWTVerifier(JWTOptions{JWKSURL: jwks.URL})
+		verifier.jwks = map[string]interface{}{"k1": &key1.PublicKey}
+		verifier.fetchedAt = time.Now().Add(-2 * time.Minute)
+
+		// Unknown kids trigger a refresh that the endpoint holds open
+		errs := make(chan error, 5)
+		for i := 0; i < 5; i++ {
+			go func() {
+				_, err := verifier.Verify(signES256(t, key2, "k2", map[string]interface{}{"sub": "b"}))
+				errs <- err
+			}()
+		}
+		<-started
+
+		verified := make(chan error, 1)
+		go func() {
+			_, err := verifier.Verify(signES256(t, key1, "k1", map[string]interface{}{"sub": "a"}))
+			verified <- err
+		}()
+		select {
+		case err := <-verified:
+			if err != nil {
+				t.Errorf("Expected cached key to verify, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("Expected cached key to verify during a JWKS fetch")
+		}
+
+		close(release)
+		for i := 0; i < 5; i++ {
+			if err := <-errs; err != nil {
+				t.Errorf("Expected rotated key to verify, got %v", err)
+			}
+		}
+		if n := fetches.Load(); n != 1 {
+			t.Errorf("Expected 1 JWKS fetch, got %d", n)
+		}
+	})
+
+	t.Run("Per-Route Requirement", func(t *testing.T) {
+		mux := New()
+		mux.Use(JWT(JWTOptions{Secret: secret}))
+		mux.Handle("/private", handler, MethodGet)
+		mux.Handle("/feed", handler, MethodGet).AuthLevel(AuthOptional)
+
+		tests := []struct {
+			path     string
+			token    string
+			expected int
+		}{
+			{"/private", "", http.StatusUnauthorized},
+			{"/feed", "", http.StatusOK},
+			{"/feed", "garbage.token.value", http.StatusUnauthorized},
+			{"/feed", signHS256(t, secret, map[string]interface{}{"sub": "u2"}), http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, tt.path, nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.path, tt.expected, w.Code)
+			}
+		}
+	})
+}
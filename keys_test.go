@@ -0,0 +1,89 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestKeyring(t *testing.T) {
+	t.Run("Rotation Keeps Previous Signatures Valid", func(t *testing.T) {
+		keys := NewKeyring([]byte("old"))
+		signed := keys.Sign("session-data")
+
+		keys.Rotate([]byte("new"), 1)
+		if value, ok := keys.Verify(signed); !ok || value != "session-data" {
+			t.Errorf("Expected value signed with previous key to verify, got '%s' %v", value, ok)
+		}
+		if fresh := keys.Sign("session-data"); fresh == signed {
+			t.Error("Expected new signatures to use the current key")
+		}
+
+		keys.Rotate([]byte("newer"), 1)
+		if _, ok := keys.Verify(signed); ok {
+			t.Error("Expected key older than the retention window to be rejected")
+		}
+		if _, ok := keys.Verify("session-data.tampered"); ok {
+			t.Error("Expected tampered value to be rejected")
+		}
+	})
+
+	t.Run("Signed URLs", func(t *testing.T) {
+		keys := NewKeyring([]byte("k1"))
+		handler := RequireSignedURL(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		signed, err := keys.SignURL("/downloads/report.pdf?user=42", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expired, _ := keys.SignURL("/downloads/report.pdf?user=42", time.Now().Add(-time.Minute))
+		keys.Rotate([]byte("k2"), 1)
+
+		u, _ := url.Parse(signed)
+		tampered := u.Query()
+		tampered.Set("user", "43")
+
+		tests := []struct {
+			name     string
+			target   string
+			expected int
+		}{
+			{"valid after rotation", signed, http.StatusOK},
+			{"tampered", u.Path + "?" + tampered.Encode(), http.StatusForbidden},
+			{"expired", expired, http.StatusForbidden},
+			{"unsigned", "/downloads/report.pdf", http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.target, nil))
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.name, tt.expected, w.Code)
+			}
+		}
+	})
+
+	t.Run("CSRF Previous Keys", func(t *testing.T) {
+		handler := Security(SecurityOptions{
+			CSRFEnabled:      true,
+			CSRFKey:          "current",
+			CSRFPreviousKeys: []string{"previous"},
+			RateLimit:        RateLimitOptions{Requests: 100, Duration: time.Minute},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for token, expected := range map[string]int{
+			"current":  http.StatusOK,
+			"previous": http.StatusOK,
+			"retired":  http.StatusForbidden,
+		} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodPost, "/", nil)
+			r.Header.Set("X-CSRF-Token", token)
+			handler.ServeHTTP(w, r)
+			if w.Code != expected {
+				t.Errorf("%s: expected status code %d, got %d", token, expected, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package GoFlow
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RequestInfo describes a completed request passed to OnRequestDone hooks
+// and LoggerOptions.OnSlow
+type RequestInfo struct {
+	Method string
+	Path   string
+	// Route is the matched route's pattern, empty when no route matched,
+	// and Params its parameters
+	Route    string
+	Params   map[string]string
+	Status   int
+	Size     int64
+	Duration time.Duration
+	Request  *http.Request
+	// Intercepted is set when an OnBeforeRoute hook answered the request
+	Intercepted bool
+}
+
+// RouteMatch is the route a request is about to be served by, passed to
+// OnBeforeRoute hooks
+type RouteMatch struct {
+	// RouteInfo is as returned by Match: Route is nil when no route
+	// handles the request's method, and Methods empty when the path
+	// matched nothing
+	RouteInfo
+	Params map[string]string
+}
+
+// muxHooks is shared by a mux and its groups
+type muxHooks struct {
+	mu     sync.Mutex
+	done   atomic.Pointer[[]func(RequestInfo)]
+	before atomic.Pointer[[]func(http.ResponseWriter, *http.Request, RouteMatch) bool]
+	auth   atomic.Pointer[[]func(AuthEvent)]
+}
+
+// OnBeforeRoute registers fn to run for every request before the mux's
+// middleware and handlers, with the route it matched, e.g. for a
+// maintenance gate or rejecting hosts and paths across all routes. A hook
+// that answers the request returns true, which skips the remaining hooks
+// and the route; OnRequestDone hooks still run. Hooks run in registration
+// order.
+func (m *Mux) OnBeforeRoute(fn func(w http.ResponseWriter, r *http.Request, match RouteMatch) bool) {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+	var before []func(http.ResponseWriter, *http.Request, RouteMatch) bool
+	if current := m.hooks.before.Load(); current != nil {
+		before = append(before, *current...)
+	}
+	before = append(before, fn)
+	m.hooks.before.Store(&before)
+}
+
+// intercept runs the OnBeforeRoute hooks and reports whether one of them
+// answered the request
+func (m *Mux) intercept(w http.ResponseWriter, r *http.Request) bool {
+	if m.hooks == nil {
+		return false
+	}
+	before := m.hooks.before.Load()
+	if before == nil {
+		return false
+	}
+	var match RouteMatch
+	match.RouteInfo, match.Params, _ = m.Match(r.Method, r.URL.Path)
+	for _, fn := range *before {
+		if fn(w, r, match) {
+			return true
+		}
+	}
+	return false
+}
+
+// OnRequestDone registers fn to run after every request served by the mux,
+// including 404 and 405 responses, e.g. for custom accounting. Hooks run in
+// registration order on the request's goroutine.
+func (m *Mux) OnRequestDone(fn func(info RequestInfo)) {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+	var done []func(RequestInfo)
+	if current := m.hooks.done.Load(); current != nil {
+		done = append(done, *current...)
+	}
+	done = append(done, fn)
+	m.hooks.done.Store(&done)
+}
+
+func (m *Mux) serveWithHooks(w http.ResponseWriter, r *http.Request, done []func(RequestInfo)) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w}
+	ww := wrapWriter(sw)
+	holder := &logHolder{}
+	intercepted := m.intercept(ww, r)
+	if !intercepted {
+		m.serve(ww, r.WithContext(context.WithValue(r.Context(), logHolderKey{}, holder)))
+	}
+
+	info := RequestInfo{
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Status:      sw.status,
+		Size:        sw.size,
+		Duration:    time.Since(start),
+		Request:     r,
+		Intercepted: intercepted,
+	}
+	if info.Status == 0 {
+		info.Status = http.StatusOK
+	}
+	if holder.writeTimedOut {
+		info.Status = StatusWriteTimeout
+	}
+	if holder.route != nil {
+		info.Route, info.Params = holder.route.pattern, holder.params
+	} else if rt := m.route(r.Method, r.URL.Path); rt != nil {
+		info.Route = rt.pattern
+	}
+	for _, fn := range done {
+		fn(info)
+	}
+}
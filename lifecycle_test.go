@@ -0,0 +1,138 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLifecycleHooks(t *testing.T) {
+	t.Run("Request Done", func(t *testing.T) {
+		mux := New()
+		var infos []RequestInfo
+		mux.Group(func(m *Mux) {
+			m.OnRequestDone(func(info RequestInfo) {
+				infos = append(infos, info)
+			})
+			m.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+			}), MethodPost)
+		})
+
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodPost, "/users/42", nil))
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/missing", nil))
+
+		if len(infos) != 2 {
+			t.Fatalf("Expected 2 completed requests, got %d", len(infos))
+		}
+		if got := infos[0]; got.Method != MethodPost || got.Route != "/users/:id" || got.Status != http.StatusCreated || got.Size != 7 {
+			t.Errorf("Expected POST /users/:id 201 7 bytes, got %s %s %d %d bytes", got.Method, got.Route, got.Status, got.Size)
+		}
+		if got := infos[0].Params["id"]; got != "42" {
+			t.Errorf("Expected param id 42, got '%s'", got)
+		}
+		if got := infos[1]; got.Route != "" || got.Status != http.StatusNotFound || got.Path != "/missing" {
+			t.Errorf("Expected unmatched 404 for /missing, got '%s' %d %s", got.Route, got.Status, got.Path)
+		}
+	})
+
+	t.Run("Before Route", func(t *testing.T) {
+		mux := New()
+		maintenance := true
+		var matches []RouteMatch
+		var infos []RequestInfo
+		mux.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Middleware", "ran")
+				next.ServeHTTP(w, r)
+			})
+		})
+		mux.Group(func(m *Mux) {
+			m.OnBeforeRoute(func(w http.ResponseWriter, r *http.Request, match RouteMatch) bool {
+				matches = append(matches, match)
+				return false
+			})
+		})
+		mux.OnBeforeRoute(func(w http.ResponseWriter, r *http.Request, match RouteMatch) bool {
+			if maintenance && match.Route != nil && match.Route.Value("public") == nil {
+				http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+				return true
+			}
+			return false
+		})
+		mux.OnRequestDone(func(info RequestInfo) {
+			infos = append(infos, info)
+		})
+		noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		mux.Handle("/users/:id", noop, MethodGet)
+		mux.Handle("/status", noop, MethodGet).Set("public", true)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/users/7", nil))
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Middleware") != "" {
+			t.Errorf("Expected 503 before any middleware ran, got %d %v", w.Code, w.Header())
+		}
+		if m := matches[0]; m.Pattern != "/users/:id" || m.Params["id"] != "7" {
+			t.Errorf("Expected the match passed to hooks, got %+v", m)
+		}
+		if !infos[0].Intercepted || infos[0].Status != http.StatusServiceUnavailable {
+			t.Errorf("Expected the intercepted request reported as done, got %+v", infos[0])
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/status", nil))
+		if w.Code != http.StatusOK || w.Header().Get("X-Middleware") != "ran" || infos[1].Intercepted {
+			t.Errorf("Expected the public route served, got %d", w.Code)
+		}
+
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodPost, "/status", nil))
+		if m := matches[2]; m.Route != nil || !equalSlices(m.Methods, []string{MethodGet, MethodHead}) {
+			t.Errorf("Expected the allowed methods without a route, got %+v", m)
+		}
+	})
+
+	t.Run("Start Hooks", func(t *testing.T) {
+		addr := freeAddr(t)
+		s := NewServer(addr, http.NotFoundHandler())
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		var order []string
+		s.OnStart(func(ctx context.Context) error {
+			order = append(order, "warm cache")
+			return nil
+		})
+		s.OnStart(func(ctx context.Context) error {
+			order = append(order, "register")
+			return errors.New("discovery unavailable")
+		})
+		s.OnStart(func(ctx context.Context) error {
+			order = append(order, "never")
+			return nil
+		})
+
+		if err := s.Run(); err == nil || err.Error() != "discovery unavailable" {
+			t.Errorf("Expected start hook error, got %v", err)
+		}
+		if !equalSlices(order, []string{"warm cache", "register"}) {
+			t.Errorf("Expected hooks to stop at the failure, got %v", order)
+		}
+
+		// The listener is released again
+		s = NewServer(addr, http.NotFoundHandler())
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		started := make(chan struct{})
+		s.OnStart(func(ctx context.Context) error {
+			close(started)
+			return nil
+		})
+		go s.Run()
+		defer s.Close()
+		<-started
+		waitForServer(t, addr)
+	})
+}
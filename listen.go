@@ -0,0 +1,106 @@
+package GoFlow
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// listen opens a TCP address, a "unix:" socket path or a "systemd:" socket
+func (s *Server) listen(addr string) (net.Listener, error) {
+	if name, ok := strings.CutPrefix(addr, "systemd:"); ok {
+		return systemdListener(name)
+	}
+
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		lc := net.ListenConfig{}
+		if s.ReusePort {
+			lc.Control = reusePort
+		}
+		return lc.Listen(context.Background(), "tcp", addr)
+	}
+
+	// Remove a socket left behind by a previous run
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if s.SocketMode != 0 {
+		if err := os.Chmod(path, s.SocketMode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+var systemd struct {
+	once      sync.Once
+	listeners map[string]net.Listener
+	names     []string
+	err       error
+}
+
+// systemdListener returns the inherited socket called name, or the first
+// one when name is empty. Each socket can be taken once.
+func systemdListener(name string) (net.Listener, error) {
+	systemd.once.Do(func() {
+		systemd.listeners, systemd.names, systemd.err = inheritListeners()
+	})
+	if systemd.err != nil {
+		return nil, systemd.err
+	}
+	if name == "" && len(systemd.names) > 0 {
+		name = systemd.names[0]
+	}
+
+	ln, ok := systemd.listeners[name]
+	if !ok {
+		return nil, fmt.Errorf("GoFlow: no systemd socket named %q", name)
+	}
+	delete(systemd.listeners, name)
+	return ln, nil
+}
+
+// inheritListeners implements the sd_listen_fds protocol: LISTEN_FDS sockets
+// starting at file descriptor 3, named by LISTEN_FDNAMES
+func inheritListeners() (map[string]net.Listener, []string, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil, fmt.Errorf("GoFlow: no sockets passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil, fmt.Errorf("GoFlow: no sockets passed by systemd")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make(map[string]net.Listener, n)
+	order := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		name := strconv.Itoa(i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(3+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("GoFlow: systemd socket %q: %w", name, err)
+		}
+		listeners[name] = ln
+		order = append(order, name)
+	}
+	return listeners, order, nil
+}
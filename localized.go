@@ -0,0 +1,87 @@
+package GoFlow
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Localized registers the routes fn adds once under a locale prefix, e.g.
+// /en/about and /de/about for "/about", with the locale available as
+// Param(ctx, "locale") and as the I18n locale. GET requests for the bare
+// path are redirected to the locale I18n chose, or else the one negotiated
+// from Accept-Language, defaulting to the first of locales.
+//
+// The prefix is a parameter with a pattern at the root of the tree, so it
+// must not be combined with other root-level parameters.
+func (m *Mux) Localized(locales []string, fn func(*Mux)) {
+	if len(locales) == 0 {
+		panic("GoFlow: Localized requires at least one locale")
+	}
+	canonical := make([]string, len(locales))
+	quoted := make([]string, len(locales))
+	for i, locale := range locales {
+		canonical[i] = canonicalLocale(locale)
+		quoted[i] = regexp.QuoteMeta(canonical[i])
+	}
+	prefix := joinPattern(m.prefix, "/:locale|^(?:"+strings.Join(quoted, "|")+")$")
+
+	start := len(m.Routes())
+	m.Group(func(sub *Mux) {
+		sub.prefix = prefix
+		sub.Use(pathLocale)
+		fn(sub)
+	})
+
+	redirect := localeRedirect(canonical, m.prefix)
+	seen := make(map[string]bool)
+	for _, rt := range m.Routes()[start:] {
+		if !strings.HasPrefix(rt.pattern, prefix) || !contains(rt.methods, MethodGet) {
+			continue
+		}
+		bare := strings.TrimPrefix(rt.pattern, prefix)
+		if bare == "" {
+			bare = "/"
+		}
+		if !seen[bare] {
+			seen[bare] = true
+			m.Handle(bare, redirect, MethodGet).Hidden()
+		}
+	}
+}
+
+// pathLocale makes the locale from the URL the I18n locale
+func pathLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l := CurrentLocalizer(r.Context()); l != nil {
+			locale := Param(r.Context(), "locale")
+			w.Header().Set("Content-Language", locale)
+			ctx := context.WithValue(r.Context(), localizerKey{}, l.catalog.Localizer(locale, l.fallback))
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func localeRedirect(locales []string, base string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := ""
+		if l := CurrentLocalizer(r.Context()); l != nil && contains(locales, l.locale) {
+			locale = l.locale
+		} else if locale = NegotiateLocale(r, locales...); locale == "" {
+			locale = locales[0]
+		}
+
+		rest := strings.TrimPrefix(r.URL.Path, base)
+		target := BasePath(r.Context()) + base + "/" + locale
+		if rest != "/" && rest != "" {
+			target += rest
+		}
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		AddVary(w.Header(), "Accept-Language")
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+}
@@ -0,0 +1,73 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalized(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Add("en", map[string]string{"title": "About"})
+	catalog.Add("de", map[string]string{"title": "Über uns"})
+
+	mux := New()
+	mux.Use(I18n(catalog, "en"))
+	mux.Localized([]string{"en", "de"}, func(m *Mux) {
+		m.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("home " + Param(r.Context(), "locale")))
+		}), MethodGet)
+		m.Handle("/about", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(T(r.Context(), "title")))
+		}), MethodGet)
+		m.Handle("/contact", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MethodPost)
+	})
+
+	t.Run("Prefixed Routes", func(t *testing.T) {
+		for path, want := range map[string]string{
+			"/en":       "home en",
+			"/de/":      "home de",
+			"/en/about": "About",
+			"/de/about": "Über uns",
+		} {
+			req := httptest.NewRequest(MethodGet, path, nil)
+			req.Header.Set("Accept-Language", "en")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != http.StatusOK || w.Body.String() != want {
+				t.Errorf("%s: Expected '%s', got %d '%s'", path, want, w.Code, w.Body.String())
+			}
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/fr/about", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unsupported locale, got %d", w.Code)
+		}
+	})
+
+	t.Run("Bare Path Redirect", func(t *testing.T) {
+		tests := []struct {
+			path, accept, want string
+		}{
+			{"/about?x=1", "de-DE, en;q=0.5", "/de/about?x=1"},
+			{"/", "fr", "/en"},
+			{"/about?lang=de", "en", "/de/about?lang=de"},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest(MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Language", tt.accept)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+			if w.Code != http.StatusFound || w.Header().Get("Location") != tt.want {
+				t.Errorf("%s: Expected redirect to %s, got %d '%s'", tt.path, tt.want, w.Code, w.Header().Get("Location"))
+			}
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodPost, "/contact", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected no redirect for POST-only routes, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginGuardOptions configures brute-force protection for login endpoints
+type LoginGuardOptions struct {
+	// Identity extracts the account being logged into. Defaults to the
+	// "username" form value. Requests with an empty identity are not tracked.
+	Identity func(r *http.Request) string
+
+	// Failure reports whether a response status is a failed attempt.
+	// Defaults to 401 and 403.
+	Failure func(status int) bool
+
+	// MaxAttempts is the number of consecutive failures that lock the
+	// identity out. Defaults to 5.
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failure, doubling with every
+	// further failure up to MaxDelay. Defaults to 1s and 1m.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// LockoutDuration is how long an identity stays locked. Defaults to 15m.
+	LockoutDuration time.Duration
+
+	// OnLockout is called once when an identity becomes locked out,
+	// e.g. to notify the account owner or alert operators
+	OnLockout func(r *http.Request, identity string, failures int)
+
+	// Throttled handles rejected attempts. Defaults to 429 with Retry-After.
+	Throttled http.Handler
+}
+
+// LoginGuard tracks failed logins per identity rather than per IP, so that
+// attackers spread over many addresses are still slowed down
+type LoginGuard struct {
+	opts LoginGuardOptions
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+	inFlight    bool
+}
+
+// NewLoginGuard creates a LoginGuard
+func NewLoginGuard(opts LoginGuardOptions) *LoginGuard {
+	if opts.Identity == nil {
+		opts.Identity = func(r *http.Request) string {
+			return r.FormValue("username")
+		}
+	}
+	if opts.Failure == nil {
+		opts.Failure = func(status int) bool {
+			return status == http.StatusUnauthorized || status == http.StatusForbidden
+		}
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = time.Minute
+	}
+	if opts.LockoutDuration == 0 {
+		opts.LockoutDuration = 15 * time.Minute
+	}
+	return &LoginGuard{opts: opts, attempts: make(map[string]*loginAttempts)}
+}
+
+// Middleware rejects attempts for identities that are backing off or locked
+// out, and records the outcome of the attempts it lets through
+func (g *LoginGuard) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := strings.ToLower(strings.TrimSpace(g.opts.Identity(r)))
+			if identity == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if wait := g.begin(identity); wait > 0 {
+				if g.opts.Throttled != nil {
+					g.opts.Throttled.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			completed := false
+			defer func() {
+				if !completed {
+					// The handler panicked; release the reservation
+					g.settle(identity, false, false)
+				}
+			}()
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(wrapWriter(sw), r)
+			completed = true
+
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			failed := g.opts.Failure(status)
+			if failures, locked := g.settle(identity, failed, !failed && status < 400); locked && g.opts.OnLockout != nil {
+				g.opts.OnLockout(r, identity, failures)
+			}
+		})
+	}
+}
+
+// RetryAfter returns how long identity must wait before its next attempt
+func (g *LoginGuard) RetryAfter(identity string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.retryAfter(strings.ToLower(identity), time.Now())
+}
+
+// retryAfter is RetryAfter with g.mu held
+func (g *LoginGuard) retryAfter(identity string, now time.Time) time.Duration {
+	a, ok := g.attempts[identity]
+	if !ok {
+		return 0
+	}
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now)
+	}
+	if !a.lockedUntil.IsZero() {
+		// Lockout expired; start over
+		delete(g.attempts, identity)
+		return 0
+	}
+	if wait := a.lastFailure.Add(g.backoff(a.failures)).Sub(now); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// begin reserves an attempt for identity, or returns how long it must
+// wait. Only one attempt per identity runs at a time, so that parallel
+// guesses can't all pass the check before their failures are recorded.
+func (g *LoginGuard) begin(identity string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.prune(now)
+	if wait := g.retryAfter(identity, now); wait > 0 {
+		return wait
+	}
+	a, ok := g.attempts[identity]
+	if !ok {
+		a = &loginAttempts{}
+		g.attempts[identity] = a
+	}
+	if a.inFlight {
+		return g.opts.BaseDelay
+	}
+	a.inFlight = true
+	return 0
+}
+
+// Reset clears the failures recorded for identity, e.g. after an
+// administrator unlocks the account
+func (g *LoginGuard) Reset(identity string) {
+	g.mu.Lock()
+	delete(g.attempts, strings.ToLower(identity))
+	g.mu.Unlock()
+}
+
+// settle records the outcome of an attempt reserved with begin and reports
+// whether it caused a lockout
+func (g *LoginGuard) settle(identity string, failed, succeeded bool) (int, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, ok := g.attempts[identity]
+	if !ok {
+		// Reset while the attempt ran
+		return 0, false
+	}
+	a.inFlight = false
+	if succeeded || (!failed && a.failures == 0) {
+		delete(g.attempts, identity)
+		return 0, false
+	}
+	if !failed {
+		return a.failures, false
+	}
+
+	now := time.Now()
+	a.failures++
+	a.lastFailure = now
+	if a.failures >= g.opts.MaxAttempts && a.lockedUntil.IsZero() {
+		a.lockedUntil = now.Add(g.opts.LockoutDuration)
+		return a.failures, true
+	}
+	return a.failures, false
+}
+
+func (g *LoginGuard) backoff(failures int) time.Duration {
+	delay := g.opts.BaseDelay
+	for i := 1; i < failures && delay < g.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.opts.MaxDelay {
+		delay = g.opts.MaxDelay
+	}
+	return delay
+}
+
+// prune drops stale entries once the table grows large
+func (g *LoginGuard) prune(now time.Time) {
+	if len(g.attempts) < 32768 {
+		return
+	}
+	for id, a := range g.attempts {
+		if !a.inFlight && now.After(a.lockedUntil) && now.Sub(a.lastFailure) > g.opts.MaxDelay {
+			delete(g.attempts, id)
+		}
+	}
+}
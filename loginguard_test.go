@@ -0,0 +1,114 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoginGuard(t *testing.T) {
+	login := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+
+	attempt := func(h http.Handler, username, password, addr string) *httptest.ResponseRecorder {
+		form := url.Values{"username": {username}, "password": {password}}
+		r := httptest.NewRequest(MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Exponential Backoff", func(t *testing.T) {
+		guard := NewLoginGuard(LoginGuardOptions{BaseDelay: time.Minute, MaxDelay: time.Hour})
+		handler := guard.Middleware()(login)
+
+		if w := attempt(handler, "alice", "wrong", "192.0.2.1:1"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status code %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+
+		// A different address doesn't help: the identity is what's tracked
+		w := attempt(handler, "Alice", "secret", "198.51.100.7:1")
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected status code %d, got %d", http.StatusTooManyRequests, w.Code)
+		}
+		if w.Header().Get("Retry-After") != "60" {
+			t.Errorf("Expected Retry-After 60, got '%s'", w.Header().Get("Retry-After"))
+		}
+
+		if w := attempt(handler, "bob", "secret", "192.0.2.1:1"); w.Code != http.StatusOK {
+			t.Errorf("Expected other identities to be unaffected, got %d", w.Code)
+		}
+
+		guard.Reset("alice")
+		if w := attempt(handler, "alice", "secret", "192.0.2.1:1"); w.Code != http.StatusOK {
+			t.Errorf("Expected status code %d after reset, got %d", http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("Lockout And Notification", func(t *testing.T) {
+		var notified []string
+		guard := NewLoginGuard(LoginGuardOptions{
+			MaxAttempts:     3,
+			BaseDelay:       time.Nanosecond,
+			MaxDelay:        time.Nanosecond,
+			LockoutDuration: time.Hour,
+			OnLockout: func(r *http.Request, identity string, failures int) {
+				notified = append(notified, identity)
+			},
+		})
+		handler := guard.Middleware()(login)
+
+		for i := 0; i < 3; i++ {
+			time.Sleep(time.Millisecond)
+			if w := attempt(handler, "carol", "wrong", "192.0.2.1:1"); w.Code != http.StatusUnauthorized {
+				t.Fatalf("Attempt %d: expected status code %d, got %d", i+1, http.StatusUnauthorized, w.Code)
+			}
+		}
+
+		if w := attempt(handler, "carol", "secret", "192.0.2.1:1"); w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected locked identity to be rejected, got %d", w.Code)
+		}
+		if guard.RetryAfter("carol") < 59*time.Minute {
+			t.Errorf("Expected lockout of about an hour, got %s", guard.RetryAfter("carol"))
+		}
+		if len(notified) != 1 || notified[0] != "carol" {
+			t.Errorf("Expected a single lockout notification, got %v", notified)
+		}
+	})
+
+	t.Run("Parallel Attempts", func(t *testing.T) {
+		var tried atomic.Int32
+		guard := NewLoginGuard(LoginGuardOptions{BaseDelay: time.Minute, MaxDelay: time.Hour})
+		handler := guard.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tried.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			login.ServeHTTP(w, r)
+		}))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attempt(handler, "dave", "guess", "192.0.2.1:1")
+			}()
+		}
+		wg.Wait()
+		if tried.Load() != 1 {
+			t.Errorf("Expected one guess to reach the handler, got %d", tried.Load())
+		}
+		if guard.RetryAfter("dave") <= 0 {
+			t.Error("Expected the failed guess to be recorded")
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package GoFlow
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LogSink is a destination of the request log with its own level and
+// filter, e.g. JSON on stdout for a collector, a text file for local
+// debugging and an audit log
+type LogSink struct {
+	// Handler formats and writes the records, e.g.
+	// slog.NewJSONHandler(os.Stdout, nil)
+	Handler slog.Handler
+
+	// Level is the lowest level the sink receives. Requests are logged at
+	// Info, 4xx responses at Warn and 5xx responses at Error. Defaults to
+	// Info.
+	Level slog.Leveler
+
+	// Filter, if set, picks the requests the sink receives, e.g. only
+	// writes for an audit log
+	Filter func(info RequestInfo) bool
+}
+
+// LogTo logs the requests of the mux to sinks, each record going to every
+// sink whose level and filter accept it. It is short for Use with
+// LoggerWithOptions and the sinks.
+func (m *Mux) LogTo(sinks ...LogSink) {
+	m.Use(LoggerWithOptions(LoggerOptions{Sinks: sinks}))
+}
+
+// logToSinks writes a "request" record of the request to the sinks that
+// accept it
+func logToSinks(r *http.Request, sw *statusWriter, duration time.Duration, holder *logHolder, ip string, sinks []LogSink) {
+	info, route := loggedRequest(r, sw, duration, holder)
+	level := slog.LevelInfo
+	switch {
+	case info.Status >= 500:
+		level = slog.LevelError
+	case info.Status >= 400:
+		level = slog.LevelWarn
+	}
+
+	var record *slog.Record
+	ctx := r.Context()
+	for _, sink := range sinks {
+		if sink.Level != nil && level < sink.Level.Level() {
+			continue
+		}
+		if (sink.Filter != nil && !sink.Filter(info)) || !sink.Handler.Enabled(ctx, level) {
+			continue
+		}
+		if record == nil {
+			rec := slog.NewRecord(time.Now(), level, "request", 0)
+			rec.AddAttrs(
+				slog.String("method", info.Method),
+				slog.String("path", info.Path),
+				slog.String("route", info.Route),
+				slog.Int("status", info.Status),
+				slog.Duration("duration", duration),
+				slog.Int64("bytes", info.Size),
+				slog.String("ip", ip),
+				slog.String("user_agent", r.UserAgent()),
+			)
+			if holder.principal != nil {
+				rec.AddAttrs(slog.String("user", holder.principal.Subject))
+			}
+			for _, exp := range holder.experiments {
+				rec.AddAttrs(slog.String("experiment", exp))
+			}
+			rec.AddAttrs(requestAttrs(info, route, holder)...)
+			record = &rec
+		}
+		sink.Handler.Handle(ctx, *record)
+	}
+}
@@ -1,14 +1,22 @@
 package GoFlow
 
 import (
+	"bufio"
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"hash/maphash"
+	"io"
 	"log"
+	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,7 +31,7 @@ func Recovery() func(http.Handler) http.Handler {
 			defer func() {
 				if err := recover(); err != nil {
 					log.Printf("panic: %v\n%s", err, debug.Stack())
-					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					http.Error(w, StatusText(r.Context(), http.StatusInternalServerError), http.StatusInternalServerError)
 				}
 			}()
 			next.ServeHTTP(w, r)
@@ -31,41 +39,63 @@ func Recovery() func(http.Handler) http.Handler {
 	}
 }
 
-// Timeout adds a timeout to the request context
-func Timeout(duration time.Duration) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx, cancel := context.WithTimeout(r.Context(), duration)
-			defer cancel()
+// LoggerOptions configures the Logger middleware
+type LoggerOptions struct {
+	// SlowThreshold flags requests that take at least this long. Zero
+	// disables it.
+	SlowThreshold time.Duration
 
-			done := make(chan struct{})
-			go func() {
-				next.ServeHTTP(w, r.WithContext(ctx))
-				close(done)
-			}()
+	// SlowLogger receives a warning with the matched route and parameters
+	// for each slow request. Defaults to slog.Default().
+	SlowLogger *slog.Logger
 
-			select {
-			case <-done:
-				return
-			case <-ctx.Done():
-				w.WriteHeader(http.StatusGatewayTimeout)
-				return
-			}
-		})
-	}
+	// OnSlow is called for each slow request, e.g. to count them or alert
+	OnSlow func(info RequestInfo)
+
+	// Sinks receive each request as a structured record instead of the
+	// standard logger's line, see LogSink
+	Sinks []LogSink
 }
 
 // Logger logs request information
 func Logger() func(http.Handler) http.Handler {
+	return LoggerWithOptions(LoggerOptions{})
+}
+
+// LoggerWithOptions logs request information like Logger, and reports
+// requests slower than opts.SlowThreshold
+func LoggerWithOptions(opts LoggerOptions) func(http.Handler) http.Handler {
+	if opts.SlowLogger == nil {
+		opts.SlowLogger = slog.Default()
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			sw := &statusWriter{ResponseWriter: w}
 
-			next.ServeHTTP(sw, r)
+			// Capture the principal, tenant and route set by downstream
+			// middleware, sharing the holder of an outer Logger or hook
+			holder, ok := r.Context().Value(logHolderKey{}).(*logHolder)
+			if !ok {
+				holder = &logHolder{}
+				r = r.WithContext(context.WithValue(r.Context(), logHolderKey{}, holder))
+			}
+			if holder.tenant == "" {
+				holder.tenant = GetTenant(r.Context())
+			}
+			if tc, ok := GetTrace(r.Context()); ok {
+				holder.trace = &tc
+			}
+
+			next.ServeHTTP(wrapWriter(sw), r)
 
 			duration := time.Since(start)
 
+			user := "-"
+			if holder.principal != nil {
+				user = holder.principal.Subject
+			}
+
 			// Get real IP from proxy headers if available
 			ip := r.Header.Get("X-Real-IP")
 			if ip == "" {
@@ -75,33 +105,135 @@ func Logger() func(http.Handler) http.Handler {
 				}
 			}
 
-			log.Printf(
-				"[%s] %s %s %d %s %d bytes %s",
-				ip,
-				r.Method,
-				r.URL.Path,
-				sw.status,
-				duration,
-				sw.size,
-				r.UserAgent(),
-			)
+			extra := ""
+			if holder.tenant != "" {
+				extra = " tenant=" + holder.tenant
+			}
+			for _, exp := range holder.experiments {
+				extra += " experiment=" + exp
+			}
+			if holder.trace != nil {
+				extra += " request_id=" + holder.trace.RequestID + " trace_id=" + holder.trace.TraceID
+			}
+
+			status := sw.status
+			if holder.writeTimedOut {
+				status = StatusWriteTimeout
+			}
+
+			if len(opts.Sinks) > 0 {
+				logToSinks(r, sw, duration, holder, ip, opts.Sinks)
+			} else {
+				log.Printf(
+					"[%s] %s %s %s %d %s %d bytes %s%s",
+					ip,
+					user,
+					r.Method,
+					r.URL.Path,
+					status,
+					duration,
+					sw.size,
+					r.UserAgent(),
+					extra,
+				)
+			}
+
+			if opts.SlowThreshold > 0 && duration >= opts.SlowThreshold {
+				logSlow(r, sw, duration, holder, opts)
+			}
 		})
 	}
 }
 
-// Sharded bucket storage for reduced lock contention
+func logSlow(r *http.Request, sw *statusWriter, duration time.Duration, holder *logHolder, opts LoggerOptions) {
+	info, route := loggedRequest(r, sw, duration, holder)
+	attrs := []slog.Attr{
+		slog.String("method", info.Method),
+		slog.String("path", info.Path),
+		slog.String("route", info.Route),
+		slog.Int("status", info.Status),
+		slog.Duration("duration", duration),
+		slog.Duration("threshold", opts.SlowThreshold),
+	}
+	attrs = append(attrs, requestAttrs(info, route, holder)...)
+	opts.SlowLogger.LogAttrs(r.Context(), slog.LevelWarn, "slow request", attrs...)
+
+	if opts.OnSlow != nil {
+		opts.OnSlow(info)
+	}
+}
+
+// loggedRequest describes a request logged by Logger, and the route it
+// matched
+func loggedRequest(r *http.Request, sw *statusWriter, duration time.Duration, holder *logHolder) (RequestInfo, *Route) {
+	info := RequestInfo{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Params:   holder.params,
+		Status:   sw.status,
+		Size:     sw.size,
+		Duration: duration,
+		Request:  r,
+	}
+	if info.Status == 0 {
+		info.Status = http.StatusOK
+	}
+	if holder.writeTimedOut {
+		info.Status = StatusWriteTimeout
+	}
+	route := holder.route
+	if route == nil {
+		// Logger runs inside the route's chain when used on a Mux
+		route, _ = r.Context().Value(routeContextKey{}).(*Route)
+		info.Params, _ = r.Context().Value(paramContextKey{}).(map[string]string)
+	}
+	if route != nil {
+		info.Route = route.pattern
+	}
+	return info, route
+}
+
+// requestAttrs are the query, parameters, tenant and trace of a logged
+// request
+func requestAttrs(info RequestInfo, route *Route, holder *logHolder) []slog.Attr {
+	var attrs []slog.Attr
+	if q := info.Request.URL.RawQuery; q != "" {
+		attrs = append(attrs, slog.String("query", redactionFor(route).RedactQuery(q)))
+	}
+	if len(info.Params) > 0 {
+		params := make([]any, 0, len(info.Params))
+		for _, k := range slices.Sorted(maps.Keys(info.Params)) {
+			params = append(params, slog.String(k, info.Params[k]))
+		}
+		attrs = append(attrs, slog.Group("params", params...))
+	}
+	if holder.tenant != "" {
+		attrs = append(attrs, slog.String("tenant", holder.tenant))
+	}
+	if holder.trace != nil {
+		attrs = append(attrs, slog.String("request_id", holder.trace.RequestID), slog.String("trace_id", holder.trace.TraceID))
+	}
+	return attrs
+}
+
+// Sharded bucket storage for reduced lock contention. Shards are padded to
+// a cache line so that neighbouring locks don't contend.
 type bucketShard struct {
 	sync.RWMutex
 	buckets map[string]*bucket
+	_       [32]byte
 }
 
 type RateLimiter struct {
 	shards   []bucketShard
+	mask     uint64
 	requests int32
 	burst    int32
 	interval int64 // nanoseconds
 	maxSize  int32
 	seed     maphash.Seed
+	rejected atomic.Uint64
+	tier     string // set by TieredRateLimit, for Stats
 }
 
 type bucket struct {
@@ -111,7 +243,11 @@ type bucket struct {
 }
 
 func NewRateLimiter(requests int, duration time.Duration, burst int) *RateLimiter {
-	numShards := runtime.GOMAXPROCS(0) * 4
+	// A power of two, so the shard is picked with a mask
+	numShards := 1
+	for numShards < runtime.GOMAXPROCS(0)*4 {
+		numShards <<= 1
+	}
 	shards := make([]bucketShard, numShards)
 
 	for i := range shards {
@@ -120,24 +256,32 @@ func NewRateLimiter(requests int, duration time.Duration, burst int) *RateLimite
 		}
 	}
 
-	return &RateLimiter{
+	rl := &RateLimiter{
 		shards:   shards,
+		mask:     uint64(numShards - 1),
 		requests: int32(requests),
 		burst:    int32(burst),
 		interval: duration.Nanoseconds(),
 		maxSize:  32768,
 		seed:     maphash.MakeSeed(),
 	}
+	registerStats(&registry.limiters, rl)
+	return rl
 }
 
 func (rl *RateLimiter) getShard(key string) *bucketShard {
-	h := maphash.Hash{}
-	h.SetSeed(rl.seed)
-	h.WriteString(key)
-	return &rl.shards[h.Sum64()%uint64(len(rl.shards))]
+	return &rl.shards[maphash.String(rl.seed, key)&rl.mask]
 }
 
 func (rl *RateLimiter) Allow(key string) bool {
+	if rl.allow(key) {
+		return true
+	}
+	rl.rejected.Add(1)
+	return false
+}
+
+func (rl *RateLimiter) allow(key string) bool {
 	shard := rl.getShard(key)
 	now := time.Now().UnixNano()
 
@@ -145,49 +289,15 @@ func (rl *RateLimiter) Allow(key string) bool {
 	shard.RLock()
 	b, exists := shard.buckets[key]
 	shard.RUnlock()
-
 	if exists {
-		lastSeen := atomic.LoadInt64(&b.lastSeen)
-		elapsed := now - lastSeen
-
-		if elapsed >= rl.interval {
-			// Reset tokens atomically
-			atomic.StoreInt32(&b.tokens, rl.requests)
-			atomic.StoreInt32(&b.burst, rl.burst)
-			atomic.StoreInt64(&b.lastSeen, now)
-			return true
-		}
-
-		// Try regular tokens first
-		for {
-			tokens := atomic.LoadInt32(&b.tokens)
-			if tokens <= 0 {
-				break
-			}
-			if atomic.CompareAndSwapInt32(&b.tokens, tokens, tokens-1) {
-				return true
-			}
-		}
-
-		// Try burst tokens if available
-		for {
-			burst := atomic.LoadInt32(&b.burst)
-			if burst <= 0 {
-				return false
-			}
-			if atomic.CompareAndSwapInt32(&b.burst, burst, burst-1) {
-				return true
-			}
-		}
+		return rl.take(b, now)
 	}
 
 	// Slow path: create new bucket
 	shard.Lock()
-	defer shard.Unlock()
-
-	// Double check after lock
-	if _, ok := shard.buckets[key]; ok {
-		return rl.Allow(key) // Retry fast path
+	if b, exists = shard.buckets[key]; exists {
+		shard.Unlock()
+		return rl.take(b, now)
 	}
 
 	// Clean old entries if needed
@@ -200,125 +310,251 @@ func (rl *RateLimiter) Allow(key string) bool {
 		}
 	}
 
-	// Create new bucket
-	b = &bucket{
+	shard.buckets[key] = &bucket{
 		tokens:   rl.requests - 1,
 		burst:    rl.burst,
 		lastSeen: now,
 	}
-	shard.buckets[key] = b
+	shard.Unlock()
 	return true
 }
 
+// take spends a token from b, falling back to the burst allowance. Each
+// step is a single atomic operation, so contended keys never spin.
+func (rl *RateLimiter) take(b *bucket, now int64) bool {
+	// One caller refills the bucket once the interval has passed
+	if lastSeen := atomic.LoadInt64(&b.lastSeen); now-lastSeen >= rl.interval &&
+		atomic.CompareAndSwapInt64(&b.lastSeen, lastSeen, now) {
+		atomic.StoreInt32(&b.tokens, rl.requests-1)
+		atomic.StoreInt32(&b.burst, rl.burst)
+		return true
+	}
+
+	// Counters may dip below zero by the number of concurrent callers; the
+	// load keeps them from drifting further while a key is throttled
+	if atomic.LoadInt32(&b.tokens) > 0 && atomic.AddInt32(&b.tokens, -1) >= 0 {
+		return true
+	}
+	return atomic.LoadInt32(&b.burst) > 0 && atomic.AddInt32(&b.burst, -1) >= 0
+}
+
 // RateLimit implements a token bucket rate limiting middleware
 func RateLimit(requests int, duration time.Duration, burst int) func(http.Handler) http.Handler {
-	limiter := NewRateLimiter(requests, duration, burst)
+	return RateLimitWithOptions(RateLimitOptions{Requests: requests, Duration: duration, BurstSize: burst})
+}
+
+// RateLimitWithOptions is RateLimit with clients in TrustedIPs exempt and
+// the client address forwarded by TrustedProxies
+func RateLimitWithOptions(opts RateLimitOptions) func(http.Handler) http.Handler {
+	limiter := NewRateLimiter(opts.Requests, opts.Duration, opts.BurstSize)
+	trusted := make(map[string]struct{}, len(opts.TrustedIPs))
+	for _, ip := range opts.TrustedIPs {
+		trusted[ip] = struct{}{}
+	}
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ip := r.Header.Get("X-Real-IP")
-			if ip == "" {
-				ip = r.Header.Get("X-Forwarded-For")
-				if ip == "" {
-					ip = r.RemoteAddr
-				}
+			// Forwarding headers only count from a trusted proxy, so
+			// clients can't claim a trusted address
+			ip := getRealIP(r, trustedProxies)
+
+			if _, ok := trusted[ip]; ok || isACMEChallenge(r) {
+				debugNote(r.Context(), "rate limit: %s trusted", ip)
+				next.ServeHTTP(w, r)
+				return
 			}
 
-			if !limiter.Allow(ip) {
+			if !limiter.Allow(tenantKey(r, ip)) {
+				debugNote(r.Context(), "rate limit: %s limited", ip)
 				w.Header().Set("X-RateLimit-Limit", toString(int(limiter.requests)))
 				w.Header().Set("X-RateLimit-Burst", toString(int(limiter.burst)))
 				w.Header().Set("X-RateLimit-Remaining", "0")
 				w.Header().Set("X-RateLimit-Reset", toString(int(limiter.interval/1e9)))
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
 				return
 			}
+			debugNote(r.Context(), "rate limit: %s allowed", ip)
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// CORS middleware adds Cross-Origin Resource Sharing headers
-func CORS(allowedOrigins []string, allowedMethods []string, allowedHeaders []string) func(http.Handler) http.Handler {
-	allowedOriginsMap := make(map[string]bool)
-	for _, origin := range allowedOrigins {
-		allowedOriginsMap[origin] = true
-	}
-
-	allowedMethodsStr := strings.Join(allowedMethods, ", ")
-	allowedHeadersStr := strings.Join(allowedHeaders, ", ")
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-
-			// Check if origin is allowed
-			if origin != "" {
-				if allowedOriginsMap["*"] {
-					w.Header().Set("Access-Control-Allow-Origin", "*")
-				} else if allowedOriginsMap[origin] {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					w.Header().Set("Vary", "Origin")
-				}
-			}
-
-			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				w.Header().Set("Access-Control-Allow-Methods", allowedMethodsStr)
-				w.Header().Set("Access-Control-Allow-Headers", allowedHeadersStr)
-				w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
-	}
+// CompressionOptions configures the Compression middleware
+type CompressionOptions struct {
+	// Level is the gzip level, from gzip.HuffmanOnly (-2) to
+	// gzip.BestCompression (9). Zero selects gzip.DefaultCompression.
+	Level int
 }
 
 // Compression middleware for response compression
 func Compression() func(http.Handler) http.Handler {
+	return CompressionWithOptions(CompressionOptions{})
+}
+
+// CompressionWithOptions is Compression with a configurable gzip level
+func CompressionWithOptions(opts CompressionOptions) func(http.Handler) http.Handler {
+	level := opts.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	if _, err := gzip.NewWriterLevel(nil, level); err != nil {
+		panic("GoFlow: " + err.Error())
+	}
 	pool := sync.Pool{
 		New: func() interface{} {
-			return gzip.NewWriter(nil)
+			gzipPoolStats.allocations.Add(1)
+			gz, _ := gzip.NewWriterLevel(nil, level)
+			return gz
 		},
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			// gRPC negotiates its own per-message compression
+			if isStreamingRPC(r) || isWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			AddVary(w.Header(), "Accept-Encoding")
+			encoding, ok := negotiateEncoding(r.Header.Values("Accept-Encoding"), "gzip")
+			if !ok {
+				http.Error(w, StatusText(r.Context(), http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+			if encoding == "identity" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			gzipPoolStats.gets.Add(1)
 			gz := pool.Get().(*gzip.Writer)
 			defer pool.Put(gz)
 
 			gz.Reset(w)
-			defer gz.Close()
-
-			w.Header().Set("Content-Encoding", "gzip")
-			w.Header().Del("Content-Length")
-
-			gzw := &gzipResponseWriter{
-				ResponseWriter: w,
-				Writer:         gz,
+			gw := &gzipResponseWriter{ResponseWriter: w, Writer: gz}
+			next.ServeHTTP(wrapWriter(gw), r)
+			if gw.wroteHeader && !gw.identity {
+				gz.Close()
 			}
-			next.ServeHTTP(gzw, r)
 		})
 	}
 }
 
-// Cache middleware for response caching
+// negotiateEncoding picks the content coding the client prefers by the
+// quality values of its Accept-Encoding header (RFC 9110, section 12.5.3),
+// from supported and identity. On a tie compression wins. It reports false
+// when the client rules out identity and accepts none of supported.
+func negotiateEncoding(header []string, supported ...string) (string, bool) {
+	if len(header) == 0 {
+		return "identity", true
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(strings.Join(header, ","), ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		qualities[coding] = q
+	}
+
+	quality := func(coding string) float64 {
+		if q, ok := qualities[coding]; ok {
+			return q
+		}
+		if q, ok := qualities["*"]; ok {
+			return q
+		}
+		if coding == "identity" {
+			// Acceptable unless excluded, but after any listed coding
+			return 0.001
+		}
+		return 0
+	}
+
+	best, bestQ := "identity", quality("identity")
+	for _, coding := range supported {
+		if q := quality(coding); q > 0 && q >= bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// CacheAuthPolicy decides how Cache treats requests that carry credentials
+type CacheAuthPolicy int
+
+const (
+	// CacheBypassAuthenticated serves authenticated requests without the
+	// cache, neither reading nor storing entries. It is the default.
+	CacheBypassAuthenticated CacheAuthPolicy = iota
+	// CachePerUser keeps separate entries per user, see CacheOptions.UserKey
+	CachePerUser
+	// CacheShared caches authenticated requests like anonymous ones. Only
+	// use it for responses that don't depend on the user.
+	CacheShared
+)
+
+// CacheOptions configures the Cache middleware
+type CacheOptions struct {
+	// Duration is how long responses are kept
+	Duration time.Duration
+
+	// NegativeDuration, when set, caches 404 Not Found and 410 Gone
+	// responses for that long, so scrapers probing nonexistent URLs don't
+	// reach the handler each time. Use PurgeCache when the content is
+	// created later.
+	NegativeDuration time.Duration
+
+	// Authenticated applies to requests with an Authorization header, a
+	// SessionCookies cookie or a principal set by earlier middleware
+	Authenticated CacheAuthPolicy
+
+	// SessionCookies name the cookies that identify a user, such as
+	// "goflow_session"
+	SessionCookies []string
+
+	// UserKey returns the key CachePerUser stores a request's entries
+	// under. Defaults to the principal's subject, or a hash of the
+	// credentials.
+	UserKey func(r *http.Request) string
+}
+
+// Cache middleware for response caching. Authenticated requests bypass the
+// cache; see CacheWithOptions to cache them per user.
 func Cache(duration time.Duration) func(http.Handler) http.Handler {
-	cache := sync.Map{}
+	return CacheWithOptions(CacheOptions{Duration: duration})
+}
+
+// CacheWithOptions is Cache with control over authenticated requests.
+// Responses that set cookies or are marked Cache-Control: no-store are
+// never stored, nor are private ones unless cached per user.
+func CacheWithOptions(opts CacheOptions) func(http.Handler) http.Handler {
+	duration := opts.Duration
+	if opts.UserKey == nil {
+		opts.UserKey = func(r *http.Request) string { return defaultCacheUserKey(r, opts.SessionCookies) }
+	}
+	cache := &cacheStore{}
+	registerStats(&registry.caches, cache)
 
 	// Clean up expired entries periodically
 	go func() {
 		for range time.Tick(duration) {
-			cache.Range(func(key, value interface{}) bool {
+			cache.m.Range(func(key, value interface{}) bool {
 				if entry := value.(*cacheEntry); entry.expired() {
-					cache.Delete(key)
+					cache.delete(key)
 				}
 				return true
 			})
@@ -328,61 +564,237 @@ func Cache(duration time.Duration) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Only cache GET requests
-			if r.Method != http.MethodGet {
+			if r.Method != http.MethodGet || isWebSocketUpgrade(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			key := r.URL.String()
-			if cached, ok := cache.Load(key); ok {
+			url := tenantKey(r, r.URL.String())
+			perUser := false
+			if cacheAuthenticated(r, opts.SessionCookies) {
+				switch opts.Authenticated {
+				case CacheBypassAuthenticated:
+					debugNote(r.Context(), "cache: bypassed for authenticated request")
+					next.ServeHTTP(w, r)
+					return
+				case CachePerUser:
+					url += "\x00user=" + opts.UserKey(r)
+					perUser = true
+				}
+			}
+			key := cache.key(url, r)
+			if cached, ok := cache.m.Load(key); ok {
 				entry := cached.(*cacheEntry)
 				if !entry.expired() {
-					for k, values := range entry.headers {
-						for _, v := range values {
-							w.Header().Add(k, v)
-						}
+					debugNote(r.Context(), "cache: hit %s", url)
+					cache.hits.Add(1)
+					copyHeaders(w.Header(), entry.headers)
+					if entry.status != http.StatusOK {
+						w.WriteHeader(entry.status)
 					}
 					w.Write(entry.data)
+					copyTrailers(w.Header(), entry.headers)
 					return
 				}
-				cache.Delete(key)
+				cache.delete(key)
 			}
 
+			debugNote(r.Context(), "cache: miss %s", url)
+			cache.misses.Add(1)
 			cw := &cacheWriter{
 				ResponseWriter: w,
 				headers:        make(http.Header),
 			}
-			next.ServeHTTP(cw, r)
-
-			if cw.status == http.StatusOK {
-				cache.Store(key, &cacheEntry{
+			defer cw.reserved.free()
+			next.ServeHTTP(wrapWriter(cw), r)
+			if !cw.wroteHeader {
+				cw.WriteHeader(http.StatusOK)
+			}
+			copyTrailers(w.Header(), cw.headers)
+
+			if cw.overBudget {
+				debugNote(r.Context(), "cache: not stored, buffer budget exhausted")
+			} else if reason := uncacheable(cw.headers, perUser); reason != "" {
+				debugNote(r.Context(), "cache: not stored, %s", reason)
+			} else if ttl := cacheTTL(cw.status, duration, opts.NegativeDuration); ttl > 0 && !varies(cw.headers.Values("Vary"), "*") {
+				// The response may vary by headers the lookup didn't key by
+				key = cache.setVary(url, varyNames(cw.headers.Values("Vary")), r)
+				cache.store(key, &cacheEntry{
 					data:    cw.data.Bytes(),
 					headers: cw.headers.Clone(),
-					expires: time.Now().Add(duration),
+					status:  cw.status,
+					path:    r.URL.Path,
+					expires: time.Now().Add(ttl),
 				})
+				debugNote(r.Context(), "cache: stored %d for %s", cw.status, ttl)
 			}
 		})
 	}
 }
 
-var responseWriterPool = sync.Pool{
-	New: func() interface{} {
-		return &statusWriter{
-			headers: make(http.Header),
+// cacheTTL returns how long a response with status is kept, or 0 if it
+// isn't cached
+func cacheTTL(status int, duration, negative time.Duration) time.Duration {
+	switch status {
+	case http.StatusOK:
+		return duration
+	case http.StatusNotFound, http.StatusGone:
+		return negative
+	}
+	return 0
+}
+
+// PurgeCache removes the entries of every Cache middleware whose request
+// path starts with prefix, for all tenants and users, e.g. once content
+// that was cached as 404 Not Found is created, and the values cached with
+// Cached under keys starting with prefix. It returns the number of entries
+// removed.
+func PurgeCache(prefix string) int {
+	registry.mu.Lock()
+	caches := liveStats(registry.caches)
+	registry.mu.Unlock()
+
+	purged := readThrough.current().Purge(prefix)
+	for _, c := range caches {
+		purged += c.purge(prefix)
+	}
+	return purged
+}
+
+// cacheStore holds a Cache middleware's entries and tracks their size for
+// Stats
+type cacheStore struct {
+	m       sync.Map
+	entries atomic.Int64
+	bytes   atomic.Int64
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+
+	// vary maps a URL to the request headers its last response listed in
+	// Vary, which are part of the key of its entries
+	vary sync.Map
+}
+
+// key returns the key a request's response is cached under: the URL and
+// the values of the request headers the URL's responses vary by
+func (c *cacheStore) key(url string, r *http.Request) string {
+	names, _ := c.vary.Load(url)
+	list, _ := names.([]string)
+	return cacheKey(url, list, r)
+}
+
+// setVary records the headers url's responses vary by and returns the key
+// for r's response
+func (c *cacheStore) setVary(url string, names []string, r *http.Request) string {
+	c.vary.Store(url, names)
+	return cacheKey(url, names, r)
+}
+
+// cacheAuthenticated reports whether r carries credentials
+func cacheAuthenticated(r *http.Request, sessionCookies []string) bool {
+	if r.Header.Get("Authorization") != "" || GetPrincipal(r.Context()) != nil {
+		return true
+	}
+	for _, name := range sessionCookies {
+		if _, err := r.Cookie(name); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultCacheUserKey keys entries by the principal's subject, or by a hash
+// of the credentials so they never appear in the key
+func defaultCacheUserKey(r *http.Request, sessionCookies []string) string {
+	if p := GetPrincipal(r.Context()); p != nil && p.Subject != "" {
+		return "sub:" + p.Subject
+	}
+	h := sha256.New()
+	io.WriteString(h, r.Header.Get("Authorization"))
+	for _, name := range sessionCookies {
+		if c, err := r.Cookie(name); err == nil {
+			io.WriteString(h, "\x00"+c.Value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// uncacheable returns why a response must not be stored, or ""
+func uncacheable(h http.Header, perUser bool) string {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return "response sets cookies"
+	}
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store":
+				return "Cache-Control: no-store"
+			case "private":
+				if !perUser {
+					return "Cache-Control: private"
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func cacheKey(url string, names []string, r *http.Request) string {
+	if len(names) == 0 {
+		return url
+	}
+	var b strings.Builder
+	b.WriteString(url)
+	for _, name := range names {
+		b.WriteByte(0)
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+func (c *cacheStore) store(key string, entry *cacheEntry) {
+	c.entries.Add(1)
+	c.bytes.Add(entry.size())
+	if prev, loaded := c.m.Swap(key, entry); loaded {
+		c.entries.Add(-1)
+		c.bytes.Add(-prev.(*cacheEntry).size())
+	}
+}
+
+func (c *cacheStore) purge(prefix string) int {
+	purged := 0
+	c.m.Range(func(key, value interface{}) bool {
+		if strings.HasPrefix(value.(*cacheEntry).path, prefix) {
+			c.delete(key)
+			purged++
 		}
-	},
+		return true
+	})
+	return purged
+}
+
+func (c *cacheStore) delete(key interface{}) {
+	if prev, loaded := c.m.LoadAndDelete(key); loaded {
+		c.entries.Add(-1)
+		c.bytes.Add(-prev.(*cacheEntry).size())
+	}
 }
 
 // Helper types
+
+// statusWriter records the status and size of a response for logging and
+// hooks
 type statusWriter struct {
 	http.ResponseWriter
-	status  int
-	size    int64
-	headers http.Header
+	status int
+	size   int64
 }
 
 func (w *statusWriter) WriteHeader(status int) {
-	w.status = status
+	if w.status == 0 || w.status < http.StatusOK {
+		w.status = status
+	}
 	w.ResponseWriter.WriteHeader(status)
 }
 
@@ -395,25 +807,120 @@ func (w *statusWriter) Write(b []byte) (int, error) {
 	return n, err
 }
 
+// Flush keeps streaming responses such as gRPC and server-sent events working
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ReadFrom lets io.Copy use sendfile when the underlying writer supports it
+func (w *statusWriter) ReadFrom(src io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		w.size += n
+		return n, err
+	}
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Hijack records a hijacked connection as 101 Switching Protocols
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// gzipResponseWriter compresses the response, unless its status or headers
+// show it must be sent as is
 type gzipResponseWriter struct {
 	http.ResponseWriter
 	Writer *gzip.Writer
+
+	wroteHeader bool
+	identity    bool
+}
+
+// WriteHeader picks the encoding. Partial content keeps the identity
+// encoding its Content-Range refers to, and bodies the handler encoded
+// itself aren't compressed twice.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader || status < http.StatusOK {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	switch {
+	case status == http.StatusPartialContent || status == http.StatusNoContent || status == http.StatusNotModified,
+		h.Get("Content-Range") != "", h.Get("Content-Encoding") != "":
+		w.identity = true
+	default:
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+	}
+	w.ResponseWriter.WriteHeader(status)
 }
 
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.identity {
+		return w.ResponseWriter.Write(b)
+	}
 	return w.Writer.Write(b)
 }
 
 func (w *gzipResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.identity {
+		w.Writer.Flush()
+	}
 	if f, ok := w.ResponseWriter.(http.Flusher); ok {
 		f.Flush()
 	}
-	w.Writer.Flush()
+}
+
+// ReadFrom compresses src; the underlying ReadFrom would bypass gzip
+func (w *gzipResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.identity {
+		if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+			return rf.ReadFrom(src)
+		}
+		return io.Copy(writerOnly{w.ResponseWriter}, src)
+	}
+	return io.Copy(w.Writer, src)
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 type cacheEntry struct {
 	data    []byte
 	headers http.Header
+	status  int
+	// path is the request path, matched by PurgeCache
+	path    string
 	expires time.Time
 }
 
@@ -421,20 +928,55 @@ func (c *cacheEntry) expired() bool {
 	return time.Now().After(c.expires)
 }
 
+// size approximates the memory held by the entry
+func (c *cacheEntry) size() int64 {
+	n := len(c.data)
+	for k, v := range c.headers {
+		n += len(k)
+		for _, s := range v {
+			n += len(s)
+		}
+	}
+	return int64(n)
+}
+
+// cacheWriter records the response headers separately so they can be
+// stored, passing them on when the header is written
 type cacheWriter struct {
 	http.ResponseWriter
-	status  int
-	headers http.Header
-	data    bytes.Buffer
+	status      int
+	wroteHeader bool
+	headers     http.Header
+	data        bytes.Buffer
+
+	// overBudget stops buffering once the buffer budget is exhausted
+	reserved   bufferReservation
+	overBudget bool
 }
 
 func (w *cacheWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
 	w.status = status
+	copyHeaders(w.ResponseWriter.Header(), w.headers)
 	w.ResponseWriter.WriteHeader(status)
 }
 
 func (w *cacheWriter) Write(b []byte) (int, error) {
-	w.data.Write(b)
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overBudget {
+		if w.reserved.grow(len(b)) {
+			w.data.Write(b)
+		} else {
+			w.overBudget = true
+			w.data = bytes.Buffer{}
+			w.reserved.free()
+		}
+	}
 	return w.ResponseWriter.Write(b)
 }
 
@@ -442,7 +984,81 @@ func (w *cacheWriter) Header() http.Header {
 	return w.headers
 }
 
+func (w *cacheWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *cacheWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Hijack passes the handler's headers on and keeps the response out of the
+// cache
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.wroteHeader {
+		copyHeaders(w.ResponseWriter.Header(), w.headers)
+		w.wroteHeader = true
+		w.status = http.StatusSwitchingProtocols
+	}
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Helper functions
+
+// copyHeaders copies src into dst for a response that has not been written
+// yet, leaving out trailer values so they aren't sent as headers
+func copyHeaders(dst, src http.Header) {
+	trailers := declaredTrailers(src)
+	for k, v := range src {
+		if trailers[k] || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		if k == "Vary" {
+			// Keep what outer middleware added
+			AddVary(dst, varyNames(v)...)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// copyTrailers copies the trailer values in src into dst once the body has
+// been written. Values are recognized by their declaration in the Trailer
+// header or by http.TrailerPrefix.
+func copyTrailers(dst, src http.Header) {
+	trailers := declaredTrailers(src)
+	for k, v := range src {
+		if trailers[k] || strings.HasPrefix(k, http.TrailerPrefix) {
+			dst[k] = v
+		}
+	}
+}
+
+func declaredTrailers(h http.Header) map[string]bool {
+	declared := h["Trailer"]
+	if len(declared) == 0 {
+		return nil
+	}
+	trailers := make(map[string]bool)
+	for _, v := range declared {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				trailers[http.CanonicalHeaderKey(k)] = true
+			}
+		}
+	}
+	return trailers
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
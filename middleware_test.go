@@ -0,0 +1,450 @@
+package GoFlow
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTrailers(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("payload"))
+		w.Header().Set("X-Checksum", "abc123")
+		w.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	})
+
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+	}{
+		{"Logger", Logger()},
+		{"Compression", Compression()},
+		{"Cache", Cache(time.Minute)},
+		{"Timeout", Timeout(time.Second)},
+		{"Retry", Retry(RetryOptions{})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(tt.middleware(handler))
+			defer srv.Close()
+
+			// Twice, so the Cache middleware also replays a stored response
+			for i := 0; i < 2; i++ {
+				resp, err := http.Get(srv.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+
+				if string(body) != "payload" {
+					t.Errorf("Expected body 'payload', got '%s'", body)
+				}
+				if resp.Header.Get("Content-Type") != "text/plain" {
+					t.Errorf("Expected Content-Type header, got %v", resp.Header)
+				}
+				if resp.Header.Get("X-Checksum") != "" {
+					t.Errorf("Expected trailer value not to be sent as a header, got %v", resp.Header)
+				}
+				if resp.Trailer.Get("X-Checksum") != "abc123" || resp.Trailer.Get("Grpc-Status") != "0" {
+					t.Errorf("Expected trailers, got %v", resp.Trailer)
+				}
+			}
+		})
+	}
+}
+
+func TestWriterInterfaces(t *testing.T) {
+	stacks := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+	}{
+		{"Logger", Logger()},
+		{"Compression", Compression()},
+		{"Cache", Cache(time.Minute)},
+		{"Stacked", func(next http.Handler) http.Handler {
+			return Logger()(Compression()(Cache(time.Minute)(next)))
+		}},
+	}
+
+	for _, tt := range stacks {
+		t.Run(tt.name, func(t *testing.T) {
+			var flusher, hijacker, readerFrom, pusher bool
+			handler := tt.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, flusher = w.(http.Flusher)
+				_, hijacker = w.(http.Hijacker)
+				_, readerFrom = w.(io.ReaderFrom)
+				_, pusher = w.(http.Pusher)
+				w.Header().Set("X-Cached", "yes")
+				io.Copy(w, strings.NewReader("hello"))
+			}))
+
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+			req, _ := http.NewRequest(MethodGet, srv.URL, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			for i := 0; i < 2; i++ {
+				resp, err := http.DefaultTransport.RoundTrip(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.Header.Get("X-Cached") != "yes" {
+					t.Errorf("Expected handler headers to reach the client, got %v", resp.Header)
+				}
+			}
+			if !flusher || !hijacker || !readerFrom {
+				t.Errorf("Expected Flusher, Hijacker and ReaderFrom over HTTP/1, got %v %v %v", flusher, hijacker, readerFrom)
+			}
+			if pusher {
+				t.Error("Expected no Pusher over HTTP/1")
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/recorder", nil))
+			if !flusher || hijacker || readerFrom {
+				t.Errorf("Expected only Flusher over a recorder, got %v %v %v", flusher, hijacker, readerFrom)
+			}
+		})
+	}
+
+	t.Run("Hijack Status", func(t *testing.T) {
+		done := make(chan int, 1)
+		mux := New()
+		mux.OnRequestDone(func(info RequestInfo) { done <- info.Status })
+		mux.Handle("/ws", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, brw, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+			brw.Flush()
+			conn.Close()
+		}), MethodGet)
+		srv := httptest.NewServer(mux)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/ws")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if status := <-done; resp.StatusCode != http.StatusSwitchingProtocols || status != http.StatusSwitchingProtocols {
+			t.Errorf("Expected 101 recorded for a hijack, got %d and %d", resp.StatusCode, status)
+		}
+	})
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("Contended Key", func(t *testing.T) {
+		limiter := NewRateLimiter(100, time.Hour, 20)
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					if limiter.Allow("hot") {
+						allowed.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		if allowed.Load() != 120 {
+			t.Errorf("Expected exactly 120 requests allowed, got %d", allowed.Load())
+		}
+	})
+
+	t.Run("Refill", func(t *testing.T) {
+		limiter := NewRateLimiter(2, 20*time.Millisecond, 0)
+		if !limiter.Allow("k") || !limiter.Allow("k") || limiter.Allow("k") {
+			t.Fatal("Expected two requests allowed before the refill")
+		}
+		time.Sleep(25 * time.Millisecond)
+		if !limiter.Allow("k") || !limiter.Allow("k") || limiter.Allow("k") {
+			t.Error("Expected two requests allowed after the refill")
+		}
+	})
+
+	t.Run("Spoofed Forwarding Headers", func(t *testing.T) {
+		handler := RateLimitWithOptions(RateLimitOptions{
+			Requests:       1,
+			Duration:       time.Hour,
+			TrustedIPs:     []string{"10.0.0.9"},
+			TrustedProxies: []string{"10.0.0.1"},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		serve := func(remoteAddr string, header map[string]string) int {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.RemoteAddr = remoteAddr
+			for k, v := range header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w.Code
+		}
+
+		spoofed := map[string]string{"X-Real-IP": "10.0.0.9", "X-Forwarded-For": "10.0.0.9"}
+		serve("203.0.113.5:1000", spoofed)
+		if code := serve("203.0.113.5:1001", spoofed); code != http.StatusTooManyRequests {
+			t.Errorf("Expected spoofed headers to be limited, got %d", code)
+		}
+		for i := 0; i < 3; i++ {
+			if code := serve("10.0.0.1:1000", map[string]string{"X-Forwarded-For": "10.0.0.9"}); code != http.StatusOK {
+				t.Errorf("Expected a trusted IP forwarded by a trusted proxy to bypass the limit, got %d", code)
+			}
+		}
+	})
+}
+
+func TestCacheAuthenticated(t *testing.T) {
+	var calls atomic.Int32
+	handler := func(header, value string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			if header != "" {
+				w.Header().Set(header, value)
+			}
+			w.Write([]byte(r.Header.Get("Authorization") + "#" + string(rune('0'+n))))
+		})
+	}
+	get := func(h http.Handler, auth string) string {
+		r := httptest.NewRequest(MethodGet, "/me", nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	t.Run("Bypass By Default", func(t *testing.T) {
+		calls.Store(0)
+		h := Cache(time.Minute)(handler("", ""))
+		if a, b := get(h, "Bearer alice"), get(h, "Bearer alice"); a == b {
+			t.Errorf("Expected authenticated requests to bypass the cache, got %q twice", a)
+		}
+		if a, b := get(h, ""), get(h, ""); a != b {
+			t.Errorf("Expected anonymous requests cached, got %q and %q", a, b)
+		}
+	})
+
+	t.Run("Per User", func(t *testing.T) {
+		calls.Store(0)
+		h := CacheWithOptions(CacheOptions{Duration: time.Minute, Authenticated: CachePerUser})(handler("Cache-Control", "private"))
+		alice := get(h, "Bearer alice")
+		if got := get(h, "Bearer alice"); got != alice {
+			t.Errorf("Expected alice's response cached, got %q and %q", alice, got)
+		}
+		if got := get(h, "Bearer bob"); got != "Bearer bob#2" {
+			t.Errorf("Expected bob to get his own entry, got %q", got)
+		}
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		calls.Store(0)
+		h := CacheWithOptions(CacheOptions{Duration: time.Minute, Authenticated: CacheShared})(handler("", ""))
+		if a, b := get(h, "Bearer alice"), get(h, "Bearer bob"); a != b {
+			t.Errorf("Expected one shared entry, got %q and %q", a, b)
+		}
+	})
+
+	t.Run("Session Cookie", func(t *testing.T) {
+		calls.Store(0)
+		h := CacheWithOptions(CacheOptions{Duration: time.Minute, SessionCookies: []string{"sid"}})(handler("", ""))
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest(MethodGet, "/me", nil)
+			r.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		if calls.Load() != 2 {
+			t.Errorf("Expected requests with a session cookie to bypass the cache, got %d calls", calls.Load())
+		}
+	})
+
+	for _, tc := range []struct{ header, value string }{
+		{"Set-Cookie", "sid=abc"},
+		{"Cache-Control", "no-store"},
+		{"Cache-Control", "max-age=60, private"},
+	} {
+		t.Run("Not Stored "+tc.value, func(t *testing.T) {
+			calls.Store(0)
+			h := Cache(time.Minute)(handler(tc.header, tc.value))
+			if a, b := get(h, ""), get(h, ""); a == b {
+				t.Errorf("Expected a response with %s: %s not to be stored, got %q twice", tc.header, tc.value, a)
+			}
+		})
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	defer PurgeCache("/posts/")
+	var calls atomic.Int32
+	exists := false
+	h := CacheWithOptions(CacheOptions{Duration: time.Minute, NegativeDuration: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("post"))
+	}))
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := get("/posts/1"); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected 404, got %d", w.Code)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the 404 served from the cache, got %d calls", calls.Load())
+	}
+
+	exists = true
+	get("/other")
+	if n := PurgeCache("/posts/"); n != 1 {
+		t.Errorf("Expected 1 entry purged, got %d", n)
+	}
+	if w := get("/posts/1"); w.Code != http.StatusOK || w.Body.String() != "post" {
+		t.Errorf("Expected the created post after the purge, got %d %q", w.Code, w.Body.String())
+	}
+
+	plain := Cache(time.Minute)(http.NotFoundHandler())
+	plain.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/missing", nil))
+	if n := PurgeCache("/missing"); n != 0 {
+		t.Errorf("Expected 404s not cached without NegativeDuration, got %d entries", n)
+	}
+}
+
+func TestSlowRequests(t *testing.T) {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(orig)
+
+	var slow []RequestInfo
+	mux := New()
+	mux.Use(LoggerWithOptions(LoggerOptions{
+		SlowThreshold: 20 * time.Millisecond,
+		SlowLogger:    slog.New(slog.NewTextHandler(&buf, nil)),
+		OnSlow:        func(info RequestInfo) { slow = append(slow, info) },
+	}))
+	mux.Handle("/reports/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("slow") {
+			time.Sleep(30 * time.Millisecond)
+		}
+	}), MethodGet)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/reports/7", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/reports/9?slow=1", nil))
+
+	if len(slow) != 1 {
+		t.Fatalf("Expected 1 slow request, got %d", len(slow))
+	}
+	if got := slow[0]; got.Route != "/reports/:id" || got.Params["id"] != "9" || got.Status != http.StatusOK {
+		t.Errorf("Expected /reports/:id with id 9, got %s %v %d", got.Route, got.Params, got.Status)
+	}
+	line := buf.String()
+	for _, want := range []string{"level=WARN", `msg="slow request"`, "route=/reports/:id", "params.id=9"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("Expected %s in the slow log, got %s", want, line)
+		}
+	}
+}
+
+func TestLogSinks(t *testing.T) {
+	var std, stdout, failures, audit bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&std)
+	defer log.SetOutput(orig)
+
+	mux := New()
+	mux.LogTo(
+		LogSink{Handler: slog.NewJSONHandler(&stdout, nil)},
+		LogSink{Handler: slog.NewTextHandler(&failures, nil), Level: slog.LevelError},
+		LogSink{
+			Handler: slog.NewJSONHandler(&audit, nil),
+			Filter:  func(info RequestInfo) bool { return info.Method != MethodGet },
+		},
+	)
+	mux.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == MethodDelete {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), MethodGet, MethodDelete)
+
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/users/1?token=secret", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodDelete, "/users/2", nil))
+
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[0], `"route":"/users/:id"`) || !strings.Contains(lines[0], `"params":{"id":"1"}`) ||
+		!strings.Contains(lines[0], `"query":"token=%5BREDACTED%5D"`) || !strings.Contains(lines[1], `"level":"ERROR"`) {
+		t.Errorf("Expected both requests as JSON, got %s", stdout.String())
+	}
+	if out := failures.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, "method=DELETE") {
+		t.Errorf("Expected only the failed request in the error sink, got %s", out)
+	}
+	if out := audit.String(); strings.Count(out, "\n") != 1 || !strings.Contains(out, `"method":"DELETE"`) {
+		t.Errorf("Expected only the write in the audit sink, got %s", out)
+	}
+	if std.Len() != 0 {
+		t.Errorf("Expected no line from the standard logger, got %s", std.String())
+	}
+}
+
+func TestAcceptEncoding(t *testing.T) {
+	handler := Compression()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("compressible ", 100)))
+	}))
+
+	tests := []struct {
+		header   string
+		status   int
+		encoding string
+	}{
+		{"", http.StatusOK, ""},
+		{"gzip", http.StatusOK, "gzip"},
+		{"GZIP;q=0.8", http.StatusOK, "gzip"},
+		{"x-gzip", http.StatusOK, "gzip"},
+		{"br, deflate", http.StatusOK, ""},
+		{"notgzip", http.StatusOK, ""},
+		{"gzip;q=0", http.StatusOK, ""},
+		{"gzip;q=0.5, identity", http.StatusOK, ""},
+		{"gzip;q=0.5, identity;q=0.5", http.StatusOK, "gzip"},
+		{"*", http.StatusOK, "gzip"},
+		{"*;q=0, identity", http.StatusOK, ""},
+		{"identity;q=0", http.StatusNotAcceptable, ""},
+		{"br, *;q=0", http.StatusNotAcceptable, ""},
+		{"gzip, identity;q=0", http.StatusOK, "gzip"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Accept-Encoding", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status || w.Header().Get("Content-Encoding") != tt.encoding {
+			t.Errorf("Accept-Encoding %q: expected %d %q, got %d %q", tt.header, tt.status, tt.encoding, w.Code, w.Header().Get("Content-Encoding"))
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MirrorOptions configures the Mirror middleware
+type MirrorOptions struct {
+	// Target is the shadow upstream, e.g. "http://orders-v2:8080". A path
+	// in it prefixes the request paths, as for Mux.Proxy.
+	Target string
+
+	// Percent of requests to mirror, from 0 to 100. Zero, including an
+	// unset value, turns mirroring off and leaves the handler unwrapped;
+	// Target is not required then.
+	Percent float64
+
+	// MaxBodySize is the largest request body that is mirrored; requests
+	// with larger bodies are only served normally. Defaults to 64KB.
+	MaxBodySize int64
+
+	// Timeout bounds each mirrored request. Defaults to 5s.
+	Timeout time.Duration
+
+	// MaxInFlight caps concurrent mirrored requests; requests beyond it are
+	// not mirrored. Defaults to 100.
+	MaxInFlight int
+
+	// Client sends mirrored requests. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Logger receives mirror failures at debug level. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Mirror asynchronously duplicates a share of requests to a shadow upstream
+// and discards its responses, so a new service version can be validated
+// against production traffic without affecting clients
+func Mirror(opts MirrorOptions) func(http.Handler) http.Handler {
+	if opts.Percent <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	target := mustParseTarget(opts.Target)
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = 64 << 10
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxInFlight == 0 {
+		opts.MaxInFlight = 100
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	slots := make(chan struct{}, opts.MaxInFlight)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Percent < 100 && rand.Float64()*100 >= opts.Percent {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > opts.MaxBodySize {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				body, err = io.ReadAll(io.LimitReader(r.Body, opts.MaxBodySize+1))
+				if err != nil || int64(len(body)) > opts.MaxBodySize {
+					// Too large or unreadable: restore what was read and skip mirroring
+					r.Body = struct {
+						io.Reader
+						io.Closer
+					}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+					next.ServeHTTP(w, r)
+					return
+				}
+				r.Body.Close()
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			select {
+			case slots <- struct{}{}:
+				shadow := mirrorRequest(r, target, body)
+				go func() {
+					defer func() { <-slots }()
+					ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+					defer cancel()
+
+					resp, err := opts.Client.Do(shadow.WithContext(ctx))
+					if err != nil {
+						opts.Logger.Debug("mirror request failed",
+							slog.String("target", opts.Target),
+							slog.String("path", shadow.URL.Path),
+							slog.String("error", err.Error()),
+						)
+						return
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}()
+			default:
+				// Shadow upstream is saturated; drop the copy
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// mirrorRequest copies r for sending to another host. It is detached from
+// r's context so that it isn't canceled when the original request completes.
+func mirrorRequest(r *http.Request, target *url.URL, body []byte) *http.Request {
+	shadow := r.Clone(context.Background())
+	shadow.RequestURI = ""
+	shadow.URL.Scheme = target.Scheme
+	shadow.URL.Host = target.Host
+	if target.Path != "" {
+		shadow.URL.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(r.URL.Path, "/")
+		shadow.URL.RawPath = ""
+	}
+	if target.RawQuery != "" {
+		shadow.URL.RawQuery = strings.TrimSuffix(target.RawQuery+"&"+r.URL.RawQuery, "&")
+	}
+	shadow.Host = target.Host
+	shadow.Header.Set("X-Mirrored-From", r.Host)
+	shadow.Body = http.NoBody
+	shadow.ContentLength = int64(len(body))
+	if body != nil {
+		shadow.Body = io.NopCloser(bytes.NewReader(body))
+		shadow.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return shadow
+}
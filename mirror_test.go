@@ -0,0 +1,89 @@
+package GoFlow
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	type mirrored struct {
+		method, path, body, origin string
+	}
+	received := make(chan mirrored, 10)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- mirrored{r.Method, r.URL.RequestURI(), string(body), r.Header.Get("X-Mirrored-From")}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Duplicates Requests", func(t *testing.T) {
+		handler := Mirror(MirrorOptions{Target: shadow.URL, Percent: 100, Logger: quiet})(echo)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodPost, "http://api.example/orders?dry=1", strings.NewReader(`{"id":1}`)))
+
+		if w.Code != http.StatusOK || w.Body.String() != `{"id":1}` {
+			t.Errorf("Expected primary response to be unaffected, got %d '%s'", w.Code, w.Body.String())
+		}
+
+		select {
+		case m := <-received:
+			expected := mirrored{MethodPost, "/orders?dry=1", `{"id":1}`, "api.example"}
+			if m != expected {
+				t.Errorf("Expected mirrored request %+v, got %+v", expected, m)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Request was not mirrored")
+		}
+	})
+
+	t.Run("Target Path", func(t *testing.T) {
+		handler := Mirror(MirrorOptions{Target: shadow.URL + "/v2/?shadow=1", Percent: 100, Logger: quiet})(echo)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "http://api.example/orders?dry=1", nil))
+
+		select {
+		case m := <-received:
+			if m.path != "/v2/orders?shadow=1&dry=1" {
+				t.Errorf("Expected mirrored path '/v2/orders?shadow=1&dry=1', got '%s'", m.path)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Request was not mirrored")
+		}
+	})
+
+	t.Run("Percentage And Body Limit", func(t *testing.T) {
+		none := Mirror(MirrorOptions{Target: shadow.URL, Percent: 0.0001, Logger: quiet})(echo)
+		unset := Mirror(MirrorOptions{Target: shadow.URL, Logger: quiet})(echo)
+		limited := Mirror(MirrorOptions{Target: shadow.URL, Percent: 100, MaxBodySize: 4, Logger: quiet})(echo)
+
+		for i := 0; i < 20; i++ {
+			none.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+			unset.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodPut, "/", io.NopCloser(strings.NewReader("larger than four bytes")))
+		r.ContentLength = -1
+		limited.ServeHTTP(w, r)
+
+		if w.Body.String() != "larger than four bytes" {
+			t.Errorf("Expected oversized body to reach the handler intact, got '%s'", w.Body.String())
+		}
+		select {
+		case m := <-received:
+			t.Errorf("Expected nothing to be mirrored, got %+v", m)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+}
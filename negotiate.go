@@ -0,0 +1,203 @@
+package GoFlow
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// contentTypes are the media types a route declared with Consumes and Produces
+type contentTypes struct {
+	consumes []string
+	produces []string
+}
+
+type (
+	contentTypesKey   struct{}
+	negotiatedTypeKey struct{}
+)
+
+// Consumes restricts the request Content-Type of this route to types, e.g.
+// "application/json" or "image/*". Requests with a body of any other type
+// are answered with 415 Unsupported Media Type before the handler runs.
+func (rt *Route) Consumes(types ...string) *Route {
+	ct, _ := rt.Value(contentTypesKey{}).(contentTypes)
+	ct.consumes = normalizeMediaTypes(types)
+	rt.Set(contentTypesKey{}, ct)
+	rt.compile()
+	return rt
+}
+
+// Produces declares the response types of this route in order of
+// preference. Requests whose Accept header allows none of them are answered
+// with 406 Not Acceptable; otherwise NegotiatedType reports the best match.
+func (rt *Route) Produces(types ...string) *Route {
+	ct, _ := rt.Value(contentTypesKey{}).(contentTypes)
+	ct.produces = normalizeMediaTypes(types)
+	rt.Set(contentTypesKey{}, ct)
+	rt.compile()
+	return rt
+}
+
+// Consumes sets Route.Consumes for routes registered afterwards on this mux
+// or group
+func (m *Mux) Consumes(types ...string) {
+	ct, _ := m.meta[contentTypesKey{}].(contentTypes)
+	ct.consumes = normalizeMediaTypes(types)
+	m.Set(contentTypesKey{}, ct)
+}
+
+// Produces sets Route.Produces for routes registered afterwards on this mux
+// or group
+func (m *Mux) Produces(types ...string) {
+	ct, _ := m.meta[contentTypesKey{}].(contentTypes)
+	ct.produces = normalizeMediaTypes(types)
+	m.Set(contentTypesKey{}, ct)
+}
+
+// NegotiatedType returns the type chosen from the route's Produces list for
+// the request's Accept header, or "" if the route declares none
+func NegotiatedType(ctx context.Context) string {
+	t, _ := ctx.Value(negotiatedTypeKey{}).(string)
+	return t
+}
+
+// Negotiate returns the offer the Accept header of r prefers, or "" if it
+// accepts none of them. Offers are tried in order when the header ranks
+// several equally; a missing Accept header accepts the first offer.
+func Negotiate(r *http.Request, offers ...string) string {
+	accept := r.Header.Values("Accept")
+	if len(accept) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+	ranges := parseAccept(strings.Join(accept, ","))
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// negotiate answers 415 and 406 for requests the route can't handle
+func negotiate(ct contentTypes, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(ct.consumes) > 0 && hasBody(r) {
+			mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !matchesAny(ct.consumes, mt) {
+				switch r.Method {
+				case MethodPost:
+					w.Header().Set("Accept-Post", strings.Join(ct.consumes, ", "))
+				case MethodPatch:
+					w.Header().Set("Accept-Patch", strings.Join(ct.consumes, ", "))
+				}
+				http.Error(w, StatusText(r.Context(), http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+
+		if len(ct.produces) > 0 {
+			AddVary(w.Header(), "Accept")
+			t := Negotiate(r, ct.produces...)
+			if t == "" {
+				http.Error(w, StatusText(r.Context(), http.StatusNotAcceptable), http.StatusNotAcceptable)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), negotiatedTypeKey{}, t))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasBody reports whether r carries a body. A Content-Type header alone,
+// as some clients send on every request, doesn't count.
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || len(r.TransferEncoding) > 0 ||
+		r.ContentLength == -1 && r.Body != nil && r.Body != http.NoBody
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mt, q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching offer
+func acceptQuality(ranges []acceptRange, offer string) float64 {
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		s := mediaTypeSpecificity(ar.mediaType, offer)
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q
+}
+
+// mediaTypeSpecificity reports how closely pattern matches mt: 2 for an
+// exact match, 1 for "type/*", 0 for "*/*" and -1 for no match
+func mediaTypeSpecificity(pattern, mt string) int {
+	switch {
+	case pattern == mt:
+		return 2
+	case pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mt, pattern[:len(pattern)-1]):
+		return 1
+	}
+	return -1
+}
+
+func matchesAny(patterns []string, mt string) bool {
+	for _, p := range patterns {
+		if mediaTypeSpecificity(p, mt) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeMediaTypes(types []string) []string {
+	normalized, err := parseMediaTypes(types)
+	if err != nil {
+		panic("GoFlow: " + err.Error())
+	}
+	return normalized
+}
+
+func parseMediaTypes(types []string) ([]string, error) {
+	normalized := make([]string, 0, len(types))
+	for _, t := range types {
+		mt, _, err := mime.ParseMediaType(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid media type %q", t)
+		}
+		normalized = append(normalized, mt)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,78 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentNegotiation(t *testing.T) {
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(NegotiatedType(r.Context())))
+	})
+
+	mux := New()
+	mux.Handle("/users", echo, MethodPost, MethodPatch).
+		Consumes("application/json", "text/*").
+		Produces("application/json", "text/csv")
+	mux.Group(func(m *Mux) {
+		m.Consumes("image/*")
+		m.Handle("/avatar", echo, MethodPut)
+	})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		accept      string
+		body        string
+		status      int
+		want        string
+	}{
+		{"Accepted Type", MethodPost, "/users", "application/json; charset=utf-8", "", "{}", http.StatusOK, "application/json"},
+		{"Wildcard Subtype", MethodPost, "/users", "text/plain", "", "x", http.StatusOK, "application/json"},
+		{"Unsupported Type", MethodPost, "/users", "application/xml", "", "<x/>", http.StatusUnsupportedMediaType, ""},
+		{"Missing Type", MethodPost, "/users", "", "", "{}", http.StatusUnsupportedMediaType, ""},
+		{"No Body", MethodPost, "/users", "", "", "", http.StatusOK, "application/json"},
+		{"Type Without Body", MethodPost, "/users", "application/xml", "", "", http.StatusOK, "application/json"},
+		{"Preferred Accept", MethodPost, "/users", "", "text/csv, application/json;q=0.5", "", http.StatusOK, "text/csv"},
+		{"Range Accept", MethodPost, "/users", "", "text/*", "", http.StatusOK, "text/csv"},
+		{"Excluded Accept", MethodPost, "/users", "", "*/*, application/json;q=0", "", http.StatusOK, "text/csv"},
+		{"Unsatisfiable Accept", MethodPost, "/users", "", "application/xml", "", http.StatusNotAcceptable, ""},
+		{"Group Default", MethodPut, "/avatar", "image/png", "", "png", http.StatusOK, ""},
+		{"Group Unsupported", MethodPut, "/avatar", "text/plain", "", "x", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status code %d, got %d", tt.status, w.Code)
+			}
+			if tt.status == http.StatusOK && w.Body.String() != tt.want {
+				t.Errorf("Expected negotiated type '%s', got '%s'", tt.want, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("Accept-Post", func(t *testing.T) {
+		req := httptest.NewRequest(MethodPost, "/users", strings.NewReader("<x/>"))
+		req.Header.Set("Content-Type", "application/xml")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if got := w.Header().Get("Accept-Post"); got != "application/json, text/*" {
+			t.Errorf("Expected Accept-Post to list the accepted types, got '%s'", got)
+		}
+	})
+}
@@ -0,0 +1,339 @@
+// Package openapi generates OpenAPI 3.1 documents from the routes of a
+// GoFlow Mux, with request and response schemas derived from Go types.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jie10/GoFlow"
+)
+
+// Info is the document's info object
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Config configures document generation
+type Config struct {
+	Info Info
+
+	// Servers are base URLs of the API, e.g. "https://api.example.com"
+	Servers []string
+}
+
+// Operation documents a route. Request and response types are Go values
+// whose types are converted to JSON schemas, e.g. CreateUser{}.
+type Operation struct {
+	Summary     string
+	Description string
+	OperationID string
+	Tags        []string
+	Deprecated  bool
+
+	// Request is the JSON request body type
+	Request any
+
+	// Responses maps status codes to JSON response body types; a nil type
+	// documents a response without a body
+	Responses map[int]any
+
+	// Consumes and Produces are the request and response media types.
+	// Both default to the route's Consumes and Produces, then to
+	// application/json.
+	Consumes []string
+	Produces []string
+
+	// Hidden leaves the route out of the document
+	Hidden bool
+}
+
+type operationKey struct{}
+
+// Annotate attaches documentation to rt. Fields set in op take precedence
+// over the route's own annotations, e.g. rt.Summary.
+func Annotate(rt *GoFlow.Route, op Operation) *GoFlow.Route {
+	return rt.Set(operationKey{}, op)
+}
+
+// operation merges the route's annotations with any Annotate operation
+func operation(rt *GoFlow.Route) Operation {
+	doc := rt.Doc()
+	op, _ := rt.Value(operationKey{}).(Operation)
+	if op.Summary == "" {
+		op.Summary = doc.Summary
+	}
+	if op.Description == "" {
+		op.Description = doc.Description
+	}
+	if op.Tags == nil {
+		op.Tags = doc.Tags
+	}
+	if op.Request == nil {
+		op.Request = doc.Request
+	}
+	if op.Responses == nil {
+		op.Responses = doc.Responses
+	}
+	if op.Consumes == nil {
+		op.Consumes = doc.Consumes
+	}
+	if op.Produces == nil {
+		op.Produces = doc.Produces
+	}
+	op.Deprecated = op.Deprecated || doc.Deprecated
+	op.Hidden = op.Hidden || doc.Hidden
+	return op
+}
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []ServerObject      `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// ServerObject is a base URL of the API
+type ServerObject struct {
+	URL string `json:"url"`
+}
+
+// PathItem maps lowercase method names to operations
+type PathItem map[string]*OperationObject
+
+// OperationObject is a documented method on a path
+type OperationObject struct {
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	OperationID string               `json:"operationId,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Deprecated  bool                 `json:"deprecated,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one status code of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema for one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the named schemas referenced from operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// documentedMethods are the methods OpenAPI can describe, in output order
+var documentedMethods = []string{
+	GoFlow.MethodGet, GoFlow.MethodPut, GoFlow.MethodPost, GoFlow.MethodDelete,
+	GoFlow.MethodOptions, GoFlow.MethodHead, GoFlow.MethodPatch, GoFlow.MethodTrace,
+}
+
+// Generate builds a document from the routes registered on mux
+func Generate(mux *GoFlow.Mux, config Config) *Document {
+	doc := &Document{
+		OpenAPI: "3.1.0",
+		Info:    config.Info,
+		Paths:   make(map[string]PathItem),
+	}
+	for _, server := range config.Servers {
+		doc.Servers = append(doc.Servers, ServerObject{URL: server})
+	}
+	schemas := newSchemaGenerator()
+
+	for _, rt := range mux.Routes() {
+		op := operation(rt)
+		if op.Hidden {
+			continue
+		}
+		path, params := convertPattern(rt.Pattern())
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+
+		methods := rt.Methods()
+		for _, method := range documentedMethods {
+			if !contains(methods, method) {
+				continue
+			}
+			// HEAD is added implicitly to GET routes
+			if method == GoFlow.MethodHead && contains(methods, GoFlow.MethodGet) {
+				continue
+			}
+			item[strings.ToLower(method)] = buildOperation(op, params, schemas)
+		}
+	}
+
+	if len(schemas.components) > 0 {
+		doc.Components = &Components{Schemas: schemas.components}
+	}
+	return doc
+}
+
+func buildOperation(op Operation, params []Parameter, schemas *schemaGenerator) *OperationObject {
+	o := &OperationObject{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.OperationID,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Parameters:  params,
+		Responses:   make(map[string]*Response),
+	}
+	if op.Request != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content:  content(op.Consumes, schemas.schemaOf(op.Request)),
+		}
+	}
+
+	codes := make([]int, 0, len(op.Responses))
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		resp := &Response{Description: http.StatusText(code)}
+		if body := op.Responses[code]; body != nil {
+			resp.Content = content(op.Produces, schemas.schemaOf(body))
+		}
+		o.Responses[strconv.Itoa(code)] = resp
+	}
+	// The router answers these itself for declared media types
+	if len(op.Consumes) > 0 && o.Responses["415"] == nil {
+		o.Responses["415"] = &Response{Description: http.StatusText(http.StatusUnsupportedMediaType)}
+	}
+	if len(op.Produces) > 0 && o.Responses["406"] == nil {
+		o.Responses["406"] = &Response{Description: http.StatusText(http.StatusNotAcceptable)}
+	}
+	if len(o.Responses) == 0 {
+		o.Responses["default"] = &Response{Description: "Response"}
+	}
+	return o
+}
+
+// content maps each media type to schema, defaulting to application/json
+func content(types []string, schema *Schema) map[string]MediaType {
+	if len(types) == 0 {
+		types = []string{"application/json"}
+	}
+	c := make(map[string]MediaType, len(types))
+	for _, t := range types {
+		c[t] = MediaType{Schema: schema}
+	}
+	return c
+}
+
+// convertPattern turns "/users/:id|^\d+$/files/..." into an OpenAPI path
+// template and its path parameters
+func convertPattern(pattern string) (string, []Parameter) {
+	var params []Parameter
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "...":
+			segments[i] = "{path}"
+			params = append(params, Parameter{
+				Name: "path", In: "path", Required: true,
+				Description: "Remaining path segments",
+				Schema:      &Schema{Type: "string"},
+			})
+		case strings.HasPrefix(segment, ":"):
+			name, rx, _ := strings.Cut(segment[1:], "|")
+			if rx != "" && (!strings.HasPrefix(rx, "^") || !strings.HasSuffix(rx, "$")) {
+				// Route constraints match whole segments
+				rx = "^(?:" + rx + ")$"
+			}
+			segments[i] = "{" + name + "}"
+			params = append(params, Parameter{
+				Name: name, In: "path", Required: true,
+				Schema: &Schema{Type: "string", Pattern: rx},
+			})
+		}
+	}
+	return "/" + strings.Join(segments, "/"), params
+}
+
+// JSON encodes the document
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML encodes the document
+func (d *Document) YAML() ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToYAML(b)
+}
+
+// Handler serves the document for mux as JSON, generated on each request so
+// that it reflects routes added at runtime
+func Handler(mux *GoFlow.Mux, config Config) http.Handler {
+	return serve(mux, config, "application/json", (*Document).JSON)
+}
+
+// YAMLHandler serves the document for mux as YAML
+func YAMLHandler(mux *GoFlow.Mux, config Config) http.Handler {
+	return serve(mux, config, "application/yaml", (*Document).YAML)
+}
+
+// Mount serves the document at /openapi.json and /openapi.yaml. Those
+// routes are left out of the document.
+func Mount(mux *GoFlow.Mux, config Config) {
+	mux.Handle("/openapi.json", Handler(mux, config), GoFlow.MethodGet).Hidden()
+	mux.Handle("/openapi.yaml", YAMLHandler(mux, config), GoFlow.MethodGet).Hidden()
+}
+
+func serve(mux *GoFlow.Mux, config Config, contentType string, encode func(*Document) ([]byte, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := encode(Generate(mux, config))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(b)
+	})
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jie10/GoFlow"
+)
+
+type Address struct {
+	City string `json:"city"`
+}
+
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Address   *Address  `json:"address,omitempty"`
+	Manager   *User     `json:"manager,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	password  string
+}
+
+type CreateUser struct {
+	Name string `json:"name"`
+}
+
+func newMux() *GoFlow.Mux {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux := GoFlow.New()
+	Annotate(mux.Handle("/users/:id|^\\d+$", noop, GoFlow.MethodGet), Operation{
+		Summary:   "Get a user",
+		Tags:      []string{"users"},
+		Responses: map[int]any{200: User{}, 404: nil},
+	})
+	mux.Handle("/users", noop, GoFlow.MethodPost).
+		Summary("Create a user").
+		Request(CreateUser{}).
+		Response(201, &User{}).
+		Consumes("application/json", "application/x-www-form-urlencoded").
+		Produces("application/json")
+	mux.Handle("/users/:id|^\\d+$", noop, GoFlow.MethodDelete).
+		Summary("Delete a user").
+		Deprecated().
+		Response(204, nil)
+	mux.Handle("/files/...", noop, GoFlow.MethodGet)
+	mux.MountAPIDocs("/docs")
+	Mount(mux, Config{Info: Info{Title: "Users API", Version: "1.0.0"}, Servers: []string{"https://api.example.com"}})
+	return mux
+}
+
+func TestGenerate(t *testing.T) {
+	doc := Generate(newMux(), Config{Info: Info{Title: "Users API", Version: "1.0.0"}})
+
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Users API" {
+		t.Errorf("Expected OpenAPI 3.1.0 'Users API', got %s '%s'", doc.OpenAPI, doc.Info.Title)
+	}
+	if _, ok := doc.Paths["/openapi.json"]; ok {
+		t.Error("Expected the spec endpoints to be hidden")
+	}
+
+	get := doc.Paths["/users/{id}"]["get"]
+	if get == nil {
+		t.Fatalf("Expected GET /users/{id}, got %v", doc.Paths)
+	}
+	if _, ok := doc.Paths["/users/{id}"]["head"]; ok {
+		t.Error("Expected implicit HEAD to be left out")
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].Name != "id" || get.Parameters[0].Schema.Pattern != `^\d+$` {
+		t.Errorf("Expected id path parameter with pattern, got %+v", get.Parameters)
+	}
+	if get.Responses["200"].Content["application/json"].Schema.Ref != "#/components/schemas/User" {
+		t.Errorf("Expected 200 to reference User, got %+v", get.Responses["200"])
+	}
+	if get.Responses["404"].Content != nil || get.Responses["404"].Description != "Not Found" {
+		t.Errorf("Expected 404 without body, got %+v", get.Responses["404"])
+	}
+
+	del := doc.Paths["/users/{id}"]["delete"]
+	if del == nil || !del.Deprecated || del.Summary != "Delete a user" || del.Responses["204"] == nil {
+		t.Errorf("Expected deprecated DELETE /users/{id} from route annotations, got %+v", del)
+	}
+	if _, ok := doc.Paths["/docs"]; ok {
+		t.Error("Expected the API docs page to be hidden")
+	}
+
+	post := doc.Paths["/users"]["post"]
+	if post == nil || post.RequestBody == nil || post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/CreateUser" {
+		t.Fatalf("Expected POST /users with CreateUser body, got %+v", post)
+	}
+	if _, ok := post.RequestBody.Content["application/x-www-form-urlencoded"]; !ok {
+		t.Errorf("Expected declared request media types, got %v", post.RequestBody.Content)
+	}
+	if post.Responses["415"] == nil || post.Responses["406"] == nil {
+		t.Errorf("Expected 415 and 406 responses for declared media types, got %v", post.Responses)
+	}
+
+	files := doc.Paths["/files/{path}"]["get"]
+	if files == nil || files.Responses["default"] == nil {
+		t.Errorf("Expected undocumented wildcard route with default response, got %+v", files)
+	}
+
+	user := doc.Components.Schemas["User"]
+	if user == nil {
+		t.Fatal("Expected User schema")
+	}
+	if !equal(user.Required, []string{"id", "name", "created_at"}) {
+		t.Errorf("Expected required id, name, created_at, got %v", user.Required)
+	}
+	if user.Properties["created_at"].Format != "date-time" || user.Properties["id"].Format != "int64" {
+		t.Errorf("Expected date-time and int64 formats, got %+v %+v", user.Properties["created_at"], user.Properties["id"])
+	}
+	if user.Properties["manager"].Ref != "#/components/schemas/User" || user.Properties["tags"].Items.Type != "string" {
+		t.Errorf("Expected recursive reference and string array, got %+v %+v", user.Properties["manager"], user.Properties["tags"])
+	}
+	if _, ok := user.Properties["password"]; ok {
+		t.Error("Expected unexported field to be skipped")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	mux := newMux()
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(GoFlow.MethodGet, "/openapi.json", nil))
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || doc["openapi"] != "3.1.0" {
+		t.Errorf("Expected JSON document, got %v: %s", err, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(GoFlow.MethodGet, "/openapi.yaml", nil))
+	if w.Header().Get("Content-Type") != "application/yaml" {
+		t.Errorf("Expected YAML content type, got '%s'", w.Header().Get("Content-Type"))
+	}
+	for _, want := range []string{
+		`openapi: "3.1.0"`,
+		"servers:\n  -\n    url: \"https://api.example.com\"",
+		`  "/users/{id}":`,
+		`$ref: "#/components/schemas/User"`,
+		`        "404":`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", want, w.Body.String())
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// yamlNode is a JSON value with object keys kept in document order
+type yamlNode struct {
+	object bool
+	array  bool
+	keys   []string
+	values []*yamlNode
+	scalar string // JSON literal, valid as YAML
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_.$-]*$`)
+
+// jsonToYAML re-encodes a JSON document as block-style YAML
+func jsonToYAML(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	root, err := decodeNode(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if root.object || root.array {
+		writeYAML(&buf, root, 0)
+	} else {
+		buf.WriteString(root.scalar + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeNode(dec *json.Decoder) (*yamlNode, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		n := &yamlNode{object: tok == '{', array: tok == '['}
+		for dec.More() {
+			if n.object {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.keys = append(n.keys, key.(string))
+			}
+			child, err := decodeNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.values = append(n.values, child)
+		}
+		// Consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	default:
+		literal, err := json.Marshal(tok)
+		if err != nil {
+			return nil, err
+		}
+		return &yamlNode{scalar: string(literal)}, nil
+	}
+}
+
+func writeYAML(buf *bytes.Buffer, n *yamlNode, indent int) {
+	pad := strings.Repeat("  ", indent)
+	for i, child := range n.values {
+		if n.object {
+			buf.WriteString(pad + yamlKey(n.keys[i]) + ":")
+		} else {
+			buf.WriteString(pad + "-")
+		}
+
+		switch {
+		case !child.object && !child.array:
+			buf.WriteString(" " + child.scalar + "\n")
+		case len(child.values) == 0 && child.object:
+			buf.WriteString(" {}\n")
+		case len(child.values) == 0:
+			buf.WriteString(" []\n")
+		default:
+			buf.WriteString("\n")
+			writeYAML(buf, child, indent+1)
+		}
+	}
+}
+
+func yamlKey(key string) string {
+	switch strings.ToLower(key) {
+	case "true", "false", "null", "yes", "no", "on", "off", "y", "n", "~":
+	default:
+		if plainKey.MatchString(key) {
+			return key
+		}
+	}
+	b, _ := json.Marshal(key)
+	return string(b)
+}
@@ -0,0 +1,110 @@
+package GoFlow
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// orderIssue is a middleware misordering found on one or more routes
+type orderIssue struct {
+	// fatal issues break requests; the others waste work or miss cases
+	fatal   bool
+	message string
+}
+
+// authMiddleware reject unauthenticated requests, including preflights
+var authMiddleware = map[string]bool{
+	"GoFlow.JWT":                         true,
+	"GoFlow.APIKeyAuth":                  true,
+	"GoFlow.BasicAuth":                   true,
+	"GoFlow.Authorize":                   true,
+	"GoFlow.RequireScopes":               true,
+	"GoFlow.RequireRole":                 true,
+	"GoFlow.RequireSignedURL":            true,
+	"GoFlow.VerifyWebhook":               true,
+	"auth.(*Authenticator).RequireLogin": true,
+}
+
+// orderIssues checks each route's middleware, from the outermost in,
+// against the composition rules. Routes sharing a stack report an issue
+// once, naming the first route and counting the others.
+func (m *Mux) orderIssues() []orderIssue {
+	var issues []orderIssue
+	first := make(map[string]string)
+	counts := make(map[string]int)
+	for _, rt := range m.Routes() {
+		var names []string
+		for _, mw := range rt.inherited() {
+			names = append(names, middlewareName(mw))
+		}
+		for _, mw := range rt.local {
+			names = append(names, middlewareName(mw))
+		}
+		for _, issue := range append(checkOrder(names), rt.unmatchedWithout()...) {
+			if _, ok := first[issue.message]; !ok {
+				first[issue.message] = rt.pattern
+				issues = append(issues, issue)
+			}
+			counts[issue.message]++
+		}
+	}
+	for i, issue := range issues {
+		where := first[issue.message]
+		if n := counts[issue.message]; n > 1 {
+			where += fmt.Sprintf(" and %d more routes", n-1)
+		}
+		issues[i].message = where + ": " + issue.message
+	}
+	return issues
+}
+
+// unmatchedWithout reports names given to Without that remove nothing, such
+// as a typo, which would otherwise leave the middleware running silently
+func (rt *Route) unmatchedWithout() []orderIssue {
+	var issues []orderIssue
+	without, _ := rt.Value(withoutKey{}).([]string)
+	for _, name := range without {
+		if !slices.ContainsFunc(rt.middlewares, func(mw func(http.Handler) http.Handler) bool {
+			return middlewareNamed(middlewareName(mw), []string{name})
+		}) {
+			issues = append(issues, orderIssue{fatal: true, message: fmt.Sprintf("Without(%q) matches no inherited middleware", name)})
+		}
+	}
+	return issues
+}
+
+// checkOrder applies the rules to one stack of middleware names
+func checkOrder(names []string) []orderIssue {
+	var issues []orderIssue
+	index := func(match func(string) bool) int {
+		for i, name := range names {
+			if match(name) {
+				return i
+			}
+		}
+		return -1
+	}
+	is := func(want string) func(string) bool {
+		return func(name string) bool { return name == want || name == want+"WithOptions" }
+	}
+
+	if i := index(is("GoFlow.Recovery")); i > 0 {
+		issues = append(issues, orderIssue{message: fmt.Sprintf(
+			"%s runs outside Recovery, so its panics aren't recovered; register Recovery first", shortName(names[0]))})
+	}
+	if cors, auth := index(is("GoFlow.CORS")), index(func(name string) bool { return authMiddleware[name] }); cors > auth && auth >= 0 {
+		issues = append(issues, orderIssue{fatal: true, message: fmt.Sprintf(
+			"CORS runs inside %s, so preflight requests are rejected without CORS headers; register CORS first", shortName(names[auth]))})
+	}
+	if gzip, cache := index(is("GoFlow.Compression")), index(is("GoFlow.Cache")); gzip >= 0 && gzip < cache {
+		issues = append(issues, orderIssue{message: "Compression runs outside Cache, so every cache hit is compressed again; register Cache first"})
+	}
+	return issues
+}
+
+// shortName turns "GoFlow.CORSWithOptions" into "CORS"
+func shortName(name string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(name, "GoFlow."), "WithOptions")
+}
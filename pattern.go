@@ -0,0 +1,213 @@
+package GoFlow
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// segmentMatcher tests a path segment against a parameter's constraint
+type segmentMatcher interface {
+	MatchString(segment string) bool
+}
+
+// literalMatcher accepts a fixed set of segments, e.g. ^(?:en|de)$
+type literalMatcher map[string]bool
+
+func (l literalMatcher) MatchString(segment string) bool {
+	return l[segment]
+}
+
+// classMatcher accepts segments of min to max bytes from an ASCII class,
+// e.g. ^\d+$ or [a-z0-9-]{1,32}. max < 0 means unbounded.
+type classMatcher struct {
+	set      [4]uint64
+	min, max int
+}
+
+func (c *classMatcher) MatchString(segment string) bool {
+	if len(segment) < c.min || c.max >= 0 && len(segment) > c.max {
+		return false
+	}
+	for i := 0; i < len(segment); i++ {
+		if b := segment[i]; c.set[b>>6]&(1<<(b&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidatePattern reports whether pattern is a valid route pattern: every
+// parameter is named and its constraint compiles, and a wildcard is the
+// last segment. Handle panics with the same error.
+func ValidatePattern(pattern string) error {
+	if err := checkPattern(pattern); err != nil {
+		return fmt.Errorf("GoFlow: pattern %s: %v", pattern, err)
+	}
+	return nil
+}
+
+func checkPattern(pattern string) error {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "...":
+			if i != len(segments)-1 {
+				return errors.New("wildcard must be the last segment")
+			}
+		case strings.HasPrefix(segment, ":"):
+			name, rx, hasRx := strings.Cut(segment[1:], "|")
+			if name == "" {
+				return errors.New("parameter without a name")
+			}
+			if hasRx {
+				if _, err := compileMatcher(rx); err != nil {
+					return fmt.Errorf("parameter :%s: %v", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compileMatcher compiles a parameter constraint. Constraints always match
+// the whole segment, as if written ^(?:rx)$. Those that only list literals
+// or repeat an ASCII character class are matched without regexp.
+func compileMatcher(rx string) (segmentMatcher, error) {
+	anchored := "^(?:" + rx + ")$"
+	re, err := syntax.Parse(anchored, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if core, ok := unanchor(re); ok {
+		if lits, ok := literals(core, 64); ok {
+			set := make(literalMatcher, len(lits))
+			for _, l := range lits {
+				set[l] = true
+			}
+			return set, nil
+		}
+		if c, ok := classRepeat(core); ok {
+			return c, nil
+		}
+	}
+	return regexp.Compile(anchored)
+}
+
+// unanchor strips the ^ and $ of an anchored expression, including anchors
+// the constraint spelled out itself
+func unanchor(re *syntax.Regexp) (*syntax.Regexp, bool) {
+	for re.Op == syntax.OpCapture {
+		re = re.Sub[0]
+	}
+	if re.Op != syntax.OpConcat {
+		return nil, false
+	}
+	sub := re.Sub
+	for len(sub) > 0 && sub[0].Op == syntax.OpBeginText {
+		sub = sub[1:]
+	}
+	for len(sub) > 0 && sub[len(sub)-1].Op == syntax.OpEndText {
+		sub = sub[:len(sub)-1]
+	}
+	for _, s := range sub {
+		if s.Op == syntax.OpBeginText || s.Op == syntax.OpEndText {
+			return nil, false
+		}
+	}
+	switch len(sub) {
+	case 0:
+		return &syntax.Regexp{Op: syntax.OpEmptyMatch}, true
+	case 1:
+		return sub[0], true
+	}
+	return &syntax.Regexp{Op: syntax.OpConcat, Sub: sub}, true
+}
+
+// literals returns every string re matches, if there are at most limit
+func literals(re *syntax.Regexp, limit int) ([]string, bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return []string{""}, true
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
+	case syntax.OpCapture:
+		return literals(re.Sub[0], limit)
+	case syntax.OpCharClass:
+		var out []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(out) == limit {
+					return nil, false
+				}
+				out = append(out, string(r))
+			}
+		}
+		return out, true
+	case syntax.OpAlternate:
+		var out []string
+		for _, sub := range re.Sub {
+			lits, ok := literals(sub, limit-len(out))
+			if !ok {
+				return nil, false
+			}
+			out = append(out, lits...)
+		}
+		return out, true
+	case syntax.OpConcat:
+		out := []string{""}
+		for _, sub := range re.Sub {
+			lits, ok := literals(sub, limit)
+			if !ok || len(out)*len(lits) > limit {
+				return nil, false
+			}
+			next := make([]string, 0, len(out)*len(lits))
+			for _, prefix := range out {
+				for _, l := range lits {
+					next = append(next, prefix+l)
+				}
+			}
+			out = next
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// classRepeat recognizes an ASCII character class repeated a number of
+// times, such as \d+, [a-f0-9]* or \w{2,8}
+func classRepeat(re *syntax.Regexp) (*classMatcher, bool) {
+	c := &classMatcher{min: 1, max: 1}
+	switch re.Op {
+	case syntax.OpStar:
+		c.min, c.max = 0, -1
+	case syntax.OpPlus:
+		c.min, c.max = 1, -1
+	case syntax.OpQuest:
+		c.min, c.max = 0, 1
+	case syntax.OpRepeat:
+		c.min, c.max = re.Min, re.Max
+	default:
+		return nil, false
+	}
+	class := re.Sub[0]
+	if class.Op != syntax.OpCharClass {
+		return nil, false
+	}
+	for i := 0; i < len(class.Rune); i += 2 {
+		// Negated classes such as [^/] reach past ASCII
+		lo, hi := class.Rune[i], class.Rune[i+1]
+		if hi > 0x7f {
+			return nil, false
+		}
+		for r := lo; r <= hi; r++ {
+			c.set[r>>6] |= 1 << (r & 63)
+		}
+	}
+	return c, true
+}
@@ -0,0 +1,87 @@
+package GoFlow
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParamConstraints(t *testing.T) {
+	t.Run("Anchoring", func(t *testing.T) {
+		mux := New()
+		noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		mux.Handle("/items/:id|\\d+", noop, MethodGet)
+		mux.Handle("/lang/:code|en|de", noop, MethodGet)
+		mux.Handle("/hex/:h|[0-9a-f]{2,4}", noop, MethodGet)
+		mux.Handle("/any/:v|.+x", noop, MethodGet)
+
+		tests := []struct {
+			path string
+			code int
+		}{
+			{"/items/42", http.StatusOK},
+			{"/items/a42", http.StatusNotFound},
+			{"/items/42a", http.StatusNotFound},
+			{"/lang/en", http.StatusOK},
+			{"/lang/den", http.StatusNotFound},
+			{"/hex/0f", http.StatusOK},
+			{"/hex/0f0f0", http.StatusNotFound},
+			{"/hex/g0", http.StatusNotFound},
+			{"/any/abx", http.StatusOK},
+			{"/any/xab", http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.path, nil))
+			if w.Code != tt.code {
+				t.Errorf("%s: expected %d, got %d", tt.path, tt.code, w.Code)
+			}
+		}
+	})
+
+	t.Run("Matchers", func(t *testing.T) {
+		tests := []struct {
+			rx       string
+			expected string
+		}{
+			{`^\d+$`, "*GoFlow.classMatcher"},
+			{`[a-z0-9-]{1,32}`, "*GoFlow.classMatcher"},
+			{`^(?:en|de)$`, "GoFlow.literalMatcher"},
+			{`v[12]`, "GoFlow.literalMatcher"},
+			{`(?i)abc`, "*regexp.Regexp"},
+			{`[^/]+`, "*regexp.Regexp"},
+		}
+		for _, tt := range tests {
+			m, err := compileMatcher(tt.rx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%T", m); got != tt.expected {
+				t.Errorf("%s: expected %s, got %s", tt.rx, tt.expected, got)
+			}
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for pattern, want := range map[string]string{
+			"/users/:id|[0-9":   "parameter :id: error parsing regexp",
+			"/users/:":          "parameter without a name",
+			"/files/.../extra":  "wildcard must be the last segment",
+			"/users/:id|^\\d+$": "",
+		} {
+			err := ValidatePattern(pattern)
+			if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+				t.Errorf("%s: expected error containing '%s', got %v", pattern, want, err)
+			}
+		}
+
+		defer func() {
+			if r := recover(); r == nil || !strings.HasPrefix(r.(string), "GoFlow: pattern /bad/:id|(") {
+				t.Errorf("Expected Handle to panic with the validation error, got %v", r)
+			}
+		}()
+		New().Handle("/bad/:id|(", http.NotFoundHandler())
+	})
+}
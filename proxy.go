@@ -0,0 +1,174 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ProxyOptions configures a reverse proxy created with Mux.Proxy or NewProxy
+type ProxyOptions struct {
+	// StripPrefix removes the route's static prefix before forwarding, so
+	// that /api/... forwards /api/users as /users
+	StripPrefix bool
+
+	// Rewrite maps the incoming path to the upstream path. It runs after
+	// StripPrefix and before the target's own path is prepended.
+	Rewrite func(path string) string
+
+	// PreserveHost forwards the client's Host header instead of the target's
+	PreserveHost bool
+
+	// TrustForwarded appends to X-Forwarded-For and keeps incoming
+	// X-Forwarded-Host/Proto instead of replacing them. Only enable it
+	// behind a proxy that sets these headers itself.
+	TrustForwarded bool
+
+	// SetHeaders and RemoveHeaders adjust the upstream request headers
+	SetHeaders    map[string]string
+	RemoveHeaders []string
+
+	// ModifyRequest runs last on the outgoing request
+	ModifyRequest func(r *http.Request)
+
+	// ModifyResponse can inspect or change the upstream response. Returning
+	// an error passes it to ErrorHandler.
+	ModifyResponse func(resp *http.Response) error
+
+	// ErrorHandler handles upstream failures. Defaults to 504 for timeouts
+	// and 502 otherwise.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Transport sends upstream requests. Defaults to http.DefaultTransport;
+	// wrap it with RetryTransport to retry idempotent requests.
+	Transport http.RoundTripper
+
+	// FlushInterval is passed to httputil.ReverseProxy. A negative value
+	// flushes after every write, for streaming responses.
+	FlushInterval time.Duration
+}
+
+// NewProxy returns a reverse proxy forwarding requests to target
+func NewProxy(target string, opts ProxyOptions) http.Handler {
+	u := mustParseTarget(target)
+	return newReverseProxy(func(*http.Request) *url.URL { return u }, "", opts)
+}
+
+// Proxy forwards requests matching pattern to target, making the mux act as
+// a lightweight gateway. It answers to all methods. The time left until the
+// request's deadline, e.g. from Timeout, is passed on with InjectDeadline.
+//
+//	mux.Proxy("/api/...", "http://users-service:8080", GoFlow.ProxyOptions{StripPrefix: true})
+func (m *Mux) Proxy(pattern, target string, opts ProxyOptions) *Route {
+	u := mustParseTarget(target)
+	handler := newReverseProxy(func(*http.Request) *url.URL { return u }, staticPrefix(pattern), opts)
+	return m.Handle(pattern, handler)
+}
+
+// newReverseProxy builds the proxy around a per-request target lookup.
+// WebSocket upgrades are tunneled to the same target.
+func newReverseProxy(target func(r *http.Request) *url.URL, prefix string, opts ProxyOptions) http.Handler {
+	errorHandler := opts.ErrorHandler
+	if errorHandler == nil {
+		errorHandler = defaultProxyErrorHandler
+	}
+
+	rewrite := func(pr *httputil.ProxyRequest) {
+		path := pr.In.URL.Path
+		if opts.StripPrefix && prefix != "" {
+			path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+		}
+		if opts.Rewrite != nil {
+			path = opts.Rewrite(path)
+		}
+		pr.Out.URL.Path = path
+		pr.Out.URL.RawPath = ""
+		pr.SetURL(target(pr.In))
+
+		if opts.TrustForwarded {
+			pr.Out.Header["X-Forwarded-For"] = pr.In.Header["X-Forwarded-For"]
+		}
+		pr.SetXForwarded()
+		if opts.TrustForwarded {
+			if v := pr.In.Header.Get("X-Forwarded-Host"); v != "" {
+				pr.Out.Header.Set("X-Forwarded-Host", v)
+			}
+			if v := pr.In.Header.Get("X-Forwarded-Proto"); v != "" {
+				pr.Out.Header.Set("X-Forwarded-Proto", v)
+			}
+		}
+
+		if tc, ok := GetTrace(pr.In.Context()); ok {
+			tc.Inject(pr.Out.Header)
+		}
+		InjectDeadline(pr.In.Context(), pr.Out.Header)
+		if opts.PreserveHost {
+			pr.Out.Host = pr.In.Host
+		}
+		for k, v := range opts.SetHeaders {
+			pr.Out.Header.Set(k, v)
+		}
+		for _, k := range opts.RemoveHeaders {
+			pr.Out.Header.Del(k)
+		}
+		if opts.ModifyRequest != nil {
+			opts.ModifyRequest(pr.Out)
+		}
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite:        rewrite,
+		Transport:      opts.Transport,
+		ModifyResponse: opts.ModifyResponse,
+		ErrorHandler:   errorHandler,
+		FlushInterval:  opts.FlushInterval,
+	}
+	ws := &webSocketProxy{
+		rewrite:        rewrite,
+		transport:      opts.Transport,
+		modifyResponse: opts.ModifyResponse,
+		errorHandler:   errorHandler,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebSocketUpgrade(r) {
+			ws.ServeHTTP(w, r)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+func defaultProxyErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	status := (&UpstreamError{Err: err}).StatusCode()
+	if !errors.Is(err, context.Canceled) {
+		slog.Warn("proxy error", slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+	}
+	http.Error(w, StatusText(r.Context(), status), status)
+}
+
+func mustParseTarget(target string) *url.URL {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		panic("GoFlow: invalid proxy target " + target)
+	}
+	return u
+}
+
+// staticPrefix returns the part of pattern before its first parameter or wildcard
+func staticPrefix(pattern string) string {
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	prefix := ""
+	for _, seg := range segments {
+		if seg == "" || seg == "..." || strings.HasPrefix(seg, ":") {
+			break
+		}
+		prefix += "/" + seg
+	}
+	return prefix
+}
@@ -0,0 +1,175 @@
+package GoFlow
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend-Path", r.URL.Path)
+		w.Header().Set("X-Backend-Host", r.Host)
+		w.Header().Set("X-Backend-XFF", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Backend-Key", r.Header.Get("X-Api-Key"))
+		w.Header().Set("X-Backend-Cookie", r.Header.Get("Cookie"))
+		if r.URL.Path == "/v1/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		io.WriteString(w, r.URL.RawQuery)
+	}))
+	defer backend.Close()
+
+	t.Run("Path Rewriting And Headers", func(t *testing.T) {
+		mux := New()
+		mux.Proxy("/api/...", backend.URL+"/v1", ProxyOptions{
+			StripPrefix:   true,
+			SetHeaders:    map[string]string{"X-Api-Key": "internal"},
+			RemoveHeaders: []string{"Cookie"},
+			ModifyResponse: func(resp *http.Response) error {
+				resp.Header.Set("X-Via", "goflow")
+				return nil
+			},
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodPost, "http://gateway.example/api/users/42?expand=1", nil)
+		r.Header.Set("Cookie", "session=secret")
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		r.RemoteAddr = "192.0.2.10:5555"
+		mux.ServeHTTP(w, r)
+
+		expected := map[string]string{
+			"X-Backend-Path":   "/v1/users/42",
+			"X-Backend-Host":   strings.TrimPrefix(backend.URL, "http://"),
+			"X-Backend-XFF":    "192.0.2.10",
+			"X-Backend-Key":    "internal",
+			"X-Backend-Cookie": "",
+			"X-Via":            "goflow",
+		}
+		for k, v := range expected {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("Expected %s '%s', got '%s'", k, v, got)
+			}
+		}
+		if w.Body.String() != "expand=1" {
+			t.Errorf("Expected query to be forwarded, got '%s'", w.Body.String())
+		}
+	})
+
+	t.Run("Host Preservation And Trusted Forwarding", func(t *testing.T) {
+		mux := New()
+		mux.Proxy("/...", backend.URL, ProxyOptions{PreserveHost: true, TrustForwarded: true})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, "http://gateway.example/", nil)
+		r.Header.Set("X-Forwarded-For", "1.2.3.4")
+		r.RemoteAddr = "192.0.2.10:5555"
+		mux.ServeHTTP(w, r)
+
+		if w.Header().Get("X-Backend-Host") != "gateway.example" {
+			t.Errorf("Expected preserved host, got '%s'", w.Header().Get("X-Backend-Host"))
+		}
+		if w.Header().Get("X-Backend-XFF") != "1.2.3.4, 192.0.2.10" {
+			t.Errorf("Expected appended X-Forwarded-For, got '%s'", w.Header().Get("X-Backend-XFF"))
+		}
+	})
+
+	t.Run("Error Handling", func(t *testing.T) {
+		mux := New()
+		mux.Proxy("/down/...", "http://127.0.0.1:1", ProxyOptions{})
+		mux.Proxy("/slow", backend.URL+"/v1", ProxyOptions{
+			Transport: &http.Transport{ResponseHeaderTimeout: 20 * time.Millisecond},
+		})
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/down/x", nil))
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, w.Code)
+		}
+
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/slow", nil))
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status code %d, got %d", http.StatusGatewayTimeout, w.Code)
+		}
+	})
+	t.Run("WebSocket Tunnel", func(t *testing.T) {
+		// Raw echo upstream: answers the upgrade, then echoes bytes back
+		echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isWebSocketUpgrade(r) || r.URL.Path != "/v1/chat" {
+				http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+				return
+			}
+			conn, brw, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+			brw.WriteString("Sec-WebSocket-Protocol: " + strings.Split(r.Header.Get("Sec-WebSocket-Protocol"), ",")[0] + "\r\n\r\n")
+			brw.Flush()
+			io.Copy(conn, brw)
+		}))
+		defer echo.Close()
+
+		mux := New()
+		mux.Use(Logger(), Compression(), Timeout(50*time.Millisecond))
+		mux.Proxy("/ws/...", echo.URL+"/v1", ProxyOptions{StripPrefix: true})
+
+		addr := freeAddr(t)
+		s := NewServer(addr, mux)
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		go s.Run()
+		defer s.Close()
+		waitForServer(t, addr)
+
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		io.WriteString(conn, "GET /ws/chat HTTP/1.1\r\nHost: "+addr+"\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+			"Accept-Encoding: gzip\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+			"Sec-WebSocket-Protocol: chat.v2, chat.v1\r\n\r\n")
+
+		br := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("Expected status code %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+		}
+		if resp.Header.Get("Sec-WebSocket-Protocol") != "chat.v2" {
+			t.Errorf("Expected negotiated subprotocol 'chat.v2', got '%s'", resp.Header.Get("Sec-WebSocket-Protocol"))
+		}
+
+		// Longer than the Timeout middleware allows
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(conn, "ping")
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(br, buf); err != nil || string(buf) != "ping" {
+			t.Errorf("Expected echoed 'ping', got '%s' (%v)", buf, err)
+		}
+
+		// Shutdown tears the tunnel down
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := s.Shutdown(ctx); err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := br.ReadByte(); err != io.EOF {
+			t.Errorf("Expected tunnel to be closed, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routeTree is a node of the compressed radix trie routes are stored in.
+// Static nodes hold the bytes of a path that their routes have in common,
+// so /users, /users/:id and /usersettings share the node "/users". A
+// parameter node matches one path segment and hangs off the static node
+// ending in the slash before it; its own children start with a slash.
+type routeTree struct {
+	prefix   string
+	indices  string // first byte of each static child, in order
+	children []*routeTree
+
+	paramChild *routeTree
+	paramName  string
+	rxPattern  segmentMatcher
+
+	// methods serve the path ending at the node, and wildcard the routes
+	// capturing the rest of the path below it as "...". They are kept
+	// apart so /docs and /docs/... don't share handlers.
+	methods  *methodHandler
+	wildcard *methodHandler
+
+	// staticHandlers maps the paths of routes without parameters or
+	// wildcards to their handlers, for a lookup that skips the tree. Only
+	// the root's is used.
+	staticHandlers map[string]*methodHandler
+}
+
+func (m *Mux) addRoute(pattern string, method string, handler http.Handler) {
+	current := m.root
+	static := ""
+	isStatic := true
+	for _, segment := range strings.Split(pattern, "/") {
+		if segment == "" {
+			continue
+		}
+		if segment == "..." {
+			current = current.insertStatic(static)
+			if current.wildcard == nil {
+				current.wildcard = newMethodHandler()
+			}
+			current.wildcard.addHandler(method, handler)
+			return
+		}
+		if !strings.HasPrefix(segment, ":") {
+			static += "/" + segment
+			continue
+		}
+
+		current = current.insertStatic(static + "/")
+		paramName, rxPattern, hasRx := strings.Cut(segment[1:], "|")
+		if current.paramChild == nil {
+			current.paramChild = &routeTree{paramName: paramName}
+			if hasRx {
+				current.paramChild.rxPattern = m.compilePattern(rxPattern)
+			}
+		}
+		current = current.paramChild
+		static = ""
+		isStatic = false
+	}
+	current = current.insertStatic(static)
+
+	if current.methods == nil {
+		current.methods = newMethodHandler()
+	}
+	current.methods.addHandler(method, handler)
+
+	if isStatic {
+		if static == "" {
+			static = "/"
+		}
+		m.root.staticHandlers[static] = current.methods
+	}
+}
+
+// insertStatic returns the node for path below n, splitting existing nodes
+// where path diverges from them
+func (n *routeTree) insertStatic(path string) *routeTree {
+	for path != "" {
+		i := strings.IndexByte(n.indices, path[0])
+		if i < 0 {
+			child := &routeTree{prefix: path}
+			n.indices += path[:1]
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := commonPrefix(path, child.prefix)
+		if l < len(child.prefix) {
+			tail := *child
+			tail.prefix = child.prefix[l:]
+			*child = routeTree{
+				prefix:   child.prefix[:l],
+				indices:  tail.prefix[:1],
+				children: []*routeTree{&tail},
+			}
+		}
+		path = path[l:]
+		n = child
+	}
+	return n
+}
+
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// findHandler matches path, normalized by routePath, below node. It prefers
+// static nodes, then parameters, then the nearest wildcard, backtracking
+// when a branch dead-ends.
+func (m *Mux) findHandler(node *routeTree, path string, params map[string]string) (*methodHandler, map[string]string, bool) {
+	if methods := node.match(path, params); methods != nil {
+		return methods, params, true
+	}
+	return nil, nil, false
+}
+
+func (n *routeTree) match(path string, params map[string]string) *methodHandler {
+	if path == "" {
+		// The wildcard's prefix alone matches it unless a route has that
+		// exact path
+		if n.methods != nil {
+			return n.methods
+		}
+		return n.wildcard
+	}
+
+	// Static children, compared byte by byte
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.prefix) {
+			if methods := child.match(path[len(child.prefix):], params); methods != nil {
+				return methods
+			}
+		}
+	}
+
+	// A parameter takes the segment up to the next slash
+	if pc := n.paramChild; pc != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if segment := path[:end]; pc.rxPattern == nil || pc.rxPattern.MatchString(segment) {
+			params[pc.paramName] = segment
+			if methods := pc.match(path[end:], params); methods != nil {
+				return methods
+			}
+			delete(params, pc.paramName)
+		}
+	}
+
+	// Wildcard captures the rest of the path
+	if n.wildcard != nil && path[0] == '/' {
+		params["..."] = path[1:]
+		return n.wildcard
+	}
+	return nil
+}
+
+// routePath normalizes a request path the way patterns are stored: no
+// trailing or repeated slashes, and "" for the root. It only allocates for
+// paths that need cleaning.
+func routePath(path string) string {
+	clean := path != "" && path[0] == '/' && path[len(path)-1] != '/' && !strings.Contains(path, "//")
+	if clean {
+		return path
+	}
+	var b strings.Builder
+	b.Grow(len(path) + 1)
+	for i := 0; i < len(path); {
+		for i < len(path) && path[i] == '/' {
+			i++
+		}
+		j := i
+		for j < len(path) && path[j] != '/' {
+			j++
+		}
+		if j > i {
+			b.WriteByte('/')
+			b.WriteString(path[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,158 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRadixTree(t *testing.T) {
+	mux := New()
+	for _, pattern := range []string{
+		"/",
+		"/users",
+		"/usersettings",
+		"/users/me",
+		"/users/:id",
+		"/users/:id/posts",
+		"/users/:id/posts/:post|^\\d+$",
+		"/files/...",
+		"/files/:name/meta",
+		"/use",
+	} {
+		pattern := pattern
+		mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(pattern + " " + Param(r.Context(), "id") + Param(r.Context(), "post") +
+				Param(r.Context(), "name") + Param(r.Context(), "...")))
+		}), MethodGet)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/", "/ "},
+		{"/users", "/users "},
+		{"/users/", "/users "},
+		{"//users//me", "/users/me "},
+		{"/usersettings", "/usersettings "},
+		{"/use", "/use "},
+		{"/users/42", "/users/:id 42"},
+		{"/users/42/posts", "/users/:id/posts 42"},
+		{"/users/42/posts/7", "/users/:id/posts/:post|^\\d+$ 427"},
+		{"/files/a.txt/meta", "/files/:name/meta a.txt"},
+		{"/files/a/b/c", "/files/... a/b/c"},
+		{"/files/a/meta/x", "/files/... a/meta/x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.path, nil))
+			if w.Body.String() != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	for _, path := range []string{"/userss", "/us", "/users/42/posts/x", "/users/42/other"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Expected 404, got %d", w.Code)
+			}
+		})
+	}
+
+	t.Run("Prefix Compression", func(t *testing.T) {
+		users := mux.root.children[0]
+		if users.prefix != "/" || len(users.children) != 2 {
+			t.Fatalf("Expected root children to share '/', got '%s' with %d children", users.prefix, len(users.children))
+		}
+		if us := users.children[0]; us.prefix != "use" || us.methods == nil {
+			t.Errorf("Expected a shared 'use' node, got '%s'", us.prefix)
+		}
+	})
+
+	t.Run("Wildcard Beside Exact Path", func(t *testing.T) {
+		for name, patterns := range map[string][2]string{
+			"exact first":    {"/docs", "/docs/..."},
+			"wildcard first": {"/docs/...", "/docs"},
+		} {
+			mux := New()
+			for _, pattern := range patterns {
+				method := MethodGet
+				if pattern == "/docs/..." {
+					method = MethodPost
+				}
+				pattern := pattern
+				mux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte(pattern))
+				}), method)
+			}
+
+			tests := []struct {
+				method, path string
+				status       int
+				body         string
+			}{
+				{MethodGet, "/docs", http.StatusOK, "/docs"},
+				{MethodPost, "/docs/a", http.StatusOK, "/docs/..."},
+				{MethodGet, "/docs/a", http.StatusMethodNotAllowed, ""},
+				{MethodPost, "/docs", http.StatusMethodNotAllowed, ""},
+			}
+			for _, tt := range tests {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+				if w.Code != tt.status || tt.body != "" && w.Body.String() != tt.body {
+					t.Errorf("%s: %s %s: expected %d %q, got %d %q", name, tt.method, tt.path, tt.status, tt.body, w.Code, w.Body.String())
+				}
+			}
+		}
+	})
+}
+
+func TestStaticFastPath(t *testing.T) {
+	mux := New()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + CurrentRoute(r.Context()).Pattern()))
+	})
+	mux.Handle("/", handler, MethodGet)
+	mux.Handle("/health/", handler, MethodGet, MethodPost)
+	mux.Handle("/health/:check", handler, MethodGet)
+	mux.Group(func(m *Mux) {
+		m.Handle("/admin", handler, MethodDelete)
+	})
+
+	for path, methods := range map[string]*methodHandler{
+		"/":       mux.root.staticHandlers["/"],
+		"/health": mux.root.staticHandlers["/health"],
+		"/admin":  mux.root.staticHandlers["/admin"],
+	} {
+		if methods == nil {
+			t.Errorf("Expected %s in the static map without Optimize", path)
+		}
+	}
+	if len(mux.root.staticHandlers) != 3 {
+		t.Errorf("Expected only routes without parameters in the static map, got %d", len(mux.root.staticHandlers))
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{MethodGet, "/", http.StatusOK, "GET /"},
+		{MethodPost, "/health", http.StatusOK, "POST /health/"},
+		{MethodPost, "/health/", http.StatusOK, "POST /health/"},
+		{MethodDelete, "/admin", http.StatusOK, "DELETE /admin"},
+		{MethodPut, "/admin", http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s %s: expected %d '%s', got %d '%s'", tt.method, tt.path, tt.code, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package GoFlow
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// RangeOptions describes content served with ServeRange
+type RangeOptions struct {
+	// Name picks the Content-Type by extension when ContentType is empty,
+	// e.g. "video.mp4". Without either, the type is sniffed.
+	Name        string
+	ContentType string
+
+	// ModTime and ETag identify the version of the content, so If-Range
+	// only resumes a download of the same version
+	ModTime time.Time
+	ETag    string
+}
+
+// ServeRange serves content honoring Range and If-Range: a single range is
+// sent as 206 Partial Content, several as multipart/byteranges, and
+// unsatisfiable ones get 416. Conditional headers are evaluated as by
+// CheckPreconditions. Compression leaves partial responses uncompressed.
+func ServeRange(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, opts RangeOptions) {
+	if opts.ContentType != "" {
+		w.Header().Set("Content-Type", opts.ContentType)
+	}
+	if etag := quoteETag(opts.ETag); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	http.ServeContent(w, r, opts.Name, opts.ModTime, content)
+}
+
+// ServeRangeAt is ServeRange for content of a known size that is read at
+// offsets, such as an object in blob storage
+func ServeRangeAt(w http.ResponseWriter, r *http.Request, content io.ReaderAt, size int64, opts RangeOptions) {
+	ServeRange(w, r, io.NewSectionReader(content, 0, size), opts)
+}
@@ -0,0 +1,86 @@
+package GoFlow
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeRange(t *testing.T) {
+	const body = "0123456789abcdefghij"
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mux := New()
+	mux.Use(Compression())
+	mux.Handle("/media", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeRangeAt(w, r, strings.NewReader(body), int64(len(body)), RangeOptions{
+			ContentType: "application/octet-stream",
+			ModTime:     modified,
+			ETag:        "v1",
+		})
+	}), MethodGet)
+
+	serve := func(headers map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(MethodGet, "/media", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Single Range", func(t *testing.T) {
+		w := serve(map[string]string{"Range": "bytes=2-5", "Accept-Encoding": "gzip"})
+		if w.Code != http.StatusPartialContent || w.Body.String() != "2345" {
+			t.Errorf("Expected 206 with '2345', got %d '%s'", w.Code, w.Body.String())
+		}
+		if w.Header().Get("Content-Range") != "bytes 2-5/20" || w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected an uncompressed partial response, got %v", w.Header())
+		}
+	})
+
+	t.Run("Multipart Ranges", func(t *testing.T) {
+		w := serve(map[string]string{"Range": "bytes=0-1,18-"})
+		if w.Code != http.StatusPartialContent || !strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges") {
+			t.Errorf("Expected a multipart response, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(w.Body.String(), "01") || !strings.Contains(w.Body.String(), "ij") {
+			t.Errorf("Expected both ranges in the body, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("If-Range", func(t *testing.T) {
+		if w := serve(map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`}); w.Code != http.StatusPartialContent {
+			t.Errorf("Expected the current version to resume, got %d", w.Code)
+		}
+		w := serve(map[string]string{"Range": "bytes=0-1", "If-Range": `"v0"`})
+		if w.Code != http.StatusOK || w.Body.String() != body {
+			t.Errorf("Expected the full body for a changed version, got %d", w.Code)
+		}
+	})
+
+	t.Run("Unsatisfiable", func(t *testing.T) {
+		if w := serve(map[string]string{"Range": "bytes=50-"}); w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Errorf("Expected 416, got %d", w.Code)
+		}
+	})
+
+	t.Run("Full Response Compressed", func(t *testing.T) {
+		w := serve(map[string]string{"Accept-Encoding": "gzip"})
+		if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+			t.Fatalf("Expected a gzip response without Content-Length, got %v", w.Header())
+		}
+		gz, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := io.ReadAll(gz); string(data) != body {
+			t.Errorf("Expected the full body, got '%s'", data)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the values removed by a Redaction
+const Redacted = "[REDACTED]"
+
+// Redaction lists the parts of requests and responses that must not reach
+// logs, traces or captures
+type Redaction struct {
+	// Headers are header names, matched case-insensitively
+	Headers []string
+
+	// Query are query parameter names
+	Query []string
+
+	// JSONFields are field paths in JSON bodies such as "card.number",
+	// matched from the root of the document with arrays passed through.
+	// "*" matches any field. A single name such as "password" matches the
+	// field at any depth.
+	JSONFields []string
+}
+
+// DefaultRedaction is always applied, in addition to the policies set with
+// Mux.Redact and Route.Redact
+var DefaultRedaction = Redaction{
+	Headers:    []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Csrf-Token"},
+	Query:      []string{"access_token", "api_key", "password", "secret", "token"},
+	JSONFields: []string{"password", "secret", "token"},
+}
+
+type redactionKey struct{}
+
+// Redact adds p to the redaction policy of every route of the mux,
+// including its groups. The Logger's slow request warnings, debug traces
+// and HAR captures apply it, and custom logging can through
+// CurrentRedaction.
+func (m *Mux) Redact(p Redaction) {
+	m.table.redaction = m.table.redaction.merge(p)
+}
+
+// Redact adds p to the redaction policy for this route, e.g. the fields of
+// its request body that hold card numbers
+func (rt *Route) Redact(p Redaction) *Route {
+	if prev, ok := rt.Value(redactionKey{}).(Redaction); ok {
+		p = prev.merge(p)
+	}
+	return rt.Set(redactionKey{}, p)
+}
+
+// CurrentRedaction returns the redaction policy for the request's route:
+// DefaultRedaction, the mux's and the route's
+func CurrentRedaction(ctx context.Context) Redaction {
+	rt := CurrentRoute(ctx)
+	if rt == nil {
+		if h, ok := ctx.Value(logHolderKey{}).(*logHolder); ok {
+			rt = h.route
+		}
+	}
+	return redactionFor(rt)
+}
+
+func redactionFor(rt *Route) Redaction {
+	p := DefaultRedaction
+	if rt == nil {
+		return p
+	}
+	if rt.table != nil {
+		p = p.merge(rt.table.redaction)
+	}
+	if own, ok := rt.Value(redactionKey{}).(Redaction); ok {
+		p = p.merge(own)
+	}
+	return p
+}
+
+func (p Redaction) merge(o Redaction) Redaction {
+	return Redaction{
+		Headers:    append(p.Headers[:len(p.Headers):len(p.Headers)], o.Headers...),
+		Query:      append(p.Query[:len(p.Query):len(p.Query)], o.Query...),
+		JSONFields: append(p.JSONFields[:len(p.JSONFields):len(p.JSONFields)], o.JSONFields...),
+	}
+}
+
+// RedactHeader returns a copy of h with the values of redacted headers
+// replaced
+func (p Redaction) RedactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for name := range out {
+		if containsFold(p.Headers, name) {
+			out[name] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// RedactQuery returns the raw query string with the values of redacted
+// parameters replaced
+func (p Redaction) RedactQuery(rawQuery string) string {
+	if rawQuery == "" || len(p.Query) == 0 {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		name, _, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil && containsFold(p.Query, unescaped) {
+			pairs[i] = name + "=" + url.QueryEscape(Redacted)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// RedactURL returns u as a string with redacted query parameters replaced
+func (p Redaction) RedactURL(u *url.URL) string {
+	c := *u
+	c.RawQuery = p.RedactQuery(u.RawQuery)
+	c.User = nil
+	return c.String()
+}
+
+// RedactJSON returns body with the values of redacted fields replaced.
+// Bodies that aren't valid JSON are returned as they are.
+func (p Redaction) RedactJSON(body []byte) []byte {
+	if len(p.JSONFields) == 0 {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return body
+	}
+	paths := make([][]string, len(p.JSONFields))
+	for i, field := range p.JSONFields {
+		paths[i] = strings.Split(field, ".")
+	}
+	if !redactJSON(doc, paths, nil) {
+		return body
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactJSON replaces the fields of v matching paths, at the position
+// given by the keys leading to v, and reports whether it replaced any
+func redactJSON(v any, paths [][]string, keys []string) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for name, child := range v {
+			at := append(keys, name)
+			if matchesJSONPath(paths, at) {
+				v[name] = Redacted
+				changed = true
+				continue
+			}
+			if redactJSON(child, paths, at) {
+				changed = true
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if redactJSON(child, paths, keys) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func matchesJSONPath(paths [][]string, keys []string) bool {
+	for _, path := range paths {
+		if len(path) == 1 {
+			if path[0] == "*" || strings.EqualFold(path[0], keys[len(keys)-1]) {
+				return true
+			}
+			continue
+		}
+		if len(path) != len(keys) {
+			continue
+		}
+		match := true
+		for i, seg := range path {
+			if seg != "*" && !strings.EqualFold(seg, keys[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
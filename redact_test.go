@@ -0,0 +1,133 @@
+package GoFlow
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedaction(t *testing.T) {
+	t.Run("Headers", func(t *testing.T) {
+		h := http.Header{"Authorization": {"Bearer secret"}, "X-Card": {"4242"}, "Accept": {"*/*"}}
+		out := DefaultRedaction.merge(Redaction{Headers: []string{"x-card"}}).RedactHeader(h)
+		if out.Get("Authorization") != Redacted || out.Get("X-Card") != Redacted || out.Get("Accept") != "*/*" {
+			t.Errorf("Expected Authorization and X-Card redacted, got %v", out)
+		}
+		if h.Get("Authorization") != "Bearer secret" {
+			t.Error("Expected the original header untouched")
+		}
+	})
+
+	t.Run("Query", func(t *testing.T) {
+		got := DefaultRedaction.RedactQuery("q=shoes&token=abc&page=2&Password=x")
+		expected := "q=shoes&token=%5BREDACTED%5D&page=2&Password=%5BREDACTED%5D"
+		if got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+		u, _ := url.Parse("https://bob:pw@example.com/a?api_key=1")
+		if got := DefaultRedaction.RedactURL(u); got != "https://example.com/a?api_key=%5BREDACTED%5D" {
+			t.Errorf("Expected the user info and API key removed, got %q", got)
+		}
+	})
+
+	t.Run("JSON Fields", func(t *testing.T) {
+		p := Redaction{JSONFields: []string{"password", "card.number", "items.secret", "*.cvv"}}
+		body := `{"user":{"password":"pw","name":"bob"},"card":{"number":"4242","exp":"12/30"},` +
+			`"number":"1","items":[{"secret":"s","id":1}],"other":{"cvv":"123"}}`
+		var got map[string]any
+		if err := json.Unmarshal(p.RedactJSON([]byte(body)), &got); err != nil {
+			t.Fatal(err)
+		}
+		user := got["user"].(map[string]any)
+		card := got["card"].(map[string]any)
+		item := got["items"].([]any)[0].(map[string]any)
+		if user["password"] != Redacted || user["name"] != "bob" {
+			t.Errorf("Expected a nested password redacted, got %v", user)
+		}
+		if card["number"] != Redacted || got["number"] != "1" || card["exp"] != "12/30" {
+			t.Errorf("Expected only card.number redacted, got %v", got)
+		}
+		if item["secret"] != Redacted || item["id"] != float64(1) {
+			t.Errorf("Expected secrets in array items redacted, got %v", item)
+		}
+		if got["other"].(map[string]any)["cvv"] != Redacted {
+			t.Errorf("Expected a wildcard path matched, got %v", got["other"])
+		}
+		if string(p.RedactJSON([]byte("not json"))) != "not json" {
+			t.Error("Expected invalid JSON returned as is")
+		}
+	})
+
+	t.Run("Mux And Route Policy", func(t *testing.T) {
+		mux := New()
+		mux.Redact(Redaction{Query: []string{"ssn"}})
+		var got Redaction
+		h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = CurrentRedaction(r.Context())
+		})
+		mux.Handle("/pay", h, MethodPost).Redact(Redaction{JSONFields: []string{"card.number"}})
+		mux.Handle("/other", h, MethodGet)
+
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodPost, "/pay", nil))
+		if !containsFold(got.Query, "ssn") || !containsFold(got.Query, "token") || !containsFold(got.JSONFields, "card.number") {
+			t.Errorf("Expected the default, mux and route policies, got %+v", got)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/other", nil))
+		if containsFold(got.JSONFields, "card.number") {
+			t.Errorf("Expected the route policy kept to its route, got %+v", got)
+		}
+	})
+
+	t.Run("Slow Request Log", func(t *testing.T) {
+		var logs bytes.Buffer
+		mux := New()
+		mux.Redact(Redaction{Query: []string{"ssn"}})
+		mux.Use(LoggerWithOptions(LoggerOptions{
+			SlowThreshold: time.Nanosecond,
+			SlowLogger:    slog.New(slog.NewTextHandler(&logs, nil)),
+		}))
+		mux.Handle("/search", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond)
+		}), MethodGet)
+
+		var buf bytes.Buffer
+		orig := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(orig)
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/search?q=a&ssn=123&token=t", nil))
+
+		if out := logs.String(); !strings.Contains(out, "q=a") || strings.Contains(out, "123") || strings.Contains(out, "token=t") {
+			t.Errorf("Expected the query logged with ssn and token redacted, got %q", out)
+		}
+	})
+
+	t.Run("Debug Trace", func(t *testing.T) {
+		mux := New()
+		mux.Debug(DebugOptions{
+			Authorize: func(r *http.Request) bool { return true },
+			Logger:    slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		})
+		mux.Handle("/me", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MethodGet).
+			Redact(Redaction{Headers: []string{"X-Session"}})
+
+		r := httptest.NewRequest(MethodGet, "/me?goflow_debug=1&access_token=abc", nil)
+		r.Header.Set("Authorization", "Bearer abc")
+		r.Header.Set("X-Session", "s1")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		data := w.Result().Trailer.Get(DebugTraceHeader)
+		if strings.Contains(data, "abc") || strings.Contains(data, "s1") {
+			t.Errorf("Expected secrets redacted from the trace, got %s", data)
+		}
+		if !strings.Contains(data, "goflow_debug=1") {
+			t.Errorf("Expected the query in the trace, got %s", data)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package GoFlow
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RedirectOptions configures the HTTPSRedirect middleware
+type RedirectOptions struct {
+	// TrustForwardedProto honors X-Forwarded-Proto from a TLS-terminating proxy.
+	// Only enable it when every request passes through such a proxy.
+	TrustForwardedProto bool
+
+	// HTTPSPort is added to redirect URLs when HTTPS is served on a non-standard port
+	HTTPSPort string
+
+	// HSTS appends Strict-Transport-Security to responses served over HTTPS
+	HSTS                  bool
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+}
+
+// HTTPSRedirect permanently redirects plain HTTP requests to HTTPS
+func HTTPSRedirect(opts RedirectOptions) func(http.Handler) http.Handler {
+	if opts.HSTSMaxAge == 0 {
+		opts.HSTSMaxAge = 31536000 // 1 year
+	}
+	hsts := hstsValue(opts.HSTSMaxAge, opts.HSTSIncludeSubdomains, opts.HSTSPreload)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isHTTPS(r, opts.TrustForwardedProto) {
+				if opts.HSTS {
+					w.Header().Set("Strict-Transport-Security", hsts)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if isACMEChallenge(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := stripPort(r.Host)
+			if opts.HTTPSPort != "" && opts.HTTPSPort != "443" {
+				host = net.JoinHostPort(host, opts.HTTPSPort)
+			}
+			permanentRedirect(w, r, "https://"+host+BasePath(r.Context())+r.URL.RequestURI())
+		})
+	}
+}
+
+// HostRedirectOptions configures CanonicalHost, StripWWW and AddWWW
+type HostRedirectOptions struct {
+	// TrustedProxies may set X-Forwarded-Proto, as in SecurityOptions. Behind
+	// a TLS-terminating proxy, list it so redirects keep the https scheme.
+	TrustedProxies []string
+}
+
+// CanonicalHost redirects requests for any other host name to host,
+// preserving scheme, path and query
+func CanonicalHost(host string) func(http.Handler) http.Handler {
+	return CanonicalHostWithOptions(host, HostRedirectOptions{})
+}
+
+// CanonicalHostWithOptions is CanonicalHost with options
+func CanonicalHostWithOptions(host string, opts HostRedirectOptions) func(http.Handler) http.Handler {
+	return hostRedirect(func(h string) string {
+		return host
+	}, opts)
+}
+
+// StripWWW redirects www.example.com to example.com
+func StripWWW() func(http.Handler) http.Handler {
+	return StripWWWWithOptions(HostRedirectOptions{})
+}
+
+// StripWWWWithOptions is StripWWW with options
+func StripWWWWithOptions(opts HostRedirectOptions) func(http.Handler) http.Handler {
+	return hostRedirect(func(h string) string {
+		return strings.TrimPrefix(h, "www.")
+	}, opts)
+}
+
+// AddWWW redirects example.com to www.example.com. IP addresses and
+// single-label hosts such as localhost are left alone.
+func AddWWW() func(http.Handler) http.Handler {
+	return AddWWWWithOptions(HostRedirectOptions{})
+}
+
+// AddWWWWithOptions is AddWWW with options
+func AddWWWWithOptions(opts HostRedirectOptions) func(http.Handler) http.Handler {
+	return hostRedirect(func(h string) string {
+		name := stripPort(h)
+		if strings.HasPrefix(h, "www.") || !strings.Contains(name, ".") || net.ParseIP(name) != nil {
+			return h
+		}
+		return "www." + h
+	}, opts)
+}
+
+func hostRedirect(canonical func(host string) string, opts HostRedirectOptions) func(http.Handler) http.Handler {
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			target := canonical(r.Host)
+			if target == "" || strings.EqualFold(target, r.Host) || isACMEChallenge(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme := "http"
+			if isHTTPS(r, trustedProxies.contains(peerIP(r))) {
+				scheme = "https"
+			}
+			permanentRedirect(w, r, scheme+"://"+target+BasePath(r.Context())+r.URL.RequestURI())
+		})
+	}
+}
+
+// permanentRedirect uses 301 for safe methods and 308 otherwise, so that
+// clients don't turn a redirected POST into a GET
+func permanentRedirect(w http.ResponseWriter, r *http.Request, url string) {
+	status := http.StatusMovedPermanently
+	if r.Method != MethodGet && r.Method != MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, url, status)
+}
+
+func isHTTPS(r *http.Request, trustForwardedProto bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if trustForwardedProto {
+		proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+		return strings.EqualFold(strings.TrimSpace(proto), "https")
+	}
+	return false
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
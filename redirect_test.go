@@ -0,0 +1,120 @@
+package GoFlow
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirects(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("HTTPS Redirect", func(t *testing.T) {
+		handler := HTTPSRedirect(RedirectOptions{TrustForwardedProto: true, HSTS: true})(ok)
+
+		tests := []struct {
+			name     string
+			method   string
+			url      string
+			proto    string
+			tls      bool
+			expected int
+			location string
+		}{
+			{"plain GET", MethodGet, "http://example.com:8080/a?b=c", "", false, http.StatusMovedPermanently, "https://example.com/a?b=c"},
+			{"plain POST", MethodPost, "http://example.com/form", "", false, http.StatusPermanentRedirect, "https://example.com/form"},
+			{"proxied https", MethodGet, "http://example.com/", "https", false, http.StatusOK, ""},
+			{"direct tls", MethodGet, "https://example.com/", "", true, http.StatusOK, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(tt.method, tt.url, nil)
+				if tt.proto != "" {
+					r.Header.Set("X-Forwarded-Proto", tt.proto)
+				}
+				if !tt.tls {
+					r.TLS = nil
+				} else if r.TLS == nil {
+					r.TLS = &tls.ConnectionState{}
+				}
+				handler.ServeHTTP(w, r)
+
+				if w.Code != tt.expected {
+					t.Errorf("Expected status code %d, got %d", tt.expected, w.Code)
+				}
+				if got := w.Header().Get("Location"); got != tt.location {
+					t.Errorf("Expected location '%s', got '%s'", tt.location, got)
+				}
+				if tt.expected == http.StatusOK && w.Header().Get("Strict-Transport-Security") == "" {
+					t.Error("Expected HSTS header on HTTPS response")
+				}
+			})
+		}
+	})
+
+	t.Run("Forwarded Proto Ignored Unless Trusted", func(t *testing.T) {
+		handler := HTTPSRedirect(RedirectOptions{})(ok)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, "http://example.com/", nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusMovedPermanently {
+			t.Errorf("Expected status code %d, got %d", http.StatusMovedPermanently, w.Code)
+		}
+	})
+
+	t.Run("Canonical Host", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			mw       func(http.Handler) http.Handler
+			host     string
+			location string
+		}{
+			{"canonical", CanonicalHost("example.com"), "api.example.org", "http://example.com/p?q=1"},
+			{"already canonical", CanonicalHost("example.com"), "example.com", ""},
+			{"strip www", StripWWW(), "www.example.com", "http://example.com/p?q=1"},
+			{"add www", AddWWW(), "example.com", "http://www.example.com/p?q=1"},
+			{"add www skips localhost", AddWWW(), "localhost:8080", ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(MethodGet, "/p?q=1", nil)
+				r.Host = tt.host
+				tt.mw(ok).ServeHTTP(w, r)
+
+				if got := w.Header().Get("Location"); got != tt.location {
+					t.Errorf("Expected location '%s', got '%s'", tt.location, got)
+				}
+			})
+		}
+	})
+
+	t.Run("Canonical Host Behind Proxy", func(t *testing.T) {
+		mw := StripWWWWithOptions(HostRedirectOptions{TrustedProxies: []string{"10.0.0.1"}})
+		tests := []struct {
+			remoteAddr string
+			location   string
+		}{
+			{"10.0.0.1:1234", "https://example.com/p"},
+			{"203.0.113.9:1234", "http://example.com/p"},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(MethodGet, "/p", nil)
+			r.Host = "www.example.com"
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("X-Forwarded-Proto", "https")
+			mw(ok).ServeHTTP(w, r)
+
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("From %s: expected location '%s', got '%s'", tt.remoteAddr, tt.location, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CapturedRequest is a request recorded by a TrafficCapture or read from a
+// HAR file, ready to be replayed
+type CapturedRequest struct {
+	Method  string
+	URL     string
+	Header  http.Header
+	Body    []byte
+	Started time.Time
+
+	// Status is the status the request was originally answered with
+	Status int
+}
+
+// ReplayOptions configures Mux.Replay
+type ReplayOptions struct {
+	// Header overrides the captured headers, e.g. with credentials for
+	// staging in place of the redacted ones. An empty value removes the
+	// header.
+	Header http.Header
+
+	// DryRun builds the requests without serving them, to check what a
+	// replay would send
+	DryRun bool
+
+	// Filter selects the requests to replay. Defaults to all.
+	Filter func(r *http.Request) bool
+}
+
+// ReplayResult is the outcome of one replayed request
+type ReplayResult struct {
+	Request *http.Request
+
+	// OriginalStatus is the captured status. Status, Header and Body are
+	// the replay's response, unset in dry runs.
+	OriginalStatus int
+	Status         int
+	Header         http.Header
+	Body           []byte
+	Duration       time.Duration
+}
+
+// Changed reports whether the replay was answered with a different status
+// than the original request
+func (r ReplayResult) Changed() bool {
+	return r.Status != 0 && r.Status != r.OriginalStatus
+}
+
+// Requests returns the captured requests, oldest first
+func (c *TrafficCapture) Requests() []CapturedRequest {
+	entries := c.snapshot()
+	reqs := make([]CapturedRequest, len(entries))
+	for i, e := range entries {
+		reqs[i] = e.captured()
+	}
+	return reqs
+}
+
+// ReadHAR reads the requests of a HAR file, e.g. one exported by
+// HandleCapture or saved from browser devtools
+func ReadHAR(r io.Reader) ([]CapturedRequest, error) {
+	var har struct {
+		Log *harLog `json:"log"`
+	}
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, err
+	}
+	if har.Log == nil {
+		return nil, errors.New("GoFlow: HAR file has no log")
+	}
+	reqs := make([]CapturedRequest, len(har.Log.Entries))
+	for i, e := range har.Log.Entries {
+		reqs[i] = e.captured()
+	}
+	return reqs, nil
+}
+
+func (e harEntry) captured() CapturedRequest {
+	req := CapturedRequest{
+		Method: e.Request.Method,
+		URL:    e.Request.URL,
+		Header: make(http.Header),
+		Status: e.Response.Status,
+	}
+	req.Started, _ = time.Parse(time.RFC3339Nano, e.StartedDateTime)
+	for _, h := range e.Request.Headers {
+		// Browsers export HTTP/2 pseudo-headers such as :authority
+		if !strings.HasPrefix(h.Name, ":") {
+			req.Header.Add(h.Name, h.Value)
+		}
+	}
+	if e.Request.PostData != nil {
+		req.Body = []byte(e.Request.PostData.Text)
+	}
+	return req
+}
+
+// Replay serves captured requests through the mux, one at a time in order,
+// to reproduce production issues in staging. Headers that were redacted
+// when captured are left out, so pass credentials in opts.Header.
+// Responses are buffered in memory, never sent anywhere.
+func (m *Mux) Replay(ctx context.Context, reqs []CapturedRequest, opts ReplayOptions) ([]ReplayResult, error) {
+	var results []ReplayResult
+	for _, captured := range reqs {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		r, err := captured.request(ctx, opts.Header)
+		if err != nil {
+			return results, err
+		}
+		if opts.Filter != nil && !opts.Filter(r) {
+			continue
+		}
+		result := ReplayResult{Request: r, OriginalStatus: captured.Status}
+		if !opts.DryRun {
+			rec := &replayRecorder{header: make(http.Header)}
+			start := time.Now()
+			m.ServeHTTP(rec, r)
+			result.Duration = time.Since(start)
+			result.Status, result.Header, result.Body = rec.status, rec.header, rec.body.Bytes()
+			if result.Status == 0 {
+				result.Status = http.StatusOK
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// request builds the request to serve, with overrides applied
+func (c CapturedRequest) request(ctx context.Context, overrides http.Header) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, c.Method, c.URL, bytes.NewReader(c.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Body) == 0 {
+		r.Body = http.NoBody
+	}
+	r.RequestURI = r.URL.RequestURI()
+	r.RemoteAddr = "127.0.0.1:0"
+	for name, values := range c.Header {
+		if name == "Content-Length" {
+			// Redaction may have changed the body
+			continue
+		}
+		for _, v := range values {
+			if v != Redacted {
+				r.Header.Add(name, v)
+			}
+		}
+	}
+	for name, values := range overrides {
+		r.Header.Del(name)
+		for _, v := range values {
+			if v != "" {
+				r.Header.Add(name, v)
+			}
+		}
+	}
+	if host := r.Header.Get("Host"); host != "" {
+		r.Host = host
+		r.Header.Del("Host")
+	}
+	return r, nil
+}
+
+// replayRecorder buffers a replayed response
+type replayRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *replayRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *replayRecorder) WriteHeader(status int) {
+	if rec.status == 0 || rec.status < http.StatusOK {
+		rec.status = status
+	}
+}
+
+func (rec *replayRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(b)
+}
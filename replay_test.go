@@ -0,0 +1,88 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	var prod, staging int
+	handler := func(counter *int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*counter++
+			if r.Header.Get("Authorization") != "Bearer staging" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(r.Host + " " + string(body)))
+		})
+	}
+
+	production := New()
+	capture := NewTrafficCapture(CaptureOptions{Authorize: func(*http.Request) bool { return true }})
+	production.Use(Capture(capture))
+	production.Handle("/orders", handler(&prod), MethodPost)
+	for _, body := range []string{`{"id":1,"password":"pw"}`, `{"id":2}`} {
+		r := httptest.NewRequest(MethodPost, "/orders", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Authorization", "Bearer prod")
+		production.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	stagingMux := New()
+	stagingMux.Handle("/orders", handler(&staging), MethodPost)
+	opts := ReplayOptions{Header: http.Header{"Authorization": {"Bearer staging"}}}
+
+	t.Run("From Capture", func(t *testing.T) {
+		results, err := stagingMux.Replay(context.Background(), capture.Requests(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 2 || staging != 2 {
+			t.Fatalf("Expected 2 requests replayed, got %d results and %d calls", len(results), staging)
+		}
+		if got := string(results[0].Body); got != `example.com {"id":1,"password":"[REDACTED]"}` {
+			t.Errorf("Expected the redacted body replayed with the override, got %q", got)
+		}
+		if results[0].OriginalStatus != http.StatusUnauthorized || !results[0].Changed() {
+			t.Errorf("Expected the status change reported, got %d -> %d", results[0].OriginalStatus, results[0].Status)
+		}
+	})
+
+	t.Run("From HAR File", func(t *testing.T) {
+		var har bytes.Buffer
+		capture.WriteHAR(&har)
+		reqs, err := ReadHAR(&har)
+		if err != nil || len(reqs) != 2 {
+			t.Fatalf("Expected 2 requests from the HAR file, got %d, %v", len(reqs), err)
+		}
+		if reqs[1].Method != MethodPost || string(reqs[1].Body) != `{"id":2}` || reqs[1].Header.Get("Authorization") != Redacted {
+			t.Errorf("Expected the captured request, got %+v", reqs[1])
+		}
+		if _, err := ReadHAR(strings.NewReader(`{}`)); err == nil {
+			t.Error("Expected an error for a file without a log")
+		}
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		staging = 0
+		dry := opts
+		dry.DryRun = true
+		results, err := stagingMux.Replay(context.Background(), capture.Requests(), dry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if staging != 0 || len(results) != 2 || results[0].Status != 0 {
+			t.Errorf("Expected nothing served, got %d calls", staging)
+		}
+		if results[0].Request.Header.Get("Authorization") != "Bearer staging" {
+			t.Errorf("Expected the override applied, got %v", results[0].Request.Header)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package GoFlow
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// writerCore is implemented by every response wrapper in GoFlow. Wrappers
+// only provide the behaviour they change; wrapWriter turns them into the
+// writer handed to the next handler, exposing exactly the optional
+// interfaces of the writer underneath so streaming, websockets, sendfile
+// and server push keep working through any stack of middleware.
+type writerCore interface {
+	http.ResponseWriter
+	http.Flusher
+	io.ReaderFrom
+	unwrapper
+}
+
+// unwrapper lets http.ResponseController reach the underlying writer
+type unwrapper interface {
+	Unwrap() http.ResponseWriter
+}
+
+// writerOnly hides ReadFrom so io.Copy falls back to Write
+type writerOnly struct {
+	io.Writer
+}
+
+// hijackPassthrough and pushPassthrough forward to the underlying writer
+// for wrappers that don't need to see hijacks or pushes
+type hijackPassthrough struct {
+	w http.ResponseWriter
+}
+
+func (p hijackPassthrough) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return p.w.(http.Hijacker).Hijack()
+}
+
+type pushPassthrough struct {
+	w http.ResponseWriter
+}
+
+func (p pushPassthrough) Push(target string, opts *http.PushOptions) error {
+	return p.w.(http.Pusher).Push(target, opts)
+}
+
+// wrapWriter returns core extended with the http.Flusher, http.Hijacker,
+// http.Pusher and io.ReaderFrom implementations of the writer it wraps.
+// Hijacks go through core when it implements http.Hijacker itself.
+func wrapWriter(core writerCore) http.ResponseWriter {
+	under := core.Unwrap()
+	var (
+		hj    http.Hijacker
+		ps    http.Pusher
+		flags int
+	)
+	if _, ok := under.(http.Flusher); ok {
+		flags |= 1
+	}
+	if _, ok := under.(http.Hijacker); ok {
+		flags |= 2
+		if hj, ok = core.(http.Hijacker); !ok {
+			hj = hijackPassthrough{under}
+		}
+	}
+	if _, ok := under.(http.Pusher); ok {
+		flags |= 4
+		ps = pushPassthrough{under}
+	}
+	if _, ok := under.(io.ReaderFrom); ok {
+		flags |= 8
+	}
+
+	switch flags {
+	case 0:
+		return struct {
+			http.ResponseWriter
+			unwrapper
+		}{core, core}
+	case 1:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			unwrapper
+		}{core, core, core}
+	case 2:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			unwrapper
+		}{core, hj, core}
+	case 3:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			unwrapper
+		}{core, core, hj, core}
+	case 4:
+		return struct {
+			http.ResponseWriter
+			http.Pusher
+			unwrapper
+		}{core, ps, core}
+	case 5:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Pusher
+			unwrapper
+		}{core, core, ps, core}
+	case 6:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			http.Pusher
+			unwrapper
+		}{core, hj, ps, core}
+	case 7:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			unwrapper
+		}{core, core, hj, ps, core}
+	case 8:
+		return struct {
+			http.ResponseWriter
+			io.ReaderFrom
+			unwrapper
+		}{core, core, core}
+	case 9:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			io.ReaderFrom
+			unwrapper
+		}{core, core, core, core}
+	case 10:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			io.ReaderFrom
+			unwrapper
+		}{core, hj, core, core}
+	case 11:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+			unwrapper
+		}{core, core, hj, core, core}
+	case 12:
+		return struct {
+			http.ResponseWriter
+			http.Pusher
+			io.ReaderFrom
+			unwrapper
+		}{core, ps, core, core}
+	case 13:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+			unwrapper
+		}{core, core, ps, core, core}
+	case 14:
+		return struct {
+			http.ResponseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+			unwrapper
+		}{core, hj, ps, core, core}
+	default:
+		return struct {
+			http.ResponseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+			unwrapper
+		}{core, core, hj, ps, core, core}
+	}
+}
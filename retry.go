@@ -0,0 +1,345 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryOptions configures the Retry middleware and RetryTransport
+type RetryOptions struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Defaults to 3.
+	MaxAttempts int
+
+	// BaseDelay is the backoff before the first retry, doubling with every
+	// further attempt up to MaxDelay. Defaults to 50ms and 2s. Delays are
+	// jittered, and a Retry-After longer than MaxDelay stops retrying.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// RetryOn reports whether a response status should be retried.
+	// Defaults to 502, 503 and 504.
+	RetryOn func(status int) bool
+
+	// Methods are retried without further checks. Defaults to the idempotent
+	// methods GET, HEAD, OPTIONS, TRACE, PUT and DELETE. Other methods are
+	// only retried when the request carries an Idempotency-Key header.
+	Methods []string
+
+	// MaxBodySize is the largest request body the middleware buffers for
+	// replay; larger requests, including chunked ones found to be larger
+	// while reading, are attempted once. Defaults to 1MB.
+	MaxBodySize int64
+
+	// Budget caps retries across all requests so that retries can't
+	// multiply load on a struggling backend
+	Budget *RetryBudget
+}
+
+// RetryBudget allows retries up to a fraction of recent requests, plus a
+// fixed number per window that is always available
+type RetryBudget struct {
+	ratio      float64
+	minRetries int
+	window     time.Duration
+
+	mu       sync.Mutex
+	start    time.Time
+	requests int
+	retries  int
+}
+
+// NewRetryBudget allows ratio retries per request (e.g. 0.1 for 10%) and at
+// least minRetries retries every ten seconds
+func NewRetryBudget(ratio float64, minRetries int) *RetryBudget {
+	return &RetryBudget{ratio: ratio, minRetries: minRetries, window: 10 * time.Second}
+}
+
+func (b *RetryBudget) rollLocked() {
+	if now := time.Now(); now.Sub(b.start) > b.window {
+		b.start, b.requests, b.retries = now, 0, 0
+	}
+}
+
+func (b *RetryBudget) request() {
+	b.mu.Lock()
+	b.rollLocked()
+	b.requests++
+	b.mu.Unlock()
+}
+
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollLocked()
+	if b.retries < b.minRetries || float64(b.retries) < b.ratio*float64(b.requests) {
+		b.retries++
+		return true
+	}
+	return false
+}
+
+type retryPolicy struct {
+	RetryOptions
+	methods map[string]struct{}
+}
+
+func newRetryPolicy(opts RetryOptions) *retryPolicy {
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 3
+	}
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = 50 * time.Millisecond
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = 2 * time.Second
+	}
+	if opts.RetryOn == nil {
+		opts.RetryOn = func(status int) bool {
+			return status == http.StatusBadGateway ||
+				status == http.StatusServiceUnavailable ||
+				status == http.StatusGatewayTimeout
+		}
+	}
+	if opts.Methods == nil {
+		opts.Methods = []string{MethodGet, MethodHead, MethodOptions, MethodTrace, MethodPut, MethodDelete}
+	}
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = 1 << 20
+	}
+
+	p := &retryPolicy{RetryOptions: opts, methods: make(map[string]struct{})}
+	for _, m := range opts.Methods {
+		p.methods[m] = struct{}{}
+	}
+	return p
+}
+
+func (p *retryPolicy) retryable(r *http.Request) bool {
+	if _, ok := p.methods[r.Method]; ok {
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
+
+// wait sleeps before the given retry and reports whether to go ahead
+func (p *retryPolicy) wait(ctx context.Context, retry int, header http.Header) bool {
+	if p.Budget != nil && !p.Budget.withdraw() {
+		return false
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	delay = delay/2 + rand.N(delay/2+1)
+
+	if after, ok := retryAfter(header); ok {
+		if after > p.MaxDelay {
+			return false
+		}
+		delay = max(delay, after)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header in seconds or HTTP-date form
+func retryAfter(header http.Header) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// Retry re-runs the handler for idempotent requests that fail with a
+// retryable status, typically a reverse proxy answering 502 on connection
+// errors. The response is buffered so that only the final attempt reaches
+// the client, so don't use it for streaming handlers.
+func Retry(opts RetryOptions) func(http.Handler) http.Handler {
+	p := newRetryPolicy(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p.Budget != nil {
+				p.Budget.request()
+			}
+			if !p.retryable(r) || r.ContentLength > p.MaxBodySize || isStreamingRPC(r) || isWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				body, err = io.ReadAll(io.LimitReader(r.Body, p.MaxBodySize+1))
+				if err != nil {
+					r.Body.Close()
+					http.Error(w, StatusText(r.Context(), http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				if int64(len(body)) > p.MaxBodySize {
+					// Too large to replay: send what was read and the rest
+					// of the body to a single attempt
+					debugNote(r.Context(), "retry: body over %d bytes, attempting once", p.MaxBodySize)
+					r.Body = struct {
+						io.Reader
+						io.Closer
+					}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+					next.ServeHTTP(w, r)
+					return
+				}
+				r.Body.Close()
+			}
+
+			for attempt := 1; ; attempt++ {
+				if body != nil {
+					r.Body = io.NopCloser(bytes.NewReader(body))
+				}
+				rec := &retryRecorder{w: w, header: make(http.Header), status: http.StatusOK}
+				next.ServeHTTP(rec, r)
+
+				if rec.streaming || attempt >= p.MaxAttempts || !p.RetryOn(rec.status) || !p.wait(r.Context(), attempt, rec.header) {
+					rec.flush(w)
+					return
+				}
+				rec.reserved.free()
+			}
+		})
+	}
+}
+
+// RetryTransport wraps base (http.DefaultTransport if nil) to retry
+// idempotent requests on connection errors and retryable statuses
+func RetryTransport(base http.RoundTripper, opts RetryOptions) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{base: base, policy: newRetryPolicy(opts)}
+}
+
+type retryTransport struct {
+	base   http.RoundTripper
+	policy *retryPolicy
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.policy
+	if p.Budget != nil {
+		p.Budget.request()
+	}
+	// A consumed body can only be replayed through GetBody
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	if !p.retryable(req) || !replayable {
+		return t.base.RoundTrip(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= p.MaxAttempts || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		header := http.Header{}
+		if err == nil {
+			if !p.RetryOn(resp.StatusCode) {
+				return resp, nil
+			}
+			header = resp.Header
+		}
+		if !p.wait(req.Context(), attempt, header) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+	}
+}
+
+// retryRecorder buffers one attempt's response. Once the buffer budget is
+// exhausted it streams the attempt to w instead, which then can't be
+// retried.
+type retryRecorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	buf         bytes.Buffer
+	reserved    bufferReservation
+	streaming   bool
+}
+
+func (rec *retryRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *retryRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+}
+
+func (rec *retryRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	if rec.streaming {
+		return rec.w.Write(b)
+	}
+	if !rec.reserved.grow(len(b)) {
+		rec.streaming = true
+		copyHeaders(rec.w.Header(), rec.header)
+		rec.w.WriteHeader(rec.status)
+		if _, err := rec.w.Write(rec.buf.Bytes()); err != nil {
+			return 0, err
+		}
+		rec.buf = bytes.Buffer{}
+		rec.reserved.free()
+		return rec.w.Write(b)
+	}
+	return rec.buf.Write(b)
+}
+
+func (rec *retryRecorder) flush(w http.ResponseWriter) {
+	defer rec.reserved.free()
+	if rec.streaming {
+		copyTrailers(w.Header(), rec.header)
+		return
+	}
+	copyHeaders(w.Header(), rec.header)
+	w.WriteHeader(rec.status)
+	w.Write(rec.buf.Bytes())
+	copyTrailers(w.Header(), rec.header)
+}
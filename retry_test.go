@@ -0,0 +1,146 @@
+package GoFlow
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flaky fails with status for the first n calls
+func flaky(n int32, status int, calls *int32, header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if atomic.AddInt32(calls, 1) <= n {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			w.Write([]byte("failed"))
+			return
+		}
+		w.Write(append([]byte("ok:"), body...))
+	})
+}
+
+func TestRetry(t *testing.T) {
+	opts := RetryOptions{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	t.Run("Retries Idempotent Requests", func(t *testing.T) {
+		var calls int32
+		handler := Retry(opts)(flaky(2, http.StatusBadGateway, &calls, nil))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodPut, "/items/1", strings.NewReader("payload")))
+
+		if w.Code != http.StatusOK || w.Body.String() != "ok:payload" {
+			t.Errorf("Expected successful replay, got %d '%s'", w.Code, w.Body.String())
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("Non-Idempotent Requests", func(t *testing.T) {
+		var calls int32
+		handler := Retry(opts)(flaky(1, http.StatusServiceUnavailable, &calls, nil))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodPost, "/orders", strings.NewReader("{}")))
+		if w.Code != http.StatusServiceUnavailable || calls != 1 {
+			t.Errorf("Expected POST to be attempted once, got %d after %d calls", w.Code, calls)
+		}
+
+		calls = 0
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(MethodPost, "/orders", strings.NewReader("{}"))
+		r.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || calls != 2 {
+			t.Errorf("Expected POST with Idempotency-Key to be retried, got %d after %d calls", w.Code, calls)
+		}
+	})
+
+	t.Run("Oversized Bodies", func(t *testing.T) {
+		var calls int32
+		handler := Retry(RetryOptions{BaseDelay: time.Millisecond, MaxBodySize: 8})(flaky(1, http.StatusBadGateway, &calls, nil))
+
+		// A chunked body only turns out to be too large while reading
+		r := httptest.NewRequest(MethodPut, "/items/1", io.MultiReader(strings.NewReader("0123456789abcdef")))
+		r.ContentLength = -1
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusBadGateway || calls != 1 {
+			t.Errorf("Expected a single attempt, got %d after %d calls", w.Code, calls)
+		}
+
+		r = httptest.NewRequest(MethodPut, "/items/1", io.MultiReader(strings.NewReader("0123456789abcdef")))
+		r.ContentLength = -1
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Body.String() != "ok:0123456789abcdef" {
+			t.Errorf("Expected the whole body passed on, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("Gives Up And Returns Last Response", func(t *testing.T) {
+		var calls int32
+		handler := Retry(opts)(flaky(10, http.StatusGatewayTimeout, &calls, http.Header{"X-Upstream": {"a"}}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+		if w.Code != http.StatusGatewayTimeout || w.Body.String() != "failed" || w.Header().Get("X-Upstream") != "a" {
+			t.Errorf("Expected last failure to be returned, got %d '%s'", w.Code, w.Body.String())
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+	})
+
+	t.Run("Long Retry-After Stops Retrying", func(t *testing.T) {
+		var calls int32
+		handler := Retry(opts)(flaky(1, http.StatusServiceUnavailable, &calls, http.Header{"Retry-After": {"120"}}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+		if w.Code != http.StatusServiceUnavailable || calls != 1 {
+			t.Errorf("Expected no retry beyond MaxDelay, got %d after %d calls", w.Code, calls)
+		}
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		var calls int32
+		budgeted := opts
+		budgeted.Budget = NewRetryBudget(0, 1)
+		handler := Retry(budgeted)(flaky(100, http.StatusBadGateway, &calls, nil))
+
+		for i := 0; i < 3; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+		}
+		if calls != 4 {
+			t.Errorf("Expected a single retry from the budget (4 calls), got %d", calls)
+		}
+	})
+
+	t.Run("Transport", func(t *testing.T) {
+		var calls int32
+		srv := httptest.NewServer(flaky(2, http.StatusServiceUnavailable, &calls, nil))
+		defer srv.Close()
+
+		client := &http.Client{Transport: RetryTransport(nil, opts)}
+		req, _ := http.NewRequest(MethodPut, srv.URL, strings.NewReader("data"))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+
+		if resp.StatusCode != http.StatusOK || string(body) != "ok:data" {
+			t.Errorf("Expected successful retry, got %d '%s'", resp.StatusCode, body)
+		}
+	})
+}
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package GoFlow
+
+import (
+	"errors"
+	"syscall"
+)
+
+func reusePort(network, address string, c syscall.RawConn) error {
+	return errors.New("GoFlow: ReusePort is not supported on this platform")
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package GoFlow
+
+import (
+	"runtime"
+	"syscall"
+)
+
+// reusePort sets SO_REUSEPORT, which the syscall package doesn't export
+func reusePort(network, address string, c syscall.RawConn) error {
+	opt := 0x200
+	if runtime.GOOS == "linux" {
+		opt = 0xf
+	}
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, opt, 1)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
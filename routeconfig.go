@@ -0,0 +1,232 @@
+package GoFlow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RouteRegistry names the handlers and middleware that route configuration
+// files can refer to
+type RouteRegistry struct {
+	handlers    map[string]http.Handler
+	middlewares map[string]func(http.Handler) http.Handler
+}
+
+// NewRouteRegistry creates an empty registry
+func NewRouteRegistry() *RouteRegistry {
+	return &RouteRegistry{
+		handlers:    make(map[string]http.Handler),
+		middlewares: make(map[string]func(http.Handler) http.Handler),
+	}
+}
+
+// Handler registers h under name
+func (reg *RouteRegistry) Handler(name string, h http.Handler) *RouteRegistry {
+	reg.handlers[name] = h
+	return reg
+}
+
+// HandlerFunc registers fn under name
+func (reg *RouteRegistry) HandlerFunc(name string, fn http.HandlerFunc) *RouteRegistry {
+	return reg.Handler(name, fn)
+}
+
+// Middleware registers mw under name
+func (reg *RouteRegistry) Middleware(name string, mw func(http.Handler) http.Handler) *RouteRegistry {
+	reg.middlewares[name] = mw
+	return reg
+}
+
+// RouteConfig is the file format read by LoadRoutes
+type RouteConfig struct {
+	Routes []RouteDefinition `json:"routes"`
+}
+
+// RouteDefinition describes one route of a configuration file
+type RouteDefinition struct {
+	Pattern string   `json:"pattern"`
+	Methods []string `json:"methods"`
+	Handler string   `json:"handler"`
+
+	// Middleware names run in order after the mux's own middleware
+	Middleware []string `json:"middleware"`
+
+	Timeout   string   `json:"timeout"`
+	BodyLimit string   `json:"body_limit"`
+	Consumes  []string `json:"consumes"`
+	Produces  []string `json:"produces"`
+
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Deprecated  bool     `json:"deprecated"`
+	Hidden      bool     `json:"hidden"`
+
+	// Metadata is stored on the route with Route.Set under string keys
+	Metadata map[string]any `json:"metadata"`
+}
+
+// LoadRoutes registers the routes defined by a YAML or JSON document against
+// the handlers and middleware in reg:
+//
+//	routes:
+//	  - pattern: /users/:id
+//	    methods: [GET]
+//	    handler: getUser
+//	    middleware: [auth]
+//	    timeout: 5s
+//
+// The whole document is validated first, so a bad file registers nothing
+// and the error lists every problem found.
+func (m *Mux) LoadRoutes(r io.Reader, reg *RouteRegistry) ([]*Route, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseRouteConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	return m.AddRoutes(config, reg)
+}
+
+// LoadRoutesFile is LoadRoutes reading from the file at path
+func (m *Mux) LoadRoutesFile(path string, reg *RouteRegistry) ([]*Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	routes, err := m.LoadRoutes(f, reg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return routes, nil
+}
+
+// ParseRouteConfig decodes a YAML or JSON route configuration. JSON is
+// detected by a leading '{'.
+func ParseRouteConfig(data []byte) (RouteConfig, error) {
+	var config RouteConfig
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		v, err := parseYAML(data)
+		if err != nil {
+			return config, err
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return config, fmt.Errorf("GoFlow: route config: %w", err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return config, fmt.Errorf("GoFlow: route config: %w", err)
+	}
+	return config, nil
+}
+
+// AddRoutes validates config against reg and registers its routes
+func (m *Mux) AddRoutes(config RouteConfig, reg *RouteRegistry) ([]*Route, error) {
+	type resolved struct {
+		handler     http.Handler
+		middlewares []func(http.Handler) http.Handler
+		timeout     time.Duration
+		timeoutSet  bool
+	}
+
+	var errs []error
+	defs := make([]resolved, len(config.Routes))
+	for i, def := range config.Routes {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("GoFlow: routes[%d] %s: %s", i, def.Pattern, fmt.Sprintf(format, args...)))
+		}
+
+		if def.Pattern == "" || def.Pattern[0] != '/' {
+			fail("pattern must start with '/'")
+		} else if err := checkPattern(def.Pattern); err != nil {
+			fail("%v", err)
+		}
+		for _, method := range def.Methods {
+			if _, ok := methodMap[strings.ToUpper(method)]; !ok {
+				fail("unknown method %q", method)
+			}
+		}
+		if h, ok := reg.handlers[def.Handler]; ok {
+			defs[i].handler = h
+		} else {
+			fail("unknown handler %q", def.Handler)
+		}
+		for _, name := range def.Middleware {
+			if mw, ok := reg.middlewares[name]; ok {
+				defs[i].middlewares = append(defs[i].middlewares, mw)
+			} else {
+				fail("unknown middleware %q", name)
+			}
+		}
+		if def.Timeout != "" {
+			d, err := time.ParseDuration(def.Timeout)
+			if err != nil {
+				fail("invalid timeout %q", def.Timeout)
+			}
+			defs[i].timeout, defs[i].timeoutSet = d, true
+		}
+		if def.BodyLimit != "" {
+			if _, err := ParseSize(def.BodyLimit); err != nil {
+				fail("invalid body_limit %q", def.BodyLimit)
+			}
+		}
+		if _, err := parseMediaTypes(slices.Concat(def.Consumes, def.Produces)); err != nil {
+			fail("%v", err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	routes := make([]*Route, len(config.Routes))
+	for i, def := range config.Routes {
+		rt := m.Handle(def.Pattern, defs[i].handler, def.Methods...)
+		for k, v := range def.Metadata {
+			rt.Set(k, v)
+		}
+		if defs[i].timeoutSet {
+			rt.Timeout(defs[i].timeout)
+		}
+		if def.BodyLimit != "" {
+			rt.BodyLimit(def.BodyLimit)
+		}
+		if def.Consumes != nil {
+			rt.Consumes(def.Consumes...)
+		}
+		if def.Produces != nil {
+			rt.Produces(def.Produces...)
+		}
+		if def.Summary != "" {
+			rt.Summary(def.Summary)
+		}
+		if def.Description != "" {
+			rt.Description(def.Description)
+		}
+		if def.Tags != nil {
+			rt.Tags(def.Tags...)
+		}
+		if def.Deprecated {
+			rt.Deprecated()
+		}
+		if def.Hidden {
+			rt.Hidden()
+		}
+		rt.With(defs[i].middlewares...)
+		routes[i] = rt
+	}
+	return routes, nil
+}
@@ -0,0 +1,174 @@
+package GoFlow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteConfig(t *testing.T) {
+	reg := NewRouteRegistry().
+		HandlerFunc("getUser", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("user " + Param(r.Context(), "id")))
+		}).
+		HandlerFunc("createUser", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}).
+		Middleware("tag", func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Tag", "config")
+				next.ServeHTTP(w, r)
+			})
+		})
+
+	t.Run("YAML", func(t *testing.T) {
+		mux := New()
+		routes, err := mux.LoadRoutes(strings.NewReader(`
+# Users service
+routes:
+  - pattern: /users/:id
+    methods: [GET]
+    handler: getUser
+    middleware:
+      - tag
+    timeout: 2s
+    summary: "Get a user"   # shown in the route table
+    metadata:
+      owner: team-a
+      weight: 3
+
+  - pattern: /users
+    methods: [POST]
+    handler: createUser
+    consumes: [application/json]
+    deprecated: true
+`), reg)
+		if err != nil {
+			t.Fatalf("Expected routes to load, got %v", err)
+		}
+		if len(routes) != 2 {
+			t.Fatalf("Expected 2 routes, got %d", len(routes))
+		}
+
+		get := routes[0]
+		if get.Value(timeoutKey{}) != 2*time.Second || get.Doc().Summary != "Get a user" {
+			t.Errorf("Expected timeout and summary, got %v '%s'", get.Value(timeoutKey{}), get.Doc().Summary)
+		}
+		if get.Value("owner") != "team-a" || get.Value("weight") != float64(3) {
+			t.Errorf("Expected metadata, got %v %v", get.Value("owner"), get.Value("weight"))
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/users/7", nil))
+		if w.Body.String() != "user 7" || w.Header().Get("X-Tag") != "config" {
+			t.Errorf("Expected handler and middleware to run, got '%s' '%s'", w.Body.String(), w.Header().Get("X-Tag"))
+		}
+
+		req := httptest.NewRequest(MethodPost, "/users", strings.NewReader("<x/>"))
+		req.Header.Set("Content-Type", "application/xml")
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusUnsupportedMediaType || !routes[1].Doc().Deprecated {
+			t.Errorf("Expected 415 from declared consumes, got %d", w.Code)
+		}
+	})
+
+	t.Run("JSON File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "routes.json")
+		os.WriteFile(path, []byte(`{"routes": [{"pattern": "/u/:id", "methods": ["GET"], "handler": "getUser"}]}`), 0o600)
+
+		mux := New()
+		if _, err := mux.LoadRoutesFile(path, reg); err != nil {
+			t.Fatalf("Expected routes to load, got %v", err)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/u/9", nil))
+		if w.Body.String() != "user 9" {
+			t.Errorf("Expected 'user 9', got '%s'", w.Body.String())
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		mux := New()
+		_, err := mux.LoadRoutes(strings.NewReader(`
+routes:
+  - pattern: /a
+    methods: [FETCH]
+    handler: missing
+    middleware: [nope]
+  - pattern: b
+    handler: getUser
+    timeout: soon
+    produces: ["bad type"]
+  - pattern: /c/:id|[0-9
+    handler: getUser
+`), reg)
+		if err == nil {
+			t.Fatal("Expected validation errors")
+		}
+		for _, want := range []string{
+			`routes[0] /a: unknown method "FETCH"`,
+			`routes[0] /a: unknown handler "missing"`,
+			`routes[0] /a: unknown middleware "nope"`,
+			`routes[1] b: pattern must start with '/'`,
+			`routes[1] b: invalid timeout "soon"`,
+			`routes[1] b: invalid media type "bad type"`,
+			`routes[2] /c/:id|[0-9: parameter :id: error parsing regexp`,
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error '%s', got %v", want, err)
+			}
+		}
+		if len(mux.Routes()) != 0 {
+			t.Errorf("Expected nothing registered, got %d routes", len(mux.Routes()))
+		}
+
+		if _, err := mux.LoadRoutes(strings.NewReader("routes:\n  - pattern: /a\n    handlr: getUser\n"), reg); err == nil || !strings.Contains(err.Error(), "handlr") {
+			t.Errorf("Expected unknown field error, got %v", err)
+		}
+	})
+}
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"Scalars", "a: 1\nb: 1.5\nc: true\nd: ~\ne: hello world\nf: \"quoted # not a comment\"\ng: 'it''s'\nh: 10.0.0.1",
+			`{"a":1,"b":1.5,"c":true,"d":null,"e":"hello world","f":"quoted # not a comment","g":"it's","h":"10.0.0.1"}`},
+		{"Nested Mapping", "server:\n  tls:\n    enabled: yes\n  port: 8080", `{"server":{"port":8080,"tls":{"enabled":"yes"}}}`},
+		{"Sequence Same Indent", "methods:\n- GET\n- POST\nname: x", `{"methods":["GET","POST"],"name":"x"}`},
+		{"Sequence Of Mappings", "- a: 1\n  b: 2\n-\n  a: 3\n- plain", `[{"a":1,"b":2},{"a":3},"plain"]`},
+		{"Nested Sequences", "- - 1\n  - 2\n- [3, 4]", `[[1,2],[3,4]]`},
+		{"Flow Mappings In Sequence", "routes:\n  - {pattern: /d, handler: h}\n  - [GET, POST]", `{"routes":[{"handler":"h","pattern":"/d"},["GET","POST"]]}`},
+		{"Flow Collections", `x: {a: [1, "two", {b: c}], d: ""}`, `{"x":{"a":[1,"two",{"b":"c"}],"d":""}}`},
+		{"Literal Block", "text: |\n  line one\n    indented\n\n  last\nnext: 1", `{"next":1,"text":"line one\n  indented\n\nlast\n"}`},
+		{"Folded Block", "text: >-\n  one\n  two\n\n  three\n", `{"text":"one two\nthree"}`},
+		{"Comments And Documents", "---\n# header\na: 1 # trailing\n\n# between\nb: url#fragment", `{"a":1,"b":"url#fragment"}`},
+		{"Empty Value", "a:\nb: 2", `{"a":null,"b":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := parseYAML([]byte(tt.src))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			got, _ := json.Marshal(v)
+			if string(got) != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	for _, src := range []string{"a: 1\n  b: 2", "a: [1, 2", "a: 1\na: 2", "- a\nb: 1", `a: "open`} {
+		if _, err := parseYAML([]byte(src)); err == nil {
+			t.Errorf("Expected an error for %q", src)
+		}
+	}
+}
@@ -1,166 +1,176 @@
 package GoFlow
 
 import (
+	"context"
 	"net/http"
-	"regexp"
 	"sort"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"time"
 )
 
-func (m *Mux) addRoute(pattern string, method string, handler http.Handler) {
-	segments := strings.Split(strings.Trim(pattern, "/"), "/")
-	current := m.root
-
-	for i, segment := range segments {
-		if segment == "..." {
-			current.isWildcard = true
-			if current.methods == nil {
-				current.methods = newMethodHandler()
-			}
-			current.methods.addHandler(method, handler)
-			return
-		}
+// Route is a registered pattern together with its handler, route-level
+// middleware and metadata
+type Route struct {
+	pattern     string
+	methods     []string
+	handler     http.Handler
+	middlewares []func(http.Handler) http.Handler
+	local       []func(http.Handler) http.Handler
+	meta        map[interface{}]interface{}
+	chain       http.Handler
+	table       *routeTable
+	mux         *Mux
+
+	// deprecatedCalls counts requests to a deprecated route for Stats
+	deprecatedCalls atomic.Uint64
+	// authFailures counts rejected authentication attempts for Stats
+	authFailures atomic.Uint64
+}
 
-		var child *routeTree
-		if strings.HasPrefix(segment, ":") {
-			paramName, rxPattern, hasRx := strings.Cut(strings.TrimPrefix(segment, ":"), "|")
-			child = m.findOrCreateChild(current, "", paramName)
+type routeContextKey struct{}
 
-			if hasRx {
-				child.rxPattern = m.compilePattern(rxPattern)
-			}
-		} else {
-			child = m.findOrCreateChild(current, segment, "")
-		}
-
-		if i == len(segments)-1 {
-			if child.methods == nil {
-				child.methods = newMethodHandler()
-			}
-			child.methods.addHandler(method, handler)
-		}
-		current = child
+func newRoute(m *Mux, pattern string, handler http.Handler, methods []string) *Route {
+	route := &Route{
+		pattern:     pattern,
+		methods:     make([]string, len(methods)),
+		handler:     handler,
+		middlewares: make([]func(http.Handler) http.Handler, len(m.middlewares)),
+		table:       m.table,
+		mux:         m,
+	}
+	for i, method := range methods {
+		route.methods[i] = strings.ToUpper(method)
 	}
+	copy(route.middlewares, m.middlewares)
+	for k, v := range m.meta {
+		route.Set(k, v)
+	}
+	route.compile()
+	return route
 }
 
-// Replace existing findHandler with this
-func (m *Mux) findHandler(node *routeTree, segments []string, params map[string]string) (*methodHandler, map[string]string, bool) {
-	if len(segments) == 0 {
-		return node.methods, params, true
-	}
+// Pattern returns the pattern the route was registered with
+func (rt *Route) Pattern() string {
+	return rt.pattern
+}
 
-	segment := segments[0]
-	remaining := segments[1:]
+// Methods returns the HTTP methods the route answers to
+func (rt *Route) Methods() []string {
+	return rt.methods
+}
 
-	// Static route lookup (most common case)
-	if child := node.children[segment]; child != nil {
-		return m.findHandler(child, remaining, params)
-	}
+// With adds middleware that only applies to this route. It runs after the
+// middleware inherited from the mux or group.
+func (rt *Route) With(mw ...func(http.Handler) http.Handler) *Route {
+	rt.local = append(rt.local, mw...)
+	rt.compile()
+	return rt
+}
 
-	// Parameter matching with fast path for non-regex
-	if pc := node.paramChild; pc != nil {
-		if pc.rxPattern == nil {
-			params[pc.paramName] = segment
-			return m.findHandler(pc, remaining, params)
-		}
-		if pc.rxPattern.MatchString(segment) {
-			params[pc.paramName] = segment
-			return m.findHandler(pc, remaining, params)
-		}
+// Set attaches a metadata value to the route for middleware to consume
+func (rt *Route) Set(key, value interface{}) *Route {
+	if rt.meta == nil {
+		rt.meta = make(map[interface{}]interface{})
 	}
-
-	return nil, nil, false
+	rt.meta[key] = value
+	return rt
 }
 
-// Add this new method
-func (m *Mux) findHandlerInternal(node *routeTree, segments []string, params map[string]string) (*methodHandler, map[string]string, bool) {
-	if len(segments) == 0 {
-		return node.methods, params, true
-	}
+// Value returns the metadata value stored under key
+func (rt *Route) Value(key interface{}) interface{} {
+	return rt.meta[key]
+}
 
-	segment := segments[0]
-	remaining := segments[1:]
+// ServeHTTP runs the route's middleware chain with the route in the context
+func (rt *Route) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.serve(w, r, nil)
+}
 
-	// Static route matching with string compare
-	if child := node.children[segment]; child != nil {
-		if methods, p, found := m.findHandlerInternal(child, remaining, params); found {
-			return methods, p, true
-		}
+// serve also installs the matched parameters, so the request is only
+// copied once
+func (rt *Route) serve(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	if h, ok := r.Context().Value(logHolderKey{}).(*logHolder); ok {
+		h.route, h.params = rt, params
 	}
-
-	// Parameter matching with early return
-	if pc := node.paramChild; pc != nil && (pc.rxPattern == nil || len(segment) < 20 && pc.rxPattern.MatchString(segment)) {
-		params[pc.paramName] = segment
-		if methods, p, found := m.findHandlerInternal(pc, remaining, params); found {
-			return methods, p, true
+	ctx := context.WithValue(r.Context(), routeContextKey{}, rt)
+	if len(params) > 0 {
+		ctx = context.WithValue(ctx, paramContextKey{}, params)
+	}
+	if rt.table != nil && rt.table.debug != nil {
+		if trace, ok := ctx.Value(debugTraceKey{}).(*DebugTrace); ok {
+			rt.traced(trace, params).ServeHTTP(w, r.WithContext(ctx))
+			return
 		}
-		delete(params, pc.paramName)
 	}
+	rt.chain.ServeHTTP(w, r.WithContext(ctx))
+}
 
-	return nil, nil, false
+func (rt *Route) compile() {
+	rt.chain = rt.build(nil)
 }
 
-func (m *Mux) findOrCreateChild(node *routeTree, segment, paramName string) *routeTree {
-	if paramName != "" {
-		if node.paramChild == nil {
-			node.paramChild = &routeTree{
-				paramName: paramName,
-				children:  make(map[string]*routeTree),
-			}
+// build composes the route's chain. timed, when set, wraps the handler and
+// each middleware, innermost first, to trace them.
+func (rt *Route) build(timed func(name string, h http.Handler) http.Handler) http.Handler {
+	// layer names middleware by NamedMiddleware or the function that
+	// created them, and only when tracing
+	layer := func(name any, h http.Handler) http.Handler {
+		if timed == nil {
+			return h
 		}
-		return node.paramChild
-	}
-
-	child, exists := node.children[segment]
-	if !exists {
-		child = &routeTree{
-			segment:  segment,
-			children: make(map[string]*routeTree),
+		if s, ok := name.(string); ok {
+			return timed(s, h)
 		}
-		node.children[segment] = child
+		return timed(middlewareName(name), h)
 	}
-	return child
+	h := layer("handler", rt.handler)
+	if ct, ok := rt.Value(contentTypesKey{}).(contentTypes); ok && (ct.consumes != nil || ct.produces != nil) {
+		h = layer("Consumes/Produces", negotiate(ct, h))
+	}
+	if flags, ok := rt.Value(requiredFlagsKey{}).([]string); ok && len(flags) > 0 {
+		h = layer("RequireFlag", requireFlags(flags, rt.mux, h))
+	}
+	for i := len(rt.local) - 1; i >= 0; i-- {
+		h = layer(rt.local[i], rt.local[i](h))
+	}
+	inherited := rt.inherited()
+	for i := len(inherited) - 1; i >= 0; i-- {
+		h = layer(inherited[i], inherited[i](h))
+	}
+	if d, ok := rt.Value(deprecationKey{}).(Deprecation); ok {
+		h = layer("Deprecate", deprecate(d, &rt.deprecatedCalls, h))
+	}
+	if d, ok := rt.Value(writeTimeoutKey{}).(time.Duration); ok && d > 0 {
+		h = layer("WriteTimeout", writeTimeout(d, h))
+	}
+	return h
 }
 
-func (m *Mux) precomputeStaticPaths() {
-	m.root.staticHandlers = make(map[string]routeNode)
-	m.buildStaticPaths(m.root, "")
+// CurrentRoute returns the route matched for the request, or nil
+func CurrentRoute(ctx context.Context) *Route {
+	rt, _ := ctx.Value(routeContextKey{}).(*Route)
+	return rt
 }
 
-func (m *Mux) buildStaticPaths(node *routeTree, prefix string) {
-	if node.paramChild != nil || node.isWildcard {
-		return
+// joinPattern prefixes pattern with a group's path prefix
+func joinPattern(prefix, pattern string) string {
+	if prefix == "" {
+		return pattern
 	}
-
-	if node.methods != nil {
-		m.root.staticHandlers[prefix] = routeNode{
-			methods: node.methods,
-			get:     node.methods.handlers[MethodGet],
-		}
-	}
-
-	for segment, child := range node.children {
-		newPrefix := prefix
-		if newPrefix != "" {
-			newPrefix += "/"
-		}
-		newPrefix += segment
-		m.buildStaticPaths(child, newPrefix)
+	if pattern = strings.Trim(pattern, "/"); pattern == "" {
+		return prefix
 	}
+	return prefix + "/" + pattern
 }
 
-// Replace existing wrap method
+// wrap applies the mux's middleware stack to handler. The chain is built per
+// handler so that group-scoped middleware never leaks onto other routes.
 func (m *Mux) wrap(handler http.Handler) http.Handler {
-	if m.middlewareChain.cached != nil {
-		return m.middlewareChain.cached
-	}
-
 	h := handler
 	for i := len(m.middlewares) - 1; i >= 0; i-- {
 		h = m.middlewares[i](h)
 	}
-	m.middlewareChain.cached = h
 	return h
 }
 
@@ -184,45 +194,26 @@ func (mh *methodHandler) updateAllowedList() {
 		methods = append(methods, method)
 	}
 	sort.Strings(methods)
-	mh.allowedList = strings.Join(append(methods, MethodOptions), ", ")
-}
-
-var pathSegmentPool = sync.Pool{
-	New: func() interface{} {
-		return make([]string, 0, 16)
-	},
-}
-
-func splitPath(path string) []string {
-	if path == "" || path == "/" {
-		return nil
-	}
-
-	segments := pathSegmentPool.Get().([]string)
-	segments = segments[:0]
-
-	var start int
-	for i := 1; i < len(path); i++ {
-		if path[i] == '/' {
-			segments = append(segments, path[start+1:i])
-			start = i
-		}
+	mh.registeredList = strings.Join(methods, ", ")
+	if _, ok := mh.handlers[MethodOptions]; !ok {
+		methods = append(methods, MethodOptions)
 	}
-	if start < len(path)-1 {
-		segments = append(segments, path[start+1:])
-	}
-
-	return segments
+	mh.allowedList = strings.Join(methods, ", ")
 }
 
-func (m *Mux) compilePattern(pattern string) *regexp.Regexp {
-	if rx, ok := m.rxCache.Load(pattern); ok {
-		return rx.(*regexp.Regexp)
+// compilePattern returns the matcher for a parameter constraint, sharing
+// one per constraint. Handle has validated it.
+func (m *Mux) compilePattern(pattern string) segmentMatcher {
+	if matcher, ok := m.rxCache.Load(pattern); ok {
+		return matcher.(segmentMatcher)
 	}
 
-	rx := regexp.MustCompile(pattern)
-	m.rxCache.Store(pattern, rx)
-	return rx
+	matcher, err := compileMatcher(pattern)
+	if err != nil {
+		panic("GoFlow: " + err.Error())
+	}
+	m.rxCache.Store(pattern, matcher)
+	return matcher
 }
 
 func contains(slice []string, item string) bool {
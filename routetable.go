@@ -0,0 +1,223 @@
+package GoFlow
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// routeTable records routes in registration order and the base path they
+// are served under. It is shared by a mux and its groups.
+type routeTable struct {
+	mu     sync.Mutex
+	routes []*Route
+	base   string
+	debug  *DebugOptions
+
+	redaction Redaction
+}
+
+func (t *routeTable) add(rt *Route) {
+	t.mu.Lock()
+	t.routes = append(t.routes, rt)
+	t.mu.Unlock()
+}
+
+// Routes returns the registered routes in registration order, including
+// those registered in groups
+func (m *Mux) Routes() []*Route {
+	if m.table == nil {
+		return nil
+	}
+	m.table.mu.Lock()
+	defer m.table.mu.Unlock()
+	return append([]*Route(nil), m.table.routes...)
+}
+
+// RouteInfo describes the route a request resolves to
+type RouteInfo struct {
+	// Pattern is the matched route's pattern. When the path only answers
+	// to other methods, it is the pattern of the first of Methods.
+	Pattern string
+	// Methods are all methods the matched path answers to, sorted
+	Methods []string
+	// Route is the matched route, nil when the path only answers to other methods
+	Route *Route
+}
+
+// Match runs the routing algorithm for method and path without executing
+// any handler. ok is false when no route handles the request; if the path
+// matched but not the method, the returned RouteInfo lists the methods it
+// answers to, as a 405 response would.
+func (m *Mux) Match(method, path string) (info RouteInfo, params map[string]string, ok bool) {
+	if path == "" {
+		path = "/"
+	}
+	found := paramsPool.Get().(map[string]string)
+	defer func() {
+		clear(found)
+		paramsPool.Put(found)
+	}()
+
+	methods, _, matched := m.findHandler(m.root, routePath(path), found)
+	if !matched || methods == nil {
+		return RouteInfo{}, nil, false
+	}
+	for name := range methods.handlers {
+		info.Methods = append(info.Methods, name)
+	}
+	sort.Strings(info.Methods)
+
+	rt, ok := methods.handlers[strings.ToUpper(method)].(*Route)
+	if !ok {
+		// Methods of one path may be registered with differently spelled
+		// patterns; report the first method's
+		for _, name := range info.Methods {
+			if other, ok := methods.handlers[name].(*Route); ok {
+				info.Pattern = other.pattern
+				break
+			}
+		}
+		return info, nil, false
+	}
+	info.Pattern = rt.pattern
+	info.Route = rt
+	return info, maps.Clone(found), true
+}
+
+// route returns the route serving method and path, or nil. Unlike Match
+// it doesn't allocate.
+func (m *Mux) route(method, path string) *Route {
+	if methods, ok := m.root.staticHandlers[path]; ok {
+		if rt, ok := methods.handlers[method].(*Route); ok {
+			return rt
+		}
+	}
+	params := paramsPool.Get().(map[string]string)
+	methods, _, matched := m.findHandler(m.root, routePath(path), params)
+	clear(params)
+	paramsPool.Put(params)
+	if !matched {
+		return nil
+	}
+	rt, _ := methods.handlers[method].(*Route)
+	return rt
+}
+
+// Print writes a table of the registered routes with their methods,
+// middleware counts and summaries, followed by any conflicts found by
+// Validate and middleware registered in an order that breaks or slows
+// requests
+func (m *Mux) Print(w io.Writer) {
+	routes := m.Routes()
+	documented := false
+	for _, rt := range routes {
+		if doc := rt.Doc(); doc.Summary != "" || doc.Deprecated {
+			documented = true
+			break
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if documented {
+		fmt.Fprintln(tw, "METHODS\tPATTERN\tMIDDLEWARE\tSUMMARY")
+	} else {
+		fmt.Fprintln(tw, "METHODS\tPATTERN\tMIDDLEWARE")
+	}
+	for _, rt := range routes {
+		fmt.Fprintf(tw, "%s\t%s\t%d", strings.Join(rt.methods, ", "), rt.pattern, len(rt.inherited())+len(rt.local))
+		if documented {
+			doc := rt.Doc()
+			summary := doc.Summary
+			if doc.Deprecated {
+				summary = strings.TrimSpace("(deprecated) " + summary)
+			}
+			fmt.Fprintf(tw, "\t%s", summary)
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+
+	if conflicts := m.conflicts(); len(conflicts) > 0 {
+		fmt.Fprintln(w, "\nConflicts:")
+		for _, c := range conflicts {
+			fmt.Fprintf(w, "  - %s\n", c)
+		}
+	}
+	if issues := m.orderIssues(); len(issues) > 0 {
+		fmt.Fprintln(w, "\nMiddleware order:")
+		for _, issue := range issues {
+			fmt.Fprintf(w, "  - %s\n", issue.message)
+		}
+	}
+}
+
+// Validate reports routes that replace or alter earlier registrations, e.g.
+// the same pattern registered twice or parameters at the same position
+// with different names or patterns, and middleware ordered so that requests
+// fail, such as CORS inside authentication. Print also lists orderings that
+// only cost performance.
+func (m *Mux) Validate() error {
+	var errs []error
+	for _, c := range m.conflicts() {
+		errs = append(errs, errors.New("GoFlow: "+c))
+	}
+	for _, issue := range m.orderIssues() {
+		if issue.fatal {
+			errs = append(errs, errors.New("GoFlow: "+issue.message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// conflicts replays registrations the way addRoute stores them in the tree.
+// Parameters at the same position share one node, which keeps the name and
+// pattern it was created with, and a wildcard shares its parent's handlers.
+func (m *Mux) conflicts() []string {
+	type paramDef struct {
+		name, rx, pattern string
+	}
+	params := make(map[string]paramDef)
+	handlers := make(map[string]*Route)
+
+	var conflicts []string
+	for _, rt := range m.Routes() {
+		key := ""
+		for _, segment := range strings.Split(strings.Trim(rt.pattern, "/"), "/") {
+			if segment == "..." {
+				break
+			}
+			if !strings.HasPrefix(segment, ":") {
+				key += "/" + segment
+				continue
+			}
+
+			key += "/:"
+			name, rx, _ := strings.Cut(segment[1:], "|")
+			def, ok := params[key]
+			switch {
+			case !ok:
+				params[key] = paramDef{name, rx, rt.pattern}
+			case def.name != name:
+				conflicts = append(conflicts, fmt.Sprintf("%s: parameter :%s is captured as :%s, as named by %s",
+					rt.pattern, name, def.name, def.pattern))
+			case def.rx != rx:
+				conflicts = append(conflicts, fmt.Sprintf("%s: parameter :%s pattern %q conflicts with %q from %s",
+					rt.pattern, name, rx, def.rx, def.pattern))
+			}
+		}
+
+		for _, method := range rt.methods {
+			if prev, ok := handlers[method+" "+key]; ok && prev != rt {
+				conflicts = append(conflicts, fmt.Sprintf("%s %s: shadows %s", method, rt.pattern, prev.pattern))
+			}
+			handlers[method+" "+key] = rt
+		}
+	}
+	return conflicts
+}
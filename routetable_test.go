@@ -0,0 +1,163 @@
+package GoFlow
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteTable(t *testing.T) {
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("Print", func(t *testing.T) {
+		mux := New()
+		mux.Use(Recovery())
+		mux.Handle("/users/:id", noop, MethodGet)
+		mux.Group(func(m *Mux) {
+			m.Use(Logger())
+			m.Handle("/admin", noop, MethodPost).With(Timeout(0))
+		})
+
+		if err := mux.Validate(); err != nil {
+			t.Errorf("Expected no conflicts, got %v", err)
+		}
+
+		var buf bytes.Buffer
+		mux.Print(&buf)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected header and 2 routes, got %q", buf.String())
+		}
+		if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "GET, HEAD /users/:id 1" {
+			t.Errorf("Expected users route with 1 middleware, got '%s'", lines[1])
+		}
+		if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "POST /admin 3" {
+			t.Errorf("Expected admin route with 3 middleware, got '%s'", lines[2])
+		}
+	})
+
+	t.Run("Conflicts", func(t *testing.T) {
+		mux := New()
+		mux.Handle("/users/:id", noop, MethodGet)
+		mux.Handle("/users/:name/posts", noop, MethodGet)
+		mux.Handle("/products/:id|^\\d+$", noop, MethodGet)
+		mux.Handle("/products/:id/reviews", noop, MethodGet)
+		mux.Handle("/static", noop, MethodGet)
+		mux.Handle("/static/...", noop, MethodGet)
+		mux.Handle("/users/:id", noop, MethodDelete)
+
+		err := mux.Validate()
+		if err == nil {
+			t.Fatal("Expected conflicts")
+		}
+		for _, want := range []string{
+			"/users/:name/posts: parameter :name is captured as :id",
+			`/products/:id/reviews: parameter :id pattern "" conflicts with "^\\d+$"`,
+			"GET /static/...: shadows /static",
+		} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected conflict '%s', got %v", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "DELETE") {
+			t.Errorf("Expected a different method on the same pattern to be allowed, got %v", err)
+		}
+
+		var buf bytes.Buffer
+		mux.Print(&buf)
+		if !strings.Contains(buf.String(), "Conflicts:") {
+			t.Errorf("Expected conflicts in the table, got %q", buf.String())
+		}
+	})
+	t.Run("Match", func(t *testing.T) {
+		mux := New()
+		mux.Handle("/users/:id|^\\d+$", noop, MethodGet)
+		mux.Handle("/users/:id", noop, MethodDelete)
+		mux.Handle("/files/...", noop, MethodGet)
+
+		route, params, ok := mux.Match(MethodGet, "/users/42")
+		if !ok || route.Pattern != "/users/:id|^\\d+$" || params["id"] != "42" {
+			t.Errorf("Expected users route with id 42, got %v %v %v", ok, route.Pattern, params)
+		}
+
+		route, params, ok = mux.Match(MethodGet, "/files/css/site.css")
+		if !ok || route.Pattern != "/files/..." || params["..."] != "css/site.css" {
+			t.Errorf("Expected wildcard route, got %v %v %v", ok, route.Pattern, params)
+		}
+
+		route, _, ok = mux.Match(MethodPost, "/users/42")
+		if ok || route.Route != nil || route.Pattern != "/users/:id" || !equalSlices(route.Methods, []string{MethodDelete, MethodGet, MethodHead}) {
+			t.Errorf("Expected no match with allowed methods, got %v %q %v", ok, route.Pattern, route.Methods)
+		}
+
+		if _, _, ok := mux.Match(MethodGet, "/users/abc/extra"); ok {
+			t.Error("Expected no match for unknown path")
+		}
+	})
+
+	t.Run("Annotations", func(t *testing.T) {
+		mux := New()
+		mux.Group(func(m *Mux) {
+			m.Set(routeDocKey{}, RouteDoc{Tags: []string{"users"}})
+			m.Handle("/users/:id", noop, MethodGet).Summary("Get a user").Tags("read").Response(200, "")
+			m.Handle("/users/:id", noop, MethodDelete).Deprecated()
+		})
+
+		get := mux.Routes()[0].Doc()
+		if get.Summary != "Get a user" || !equalSlices(get.Tags, []string{"users", "read"}) || len(get.Responses) != 1 {
+			t.Errorf("Expected annotated route, got %+v", get)
+		}
+		if del := mux.Routes()[1].Doc(); !del.Deprecated || !equalSlices(del.Tags, []string{"users"}) || del.Responses != nil {
+			t.Errorf("Expected annotations not to leak between routes, got %+v", del)
+		}
+
+		var buf bytes.Buffer
+		mux.Print(&buf)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if !strings.HasSuffix(lines[0], "SUMMARY") || !strings.HasSuffix(lines[1], "Get a user") || !strings.HasSuffix(lines[2], "(deprecated)") {
+			t.Errorf("Expected summaries in the table, got %q", buf.String())
+		}
+	})
+
+	t.Run("Middleware Order", func(t *testing.T) {
+		check := func(user, pass string) bool { return true }
+		mux := New()
+		mux.Use(Logger(), Recovery(), Compression(), Cache(time.Minute))
+		mux.Group(func(api *Mux) {
+			api.Use(BasicAuth("api", check), CORS([]string{"*"}, nil, nil))
+			api.Handle("/api/users", noop, MethodGet)
+			api.Handle("/api/orders", noop, MethodGet)
+		})
+		mux.Handle("/", noop, MethodGet)
+
+		err := mux.Validate()
+		if err == nil || !strings.Contains(err.Error(), "/api/users and 1 more routes: CORS runs inside BasicAuth") {
+			t.Errorf("Expected the CORS misordering reported once, got %v", err)
+		}
+		if strings.Contains(err.Error(), "Recovery") || strings.Contains(err.Error(), "Compression") {
+			t.Errorf("Expected only misorderings that break requests as errors, got %v", err)
+		}
+
+		var buf bytes.Buffer
+		mux.Print(&buf)
+		out := buf.String()
+		for _, want := range []string{
+			"Middleware order:",
+			"/api/users and 2 more routes: Logger runs outside Recovery",
+			"Compression runs outside Cache",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Expected %q in the printed table, got %q", want, out)
+			}
+		}
+
+		ok := New()
+		ok.Use(Recovery(), Cache(time.Minute), Compression(), CORS([]string{"*"}, nil, nil), BasicAuth("api", check))
+		ok.Handle("/", noop, MethodGet)
+		if err := ok.Validate(); err != nil {
+			t.Errorf("Expected a correctly ordered stack to validate, got %v", err)
+		}
+	})
+}
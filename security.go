@@ -1,7 +1,6 @@
 package GoFlow
 
 import (
-	"crypto/subtle"
 	"fmt"
 	"net"
 	"net/http"
@@ -35,27 +34,68 @@ type SecurityOptions struct {
 	// Content Security Policy
 	CSP string
 
-	// Trusted proxies
+	// TrustedProxies may set X-Forwarded-For, as addresses or CIDR ranges
+	// such as "10.0.0.0/8"
 	TrustedProxies []string
 
 	// CSRF Protection
 	CSRFEnabled bool
 	CSRFKey     string
+
+	// CSRFPreviousKeys are still accepted while CSRFKey is being rotated
+	CSRFPreviousKeys []string
 }
 
 type RateLimitOptions struct {
-	Requests   int
-	Duration   time.Duration
+	Requests int
+	Duration time.Duration
+	// TrustedIPs are exempt from the limit. The client is the connection's
+	// peer, or the address a TrustedProxies peer forwarded for.
 	TrustedIPs []string
 	BurstSize  int
+
+	// TrustedProxies may set X-Forwarded-For, as in SecurityOptions
+	TrustedProxies []string
+}
+
+// SecurityOverride disables or adjusts parts of the Security middleware for a
+// route or group, e.g. skipping CSRF for webhooks
+type SecurityOverride struct {
+	SkipCORS      bool
+	SkipCSRF      bool
+	SkipRateLimit bool
+	SkipHeaders   bool
+
+	// Headers are applied after the default security headers. An empty value
+	// removes the header, e.g. {"X-Frame-Options": ""} allows framing.
+	Headers map[string]string
+}
+
+type securityOverrideKey struct{}
+
+// OverrideSecurity adjusts the Security middleware for this route
+func (rt *Route) OverrideSecurity(o SecurityOverride) *Route {
+	return rt.Set(securityOverrideKey{}, o)
+}
+
+// OverrideSecurity adjusts the Security middleware for routes registered
+// afterwards on this mux or group
+func (m *Mux) OverrideSecurity(o SecurityOverride) {
+	m.Set(securityOverrideKey{}, o)
+}
+
+func securityOverride(r *http.Request) SecurityOverride {
+	if rt := CurrentRoute(r.Context()); rt != nil {
+		if o, ok := rt.Value(securityOverrideKey{}).(SecurityOverride); ok {
+			return o
+		}
+	}
+	return SecurityOverride{}
 }
 
 var (
 	// Precompiled regex for origin validation
 	originRegex = regexp.MustCompile(`^https?://[\w\-\.]+(:\d+)?$`)
-
-	// Precompiled regex for IP validation
-	ipRegex = regexp.MustCompile(`^(\d{1,3}\.){3}\d{1,3}$`)
 )
 
 // Security middleware that combines multiple security features
@@ -69,10 +109,10 @@ func Security(opts SecurityOptions) func(http.Handler) http.Handler {
 		opts.RateLimit.BurstSize = opts.RateLimit.Requests / 10 // Default to 10% of base rate
 	}
 
-	trustedProxies := make(map[string]struct{})
-	for _, ip := range opts.TrustedProxies {
-		trustedProxies[ip] = struct{}{}
-	}
+	trustedProxies := parseTrustedProxies(opts.TrustedProxies)
+
+	csrfKeys := append([]string{opts.CSRFKey}, opts.CSRFPreviousKeys...)
+	origins := newOriginMatcher(opts.AllowedOrigins, false)
 
 	// Initialize rate limiter with burst parameter
 	rateLimiter := NewRateLimiter(
@@ -83,22 +123,34 @@ func Security(opts SecurityOptions) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			setSecurityHeaders(w, opts)
+			override := securityOverride(r)
+
+			if !override.SkipHeaders {
+				setSecurityHeaders(w, opts)
+			}
+			for k, v := range override.Headers {
+				if v == "" {
+					w.Header().Del(k)
+				} else {
+					w.Header().Set(k, v)
+				}
+			}
 
-			if !handleCORS(w, r, opts) {
-				http.Error(w, "Invalid CORS request", http.StatusForbidden)
+			if !override.SkipCORS && !handleCORS(w, r, opts, origins) {
+				http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
 				return
 			}
 
 			clientIP := getRealIP(r, trustedProxies)
 
-			if !rateLimiter.Allow(clientIP) {
-				http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
+			if !override.SkipRateLimit && !isACMEChallenge(r) && !rateLimiter.Allow(tenantKey(r, clientIP)) {
+				debugNote(r.Context(), "rate limit: %s limited", clientIP)
+				http.Error(w, StatusText(r.Context(), http.StatusTooManyRequests), http.StatusTooManyRequests)
 				return
 			}
 
-			if opts.CSRFEnabled && !validateCSRF(r, opts.CSRFKey) {
-				http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			if opts.CSRFEnabled && !override.SkipCSRF && !validateCSRF(r, csrfKeys) {
+				http.Error(w, StatusText(r.Context(), http.StatusForbidden), http.StatusForbidden)
 				return
 			}
 
@@ -110,14 +162,7 @@ func Security(opts SecurityOptions) func(http.Handler) http.Handler {
 func setSecurityHeaders(w http.ResponseWriter, opts SecurityOptions) {
 	// HSTS
 	if opts.HSTS {
-		hstsValue := fmt.Sprintf("max-age=%d", opts.HSTSMaxAge)
-		if opts.HSTSIncludeSubdomains {
-			hstsValue += "; includeSubDomains"
-		}
-		if opts.HSTSPreload {
-			hstsValue += "; preload"
-		}
-		w.Header().Set("Strict-Transport-Security", hstsValue)
+		w.Header().Set("Strict-Transport-Security", hstsValue(opts.HSTSMaxAge, opts.HSTSIncludeSubdomains, opts.HSTSPreload))
 	}
 
 	// XSS Protection
@@ -136,7 +181,18 @@ func setSecurityHeaders(w http.ResponseWriter, opts SecurityOptions) {
 	}
 }
 
-func handleCORS(w http.ResponseWriter, r *http.Request, opts SecurityOptions) bool {
+func hstsValue(maxAge int, includeSubdomains, preload bool) string {
+	value := fmt.Sprintf("max-age=%d", maxAge)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+	return value
+}
+
+func handleCORS(w http.ResponseWriter, r *http.Request, opts SecurityOptions, origins *originMatcher) bool {
 	origin := r.Header.Get("Origin")
 	if origin == "" {
 		return true // Same origin request
@@ -149,63 +205,105 @@ func handleCORS(w http.ResponseWriter, r *http.Request, opts SecurityOptions) bo
 
 	// Check if origin is allowed
 	allowed := false
-	for _, allowedOrigin := range opts.AllowedOrigins {
-		if allowedOrigin == "*" {
-			if !opts.AllowCredentials { // Don't allow wildcard with credentials
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-				allowed = true
-				break
-			}
-		} else if allowedOrigin == origin {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
-			allowed = true
-			break
-		}
+	if origins.any && !opts.AllowCredentials { // Don't allow wildcard with credentials
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		allowed = true
+	} else if origins.matchListed(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		AddVary(w.Header(), "Origin")
+		allowed = true
 	}
 
 	if !allowed {
 		return false
 	}
+	if opts.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
 
 	// Handle preflight
-	if r.Method == http.MethodOptions {
+	if isPreflight(r) {
 		w.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
 		w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
 		w.Header().Set("Access-Control-Max-Age", toString(opts.MaxAge))
-		if opts.AllowCredentials {
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
 	}
 
+	if len(opts.ExposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+	}
 	return true
 }
 
-func getRealIP(r *http.Request, trustedProxies map[string]struct{}) string {
-	// Get immediate client IP
+// trustedProxies are the peers allowed to set X-Forwarded-For, as single
+// addresses or CIDR ranges
+type trustedProxies []*net.IPNet
+
+// parseTrustedProxies parses addresses and CIDR ranges such as
+// "10.0.0.0/8", skipping malformed entries; Validate reports them
+func parseTrustedProxies(list []string) trustedProxies {
+	var proxies trustedProxies
+	for _, entry := range list {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			proxies = append(proxies, network)
+		} else if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * len(ip)
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 32
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return proxies
+}
+
+func (t trustedProxies) contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range t {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP returns the address of the connection's peer
+func peerIP(r *http.Request) string {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		ip = r.RemoteAddr
+		return r.RemoteAddr
 	}
+	return ip
+}
 
-	// Only process X-Forwarded-For if from trusted proxy
-	if _, trusted := trustedProxies[ip]; trusted {
-		if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
-			ips := strings.Split(forwardedFor, ",")
-			// Get the original client IP (first in the chain)
-			clientIP := strings.TrimSpace(ips[0])
-			if ipRegex.MatchString(clientIP) {
-				return clientIP
-			}
+// getRealIP returns the client's address. X-Forwarded-For is only read
+// when the peer is a trusted proxy, and from the right: each trusted proxy
+// appended the address it received the request from, so the first hop
+// that isn't one is the client. Entries further left are client-supplied.
+func getRealIP(r *http.Request, proxies trustedProxies) string {
+	ip := peerIP(r)
+	if !proxies.contains(ip) {
+		return ip
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop.String()
+		if !proxies.contains(ip) {
+			break
 		}
 	}
-
 	return ip
 }
 
-func validateCSRF(r *http.Request, key string) bool {
+func validateCSRF(r *http.Request, keys []string) bool {
 	if r.Method == http.MethodGet || r.Method == http.MethodHead ||
 		r.Method == http.MethodOptions || r.Method == http.MethodTrace {
 		return true // No CSRF check needed for safe methods
@@ -216,7 +314,12 @@ func validateCSRF(r *http.Request, key string) bool {
 		token = r.FormValue("csrf_token")
 	}
 
-	return subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1
+	valid := false
+	for _, key := range keys {
+		// Check every key so timing doesn't reveal which one matched
+		valid = secretsEqual(token, key) || valid
+	}
+	return valid
 }
 
 // Usage example:
@@ -43,3 +43,61 @@ func BenchmarkSecurityMiddleware(b *testing.B) {
 		})
 	})
 }
+
+func TestSecurityOverrides(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mux := New()
+	mux.Use(Security(SecurityOptions{
+		AllowedOrigins: []string{"https://example.com"},
+		CSRFEnabled:    true,
+		CSRFKey:        "key",
+		RateLimit:      RateLimitOptions{Requests: 1, Duration: time.Minute, BurstSize: 1},
+	}))
+
+	mux.Handle("/account", ok, MethodPost)
+	mux.Group(func(m *Mux) {
+		m.OverrideSecurity(SecurityOverride{SkipCSRF: true, SkipRateLimit: true})
+		m.Handle("/webhooks/stripe", ok, MethodPost)
+	})
+	mux.Handle("/embed", ok, MethodGet).OverrideSecurity(SecurityOverride{
+		Headers: map[string]string{"X-Frame-Options": ""},
+	})
+
+	t.Run("Defaults Apply", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodPost, "/account", nil))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status code %d, got %d", http.StatusForbidden, w.Code)
+		}
+		if w.Header().Get("X-Frame-Options") != "DENY" {
+			t.Error("Expected X-Frame-Options header")
+		}
+	})
+
+	t.Run("Group Skips CSRF And Rate Limit", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodPost, "/webhooks/stripe", nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Request %d: expected status code %d, got %d", i, http.StatusOK, w.Code)
+			}
+		}
+	})
+
+	t.Run("Route Removes Frame Restriction", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(MethodGet, "/embed", nil)
+		r.RemoteAddr = "192.0.2.50:1234"
+		mux.ServeHTTP(w, r)
+
+		if _, ok := w.Header()["X-Frame-Options"]; ok {
+			t.Error("Expected X-Frame-Options to be removed")
+		}
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("Expected other security headers to remain")
+		}
+	})
+}
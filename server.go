@@ -0,0 +1,562 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Default server limits. They are deliberately conservative: a zero
+// ReadHeaderTimeout leaves the plain http.Server open to slowloris attacks.
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = 64 << 10
+)
+
+// Server wraps http.Server with safe timeouts and structured startup logging
+type Server struct {
+	// Addr is a TCP address, a Unix socket path prefixed with "unix:", or
+	// "systemd:" followed by the name of a socket passed by systemd
+	Addr    string
+	Handler http.Handler
+
+	// Listeners are further addresses served alongside Addr, in the same
+	// format, e.g. "unix:/run/app.sock" for a local nginx plus a debug port.
+	// They always serve plain HTTP and stop together with Addr.
+	Listeners []string
+
+	// SocketMode sets the permissions of Unix socket files, e.g. 0660.
+	// Zero leaves them to the process umask.
+	SocketMode os.FileMode
+
+	// ReusePort opens TCP listeners with SO_REUSEPORT, so a new binary can
+	// bind the same port while the old one drains during a deploy
+	ReusePort bool
+
+	// Timeouts and MaxHeaderBytes default to the Default constants when
+	// zero, whether or not the Server was created with NewServer. A
+	// negative timeout disables it.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+
+	// TLSConfig is used by RunTLS and RunAutoTLS. Defaults to TLSIntermediate().
+	TLSConfig *tls.Config
+
+	// CertManager obtains certificates automatically for RunAutoTLS,
+	// e.g. an *autocert.Manager from golang.org/x/crypto/acme/autocert
+	CertManager CertManager
+
+	// H2C serves HTTP/2 without TLS alongside HTTP/1.1 on Run, so a single
+	// plaintext port can front gRPC as well as REST traffic, e.g. behind a
+	// TLS-terminating load balancer
+	H2C bool
+
+	// HTTP3 also serves HTTP/3 over QUIC on the same port for RunTLS and
+	// RunAutoTLS, and advertises it to HTTP/1.1 and HTTP/2 clients with an
+	// Alt-Svc header. It requires building with -tags http3.
+	HTTP3 bool
+
+	// Logger receives lifecycle events. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// Verbose prints the route table of a *Mux handler to stderr on start,
+	// including conflicting registrations, see Mux.Print
+	Verbose bool
+
+	// MaxConnsPerIP closes connections from a remote IP beyond this many
+	// open at once. Zero disables it. Behind a load balancer every
+	// connection comes from the balancer, so use ConcurrencyLimit there.
+	MaxConnsPerIP int
+
+	// Tasks runs work started with Go from this server's handlers. Shutdown
+	// drains it after in-flight requests and before the OnShutdown hooks,
+	// so tasks can still use resources those hooks close.
+	Tasks *TaskRunner
+
+	// DrainDelay is how long Shutdown reports the server as not ready before
+	// it stops accepting connections, giving load balancers time to notice
+	DrainDelay time.Duration
+
+	mu         sync.Mutex
+	servers    []*http.Server
+	quic       []http3Server
+	hooks      []func(context.Context) error
+	startHooks []func(context.Context) error
+	base       context.Context
+	cancel     context.CancelFunc
+	draining   atomic.Bool
+	closed     bool
+	stopped    chan struct{}
+}
+
+// NewServer creates a Server for handler listening on addr with safe defaults
+func NewServer(addr string, handler http.Handler) *Server {
+	return &Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: DefaultReadHeaderTimeout,
+		ReadTimeout:       DefaultReadTimeout,
+		WriteTimeout:      DefaultWriteTimeout,
+		IdleTimeout:       DefaultIdleTimeout,
+		MaxHeaderBytes:    DefaultMaxHeaderBytes,
+	}
+}
+
+// Run listens on Addr and Listeners and serves HTTP until the server is shut down
+func (s *Server) Run() error {
+	return s.serveAll(listenSpec{defaultAddr(s.Addr, ":http"), s.Handler, nil})
+}
+
+// RunTLS listens on Addr and serves HTTPS using the given certificate files.
+// The files are watched and reloaded when they change on disk. Both may be
+// empty when TLSConfig already provides certificates.
+func (s *Server) RunTLS(certFile, keyFile string) error {
+	config := s.tlsConfig()
+	if certFile != "" || keyFile != "" {
+		reloader, err := NewCertReloader(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		config.GetCertificate = reloader.GetCertificate
+	}
+	return s.serveAll(listenSpec{defaultAddr(s.Addr, ":https"), s.Handler, config})
+}
+
+// RunAutoTLS serves HTTPS on Addr with certificates from CertManager, and plain
+// HTTP on httpAddr for HTTP-01 challenges. Challenge requests on httpAddr are
+// passed to Handler, where a Mux gets the challenge route registered unless
+// it has one (see Mux.HandleACMEChallenge); all others redirect to HTTPS.
+// TLS-ALPN-01 challenges are answered on Addr.
+func (s *Server) RunAutoTLS(httpAddr string) error {
+	if s.CertManager == nil {
+		return errors.New("GoFlow: RunAutoTLS requires a CertManager")
+	}
+
+	ensureACMEChallenge(s.Handler, s.CertManager)
+	config := s.autoTLSConfig()
+
+	return s.serveAll(
+		listenSpec{defaultAddr(httpAddr, ":http"), acmeHTTPHandler(s.Handler), nil},
+		listenSpec{defaultAddr(s.Addr, ":https"), s.Handler, config},
+	)
+}
+
+// autoTLSConfig is the TLS configuration of RunAutoTLS, also offering the
+// TLS-ALPN-01 challenge protocol
+func (s *Server) autoTLSConfig() *tls.Config {
+	config := s.tlsConfig()
+	config.GetCertificate = s.CertManager.GetCertificate
+	withALPN(config, "h2", "http/1.1", acmeTLSALPNProto)
+	return config
+}
+
+// HTTPServer returns the underlying http.Server once Run or RunTLS has been called
+func (s *Server) HTTPServer() *http.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.servers) == 0 {
+		return nil
+	}
+	return s.servers[0]
+}
+
+// OnStart registers fn to run once the listeners are bound and before
+// requests are served, e.g. to warm caches or announce the service to
+// discovery. Hooks run in registration order; an error aborts the start.
+func (s *Server) OnStart(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	s.startHooks = append(s.startHooks, fn)
+	s.mu.Unlock()
+}
+
+// OnShutdown registers fn to run during Shutdown after in-flight requests
+// have completed, e.g. to close database pools. Hooks run in registration
+// order and receive the shutdown context.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	s.hooks = append(s.hooks, fn)
+	s.mu.Unlock()
+}
+
+// Ready reports whether the server accepts new work. It turns false as
+// soon as Shutdown starts.
+func (s *Server) Ready() bool {
+	return !s.draining.Load()
+}
+
+// ReadinessHandler answers 200 while the server is ready and 503 once
+// Shutdown has started. Register it as the load balancer's health check:
+//
+//	mux.Handle("/readyz", srv.ReadinessHandler(), "GET", "HEAD")
+func (s *Server) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if !s.Ready() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+}
+
+// Shutdown gracefully stops the server: it fails the readiness check, waits
+// DrainDelay, stops accepting connections, waits for in-flight requests until
+// ctx expires, closes hijacked connections such as proxied WebSockets, then
+// runs the OnShutdown hooks. Run, RunTLS and RunAutoTLS return once Shutdown
+// has finished.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.stopped == nil {
+		s.stopped = make(chan struct{})
+	}
+	stopped := s.stopped
+	s.mu.Unlock()
+
+	if s.draining.Swap(true) {
+		// Already shutting down; wait for the first call
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer close(stopped)
+
+	s.logger().Info("server shutting down", slog.Duration("drain_delay", s.DrainDelay))
+
+	if s.DrainDelay > 0 {
+		timer := time.NewTimer(s.DrainDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	s.mu.Lock()
+	servers, quic, hooks := s.servers, s.quic, s.hooks
+	s.mu.Unlock()
+
+	errc := make(chan error, len(servers)+len(quic))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			errc <- srv.Shutdown(ctx)
+		}(srv)
+	}
+	for _, srv := range quic {
+		go func(srv http3Server) {
+			errc <- srv.Shutdown(ctx)
+		}(srv)
+	}
+	var errs []error
+	for range len(servers) + len(quic) {
+		errs = append(errs, <-errc)
+	}
+	s.cancelConns()
+
+	if s.Tasks != nil {
+		errs = append(errs, s.Tasks.Shutdown(ctx))
+	}
+	for _, hook := range hooks {
+		errs = append(errs, hook(ctx))
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		s.logger().Error("server shutdown incomplete", slog.String("error", err.Error()))
+	}
+	return err
+}
+
+// ShutdownOnSignal calls Shutdown with the given timeout when the process
+// receives one of signals, SIGINT and SIGTERM by default
+func (s *Server) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		sig := <-ch
+		signal.Stop(ch)
+		s.logger().Info("signal received", slog.String("signal", sig.String()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		s.Shutdown(ctx)
+	}()
+}
+
+// Close immediately closes all listeners and connections
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	servers, quic := s.servers, s.quic
+	s.mu.Unlock()
+	s.cancelConns()
+
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Close())
+	}
+	for _, srv := range quic {
+		errs = append(errs, srv.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// listenSpec is an address to serve handler on, with TLS when config is set
+type listenSpec struct {
+	addr    string
+	handler http.Handler
+	config  *tls.Config
+}
+
+// serveAll binds the given addresses and one for each of Listeners, runs the
+// OnStart hooks, then serves them all. Any listener stopping takes the others
+// down with it.
+func (s *Server) serveAll(specs ...listenSpec) error {
+	for _, addr := range s.Listeners {
+		specs = append(specs, listenSpec{addr, s.Handler, nil})
+	}
+	if mux, ok := s.Handler.(interface{ Print(io.Writer) }); ok && s.Verbose {
+		mux.Print(os.Stderr)
+	}
+
+	listeners := make([]net.Listener, 0, len(specs))
+	closeAll := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+	for _, spec := range specs {
+		ln, err := s.listen(spec.addr)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		if _, ok := ln.Addr().(*net.TCPAddr); ok && s.MaxConnsPerIP > 0 {
+			ln = newLimitListener(ln, s.MaxConnsPerIP, s.logger())
+		}
+		listeners = append(listeners, ln)
+	}
+
+	s.mu.Lock()
+	startHooks := s.startHooks
+	s.mu.Unlock()
+	for _, hook := range startHooks {
+		if err := hook(context.Background()); err != nil {
+			closeAll()
+			return err
+		}
+	}
+
+	errc := make(chan error, len(specs))
+	for i, spec := range specs {
+		go func() {
+			errc <- s.serve(listeners[i], spec.handler, spec.config)
+		}()
+	}
+
+	err := <-errc
+	s.Close()
+	for range len(specs) - 1 {
+		if err2 := <-errc; err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (s *Server) serve(ln net.Listener, handler http.Handler, config *tls.Config) error {
+	h3 := config != nil && s.HTTP3
+	if h3 && newHTTP3Server == nil {
+		ln.Close()
+		return errors.New("GoFlow: HTTP3 requires building with -tags http3")
+	}
+
+	if s.Tasks != nil {
+		handler = Tasks(s.Tasks)(handler)
+	}
+	srv := s.newHTTPServer(ln.Addr().String(), handler)
+
+	scheme := "http"
+	if config != nil {
+		scheme = "https"
+		srv.TLSConfig = config
+	}
+
+	var quic http3Server
+	h3errc := make(chan error, 1)
+	if h3 {
+		// QUIC listens on the UDP port matching the TCP listener
+		quicAddr := ln.Addr().String()
+		_, port, _ := net.SplitHostPort(quicAddr)
+		srv.Handler = altSvc(handler, port)
+
+		quic = newHTTP3Server(quicAddr, handler, config)
+		s.mu.Lock()
+		s.quic = append(s.quic, quic)
+		s.mu.Unlock()
+
+		go func() {
+			err := quic.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				// Without HTTP/3 the Alt-Svc header would be wrong; stop both
+				h3errc <- err
+				srv.Close()
+			}
+		}()
+	}
+
+	s.logger().Info("server starting",
+		slog.String("addr", ln.Addr().String()),
+		slog.String("scheme", scheme),
+		slog.Duration("read_header_timeout", srv.ReadHeaderTimeout),
+		slog.Duration("read_timeout", srv.ReadTimeout),
+		slog.Duration("write_timeout", srv.WriteTimeout),
+		slog.Duration("idle_timeout", srv.IdleTimeout),
+		slog.Int("max_header_bytes", srv.MaxHeaderBytes),
+		slog.Bool("h2c", s.H2C && config == nil),
+		slog.Bool("http3", h3),
+	)
+
+	var err error
+	if config != nil {
+		err = srv.ServeTLS(ln, "", "")
+	} else {
+		err = srv.Serve(ln)
+	}
+	select {
+	case h3err := <-h3errc:
+		return h3err
+	default:
+	}
+	if quic != nil && !errors.Is(err, http.ErrServerClosed) {
+		quic.Close()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		// Serve returns as soon as Shutdown begins; wait for it to finish
+		s.mu.Lock()
+		stopped := s.stopped
+		s.mu.Unlock()
+		if stopped != nil {
+			<-stopped
+		}
+		s.logger().Info("server stopped", slog.String("addr", ln.Addr().String()))
+		return nil
+	}
+	return err
+}
+
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: orDefault(s.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       orDefault(s.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      orDefault(s.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       orDefault(s.IdleTimeout, DefaultIdleTimeout),
+		MaxHeaderBytes:    orDefault(s.MaxHeaderBytes, DefaultMaxHeaderBytes),
+		ErrorLog:          slog.NewLogLogger(s.logger().Handler(), slog.LevelWarn),
+	}
+	if s.H2C {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+
+	s.mu.Lock()
+	if s.base == nil {
+		// Hijacked connections aren't tracked by http.Server.Shutdown, so
+		// their request contexts are canceled once the drain is over
+		s.base, s.cancel = context.WithCancel(context.Background())
+	}
+	base := s.base
+	s.servers = append(s.servers, srv)
+	if s.closed {
+		// A sibling listener failed before this one started
+		srv.Close()
+	}
+	s.mu.Unlock()
+
+	srv.BaseContext = func(net.Listener) context.Context { return base }
+	return srv
+}
+
+// orDefault returns def for unset limits, so Server literals get the same
+// protection as NewServer
+func orDefault[T time.Duration | int](v, def T) T {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func (s *Server) cancelConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// http3Server is the subset of a QUIC-based HTTP/3 server used by Server
+type http3Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// newHTTP3Server is set by http3.go when built with -tags http3, which keeps
+// the QUIC dependency out of default builds
+var newHTTP3Server func(addr string, handler http.Handler, config *tls.Config) http3Server
+
+// altSvc advertises HTTP/3 on port to clients of the TCP listener
+func altSvc(next http.Handler, port string) http.Handler {
+	value := `h3=":` + port + `"; ma=86400`
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) tlsConfig() *tls.Config {
+	if s.TLSConfig != nil {
+		return s.TLSConfig.Clone()
+	}
+	return TLSIntermediate()
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+func defaultAddr(addr, fallback string) string {
+	if addr == "" {
+		return fallback
+	}
+	return addr
+}
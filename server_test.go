@@ -0,0 +1,415 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address with an unused port
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// waitForServer polls addr until it accepts connections
+func waitForServer(t *testing.T, addr string) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Server at %s did not start", addr)
+}
+
+func TestServer(t *testing.T) {
+	t.Run("Safe Defaults", func(t *testing.T) {
+		s := NewServer(":8080", New())
+		srv := s.newHTTPServer(s.Addr, s.Handler)
+
+		if srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || srv.IdleTimeout != DefaultIdleTimeout {
+			t.Errorf("Expected default timeouts, got header=%s idle=%s", srv.ReadHeaderTimeout, srv.IdleTimeout)
+		}
+		if srv.MaxHeaderBytes != DefaultMaxHeaderBytes {
+			t.Errorf("Expected MaxHeaderBytes %d, got %d", DefaultMaxHeaderBytes, srv.MaxHeaderBytes)
+		}
+	})
+
+	t.Run("Defaults Without NewServer", func(t *testing.T) {
+		s := &Server{Addr: ":8080", Handler: New(), WriteTimeout: 5 * time.Minute, IdleTimeout: -1}
+		srv := s.newHTTPServer(s.Addr, s.Handler)
+
+		if srv.ReadHeaderTimeout != DefaultReadHeaderTimeout || srv.ReadTimeout != DefaultReadTimeout || srv.MaxHeaderBytes != DefaultMaxHeaderBytes {
+			t.Errorf("Expected default limits, got header=%s read=%s bytes=%d", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.MaxHeaderBytes)
+		}
+		if srv.WriteTimeout != 5*time.Minute || srv.IdleTimeout >= 0 {
+			t.Errorf("Expected explicit values kept, got write=%s idle=%s", srv.WriteTimeout, srv.IdleTimeout)
+		}
+	})
+
+	t.Run("Run And Close", func(t *testing.T) {
+		mux := New()
+		mux.Handle("/ping", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "pong")
+		}), MethodGet)
+
+		var logs bytes.Buffer
+		addr := freeAddr(t)
+		s := NewServer(addr, mux)
+		s.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+		done := make(chan error, 1)
+		go func() { done <- s.Run() }()
+		waitForServer(t, addr)
+
+		resp, err := http.Get("http://" + addr + "/ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "pong" {
+			t.Errorf("Expected body 'pong', got '%s'", body)
+		}
+
+		s.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Expected nil error after Close, got %v", err)
+		}
+		if !strings.Contains(logs.String(), "server starting") || !strings.Contains(logs.String(), "read_header_timeout=5s") {
+			t.Errorf("Missing startup log, got '%s'", logs.String())
+		}
+	})
+
+	t.Run("Graceful Shutdown", func(t *testing.T) {
+		started := make(chan struct{})
+		mux := New()
+		mux.Handle("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			io.WriteString(w, "finished")
+		}), MethodGet)
+
+		addr := freeAddr(t)
+		s := NewServer(addr, mux)
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		s.DrainDelay = 20 * time.Millisecond
+		mux.Handle("/readyz", s.ReadinessHandler(), MethodGet)
+
+		var order []string
+		s.OnShutdown(func(ctx context.Context) error {
+			order = append(order, "flush")
+			return nil
+		})
+		s.OnShutdown(func(ctx context.Context) error {
+			order = append(order, "close db")
+			return nil
+		})
+
+		done := make(chan error, 1)
+		go func() { done <- s.Run() }()
+		waitForServer(t, addr)
+
+		inflight := make(chan string, 1)
+		go func() {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err != nil {
+				inflight <- err.Error()
+				return
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			inflight <- string(body)
+		}()
+		<-started
+
+		shutdownErr := make(chan error, 1)
+		go func() { shutdownErr <- s.Shutdown(context.Background()) }()
+
+		// Readiness fails while the listener is still draining
+		time.Sleep(5 * time.Millisecond)
+		resp, err := http.Get("http://" + addr + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected readiness %d during drain, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+		}
+
+		if body := <-inflight; body != "finished" {
+			t.Errorf("Expected in-flight request to complete, got '%s'", body)
+		}
+		if err := <-shutdownErr; err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Expected Run to return nil, got %v", err)
+		}
+		if !equalSlices(order, []string{"flush", "close db"}) {
+			t.Errorf("Expected hooks in registration order, got %v", order)
+		}
+	})
+
+	t.Run("Multiple Listeners", func(t *testing.T) {
+		addr := freeAddr(t)
+		socket := filepath.Join(t.TempDir(), "app.sock")
+		s := NewServer(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		s.Listeners = []string{"unix:" + socket}
+		s.SocketMode = 0o600
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		done := make(chan error, 1)
+		go func() { done <- s.Run() }()
+		waitForServer(t, addr)
+
+		unixClient := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		}}
+		var resp *http.Response
+		var err error
+		for i := 0; i < 100; i++ {
+			if resp, err = unixClient.Get("http://unix/"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "ok" {
+			t.Errorf("Expected 'ok' over the Unix socket, got '%s'", body)
+		}
+		if fi, err := os.Stat(socket); err != nil || fi.Mode().Perm() != 0o600 {
+			t.Errorf("Expected socket mode 0600, got %v (%v)", fi.Mode().Perm(), err)
+		}
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Errorf("Expected Run to return nil, got %v", err)
+		}
+		if _, err := os.Stat(socket); !os.IsNotExist(err) {
+			t.Errorf("Expected socket file to be removed, got %v", err)
+		}
+	})
+
+	t.Run("Reuse Port", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("SO_REUSEPORT semantics differ by platform")
+		}
+		s := &Server{ReusePort: true}
+		first, err := s.listen("127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer first.Close()
+
+		// A second process started during a deploy binds the same port
+		second, err := s.listen(first.Addr().String())
+		if err != nil {
+			t.Fatalf("Expected second listener on %s, got %v", first.Addr(), err)
+		}
+		second.Close()
+	})
+
+	t.Run("H2C And gRPC Passthrough", func(t *testing.T) {
+		mux := New()
+		mux.Use(Logger(), Compression(), Timeout(time.Second))
+		mux.Handle("/pkg.Service/Method", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor != 2 {
+				t.Errorf("Expected HTTP/2, got %s", r.Proto)
+			}
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Trailer", "Grpc-Status")
+			w.Write([]byte("frame"))
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("Expected flush to reach the connection, got %v", err)
+			}
+			w.Header().Set("Grpc-Status", "0")
+		}), MethodPost)
+
+		addr := freeAddr(t)
+		s := NewServer(addr, mux)
+		s.H2C = true
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		go s.Run()
+		defer s.Close()
+		waitForServer(t, addr)
+
+		transport := &http.Transport{Protocols: new(http.Protocols)}
+		transport.Protocols.SetUnencryptedHTTP2(true)
+		defer transport.CloseIdleConnections()
+
+		req, _ := http.NewRequest(MethodPost, "http://"+addr+"/pkg.Service/Method", strings.NewReader("req"))
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := (&http.Client{Transport: transport}).Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.ProtoMajor != 2 || string(body) != "frame" || resp.Header.Get("Content-Encoding") != "" {
+			t.Errorf("Expected uncompressed HTTP/2 response, got %s '%s' encoding '%s'", resp.Proto, body, resp.Header.Get("Content-Encoding"))
+		}
+		if resp.Trailer.Get("Grpc-Status") != "0" {
+			t.Errorf("Expected Grpc-Status trailer, got %v", resp.Trailer)
+		}
+	})
+	t.Run("HTTP3 Alt-Svc", func(t *testing.T) {
+		cert, key := writeTestCert(t, t.TempDir(), "localhost")
+		quiet := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		if newHTTP3Server == nil {
+			s := NewServer(freeAddr(t), http.NotFoundHandler())
+			s.HTTP3 = true
+			s.Logger = quiet
+			if err := s.RunTLS(cert, key); err == nil || !strings.Contains(err.Error(), "-tags http3") {
+				t.Errorf("Expected build tag error, got %v", err)
+			}
+		}
+
+		// Stand in for quic-go so the advertisement can be checked in default builds
+		fake := &fakeHTTP3Server{closed: make(chan struct{})}
+		saved := newHTTP3Server
+		newHTTP3Server = func(addr string, handler http.Handler, config *tls.Config) http3Server {
+			fake.addr = addr
+			return fake
+		}
+		defer func() { newHTTP3Server = saved }()
+
+		addr := freeAddr(t)
+		s := NewServer(addr, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+		s.HTTP3 = true
+		s.Logger = quiet
+		done := make(chan error, 1)
+		go func() { done <- s.RunTLS(cert, key) }()
+		waitForServer(t, addr)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		resp, err := client.Get("https://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		_, port, _ := net.SplitHostPort(addr)
+		if got := resp.Header.Get("Alt-Svc"); got != `h3=":`+port+`"; ma=86400` {
+			t.Errorf("Expected Alt-Svc for port %s, got '%s'", port, got)
+		}
+		if fake.addr != addr {
+			t.Errorf("Expected QUIC on %s, got %s", addr, fake.addr)
+		}
+
+		s.Shutdown(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Expected clean stop, got %v", err)
+		}
+		select {
+		case <-fake.closed:
+		default:
+			t.Error("Expected HTTP/3 server to be shut down")
+		}
+	})
+}
+
+type fakeHTTP3Server struct {
+	addr   string
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (f *fakeHTTP3Server) ListenAndServe() error {
+	<-f.closed
+	return http.ErrServerClosed
+}
+
+func (f *fakeHTTP3Server) Shutdown(ctx context.Context) error {
+	return f.Close()
+}
+
+func (f *fakeHTTP3Server) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return nil
+}
+
+func TestSystemdActivation(t *testing.T) {
+	if os.Getenv("GOFLOW_SYSTEMD_CHILD") == "1" {
+		// Re-executed with the socket as fd 3; systemd would set LISTEN_PID
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		s := NewServer("systemd:web", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("activated"))
+		}))
+		s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+		s.ShutdownOnSignal(time.Second)
+		if err := s.Run(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("socket activation is not available on Windows")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ln.(*net.TCPListener).File()
+	ln.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSystemdActivation$")
+	cmd.Env = append(os.Environ(), "GOFLOW_SYSTEMD_CHILD=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=web")
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Signal(os.Interrupt)
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "activated" {
+		t.Errorf("Expected response from the inherited socket, got '%s'", body)
+	}
+}
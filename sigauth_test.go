@@ -0,0 +1,129 @@
+package GoFlow
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestSignedRequestAuth(t *testing.T) {
+	store := StaticKeys(map[string]APIKey{
+		"AKID1": {ID: "billing", Tier: "pro", Secret: "s3cret"},
+	})
+	var gotBody, gotKey string
+	handler := SignedRequestAuth(SignedRequestOptions{Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotKey = string(body), GetAPIKey(r.Context()).ID
+	}))
+
+	signed := func(method, target, body, secret string, at time.Time) *http.Request {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if err := SignRequest(r, "AKID1", secret, at); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Valid Signature", func(t *testing.T) {
+		r := signed(MethodPost, "/invoices?b=2&a=1&a=0", `{"amount":10}`, "s3cret", time.Now())
+		if code := serve(r); code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, code)
+		}
+		if gotBody != `{"amount":10}` || gotKey != "billing" {
+			t.Errorf("Expected the body and key passed on, got %q %q", gotBody, gotKey)
+		}
+	})
+
+	t.Run("Tampered Requests", func(t *testing.T) {
+		tests := map[string]func(r *http.Request){
+			"Body":   func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`)) },
+			"Query":  func(r *http.Request) { r.RequestURI = "/invoices?a=2"; r.URL.RawQuery = "a=2" },
+			"Method": func(r *http.Request) { r.Method = MethodPut },
+			"Header": func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		}
+		for name, tamper := range tests {
+			r := signed(MethodPost, "/invoices?a=1", `{"amount":10}`, "s3cret", time.Now())
+			tamper(r)
+			if code := serve(r); code != http.StatusUnauthorized {
+				t.Errorf("%s: expected status code %d, got %d", name, http.StatusUnauthorized, code)
+			}
+		}
+	})
+
+	t.Run("Wrong Secret And Unknown Key", func(t *testing.T) {
+		if code := serve(signed(MethodGet, "/invoices", "", "guess", time.Now())); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+		r := signed(MethodGet, "/invoices", "", "s3cret", time.Now())
+		r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), "AKID1", "AKID2", 1))
+		if code := serve(r); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("Clock Skew", func(t *testing.T) {
+		if code := serve(signed(MethodGet, "/invoices", "", "s3cret", time.Now().Add(-2*time.Minute))); code != http.StatusOK {
+			t.Errorf("Expected a request within the skew accepted, got %d", code)
+		}
+		if code := serve(signed(MethodGet, "/invoices", "", "s3cret", time.Now().Add(-10*time.Minute))); code != http.StatusUnauthorized {
+			t.Errorf("Expected a stale request rejected, got %d", code)
+		}
+	})
+
+	t.Run("Missing Signature", func(t *testing.T) {
+		if code := serve(httptest.NewRequest(MethodGet, "/invoices", nil)); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("Body Limits", func(t *testing.T) {
+		var read int
+		handler := SignedRequestAuth(SignedRequestOptions{
+			Store:                store,
+			MaxBodySize:          16,
+			AllowUnsignedPayload: true,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			read = len(body)
+		}))
+		serve := func(r *http.Request) int {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w.Code
+		}
+		large := strings.Repeat("x", 64)
+
+		if code := serve(signed(MethodPost, "/uploads", large, "s3cret", time.Now())); code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, code)
+		}
+
+		r := httptest.NewRequest(MethodPost, "/uploads", strings.NewReader(large))
+		r.Header.Set(SignatureDateHeader, time.Now().UTC().Format(signatureDateFormat))
+		r.Header.Set(SignatureContentHeader, UnsignedPayload)
+		headers := []string{"host", strings.ToLower(SignatureContentHeader), strings.ToLower(SignatureDateHeader)}
+		r.Header.Set("Authorization", SignatureAlgorithm+" Credential=AKID1, SignedHeaders="+strings.Join(headers, ";")+
+			", Signature="+requestSignature("s3cret", r, r.URL, headers, UnsignedPayload))
+		if code := serve(r); code != http.StatusOK || read != len(large) {
+			t.Errorf("Expected the unsigned body streamed to the handler, got %d with %d bytes", code, read)
+		}
+
+		// Unknown keys are rejected before the body is read
+		r = signed(MethodPost, "/uploads", large, "s3cret", time.Now())
+		r.Header.Set("Authorization", strings.Replace(r.Header.Get("Authorization"), "AKID1", "AKID2", 1))
+		r.Body = io.NopCloser(iotest.ErrReader(errors.New("body read")))
+		if code := serve(r); code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+}
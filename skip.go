@@ -0,0 +1,108 @@
+package GoFlow
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Skip runs mw only for requests skip rejects; the others go straight to
+// the next handler. Use it to keep global middleware away from health
+// checks, metrics scrapes or websocket upgrades:
+//
+//	mux.Use(GoFlow.Skip(GoFlow.Logger(), func(r *http.Request) bool {
+//		return r.URL.Path == "/healthz"
+//	}))
+func Skip(mw func(http.Handler) http.Handler, skip func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+type withoutKey struct{}
+
+// Without removes middleware inherited from the mux or its groups from this
+// route. Middleware are named by the function that created them, with or
+// without the package, e.g. "Logger", "RateLimit" or "main.audit", or by
+// NamedMiddleware; the WithOptions variants answer to the short name too.
+// Mux.Validate reports names that match no inherited middleware.
+func (rt *Route) Without(names ...string) *Route {
+	prev, _ := rt.Value(withoutKey{}).([]string)
+	rt.Set(withoutKey{}, append(slices.Clip(prev), names...))
+	rt.compile()
+	return rt
+}
+
+// Without removes the named middleware from routes registered afterwards on
+// this mux or group, see Route.Without
+func (m *Mux) Without(names ...string) {
+	prev, _ := m.meta[withoutKey{}].([]string)
+	m.Set(withoutKey{}, append(slices.Clip(prev), names...))
+}
+
+// inherited returns the route's middleware from the mux and its groups,
+// less those removed with Without
+func (rt *Route) inherited() []func(http.Handler) http.Handler {
+	names, _ := rt.Value(withoutKey{}).([]string)
+	if len(names) == 0 {
+		return rt.middlewares
+	}
+	var kept []func(http.Handler) http.Handler
+	for _, mw := range rt.middlewares {
+		if !middlewareNamed(middlewareName(mw), names) {
+			kept = append(kept, mw)
+		}
+	}
+	return kept
+}
+
+func middlewareNamed(name string, names []string) bool {
+	short := name[strings.IndexByte(name, '.')+1:]
+	for _, n := range names {
+		if n == name || n == short || n+"WithOptions" == short {
+			return true
+		}
+	}
+	return false
+}
+
+// NamedMiddleware gives mw an explicit name for Without, request traces and
+// Mux.Validate, instead of the function that created it. Use it when
+// middleware is built by a shared helper, whose function name doesn't tell
+// them apart:
+//
+//	mux.Use(GoFlow.NamedMiddleware("audit", auditLog(store)))
+func NamedMiddleware(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if _, ok := next.(nameProbe); ok {
+			return nameProbe{name}
+		}
+		return mw(next)
+	}
+}
+
+// nameProbe asks a NamedMiddleware for its name
+type nameProbe struct {
+	name string
+}
+
+func (nameProbe) ServeHTTP(http.ResponseWriter, *http.Request) {}
+
+var namedMiddlewareFunc = funcName(NamedMiddleware("", nil))
+
+// middlewareName returns the name given with NamedMiddleware, or else the
+// function that created mw
+func middlewareName(mw any) string {
+	name := funcName(mw)
+	if fn, ok := mw.(func(http.Handler) http.Handler); ok && name == namedMiddlewareFunc {
+		return fn(nameProbe{}).(nameProbe).name
+	}
+	return name
+}
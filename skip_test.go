@@ -0,0 +1,100 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSkip(t *testing.T) {
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("X-Ran", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	serve := func(mux *Mux, path string) []string {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w.Header().Values("X-Ran")
+	}
+
+	t.Run("Predicate", func(t *testing.T) {
+		mux := New()
+		mux.Use(Skip(tag("audit"), func(r *http.Request) bool { return r.URL.Path == "/healthz" }))
+		mux.Handle("/healthz", noop, MethodGet)
+		mux.Handle("/users", noop, MethodGet)
+
+		if ran := serve(mux, "/healthz"); len(ran) != 0 {
+			t.Errorf("Expected the middleware skipped, got %v", ran)
+		}
+		if ran := serve(mux, "/users"); !equalSlices(ran, []string{"audit"}) {
+			t.Errorf("Expected the middleware to run, got %v", ran)
+		}
+	})
+
+	t.Run("Without", func(t *testing.T) {
+		mux := New()
+		mux.Use(tag("audit"), RateLimit(1000, 60e9, 1000))
+		metrics := mux.Handle("/metrics", noop, MethodGet).Without("Logger", "RateLimit")
+		users := mux.Handle("/users", noop, MethodGet)
+		var ws *Route
+		mux.Group(func(g *Mux) {
+			g.Without("GoFlow.RateLimitWithOptions")
+			ws = g.Handle("/ws", noop, MethodGet)
+		})
+		// Middleware added after registration is removed too
+		mux.Use(Logger())
+
+		names := func(rt *Route) []string {
+			var out []string
+			for _, mw := range rt.inherited() {
+				out = append(out, funcName(mw))
+			}
+			return out
+		}
+		if got := names(metrics); len(got) != 1 {
+			t.Errorf("Expected only the audit middleware left, got %v", got)
+		}
+		if got := names(users); len(got) != 3 {
+			t.Errorf("Expected every middleware on other routes, got %v", got)
+		}
+		if got := names(ws); len(got) != 2 || contains(got, "GoFlow.RateLimitWithOptions") {
+			t.Errorf("Expected the group to drop RateLimit, got %v", got)
+		}
+		if ran := serve(mux, "/metrics"); !equalSlices(ran, []string{"audit"}) {
+			t.Errorf("Expected only audit to run, got %v", ran)
+		}
+	})
+
+	t.Run("Named Middleware", func(t *testing.T) {
+		mux := New()
+		mux.Use(
+			SetHeaders(map[string]string{"X-Frame-Options": "DENY"}),
+			DefaultHeaders(map[string]string{"Cache-Control": "no-store"}),
+			NamedMiddleware("audit", tag("audit")),
+		)
+		mux.Handle("/embed", noop, MethodGet).Without("SetHeaders", "audit")
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/embed", nil))
+		if w.Header().Get("X-Frame-Options") != "" || w.Header().Get("X-Ran") != "" {
+			t.Errorf("Expected SetHeaders and audit removed, got %v", w.Header())
+		}
+		if w.Header().Get("Cache-Control") != "no-store" {
+			t.Error("Expected DefaultHeaders kept")
+		}
+		if err := mux.Validate(); err != nil {
+			t.Errorf("Expected no issues, got %v", err)
+		}
+
+		mux.Handle("/typo", noop, MethodGet).Without("SetHeader")
+		if err := mux.Validate(); err == nil || !strings.Contains(err.Error(), `Without("SetHeader")`) {
+			t.Errorf("Expected the unmatched name reported, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package GoFlow
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"weak"
+)
+
+// RuntimeStats is a snapshot of the memory GoFlow holds on to, for
+// operators
+type RuntimeStats struct {
+	// Muxes describes every live Mux created with New
+	Muxes []MuxStats
+	// Caches describes every live Cache middleware
+	Caches []CacheStats
+	// Cached describes the values cached with Cached; their size isn't
+	// tracked
+	Cached CacheStats
+	// RateLimiters describes every live RateLimiter
+	RateLimiters []RateLimiterStats
+	// ErrorBudgets reports every live ErrorBudget's window
+	ErrorBudgets []ErrorBudgetStats
+	// Pools reports how often the internal object pools had to allocate
+	Pools []PoolStats
+	// Buffers reports the response bytes buffered against SetBufferBudget
+	Buffers BufferStats
+}
+
+// MuxStats describes a Mux's route tree
+type MuxStats struct {
+	Routes       int
+	StaticRoutes int // served from the static map, skipping the tree
+	TreeNodes    int
+	TreeDepth    int // longest chain of nodes from the root
+
+	// Deprecated reports the use of routes marked with Route.Deprecate
+	Deprecated []DeprecatedRouteStats `json:",omitempty"`
+
+	// AuthFailures lists the routes that rejected authentication attempts
+	AuthFailures []AuthFailureStats `json:",omitempty"`
+}
+
+// DeprecatedRouteStats counts the requests to a deprecated route, showing
+// whether clients still depend on it as its sunset approaches
+type DeprecatedRouteStats struct {
+	Pattern  string
+	Methods  []string
+	Sunset   time.Time `json:",omitzero"`
+	Requests uint64
+}
+
+// AuthFailureStats counts the authentication attempts a route rejected
+type AuthFailureStats struct {
+	Pattern  string
+	Methods  []string
+	Failures uint64
+}
+
+// CacheStats describes the responses a Cache middleware stores
+type CacheStats struct {
+	Entries int64
+	Bytes   int64
+	Hits    uint64
+	Misses  uint64
+}
+
+// RateLimiterStats reports the buckets a RateLimiter tracks
+type RateLimiterStats struct {
+	// Tier names the API key tier of a TieredRateLimit limiter
+	Tier    string `json:",omitempty"`
+	Buckets int
+	// Rejected counts the requests the limiter turned away
+	Rejected uint64
+	// Shards holds the bucket count per shard, showing how evenly keys
+	// are spread
+	Shards []int
+}
+
+// PoolStats reports the use of an object pool. Allocations close to Gets
+// mean the pool rarely has objects to reuse.
+type PoolStats struct {
+	Name        string
+	Gets        uint64
+	Allocations uint64
+}
+
+// BufferStats reports the buffer budget. Exceeded counts the writes that
+// found it exhausted.
+type BufferStats struct {
+	Limit    int64
+	InUse    int64
+	Exceeded uint64
+}
+
+// statsRegistry tracks live components through weak pointers, so that
+// registering doesn't keep them alive
+type statsRegistry struct {
+	mu       sync.Mutex
+	muxes    []weak.Pointer[Mux]
+	caches   []weak.Pointer[cacheStore]
+	limiters []weak.Pointer[RateLimiter]
+	budgets  []weak.Pointer[errorBudget]
+	pools    []*poolCounter
+}
+
+var registry statsRegistry
+
+// poolCounter counts the use of a sync.Pool
+type poolCounter struct {
+	name        string
+	gets        atomic.Uint64
+	allocations atomic.Uint64
+}
+
+func newPoolCounter(name string) *poolCounter {
+	c := &poolCounter{name: name}
+	registry.mu.Lock()
+	registry.pools = append(registry.pools, c)
+	registry.mu.Unlock()
+	return c
+}
+
+var (
+	paramsPoolStats = newPoolCounter("params")
+	gzipPoolStats   = newPoolCounter("gzip")
+)
+
+func registerStats[T any](list *[]weak.Pointer[T], v *T) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	live := (*list)[:0]
+	for _, p := range *list {
+		if p.Value() != nil {
+			live = append(live, p)
+		}
+	}
+	*list = append(live, weak.Make(v))
+}
+
+func liveStats[T any](list []weak.Pointer[T]) []*T {
+	var live []*T
+	for _, p := range list {
+		if v := p.Value(); v != nil {
+			live = append(live, v)
+		}
+	}
+	return live
+}
+
+// Stats reports route counts and tree shape per mux, cache sizes,
+// rate limiter buckets per shard and pool utilization. Use the goflowvars
+// package to publish it with expvar.
+//
+// Route tables are read without locking, so take snapshots once routes
+// are registered.
+func Stats() RuntimeStats {
+	registry.mu.Lock()
+	muxes := liveStats(registry.muxes)
+	caches := liveStats(registry.caches)
+	limiters := liveStats(registry.limiters)
+	budgets := liveStats(registry.budgets)
+	pools := append([]*poolCounter(nil), registry.pools...)
+	registry.mu.Unlock()
+
+	var s RuntimeStats
+	for _, m := range muxes {
+		ms := MuxStats{
+			Routes:       len(m.Routes()),
+			StaticRoutes: len(m.root.staticHandlers),
+		}
+		ms.TreeNodes, ms.TreeDepth = m.root.shape()
+		for _, rt := range m.Routes() {
+			if d, ok := rt.Value(deprecationKey{}).(Deprecation); ok {
+				ms.Deprecated = append(ms.Deprecated, DeprecatedRouteStats{
+					Pattern:  rt.pattern,
+					Methods:  rt.methods,
+					Sunset:   d.Sunset,
+					Requests: rt.deprecatedCalls.Load(),
+				})
+			}
+			if n := rt.authFailures.Load(); n > 0 {
+				ms.AuthFailures = append(ms.AuthFailures, AuthFailureStats{
+					Pattern:  rt.pattern,
+					Methods:  rt.methods,
+					Failures: n,
+				})
+			}
+		}
+		s.Muxes = append(s.Muxes, ms)
+	}
+	for _, c := range caches {
+		s.Caches = append(s.Caches, CacheStats{
+			Entries: c.entries.Load(),
+			Bytes:   c.bytes.Load(),
+			Hits:    c.hits.Load(),
+			Misses:  c.misses.Load(),
+		})
+	}
+	s.Cached = CacheStats{
+		Entries: int64(readThrough.current().Len()),
+		Hits:    readThrough.hits.Load(),
+		Misses:  readThrough.misses.Load(),
+	}
+	for _, rl := range limiters {
+		s.RateLimiters = append(s.RateLimiters, rl.stats())
+	}
+	for _, b := range budgets {
+		s.ErrorBudgets = append(s.ErrorBudgets, b.stats())
+	}
+	for _, p := range pools {
+		s.Pools = append(s.Pools, PoolStats{Name: p.name, Gets: p.gets.Load(), Allocations: p.allocations.Load()})
+	}
+	s.Buffers = BufferStats{
+		Limit:    buffers.limit.Load(),
+		InUse:    buffers.inUse.Load(),
+		Exceeded: buffers.exceeded.Load(),
+	}
+	return s
+}
+
+// shape counts the nodes below and including n and the depth of the tree
+func (n *routeTree) shape() (nodes, depth int) {
+	children := n.children
+	if n.paramChild != nil {
+		children = append(children[:len(children):len(children)], n.paramChild)
+	}
+	for _, child := range children {
+		cn, cd := child.shape()
+		nodes += cn
+		depth = max(depth, cd)
+	}
+	return nodes + 1, depth + 1
+}
+
+func (rl *RateLimiter) stats() RateLimiterStats {
+	s := RateLimiterStats{Tier: rl.tier, Shards: make([]int, len(rl.shards)), Rejected: rl.rejected.Load()}
+	for i := range rl.shards {
+		shard := &rl.shards[i]
+		shard.RLock()
+		s.Shards[i] = len(shard.buckets)
+		shard.RUnlock()
+		s.Buckets += s.Shards[i]
+	}
+	return s
+}
@@ -0,0 +1,91 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	mux := New()
+	noop := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mux.Handle("/users", noop, MethodGet)
+	mux.Handle("/users/:id", noop, MethodGet)
+	mux.Handle("/usersettings", noop, MethodGet)
+
+	cached := Cache(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("0123456789"))
+	}))
+	limiter := NewRateLimiter(10, time.Minute, 0)
+
+	find := func() (MuxStats, bool) {
+		for _, ms := range Stats().Muxes {
+			if ms.Routes == 3 && ms.StaticRoutes == 2 {
+				return ms, true
+			}
+		}
+		return MuxStats{}, false
+	}
+
+	t.Run("Mux", func(t *testing.T) {
+		ms, ok := find()
+		if !ok {
+			t.Fatalf("Expected the mux in the stats, got %+v", Stats().Muxes)
+		}
+		// root -> "/users" -> "/" -> :id, and "/users" -> "ettings"
+		if ms.TreeNodes != 5 || ms.TreeDepth != 4 {
+			t.Errorf("Expected 5 nodes 4 deep, got %d nodes %d deep", ms.TreeNodes, ms.TreeDepth)
+		}
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		before := Stats().Caches
+		for _, path := range []string{"/a", "/b", "/a"} {
+			cached.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, path, nil))
+		}
+		after := Stats().Caches
+		if len(after) != len(before) {
+			t.Fatalf("Expected %d caches, got %d", len(before), len(after))
+		}
+		found := false
+		for _, c := range after {
+			if c.Entries == 2 && c.Bytes == 2*int64(len("0123456789")+len("Content-Type")+len("text/plain")) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a cache with 2 entries, got %+v", after)
+		}
+	})
+
+	t.Run("Rate Limiter", func(t *testing.T) {
+		for _, key := range []string{"a", "b", "c", "a"} {
+			limiter.Allow(key)
+		}
+		s := limiter.stats()
+		if s.Buckets != 3 || len(s.Shards) != len(limiter.shards) {
+			t.Errorf("Expected 3 buckets over %d shards, got %+v", len(limiter.shards), s)
+		}
+	})
+
+	t.Run("Pools", func(t *testing.T) {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/users/1", nil))
+		for _, p := range Stats().Pools {
+			if p.Name == "params" && p.Gets > 0 && p.Allocations > 0 && p.Allocations <= p.Gets {
+				return
+			}
+		}
+		t.Errorf("Expected params pool usage, got %+v", Stats().Pools)
+	})
+
+	t.Run("Collected", func(t *testing.T) {
+		mux = nil
+		runtime.GC()
+		if _, ok := find(); ok {
+			t.Error("Expected a collected mux to leave the stats")
+		}
+	})
+}
@@ -0,0 +1,195 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	// ErrTaskQueueFull is returned by Go when every worker is busy and the
+	// queue is full
+	ErrTaskQueueFull = errors.New("GoFlow: task queue is full")
+	// ErrTasksClosed is returned by Go once Shutdown has started
+	ErrTasksClosed = errors.New("GoFlow: task runner is shut down")
+	// ErrNoTaskRunner is returned by Go when ctx carries no TaskRunner
+	ErrNoTaskRunner = errors.New("GoFlow: no TaskRunner; set Server.Tasks or use the Tasks middleware")
+)
+
+// TaskOptions configures a TaskRunner
+type TaskOptions struct {
+	// Workers run tasks concurrently, from a queue of QueueSize tasks.
+	// Default to 8 and 1000.
+	Workers   int
+	QueueSize int
+
+	// Logger receives task errors and panics. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// TaskRunner runs fire-and-forget work, such as sending emails or warming
+// caches, on a bounded pool of workers and drains it on shutdown
+type TaskRunner struct {
+	opts TaskOptions
+
+	mu     sync.RWMutex
+	closed bool
+
+	queue   chan task
+	pending sync.WaitGroup
+	workers sync.WaitGroup
+	stop    context.Context
+	cancel  context.CancelFunc
+}
+
+type task struct {
+	ctx context.Context
+	fn  func(ctx context.Context) error
+}
+
+type taskRunnerKey struct{}
+
+// NewTaskRunner starts a runner's workers. Call Shutdown to drain it, or
+// set it as Server.Tasks to have the server do so.
+func NewTaskRunner(opts TaskOptions) *TaskRunner {
+	if opts.Workers == 0 {
+		opts.Workers = 8
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	t := &TaskRunner{opts: opts, queue: make(chan task, opts.QueueSize)}
+	t.stop, t.cancel = context.WithCancel(context.Background())
+	for range opts.Workers {
+		t.workers.Add(1)
+		go t.work()
+	}
+	return t
+}
+
+// Go queues fn to run on a worker. fn receives ctx's values, such as the
+// tenant, but is not canceled when ctx is, so it outlives the request.
+// Its context is canceled only if Shutdown gives up waiting. A returned
+// error or panic is logged.
+func (t *TaskRunner) Go(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrTasksClosed
+	}
+	t.pending.Add(1)
+	select {
+	case t.queue <- task{context.WithoutCancel(ctx), fn}:
+		return nil
+	default:
+		t.pending.Done()
+		return ErrTaskQueueFull
+	}
+}
+
+// Shutdown stops accepting tasks and waits for queued and running ones to
+// finish. When ctx expires first, running tasks' contexts are canceled,
+// queued tasks are dropped and Shutdown returns ctx.Err() without waiting
+// for the running tasks to return.
+func (t *TaskRunner) Shutdown(ctx context.Context) error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		t.pending.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	t.cancel()
+	// Drop queued tasks once the workers stopped, without waiting past ctx
+	// for tasks that ignore the cancellation
+	stopped := make(chan struct{})
+	go func() {
+		t.workers.Wait()
+		for {
+			select {
+			case <-t.queue:
+				t.pending.Done()
+			case <-done:
+				close(stopped)
+				return
+			}
+		}
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		t.opts.Logger.Warn("tasks canceled by shutdown", slog.String("error", err.Error()))
+	}
+	return err
+}
+
+func (t *TaskRunner) work() {
+	defer t.workers.Done()
+	for {
+		select {
+		case tk := <-t.queue:
+			t.run(tk)
+		case <-t.stop.Done():
+			return
+		}
+	}
+}
+
+func (t *TaskRunner) run(tk task) {
+	defer t.pending.Done()
+	ctx, cancel := context.WithCancel(tk.ctx)
+	defer cancel()
+	defer context.AfterFunc(t.stop, cancel)()
+
+	defer func() {
+		if v := recover(); v != nil {
+			t.opts.Logger.Error("task panic",
+				slog.String("panic", fmt.Sprint(v)),
+				slog.String("stack", string(debug.Stack())),
+			)
+		}
+	}()
+	if err := tk.fn(ctx); err != nil {
+		t.opts.Logger.Error("task failed", slog.String("error", err.Error()))
+	}
+}
+
+// Tasks makes runner available to handlers through Go. Server does this
+// itself when Server.Tasks is set.
+func Tasks(runner *TaskRunner) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), taskRunnerKey{}, runner)))
+		})
+	}
+}
+
+// Go queues fn on the TaskRunner of the request ctx belongs to, see
+// TaskRunner.Go
+func Go(ctx context.Context, fn func(ctx context.Context) error) error {
+	runner, ok := ctx.Value(taskRunnerKey{}).(*TaskRunner)
+	if !ok {
+		return ErrNoTaskRunner
+	}
+	return runner.Go(ctx, fn)
+}
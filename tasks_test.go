@@ -0,0 +1,168 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTasks(t *testing.T) {
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Outlives Request", func(t *testing.T) {
+		runner := NewTaskRunner(TaskOptions{Logger: discard})
+		r := WithTenant(httptest.NewRequest(MethodGet, "/", nil), "acme")
+		ctx, cancel := context.WithCancel(r.Context())
+
+		result := make(chan string, 1)
+		runner.Go(ctx, func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			if ctx.Err() != nil {
+				result <- "canceled"
+				return nil
+			}
+			result <- GetTenant(ctx)
+			return nil
+		})
+		cancel()
+
+		if got := <-result; got != "acme" {
+			t.Errorf("Expected the task to keep running with the request's values, got %q", got)
+		}
+		runner.Shutdown(context.Background())
+	})
+
+	t.Run("Bounded", func(t *testing.T) {
+		runner := NewTaskRunner(TaskOptions{Workers: 1, QueueSize: 1, Logger: discard})
+		release := make(chan struct{})
+		block := func(context.Context) error { <-release; return nil }
+
+		started := make(chan struct{})
+		runner.Go(context.Background(), func(ctx context.Context) error { close(started); return block(ctx) })
+		<-started
+		if err := runner.Go(context.Background(), block); err != nil {
+			t.Errorf("Expected the second task queued, got %v", err)
+		}
+		if err := runner.Go(context.Background(), block); err != ErrTaskQueueFull {
+			t.Errorf("Expected ErrTaskQueueFull, got %v", err)
+		}
+		close(release)
+		runner.Shutdown(context.Background())
+	})
+
+	t.Run("Drain", func(t *testing.T) {
+		runner := NewTaskRunner(TaskOptions{Workers: 2, Logger: discard})
+		var ran atomic.Int32
+		for range 10 {
+			runner.Go(context.Background(), func(context.Context) error {
+				time.Sleep(5 * time.Millisecond)
+				ran.Add(1)
+				return nil
+			})
+		}
+		runner.Go(context.Background(), func(context.Context) error { panic("boom") })
+		runner.Go(context.Background(), func(context.Context) error { return errors.New("failed") })
+
+		if err := runner.Shutdown(context.Background()); err != nil {
+			t.Errorf("Expected a clean drain, got %v", err)
+		}
+		if ran.Load() != 10 {
+			t.Errorf("Expected every queued task to run, got %d", ran.Load())
+		}
+		if err := runner.Go(context.Background(), func(context.Context) error { return nil }); err != ErrTasksClosed {
+			t.Errorf("Expected ErrTasksClosed after Shutdown, got %v", err)
+		}
+	})
+
+	t.Run("Shutdown Deadline", func(t *testing.T) {
+		runner := NewTaskRunner(TaskOptions{Logger: discard})
+		canceled := make(chan struct{})
+		runner.Go(context.Background(), func(ctx context.Context) error {
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if err := runner.Shutdown(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Error("Expected the running task's context canceled")
+		}
+	})
+
+	t.Run("Shutdown Ignoring Cancellation", func(t *testing.T) {
+		runner := NewTaskRunner(TaskOptions{Logger: discard})
+		release := make(chan struct{})
+		defer close(release)
+		runner.Go(context.Background(), func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := runner.Shutdown(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected Shutdown to return at the deadline, took %v", elapsed)
+		}
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		addr := freeAddr(t)
+		var finished atomic.Bool
+		mux := New()
+		mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := Go(r.Context(), func(context.Context) error {
+				time.Sleep(50 * time.Millisecond)
+				finished.Store(true)
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Expected the task started, got %v", err)
+			}
+		}), "GET")
+
+		s := NewServer(addr, mux)
+		s.Logger = discard
+		s.Tasks = NewTaskRunner(TaskOptions{Logger: discard})
+		var hookSawTask bool
+		s.OnShutdown(func(context.Context) error { hookSawTask = finished.Load(); return nil })
+		done := make(chan error, 1)
+		go func() { done <- s.Run() }()
+		waitForServer(t, addr)
+
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+		<-done
+		if !hookSawTask {
+			t.Error("Expected the task drained before the OnShutdown hooks")
+		}
+	})
+
+	t.Run("No Runner", func(t *testing.T) {
+		if err := Go(context.Background(), func(context.Context) error { return nil }); err != ErrNoTaskRunner {
+			t.Errorf("Expected ErrNoTaskRunner, got %v", err)
+		}
+	})
+}
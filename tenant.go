@@ -0,0 +1,116 @@
+package GoFlow
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// TenantResolver returns the tenant a request belongs to, or "" if it
+// can't tell
+type TenantResolver func(r *http.Request) string
+
+// TenantFromSubdomain resolves the tenant from the subdomain of domain, so
+// acme.example.com belongs to "acme" for domain "example.com". Deeper
+// subdomains and the domain itself have no tenant.
+func TenantFromSubdomain(domain string) TenantResolver {
+	suffix := "." + strings.ToLower(strings.Trim(domain, "."))
+	return func(r *http.Request) string {
+		host := strings.TrimSuffix(strings.ToLower(stripPort(r.Host)), ".")
+		sub, ok := strings.CutSuffix(host, suffix)
+		if !ok || sub == "" || strings.Contains(sub, ".") {
+			return ""
+		}
+		return sub
+	}
+}
+
+// TenantFromHeader resolves the tenant from a request header such as
+// X-Tenant-ID
+func TenantFromHeader(name string) TenantResolver {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// TenantFromParam resolves the tenant from a route parameter, e.g. "org"
+// in /orgs/:org/projects
+func TenantFromParam(name string) TenantResolver {
+	return func(r *http.Request) string {
+		return Param(r.Context(), name)
+	}
+}
+
+// FirstTenant tries resolvers in order and returns the first tenant found
+func FirstTenant(resolvers ...TenantResolver) TenantResolver {
+	return func(r *http.Request) string {
+		for _, resolve := range resolvers {
+			if tenant := resolve(r); tenant != "" {
+				return tenant
+			}
+		}
+		return ""
+	}
+}
+
+// TenantOptions configures the Tenant middleware
+type TenantOptions struct {
+	Resolver TenantResolver
+
+	// Required rejects requests without a tenant with 404 Not Found
+	Required bool
+}
+
+type tenantContextKey struct{}
+
+// Tenant resolves the tenant of each request and stores it in the
+// context, see GetTenant. Downstream, Cache and rate limits keep tenants
+// apart and Logger records the tenant.
+func Tenant(resolver TenantResolver) func(http.Handler) http.Handler {
+	return TenantWithOptions(TenantOptions{Resolver: resolver})
+}
+
+// TenantWithOptions is Tenant that can reject requests without a tenant
+func TenantWithOptions(opts TenantOptions) func(http.Handler) http.Handler {
+	if opts.Resolver == nil {
+		panic("GoFlow: TenantOptions.Resolver is required")
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := opts.Resolver(r)
+			if tenant == "" {
+				if opts.Required {
+					http.Error(w, StatusText(r.Context(), http.StatusNotFound), http.StatusNotFound)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, WithTenant(r, tenant))
+		})
+	}
+}
+
+// GetTenant returns the tenant stored in the context, or ""
+func GetTenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// WithTenant returns a shallow copy of r carrying tenant in its context
+func WithTenant(r *http.Request, tenant string) *http.Request {
+	ctx := r.Context()
+	if h, ok := ctx.Value(logHolderKey{}).(*logHolder); ok {
+		h.tenant = tenant
+	}
+	return r.WithContext(context.WithValue(ctx, tenantContextKey{}, tenant))
+}
+
+// tenantKey scopes key, such as a rate limit or cache key, to the tenant
+// of the request
+func tenantKey(r *http.Request, key string) string {
+	if tenant := GetTenant(r.Context()); tenant != "" {
+		return tenant + "\x00" + key
+	}
+	return key
+}
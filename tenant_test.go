@@ -0,0 +1,110 @@
+package GoFlow
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTenant(t *testing.T) {
+	t.Run("Resolvers", func(t *testing.T) {
+		resolve := FirstTenant(TenantFromHeader("X-Tenant-ID"), TenantFromSubdomain("example.com"))
+		tests := []struct {
+			host, header, expected string
+		}{
+			{"acme.example.com", "", "acme"},
+			{"ACME.example.com:8443", "", "acme"},
+			{"example.com", "", ""},
+			{"www.acme.example.com", "", ""},
+			{"acme.example.org", "", ""},
+			{"acme.example.com", "globex", "globex"},
+		}
+		for _, tt := range tests {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.Host = tt.host
+			if tt.header != "" {
+				r.Header.Set("X-Tenant-ID", tt.header)
+			}
+			if got := resolve(r); got != tt.expected {
+				t.Errorf("Expected tenant '%s' for %s, got '%s'", tt.expected, tt.host, got)
+			}
+		}
+	})
+
+	t.Run("Path Parameter", func(t *testing.T) {
+		mux := New()
+		mux.Use(TenantWithOptions(TenantOptions{Resolver: TenantFromParam("org"), Required: true}))
+		mux.Handle("/orgs/:org/projects", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(GetTenant(r.Context())))
+		}), MethodGet)
+		mux.Handle("/status", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), MethodGet)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/orgs/acme/projects", nil))
+		if w.Body.String() != "acme" {
+			t.Errorf("Expected tenant 'acme', got '%s'", w.Body.String())
+		}
+		w = httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/status", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 without a required tenant, got %d", w.Code)
+		}
+	})
+
+	t.Run("Scoped Cache", func(t *testing.T) {
+		handler := Tenant(TenantFromHeader("X-Tenant-ID"))(Cache(time.Minute)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("settings of " + GetTenant(r.Context())))
+			})))
+		for _, tenant := range []string{"acme", "globex", "acme"} {
+			r := httptest.NewRequest(MethodGet, "/settings", nil)
+			r.Header.Set("X-Tenant-ID", tenant)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Body.String() != "settings of "+tenant {
+				t.Errorf("Expected the response of %s, got '%s'", tenant, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("Scoped Rate Limit", func(t *testing.T) {
+		handler := Tenant(TenantFromHeader("X-Tenant-ID"))(RateLimit(1, time.Hour, 0)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+		for _, tt := range []struct {
+			tenant   string
+			expected int
+		}{
+			{"acme", http.StatusOK},
+			{"globex", http.StatusOK},
+			{"acme", http.StatusTooManyRequests},
+		} {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.Header.Set("X-Tenant-ID", tt.tenant)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.expected {
+				t.Errorf("Expected %d for %s, got %d", tt.expected, tt.tenant, w.Code)
+			}
+		}
+	})
+
+	t.Run("Logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		orig := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(orig)
+
+		handler := Logger()(Tenant(TenantFromHeader("X-Tenant-ID"))(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("X-Tenant-ID", "acme")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if !strings.Contains(buf.String(), "tenant=acme") {
+			t.Errorf("Expected the tenant in the log line, got '%s'", buf.String())
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"maps"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// TimeoutOptions configures the Timeout middleware
+type TimeoutOptions struct {
+	Duration time.Duration
+
+	// Status of the timeout response. Defaults to 504.
+	Status int
+
+	// Body of the timeout response. Defaults to the status text.
+	Body string
+
+	// Handler writes the timeout response instead of Status and Body
+	Handler http.Handler
+}
+
+type timeoutKey struct{}
+
+// Timeout cancels the request context after duration and responds with
+// 504 Gateway Timeout if the handler hasn't finished by then. Routes and
+// groups can override duration with Route.Timeout and Mux.Timeout.
+func Timeout(duration time.Duration) func(http.Handler) http.Handler {
+	return TimeoutWithOptions(TimeoutOptions{Duration: duration})
+}
+
+// TimeoutWithOptions is Timeout with a configurable timeout response.
+//
+// Like http.TimeoutHandler, the handler's output is buffered and only sent
+// once it returns in time; writes after the timeout fail with
+// http.ErrHandlerTimeout instead of reaching the client. Streaming handlers
+// should not be wrapped, as the buffered writer can't be flushed; gRPC and
+// Connect streams are detected and only get a context deadline.
+func TimeoutWithOptions(opts TimeoutOptions) func(http.Handler) http.Handler {
+	if opts.Status == 0 {
+		opts.Status = http.StatusGatewayTimeout
+	}
+	timeoutHandler := opts.Handler
+	if timeoutHandler == nil {
+		timeoutHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := opts.Body
+			if body == "" {
+				body = StatusText(r.Context(), opts.Status)
+			}
+			http.Error(w, body, opts.Status)
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			duration := opts.Duration
+			if rt := CurrentRoute(r.Context()); rt != nil {
+				if d, ok := rt.Value(timeoutKey{}).(time.Duration); ok {
+					duration = d
+				}
+			}
+			if duration <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if isWebSocketUpgrade(r) {
+				// An upgraded connection outlives any request deadline
+				next.ServeHTTP(w, r)
+				return
+			}
+			if isStreamingRPC(r) {
+				// Streams can't be buffered; rely on the context deadline alone
+				ctx, cancel := context.WithTimeout(r.Context(), duration)
+				defer cancel()
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			// Nothing to run if the client or an outer deadline already gave up
+			if r.Context().Err() != nil {
+				timeoutHandler.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), duration)
+			defer cancel()
+
+			// The handler may outlive this call, so it must not share the
+			// router's pooled parameter map
+			if params, ok := ctx.Value(paramContextKey{}).(map[string]string); ok {
+				ctx = context.WithValue(ctx, paramContextKey{}, maps.Clone(params))
+			}
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{w: w, h: make(http.Header)}
+			done := make(chan struct{})
+			panicChan := make(chan handlerPanic, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicChan <- handlerPanic{value: p, stack: debug.Stack()}
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case p := <-panicChan:
+				// Re-panic with the original value on the serving goroutine so
+				// Recovery, or net/http for ErrAbortHandler, can handle it. The
+				// handler's stack is lost in the process, so log it first.
+				if p.value != http.ErrAbortHandler {
+					log.Printf("panic: %v\n%s", p.value, p.stack)
+				}
+				panic(p.value)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				defer tw.reserved.free()
+				if tw.overBudget {
+					http.Error(w, StatusText(r.Context(), http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				copyHeaders(w.Header(), tw.h)
+				if !tw.wroteHeader {
+					tw.status = http.StatusOK
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.buf.Bytes())
+				copyTrailers(w.Header(), tw.h)
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				tw.buf = bytes.Buffer{}
+				tw.reserved.free()
+				timeoutHandler.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// handlerPanic carries a panic out of the goroutine running a timed handler
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// Timeout overrides the Timeout middleware's duration for this route.
+// Zero disables the timeout.
+func (rt *Route) Timeout(d time.Duration) *Route {
+	return rt.Set(timeoutKey{}, d)
+}
+
+// Timeout overrides the Timeout middleware's duration for routes registered
+// afterwards on this mux or group
+func (m *Mux) Timeout(d time.Duration) {
+	m.Set(timeoutKey{}, d)
+}
+
+// timeoutWriter buffers a response until the handler returns
+type timeoutWriter struct {
+	w   http.ResponseWriter
+	h   http.Header
+	buf bytes.Buffer
+
+	mu          sync.Mutex
+	reserved    bufferReservation
+	overBudget  bool
+	timedOut    bool
+	wroteHeader bool
+	status      int
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeaderLocked(http.StatusOK)
+	}
+	if tw.overBudget || !tw.reserved.grow(len(p)) {
+		tw.overBudget = true
+		return 0, ErrBufferBudget
+	}
+	return tw.buf.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeHeaderLocked(status)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(status int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+}
@@ -0,0 +1,131 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	t.Run("Late Writes Are Discarded", func(t *testing.T) {
+		writeErr := make(chan error, 1)
+		handler := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			time.Sleep(10 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte("too late"))
+			writeErr <- err
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status code %d, got %d", http.StatusGatewayTimeout, w.Code)
+		}
+		if err := <-writeErr; err != http.ErrHandlerTimeout {
+			t.Errorf("Expected ErrHandlerTimeout, got %v", err)
+		}
+		if w.Body.String() != "Gateway Timeout\n" {
+			t.Errorf("Expected only the timeout body, got '%s'", w.Body.String())
+		}
+	})
+
+	t.Run("Custom Timeout Response", func(t *testing.T) {
+		handler := TimeoutWithOptions(TimeoutOptions{
+			Duration: 10 * time.Millisecond,
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				WriteProblem(w, Problem{Status: http.StatusServiceUnavailable, Title: "Request timed out"})
+			}),
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+
+		if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("Expected custom timeout response, got %d '%s'", w.Code, w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("Fast Handler Passes Through", func(t *testing.T) {
+		mux := New()
+		mux.Use(Timeout(time.Second))
+		mux.Handle("/users/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-User", Param(r.Context(), "id"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("ok"))
+		}), MethodGet)
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/users/42", nil))
+
+		if w.Code != http.StatusCreated || w.Body.String() != "ok" || w.Header().Get("X-User") != "42" {
+			t.Errorf("Expected buffered response to be copied, got %d '%s' '%s'", w.Code, w.Body.String(), w.Header().Get("X-User"))
+		}
+	})
+
+	t.Run("Panics Reach Recovery", func(t *testing.T) {
+		handler := Recovery()(Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		})))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "/", nil))
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+		}
+	})
+
+	t.Run("Panic Value Preserved", func(t *testing.T) {
+		handler := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		defer func() {
+			if p := recover(); p != http.ErrAbortHandler {
+				t.Errorf("Expected http.ErrAbortHandler to be re-panicked, got %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodGet, "/", nil))
+	})
+
+	t.Run("Per Route And Group Durations", func(t *testing.T) {
+		sleep := func(d time.Duration) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(d):
+				case <-r.Context().Done():
+				}
+			})
+		}
+
+		mux := New()
+		mux.Use(Timeout(20 * time.Millisecond))
+		mux.Handle("/default", sleep(50*time.Millisecond), MethodGet)
+		mux.Handle("/reports", sleep(50*time.Millisecond), MethodGet).Timeout(time.Second)
+		mux.Group(func(m *Mux) {
+			m.Timeout(5 * time.Millisecond)
+			m.Handle("/health", sleep(15*time.Millisecond), MethodGet)
+		})
+
+		tests := []struct {
+			path     string
+			expected int
+		}{
+			{"/default", http.StatusGatewayTimeout},
+			{"/reports", http.StatusOK},
+			{"/health", http.StatusGatewayTimeout},
+		}
+		for _, tt := range tests {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, tt.path, nil))
+			if w.Code != tt.expected {
+				t.Errorf("%s: expected status code %d, got %d", tt.path, tt.expected, w.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package GoFlow
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ACMEChallengePath is the path prefix used by ACME HTTP-01 challenges
+const ACMEChallengePath = "/.well-known/acme-challenge/"
+
+// acmeTLSALPNProto is the ALPN protocol of ACME TLS-ALPN-01 challenges
+const acmeTLSALPNProto = "acme-tls/1"
+
+// CertManager provides certificates on demand and answers ACME HTTP-01
+// challenges. *autocert.Manager from golang.org/x/crypto/acme/autocert
+// satisfies it, which keeps GoFlow itself free of external dependencies.
+// Its GetCertificate also answers TLS-ALPN-01 challenges, which RunAutoTLS
+// advertises.
+type CertManager interface {
+	GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error)
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// TLSModern returns a TLS 1.3-only configuration for clients that support it
+func TLSModern() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+	}
+}
+
+// TLSIntermediate returns a TLS 1.2+ configuration restricted to forward-secret
+// AEAD cipher suites. It is the default for Server.
+func TLSIntermediate() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+		// Only consulted for TLS 1.2; TLS 1.3 suites are not configurable
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
+
+// CertReloader serves a certificate from disk and reloads it when the files
+// change, so renewed certificates are picked up without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	// CheckInterval limits how often the files are stat'ed. Defaults to 10s.
+	CheckInterval time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewCertReloader loads the certificate and key pair
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, CheckInterval: 10 * time.Second}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate files from disk
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("GoFlow: loading certificate: %w", err)
+	}
+
+	modTime := r.latestModTime()
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, modTime, due := r.cert, r.modTime, time.Since(r.checkedAt) > r.CheckInterval
+	r.mu.RUnlock()
+
+	if due {
+		r.mu.Lock()
+		r.checkedAt = time.Now()
+		r.mu.Unlock()
+
+		if latest := r.latestModTime(); latest.After(modTime) {
+			// Keep serving the old certificate if the new pair is incomplete
+			if err := r.Reload(); err == nil {
+				r.mu.RLock()
+				cert = r.cert
+				r.mu.RUnlock()
+			}
+		}
+	}
+	return cert, nil
+}
+
+func (r *CertReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		if fi, err := os.Stat(name); err == nil && fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest
+}
+
+// HandleACMEChallenge routes ACME HTTP-01 challenges to manager. RunAutoTLS
+// calls it when the server's handler is a Mux without such a route.
+// HTTPSRedirect, the canonical host redirects and rate limiting let
+// challenge requests through, so they reach the route.
+func (m *Mux) HandleACMEChallenge(manager CertManager) *Route {
+	return m.Handle(ACMEChallengePath+"...", manager.HTTPHandler(nil), MethodGet, MethodHead).Hidden()
+}
+
+// ensureACMEChallenge registers the challenge route on h if it is a Mux
+// that doesn't route challenges yet
+func ensureACMEChallenge(h http.Handler, manager CertManager) {
+	m, ok := h.(*Mux)
+	if !ok {
+		return
+	}
+	if info, _, found := m.Match(MethodGet, ACMEChallengePath+"probe"); found && strings.HasPrefix(info.Pattern, ACMEChallengePath) {
+		return
+	}
+	m.HandleACMEChallenge(manager)
+}
+
+// isACMEChallenge reports whether r is an ACME HTTP-01 challenge, which
+// must be answered on plain HTTP at the requested host
+func isACMEChallenge(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, ACMEChallengePath)
+}
+
+// withALPN adds protos missing from config's NextProtos
+func withALPN(config *tls.Config, protos ...string) {
+	for _, proto := range protos {
+		if !slices.Contains(config.NextProtos, proto) {
+			config.NextProtos = append(config.NextProtos, proto)
+		}
+	}
+}
+
+// acmeHTTPHandler passes challenge requests to next and redirects everything else to HTTPS
+func acmeHTTPHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isACMEChallenge(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		permanentRedirect(w, r, "https://"+stripPort(r.Host)+r.URL.RequestURI())
+	})
+}
@@ -0,0 +1,143 @@
+package GoFlow
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate for commonName to dir
+func writeTestCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+type fakeCertManager struct{}
+
+func (fakeCertManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return nil, nil
+}
+
+func (fakeCertManager) HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("challenge:" + Param(r.Context(), "...")))
+	})
+}
+
+func TestTLS(t *testing.T) {
+	t.Run("Presets", func(t *testing.T) {
+		if cfg := TLSIntermediate(); cfg.MinVersion != tls.VersionTLS12 || len(cfg.CipherSuites) == 0 {
+			t.Errorf("Unexpected intermediate config: min=%x suites=%d", cfg.MinVersion, len(cfg.CipherSuites))
+		}
+		if cfg := TLSModern(); cfg.MinVersion != tls.VersionTLS13 {
+			t.Errorf("Expected TLS 1.3 minimum, got %x", cfg.MinVersion)
+		}
+		if cfg := NewServer("", nil).tlsConfig(); cfg.MinVersion != tls.VersionTLS12 {
+			t.Errorf("Expected Server to default to the intermediate preset")
+		}
+	})
+
+	t.Run("Certificate Hot Reload", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := writeTestCert(t, dir, "old.example")
+
+		reloader, err := NewCertReloader(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reloader.CheckInterval = 0
+
+		cert, _ := reloader.GetCertificate(nil)
+		if leaf, _ := x509.ParseCertificate(cert.Certificate[0]); leaf.Subject.CommonName != "old.example" {
+			t.Fatalf("Expected old.example, got %s", leaf.Subject.CommonName)
+		}
+
+		writeTestCert(t, dir, "new.example")
+		future := time.Now().Add(time.Minute)
+		os.Chtimes(certFile, future, future)
+
+		cert, _ = reloader.GetCertificate(nil)
+		if leaf, _ := x509.ParseCertificate(cert.Certificate[0]); leaf.Subject.CommonName != "new.example" {
+			t.Errorf("Expected reloaded certificate new.example, got %s", leaf.Subject.CommonName)
+		}
+	})
+
+	t.Run("ACME Challenge Routing", func(t *testing.T) {
+		mux := New()
+		mux.HandleACMEChallenge(fakeCertManager{})
+		handler := acmeHTTPHandler(mux)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "http://example.com/.well-known/acme-challenge/tok123", nil))
+		if w.Body.String() != "challenge:tok123" {
+			t.Errorf("Expected challenge response, got '%s'", w.Body.String())
+		}
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(MethodGet, "http://example.com:80/login?next=/", nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "https://example.com/login?next=/" {
+			t.Errorf("Expected HTTPS redirect, got %d '%s'", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("Automatic Challenge Route", func(t *testing.T) {
+		mux := New()
+		mux.Use(
+			HTTPSRedirect(RedirectOptions{}),
+			CanonicalHost("www.example.com"),
+			RateLimit(1, time.Hour, 0),
+		)
+		mux.Handle("/...", http.NotFoundHandler(), MethodGet)
+		ensureACMEChallenge(mux, fakeCertManager{})
+		ensureACMEChallenge(mux, fakeCertManager{})
+		if n := len(mux.Routes()); n != 2 {
+			t.Fatalf("Expected the challenge route registered once, got %d routes", n)
+		}
+
+		for i := 0; i < 3; i++ {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "http://example.com/.well-known/acme-challenge/tok123", nil))
+			if w.Code != http.StatusOK || w.Body.String() != "challenge:tok123" {
+				t.Errorf("Expected the challenge answered past redirects and rate limits, got %d '%s'", w.Code, w.Body.String())
+			}
+		}
+	})
+
+	t.Run("TLS-ALPN", func(t *testing.T) {
+		s := &Server{CertManager: fakeCertManager{}, TLSConfig: &tls.Config{NextProtos: []string{"h2"}}}
+		protos := s.autoTLSConfig().NextProtos
+		if !slices.Equal(protos, []string{"h2", "http/1.1", "acme-tls/1"}) {
+			t.Errorf("Expected h2, http/1.1 and acme-tls/1, got %v", protos)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package GoFlow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// TraceContext identifies a request within a distributed trace, following
+// W3C Trace Context (https://www.w3.org/TR/trace-context/)
+type TraceContext struct {
+	// TraceID is 32 lowercase hex digits, shared by every span of the trace
+	TraceID string
+	// SpanID is 16 hex digits identifying this server's span; ParentID is
+	// the caller's span, empty when the trace started here
+	SpanID   string
+	ParentID string
+	// Sampled is the caller's sampling decision
+	Sampled bool
+	// State is the vendor-specific tracestate header, passed on as is
+	State string
+	// RequestID is the X-Request-ID header, or the TraceID when absent
+	RequestID string
+}
+
+// TraceParent formats tc as a traceparent header with SpanID as the parent,
+// for outgoing requests
+func (tc TraceContext) TraceParent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + flags
+}
+
+// Inject sets the traceparent, tracestate and request ID headers of an
+// outgoing request so the next service continues the trace
+func (tc TraceContext) Inject(h http.Header) {
+	h.Set("Traceparent", tc.TraceParent())
+	if tc.State != "" {
+		h.Set("Tracestate", tc.State)
+	} else {
+		h.Del("Tracestate")
+	}
+	h.Set("X-Request-Id", tc.RequestID)
+}
+
+// TracingOptions configures the Tracing middleware
+type TracingOptions struct {
+	// RequestIDHeader is read and echoed in the response. Defaults to
+	// X-Request-ID.
+	RequestIDHeader string
+
+	// Sampled is the sampling decision for traces that start here.
+	// Incoming traces keep the caller's decision.
+	Sampled bool
+}
+
+type traceContextKey struct{}
+
+// Tracing continues the trace of an incoming traceparent header, or starts
+// one, without depending on OpenTelemetry. The trace is available through
+// GetTrace, logged by Logger and forwarded by Proxy.
+func Tracing() func(http.Handler) http.Handler {
+	return TracingWithOptions(TracingOptions{})
+}
+
+// TracingWithOptions is Tracing with a custom request ID header and
+// sampling decision
+func TracingWithOptions(opts TracingOptions) func(http.Handler) http.Handler {
+	if opts.RequestIDHeader == "" {
+		opts.RequestIDHeader = "X-Request-ID"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tc := TraceContext{SpanID: randomHex(8), Sampled: opts.Sampled}
+			if traceID, parentID, sampled, ok := parseTraceParent(r.Header.Get("Traceparent")); ok {
+				tc.TraceID, tc.ParentID, tc.Sampled = traceID, parentID, sampled
+				tc.State = strings.Join(r.Header.Values("Tracestate"), ",")
+			} else {
+				tc.TraceID = randomHex(16)
+			}
+			tc.RequestID = r.Header.Get(opts.RequestIDHeader)
+			if !validRequestID(tc.RequestID) {
+				tc.RequestID = tc.TraceID
+			}
+
+			w.Header().Set(opts.RequestIDHeader, tc.RequestID)
+			next.ServeHTTP(w, WithTrace(r, tc))
+		})
+	}
+}
+
+// GetTrace returns the request's trace context set by Tracing
+func GetTrace(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// RequestID returns the request's ID set by Tracing, or ""
+func RequestID(ctx context.Context) string {
+	tc, _ := GetTrace(ctx)
+	return tc.RequestID
+}
+
+// WithTrace returns a shallow copy of r carrying tc in its context
+func WithTrace(r *http.Request, tc TraceContext) *http.Request {
+	ctx := r.Context()
+	if h, ok := ctx.Value(logHolderKey{}).(*logHolder); ok {
+		h.trace = &tc
+	}
+	return r.WithContext(context.WithValue(ctx, traceContextKey{}, tc))
+}
+
+// parseTraceParent parses a version 00 traceparent header, and the same
+// fields of later versions as the spec requires
+func parseTraceParent(v string) (traceID, parentID string, sampled, ok bool) {
+	v = strings.TrimSpace(v)
+	if len(v) < 55 || (len(v) > 55 && (v[:2] == "00" || v[55] != '-')) {
+		return "", "", false, false
+	}
+	if v[2] != '-' || v[35] != '-' || v[52] != '-' || v[:2] == "ff" || !isLowerHex(v[:2]) {
+		return "", "", false, false
+	}
+	traceID, parentID, flags := v[3:35], v[36:52], v[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(parentID) || !isLowerHex(flags) ||
+		traceID == strings.Repeat("0", 32) || parentID == strings.Repeat("0", 16) {
+		return "", "", false, false
+	}
+	b, _ := hex.DecodeString(flags)
+	return traceID, parentID, b[0]&1 == 1, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validRequestID accepts IDs that are safe to log and echo: short, printable
+// and without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
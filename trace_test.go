@@ -0,0 +1,102 @@
+package GoFlow
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	var got TraceContext
+	handler := Tracing()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetTrace(r.Context())
+	}))
+
+	t.Run("Continue Trace", func(t *testing.T) {
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.Header.Set("Tracestate", "vendor=abc")
+		r.Header.Set("X-Request-ID", "req-1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || got.ParentID != "00f067aa0ba902b7" || !got.Sampled {
+			t.Errorf("Expected the incoming trace continued, got %+v", got)
+		}
+		if len(got.SpanID) != 16 || got.SpanID == got.ParentID {
+			t.Errorf("Expected a new span ID, got %q", got.SpanID)
+		}
+		if got.State != "vendor=abc" || got.RequestID != "req-1" {
+			t.Errorf("Expected tracestate and request ID kept, got %+v", got)
+		}
+		if w.Header().Get("X-Request-ID") != "req-1" {
+			t.Errorf("Expected the request ID echoed, got %q", w.Header().Get("X-Request-ID"))
+		}
+	})
+
+	t.Run("Start Trace", func(t *testing.T) {
+		for _, tp := range []string{
+			"",
+			"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		} {
+			r := httptest.NewRequest(MethodGet, "/", nil)
+			r.Header.Set("Traceparent", tp)
+			r.Header.Set("X-Request-ID", "bad id\n")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if len(got.TraceID) != 32 || got.TraceID == "4bf92f3577b34da6a3ce929d0e0e4736" || got.ParentID != "" {
+				t.Errorf("Expected a new trace for %q, got %+v", tp, got)
+			}
+			if got.RequestID != got.TraceID {
+				t.Errorf("Expected the trace ID as request ID, got %q", got.RequestID)
+			}
+		}
+	})
+
+	t.Run("Future Version", func(t *testing.T) {
+		r := httptest.NewRequest(MethodGet, "/", nil)
+		r.Header.Set("Traceparent", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if got.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || got.Sampled {
+			t.Errorf("Expected the known fields of a later version parsed, got %+v", got)
+		}
+	})
+
+	t.Run("Logged And Proxied", func(t *testing.T) {
+		var upstream http.Header
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstream = r.Header.Clone()
+		}))
+		defer backend.Close()
+
+		var buf bytes.Buffer
+		orig := log.Writer()
+		log.SetOutput(&buf)
+		defer log.SetOutput(orig)
+
+		mux := New()
+		mux.Use(Logger(), Tracing())
+		mux.Proxy("/api/...", backend.URL, ProxyOptions{})
+
+		r := httptest.NewRequest(MethodGet, "/api/users", nil)
+		r.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		r.Header.Set("X-Request-ID", "req-2")
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+
+		tp := upstream.Get("Traceparent")
+		if !strings.HasPrefix(tp, "00-4bf92f3577b34da6a3ce929d0e0e4736-") || strings.Contains(tp, "00f067aa0ba902b7") {
+			t.Errorf("Expected the trace forwarded with this server's span, got %q", tp)
+		}
+		if upstream.Get("X-Request-ID") != "req-2" {
+			t.Errorf("Expected the request ID forwarded, got %q", upstream.Get("X-Request-ID"))
+		}
+		if line := buf.String(); !strings.Contains(line, "request_id=req-2 trace_id=4bf92f3577b34da6a3ce929d0e0e4736") {
+			t.Errorf("Expected the trace in the access log, got %s", line)
+		}
+	})
+}
@@ -0,0 +1,286 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResponseTransformer rewrites a buffered response body. It may also edit
+// the response headers, which haven't been sent yet.
+type ResponseTransformer interface {
+	TransformResponse(r *http.Request, header http.Header, body []byte) ([]byte, error)
+}
+
+// ResponseTransformerFunc adapts a function to ResponseTransformer
+type ResponseTransformerFunc func(r *http.Request, header http.Header, body []byte) ([]byte, error)
+
+// TransformResponse calls f
+func (f ResponseTransformerFunc) TransformResponse(r *http.Request, header http.Header, body []byte) ([]byte, error) {
+	return f(r, header, body)
+}
+
+// TransformOptions configures the Transform middleware
+type TransformOptions struct {
+	// Transformers maps media types such as "text/html" or "text/*" to the
+	// transformers applied to matching responses, in order
+	Transformers map[string][]ResponseTransformer
+
+	// MaxSize is the largest body buffered for transformation. Larger
+	// responses, and responses the handler flushes, are streamed through
+	// untouched. Defaults to 1MB.
+	MaxSize int64
+}
+
+type transformRegistryKey struct{}
+
+type transformRegistry struct {
+	mu      sync.Mutex
+	entries []transformEntry
+}
+
+type transformEntry struct {
+	mediaType   string
+	transformer ResponseTransformer
+}
+
+// Transform buffers responses whose Content-Type has transformers and
+// rewrites their bodies before sending them. Inner middleware and handlers
+// can add transformers for the current response with AddResponseTransformer.
+//
+// HEAD, 204 and 304 responses and responses the handler encoded itself are
+// never buffered. Install Transform inside Compression so that it sees the
+// uncompressed body.
+func Transform(opts TransformOptions) func(http.Handler) http.Handler {
+	if opts.MaxSize == 0 {
+		opts.MaxSize = 1 << 20
+	}
+	var static []transformEntry
+	for mediaType, transformers := range opts.Transformers {
+		for _, t := range transformers {
+			static = append(static, transformEntry{normalizeMediaTypes([]string{mediaType})[0], t})
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isWebSocketUpgrade(r) || isStreamingRPC(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			registry := &transformRegistry{entries: append([]transformEntry(nil), static...)}
+			r = r.WithContext(context.WithValue(r.Context(), transformRegistryKey{}, registry))
+			tw := &transformWriter{
+				ResponseWriter: w,
+				r:              r,
+				registry:       registry,
+				max:            opts.MaxSize,
+				outerEncoding:  w.Header().Get("Content-Encoding"),
+			}
+			defer tw.reserved.free()
+			next.ServeHTTP(wrapWriter(tw), r)
+			tw.finish()
+		})
+	}
+}
+
+// AddResponseTransformer registers t for the current response if its media
+// type matches mediaType. It reports false if the request isn't served
+// through the Transform middleware.
+func AddResponseTransformer(ctx context.Context, mediaType string, t ResponseTransformer) bool {
+	registry, ok := ctx.Value(transformRegistryKey{}).(*transformRegistry)
+	if !ok {
+		return false
+	}
+	registry.mu.Lock()
+	registry.entries = append(registry.entries, transformEntry{normalizeMediaTypes([]string{mediaType})[0], t})
+	registry.mu.Unlock()
+	return true
+}
+
+func (reg *transformRegistry) match(contentType string) []ResponseTransformer {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	var transformers []ResponseTransformer
+	for _, e := range reg.entries {
+		if mediaTypeSpecificity(e.mediaType, mt) >= 0 {
+			transformers = append(transformers, e.transformer)
+		}
+	}
+	return transformers
+}
+
+// transformWriter buffers a response until it is known to fit MaxSize
+type transformWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	registry *transformRegistry
+	max      int64
+
+	// outerEncoding is set when an outer middleware such as Compression
+	// encodes whatever this writer sends
+	outerEncoding string
+
+	status       int
+	wroteHeader  bool
+	passthrough  bool
+	transformers []ResponseTransformer
+	buf          bytes.Buffer
+	reserved     bufferReservation
+}
+
+func (tw *transformWriter) WriteHeader(status int) {
+	if tw.wroteHeader {
+		return
+	}
+	if status < http.StatusOK {
+		tw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+
+	h := tw.Header()
+	if status != http.StatusNoContent && status != http.StatusNotModified &&
+		tw.r.Method != MethodHead && h.Get("Content-Encoding") == tw.outerEncoding {
+		tw.transformers = tw.registry.match(h.Get("Content-Type"))
+	}
+	if len(tw.transformers) == 0 {
+		tw.passthrough = true
+		tw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (tw *transformWriter) Write(p []byte) (int, error) {
+	if !tw.wroteHeader {
+		if tw.Header().Get("Content-Type") == "" {
+			tw.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		tw.WriteHeader(http.StatusOK)
+	}
+	if tw.passthrough {
+		return tw.ResponseWriter.Write(p)
+	}
+	if int64(tw.buf.Len()+len(p)) > tw.max || !tw.reserved.grow(len(p)) {
+		if err := tw.release(); err != nil {
+			return 0, err
+		}
+		return tw.ResponseWriter.Write(p)
+	}
+	return tw.buf.Write(p)
+}
+
+// Flush gives up on transforming and streams the response from here on
+func (tw *transformWriter) Flush() {
+	if !tw.wroteHeader {
+		tw.WriteHeader(http.StatusOK)
+	}
+	if !tw.passthrough {
+		tw.release()
+	}
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (tw *transformWriter) ReadFrom(src io.Reader) (int64, error) {
+	return io.Copy(writerOnly{tw}, src)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (tw *transformWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// release sends the buffered response untransformed and switches to
+// streaming
+func (tw *transformWriter) release() error {
+	tw.passthrough = true
+	tw.ResponseWriter.WriteHeader(tw.status)
+	_, err := tw.ResponseWriter.Write(tw.buf.Bytes())
+	tw.buf = bytes.Buffer{}
+	tw.reserved.free()
+	return err
+}
+
+func (tw *transformWriter) finish() {
+	if !tw.wroteHeader || tw.passthrough {
+		return
+	}
+	body := tw.buf.Bytes()
+	for _, t := range tw.transformers {
+		var err error
+		if body, err = t.TransformResponse(tw.r, tw.Header(), body); err != nil {
+			tw.Header().Del("Content-Length")
+			http.Error(tw.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	if tw.outerEncoding == "" {
+		tw.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	} else {
+		tw.Header().Del("Content-Length")
+	}
+	tw.ResponseWriter.WriteHeader(tw.status)
+	tw.ResponseWriter.Write(body)
+}
+
+var nonceTagRegex = regexp.MustCompile(`(?i)<(script|style)\b[^>]*>`)
+
+// InjectNonce adds a nonce attribute to every <script> and <style> tag of
+// an HTML response that lacks one, for a Content-Security-Policy with
+// 'nonce-...' sources. nonce returns the value for the request.
+func InjectNonce(nonce func(r *http.Request) string) ResponseTransformer {
+	return ResponseTransformerFunc(func(r *http.Request, _ http.Header, body []byte) ([]byte, error) {
+		attr := []byte(` nonce="` + html.EscapeString(nonce(r)) + `"`)
+		return nonceTagRegex.ReplaceAllFunc(body, func(tag []byte) []byte {
+			if bytes.Contains(bytes.ToLower(tag), []byte("nonce=")) {
+				return tag
+			}
+			name := 1 + bytes.IndexAny(tag[1:], " \t\n>/")
+			return append(append(append([]byte(nil), tag[:name]...), attr...), tag[name:]...)
+		}), nil
+	})
+}
+
+var rootURLRegex = regexp.MustCompile(`(?i)\b(href|src|action)=(["'])/([^/])`)
+
+// RewriteURLPrefix prefixes root-relative href, src and action URLs in an
+// HTML response with prefix, for applications served under a path by a
+// proxy that strips it
+func RewriteURLPrefix(prefix string) ResponseTransformer {
+	replacement := []byte("$1=$2" + strings.ReplaceAll(prefix, "$", "$$") + "/$3")
+	return ResponseTransformerFunc(func(_ *http.Request, _ http.Header, body []byte) ([]byte, error) {
+		return rootURLRegex.ReplaceAll(body, replacement), nil
+	})
+}
+
+// JSONEnvelope wraps a JSON response as {"<key>": <body>}
+func JSONEnvelope(key string) ResponseTransformer {
+	k, _ := json.Marshal(key)
+	return ResponseTransformerFunc(func(_ *http.Request, _ http.Header, body []byte) ([]byte, error) {
+		if !json.Valid(body) {
+			return nil, errors.New("GoFlow: response is not valid JSON")
+		}
+		out := make([]byte, 0, len(body)+len(k)+3)
+		out = append(out, '{')
+		out = append(out, k...)
+		out = append(out, ':')
+		out = append(out, bytes.TrimSpace(body)...)
+		return append(out, '}'), nil
+	})
+}
@@ -0,0 +1,131 @@
+package GoFlow
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	page := `<html><head><script src="/app.js"></script><style nonce="keep">a{}</style></head>` +
+		`<body><a href="/about">About</a><a href="//cdn.example/x">CDN</a><form action='/login'></form></body></html>`
+
+	mux := New()
+	mux.Use(Transform(TransformOptions{
+		Transformers: map[string][]ResponseTransformer{
+			"text/html":        {RewriteURLPrefix("/shop")},
+			"application/json": {JSONEnvelope("data")},
+		},
+		MaxSize: 1024,
+	}))
+	mux.Handle("/page", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AddResponseTransformer(r.Context(), "text/html", InjectNonce(func(*http.Request) string { return "abc" }))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Length", "999")
+		w.Write([]byte(page))
+	}), MethodGet)
+	mux.Handle("/json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`[1,2]`))
+	}), MethodGet)
+	mux.Handle("/broken", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{oops`))
+	}), MethodGet)
+	mux.Handle("/large", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[" + strings.Repeat(`"x",`, 300)))
+		w.Write([]byte(`"x"]`))
+	}), MethodGet)
+	mux.Handle("/stream", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[1`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(`]`))
+	}), MethodGet)
+	mux.Handle("/text", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`plain href="/x"`))
+	}), MethodGet)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("HTML", func(t *testing.T) {
+		w := serve("/page")
+		body := w.Body.String()
+		for _, want := range []string{
+			`<script nonce="abc" src="/shop/app.js">`,
+			`<style nonce="keep">`,
+			`href="/shop/about"`,
+			`href="//cdn.example/x"`,
+			`action='/shop/login'`,
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("Expected body to contain '%s', got %s", want, body)
+			}
+		}
+		if w.Header().Get("Content-Length") != toString(len(body)) {
+			t.Errorf("Expected Content-Length %d, got '%s'", len(body), w.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("JSON Envelope", func(t *testing.T) {
+		w := serve("/json")
+		if w.Code != http.StatusCreated || w.Body.String() != `{"data":[1,2]}` {
+			t.Errorf("Expected enveloped 201, got %d %s", w.Code, w.Body.String())
+		}
+		if w := serve("/broken"); w.Code != http.StatusInternalServerError {
+			t.Errorf("Expected 500 for a failed transformation, got %d", w.Code)
+		}
+	})
+
+	t.Run("Size Limit", func(t *testing.T) {
+		w := serve("/large")
+		if !strings.HasPrefix(w.Body.String(), `["x",`) || !strings.HasSuffix(w.Body.String(), `"x"]`) {
+			t.Errorf("Expected oversized body untouched, got %.20s...", w.Body.String())
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		w := serve("/stream")
+		if w.Body.String() != `[1]` || !w.Flushed {
+			t.Errorf("Expected flushed body untouched, got %s %v", w.Body.String(), w.Flushed)
+		}
+	})
+
+	t.Run("Inside Compression", func(t *testing.T) {
+		handler := Compression()(Transform(TransformOptions{
+			Transformers: map[string][]ResponseTransformer{"application/json": {JSONEnvelope("data")}},
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`true`))
+		})))
+		req := httptest.NewRequest(MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		gz, err := gzip.NewReader(w.Body)
+		if err != nil {
+			t.Fatalf("Expected gzip body, got %v", err)
+		}
+		body, _ := io.ReadAll(gz)
+		if string(body) != `{"data":true}` || w.Header().Get("Content-Length") != "" {
+			t.Errorf("Expected compressed envelope without Content-Length, got %s '%s'", body, w.Header().Get("Content-Length"))
+		}
+	})
+
+	t.Run("Unmatched Type", func(t *testing.T) {
+		w := serve("/text")
+		if w.Body.String() != `plain href="/x"` {
+			t.Errorf("Expected text/plain untouched, got %s", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package GoFlow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// Balance selects how an UpstreamPool distributes requests
+type Balance int
+
+const (
+	// RoundRobin cycles through upstreams in order
+	RoundRobin Balance = iota
+	// LeastConnections picks the upstream with the fewest active requests
+	LeastConnections
+	// Weighted distributes requests in proportion to upstream weights,
+	// interleaving them smoothly rather than in bursts
+	Weighted
+)
+
+// UpstreamOptions configures a single upstream in a pool
+type UpstreamOptions struct {
+	// Weight is used by the Weighted strategy. Defaults to 1.
+	Weight int
+
+	// MaxConns caps concurrent requests to the upstream. Zero means no limit.
+	MaxConns int
+}
+
+// Upstream is a backend in an UpstreamPool
+type Upstream struct {
+	url      *url.URL
+	weight   int
+	maxConns int64
+
+	active  atomic.Int64
+	current int // smooth weighted round-robin state, guarded by the pool
+
+	healthy   atomic.Bool
+	successes int // consecutive probe results, owned by the health checker
+	failures  int
+}
+
+// URL returns the upstream's address
+func (u *Upstream) URL() *url.URL {
+	return u.url
+}
+
+// Weight returns the upstream's weight
+func (u *Upstream) Weight() int {
+	return u.weight
+}
+
+// Active returns the number of requests currently in flight to the upstream
+func (u *Upstream) Active() int {
+	return int(u.active.Load())
+}
+
+// Healthy reports whether the upstream passes its health checks
+func (u *Upstream) Healthy() bool {
+	return u.healthy.Load()
+}
+
+// available reports whether the upstream can take another request
+func (u *Upstream) available() bool {
+	return u.healthy.Load() && (u.maxConns == 0 || u.active.Load() < u.maxConns)
+}
+
+// UpstreamPool is a set of load-balanced backends that can be changed at runtime
+type UpstreamPool struct {
+	balance Balance
+
+	mu        sync.Mutex
+	upstreams []*Upstream
+	next      int
+}
+
+// NewUpstreamPool creates a pool with the given targets, each with default options
+func NewUpstreamPool(balance Balance, targets ...string) (*UpstreamPool, error) {
+	p := &UpstreamPool{balance: balance}
+	for _, target := range targets {
+		if err := p.Add(target, UpstreamOptions{}); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Add registers target, replacing an existing upstream with the same URL
+func (p *UpstreamPool) Add(target string, opts UpstreamOptions) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("GoFlow: invalid upstream %q", target)
+	}
+	if opts.Weight <= 0 {
+		opts.Weight = 1
+	}
+	up := &Upstream{url: u, weight: opts.Weight, maxConns: int64(opts.MaxConns)}
+	up.healthy.Store(true)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, existing := range p.upstreams {
+		if existing.url.String() == u.String() {
+			p.upstreams[i] = up
+			return nil
+		}
+	}
+	p.upstreams = append(p.upstreams, up)
+	return nil
+}
+
+// Remove takes target out of rotation. Requests already in flight complete.
+func (p *UpstreamPool) Remove(target string) bool {
+	if u, err := url.Parse(target); err == nil {
+		target = u.String()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, up := range p.upstreams {
+		if up.url.String() == target {
+			p.upstreams = append(p.upstreams[:i:i], p.upstreams[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Upstreams returns a snapshot of the pool's members
+func (p *UpstreamPool) Upstreams() []*Upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Upstream(nil), p.upstreams...)
+}
+
+// acquire picks an upstream and counts the request against it. It returns
+// nil when every upstream is unhealthy or at its connection limit.
+func (p *UpstreamPool) acquire() *Upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var picked *Upstream
+	switch p.balance {
+	case LeastConnections:
+		for _, up := range p.upstreams {
+			if up.available() && (picked == nil || up.active.Load() < picked.active.Load()) {
+				picked = up
+			}
+		}
+	case Weighted:
+		total := 0
+		for _, up := range p.upstreams {
+			if !up.available() {
+				continue
+			}
+			up.current += up.weight
+			total += up.weight
+			if picked == nil || up.current > picked.current {
+				picked = up
+			}
+		}
+		if picked != nil {
+			picked.current -= total
+		}
+	default:
+		for i := 0; i < len(p.upstreams); i++ {
+			up := p.upstreams[(p.next+i)%len(p.upstreams)]
+			if up.available() {
+				picked = up
+				p.next = (p.next + i + 1) % len(p.upstreams)
+				break
+			}
+		}
+	}
+
+	if picked != nil {
+		picked.active.Add(1)
+	}
+	return picked
+}
+
+type upstreamContextKey struct{}
+
+// NewPoolProxy returns a reverse proxy balancing requests across pool. When
+// no healthy upstream can take the request it answers 503.
+func NewPoolProxy(pool *UpstreamPool, opts ProxyOptions) http.Handler {
+	return newPoolProxy(pool, "", opts)
+}
+
+// ProxyPool is Proxy with requests balanced across the upstreams in pool
+func (m *Mux) ProxyPool(pattern string, pool *UpstreamPool, opts ProxyOptions) *Route {
+	return m.Handle(pattern, newPoolProxy(pool, staticPrefix(pattern), opts))
+}
+
+func newPoolProxy(pool *UpstreamPool, prefix string, opts ProxyOptions) http.Handler {
+	proxy := newReverseProxy(func(r *http.Request) *url.URL {
+		return r.Context().Value(upstreamContextKey{}).(*Upstream).url
+	}, prefix, opts)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		up := pool.acquire()
+		if up == nil {
+			http.Error(w, StatusText(r.Context(), http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer up.active.Add(-1)
+
+		proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), upstreamContextKey{}, up)))
+	})
+}
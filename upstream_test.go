@@ -0,0 +1,107 @@
+package GoFlow
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpstreamPool(t *testing.T) {
+	names := func(p *UpstreamPool, n int) []string {
+		var out []string
+		for i := 0; i < n; i++ {
+			up := p.acquire()
+			if up == nil {
+				out = append(out, "-")
+				continue
+			}
+			out = append(out, up.URL().Host)
+			up.active.Add(-1)
+		}
+		return out
+	}
+
+	t.Run("Round Robin With Runtime Changes", func(t *testing.T) {
+		pool, _ := NewUpstreamPool(RoundRobin, "http://a", "http://b")
+		if got := names(pool, 4); !equalSlices(got, []string{"a", "b", "a", "b"}) {
+			t.Errorf("Unexpected rotation %v", got)
+		}
+
+		pool.Add("http://c", UpstreamOptions{})
+		pool.Remove("http://a")
+		if got := names(pool, 4); !equalSlices(got, []string{"b", "c", "b", "c"}) && !equalSlices(got, []string{"c", "b", "c", "b"}) {
+			t.Errorf("Unexpected rotation after changes %v", got)
+		}
+
+		if err := pool.Add("not a url", UpstreamOptions{}); err == nil {
+			t.Error("Expected error for invalid upstream")
+		}
+	})
+
+	t.Run("Weighted", func(t *testing.T) {
+		pool, _ := NewUpstreamPool(Weighted)
+		pool.Add("http://a", UpstreamOptions{Weight: 5})
+		pool.Add("http://b", UpstreamOptions{Weight: 1})
+		pool.Add("http://c", UpstreamOptions{Weight: 1})
+
+		counts := map[string]int{}
+		for _, name := range names(pool, 70) {
+			counts[name]++
+		}
+		if counts["a"] != 50 || counts["b"] != 10 || counts["c"] != 10 {
+			t.Errorf("Expected 50/10/10 distribution, got %v", counts)
+		}
+	})
+
+	t.Run("Least Connections And Limits", func(t *testing.T) {
+		pool, _ := NewUpstreamPool(LeastConnections)
+		pool.Add("http://a", UpstreamOptions{MaxConns: 1})
+		pool.Add("http://b", UpstreamOptions{MaxConns: 2})
+
+		first, second, third := pool.acquire(), pool.acquire(), pool.acquire()
+		if first == nil || second == nil || third == nil || first.URL().Host == second.URL().Host {
+			t.Fatalf("Expected requests spread over both upstreams")
+		}
+		if pool.acquire() != nil {
+			t.Error("Expected nil when all upstreams are at their limit")
+		}
+		first.active.Add(-1)
+		if up := pool.acquire(); up == nil || up.URL().Host != first.URL().Host {
+			t.Error("Expected freed upstream to be picked")
+		}
+	})
+
+	t.Run("Proxying", func(t *testing.T) {
+		backend := func(name string) *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, name+":"+r.URL.Path)
+			}))
+		}
+		a, b := backend("a"), backend("b")
+		defer a.Close()
+		defer b.Close()
+
+		pool, _ := NewUpstreamPool(RoundRobin, a.URL, b.URL)
+		mux := New()
+		mux.ProxyPool("/svc/...", pool, ProxyOptions{StripPrefix: true})
+
+		var got []string
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/svc/items", nil))
+			got = append(got, w.Body.String())
+		}
+		if !equalSlices(got, []string{"a:/items", "b:/items"}) {
+			t.Errorf("Unexpected responses %v", got)
+		}
+
+		pool.Remove(a.URL)
+		pool.Remove(b.URL)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/svc/items", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status code %d for empty pool, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+	})
+}
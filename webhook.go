@@ -0,0 +1,473 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of outgoing webhook deliveries
+const (
+	WebhookIDHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+var (
+	// ErrWebhookQueueFull is returned by Enqueue when deliveries back up
+	ErrWebhookQueueFull = errors.New("GoFlow: webhook queue is full")
+	// ErrWebhookClosed is returned by Enqueue once Shutdown has started
+	ErrWebhookClosed = errors.New("GoFlow: webhook dispatcher is shut down")
+)
+
+// WebhookEndpoint receives events of the listed types, or all events if
+// Events is empty. Payloads are signed with Secret.
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+	Events []string
+}
+
+// WebhookEvent is an event to deliver. Payload is sent as JSON; []byte and
+// json.RawMessage payloads are sent as they are.
+type WebhookEvent struct {
+	// ID is generated when empty. Receivers use it to drop duplicates,
+	// as an event may be delivered more than once.
+	ID      string
+	Type    string
+	Payload any
+}
+
+// WebhookDelivery is an event on its way to one endpoint
+type WebhookDelivery struct {
+	Endpoint WebhookEndpoint
+	Event    WebhookEvent
+	Body     []byte
+	Attempts int
+}
+
+// WebhookOptions configures a WebhookDispatcher
+type WebhookOptions struct {
+	// Client sends deliveries. Defaults to a client with a 10s timeout.
+	Client *http.Client
+
+	// Workers deliver concurrently, from a queue of QueueSize deliveries.
+	// Default to 4 and 1000.
+	Workers   int
+	QueueSize int
+
+	// MaxAttempts is the number of attempts per delivery, including the
+	// first. Defaults to 5. Network errors, 408, 429 and 5xx responses are
+	// retried with jittered exponential backoff from BaseDelay up to
+	// MaxDelay, defaulting to 1s and 5m.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// DeadLetter receives deliveries that failed for good, including those
+	// still pending when Shutdown gives up, e.g. to store them for replay
+	DeadLetter func(d WebhookDelivery, err error)
+
+	// Logger receives failed attempts. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// WebhookDispatcher delivers events to registered endpoints in the
+// background, signing each payload with the endpoint's secret
+type WebhookDispatcher struct {
+	opts WebhookOptions
+
+	mu        sync.RWMutex
+	endpoints []WebhookEndpoint
+	closed    bool
+
+	queue   chan *WebhookDelivery
+	pending sync.WaitGroup
+	stop    context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+type webhookDispatcherKey struct{}
+
+// NewWebhookDispatcher starts a dispatcher's workers. Call Shutdown to
+// drain it, e.g. from Server.OnShutdown.
+func NewWebhookDispatcher(opts WebhookOptions) *WebhookDispatcher {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Workers == 0 {
+		opts.Workers = 4
+	}
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.MaxAttempts == 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.BaseDelay == 0 {
+		opts.BaseDelay = time.Second
+	}
+	if opts.MaxDelay == 0 {
+		opts.MaxDelay = 5 * time.Minute
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	d := &WebhookDispatcher{opts: opts, queue: make(chan *WebhookDelivery, opts.QueueSize)}
+	d.stop, d.cancel = context.WithCancel(context.Background())
+	for range opts.Workers {
+		d.workers.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Register adds an endpoint for events enqueued from now on
+func (d *WebhookDispatcher) Register(ep WebhookEndpoint) {
+	d.mu.Lock()
+	d.endpoints = append(d.endpoints, ep)
+	d.mu.Unlock()
+}
+
+// Enqueue queues ev for every endpoint subscribed to its type
+func (d *WebhookDispatcher) Enqueue(ev WebhookEvent) error {
+	if ev.ID == "" {
+		ev.ID = rand.Text()
+	}
+	body, err := webhookBody(ev.Payload)
+	if err != nil {
+		return fmt.Errorf("GoFlow: encoding webhook %s: %w", ev.Type, err)
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrWebhookClosed
+	}
+	var deliveries []*WebhookDelivery
+	for _, ep := range d.endpoints {
+		if len(ep.Events) == 0 || slices.Contains(ep.Events, ev.Type) {
+			deliveries = append(deliveries, &WebhookDelivery{Endpoint: ep, Event: ev, Body: body})
+		}
+	}
+	if len(d.queue)+len(deliveries) > cap(d.queue) {
+		return ErrWebhookQueueFull
+	}
+	for _, delivery := range deliveries {
+		d.pending.Add(1)
+		select {
+		case d.queue <- delivery:
+		default:
+			d.pending.Done()
+			d.deadLetter(delivery, ErrWebhookQueueFull)
+		}
+	}
+	return nil
+}
+
+// Shutdown stops accepting events and waits until queued deliveries and
+// their retries have finished. When ctx expires first, the rest are
+// canceled and passed to DeadLetter as they stop, and Shutdown returns
+// ctx.Err() without waiting for them.
+func (d *WebhookDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	d.cancel()
+	// Fail deliveries still queued, or requeued by a retry as it stopped,
+	// without waiting past ctx for sends in flight
+	stopped := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		for {
+			select {
+			case delivery := <-d.queue:
+				d.fail(delivery, context.Canceled)
+			case <-done:
+				close(stopped)
+				return
+			}
+		}
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return err
+}
+
+func (d *WebhookDispatcher) work() {
+	defer d.workers.Done()
+	for {
+		select {
+		case delivery := <-d.queue:
+			d.attempt(delivery)
+		case <-d.stop.Done():
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) attempt(delivery *WebhookDelivery) {
+	delivery.Attempts++
+	retryable, wait, err := d.send(delivery)
+	if err == nil {
+		d.pending.Done()
+		return
+	}
+	d.opts.Logger.Warn("webhook delivery failed",
+		slog.String("url", delivery.Endpoint.URL),
+		slog.String("event", delivery.Event.Type),
+		slog.String("id", delivery.Event.ID),
+		slog.Int("attempt", delivery.Attempts),
+		slog.String("error", err.Error()),
+	)
+	if !retryable || delivery.Attempts >= d.opts.MaxAttempts {
+		d.fail(delivery, err)
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(d.backoff(delivery.Attempts, wait))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-d.stop.Done():
+			d.fail(delivery, err)
+			return
+		}
+		select {
+		case d.queue <- delivery:
+		case <-d.stop.Done():
+			d.fail(delivery, err)
+		}
+	}()
+}
+
+// send posts the delivery and reports whether a failure may be retried,
+// and after how long the receiver asked to wait
+func (d *WebhookDispatcher) send(delivery *WebhookDelivery) (retryable bool, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(d.stop, MethodPost, delivery.Endpoint.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return false, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoFlow-Webhook")
+	req.Header.Set(WebhookIDHeader, delivery.Event.ID)
+	req.Header.Set(WebhookEventHeader, delivery.Event.Type)
+	if delivery.Endpoint.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(delivery.Endpoint.Secret, time.Now(), delivery.Body))
+	}
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return true, 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, 0, nil
+	}
+	err = fmt.Errorf("GoFlow: webhook endpoint answered %s", resp.Status)
+	wait, _ = retryAfter(resp.Header)
+	retryable = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusRequestTimeout
+	return retryable, wait, err
+}
+
+func (d *WebhookDispatcher) backoff(attempts int, wait time.Duration) time.Duration {
+	delay := d.opts.BaseDelay
+	for i := 1; i < attempts && delay < d.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxDelay {
+		delay = d.opts.MaxDelay
+	}
+	delay = delay/2 + mrand.N(delay/2+1)
+	if wait > d.opts.MaxDelay {
+		wait = d.opts.MaxDelay
+	}
+	return max(delay, wait)
+}
+
+func (d *WebhookDispatcher) fail(delivery *WebhookDelivery, err error) {
+	d.deadLetter(delivery, err)
+	d.pending.Done()
+}
+
+func (d *WebhookDispatcher) deadLetter(delivery *WebhookDelivery, err error) {
+	if d.opts.DeadLetter != nil {
+		d.opts.DeadLetter(*delivery, err)
+	}
+}
+
+func webhookBody(payload any) ([]byte, error) {
+	switch p := payload.(type) {
+	case []byte:
+		return p, nil
+	case json.RawMessage:
+		return p, nil
+	}
+	return json.Marshal(payload)
+}
+
+// Webhooks makes d available to handlers through EnqueueWebhook
+func Webhooks(d *WebhookDispatcher) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), webhookDispatcherKey{}, d)))
+		})
+	}
+}
+
+// EnqueueWebhook queues an event on the dispatcher installed by Webhooks
+func EnqueueWebhook(ctx context.Context, eventType string, payload any) error {
+	d, ok := ctx.Value(webhookDispatcherKey{}).(*WebhookDispatcher)
+	if !ok {
+		return errors.New("GoFlow: EnqueueWebhook requires the Webhooks middleware")
+	}
+	return d.Enqueue(WebhookEvent{Type: eventType, Payload: payload})
+}
+
+// SignWebhook returns the Webhook-Signature header for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">"
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + webhookMAC(secret, ts, body)
+}
+
+func webhookMAC(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookOptions configures VerifyWebhookWithOptions
+type VerifyWebhookOptions struct {
+	// Tolerance is how long ago a delivery may have been signed
+	Tolerance time.Duration
+
+	// Secrets are accepted in turn, so they can be rotated
+	Secrets []string
+
+	// MaxBodySize is the largest body read to check its signature; larger
+	// requests get 413. Defaults to 1MB.
+	MaxBodySize int64
+}
+
+// VerifyWebhook rejects requests without a valid Webhook-Signature from
+// SignWebhook, or signed more than tolerance ago, with 401. Any of secrets
+// may have signed, so secrets can be rotated.
+func VerifyWebhook(tolerance time.Duration, secrets ...string) func(http.Handler) http.Handler {
+	return VerifyWebhookWithOptions(VerifyWebhookOptions{Tolerance: tolerance, Secrets: secrets})
+}
+
+// VerifyWebhookWithOptions is VerifyWebhook with a custom body size limit
+func VerifyWebhookWithOptions(opts VerifyWebhookOptions) func(http.Handler) http.Handler {
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = defaultSignedBodySize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := readSignedBody(w, r, opts.MaxBodySize)
+			if !ok {
+				return
+			}
+			if !validWebhookSignature(r.Header.Get(WebhookSignatureHeader), body, opts.Tolerance, opts.Secrets) {
+				authFailed(r, "webhook", "invalid", "")
+				http.Error(w, StatusText(r.Context(), http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			authSucceeded(r, "webhook", "")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// defaultSignedBodySize limits the bodies read in full before their
+// signature is checked
+const defaultSignedBodySize = 1 << 20
+
+// readSignedBody reads the body so its signature can be checked and puts
+// it back for the handler. It answers 413 for bodies over limit and 400
+// for read errors.
+func readSignedBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		status := http.StatusBadRequest
+		if IsBodyTooLarge(err) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, StatusText(r.Context(), status), status)
+		return nil, false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, true
+}
+
+func validWebhookSignature(header string, body []byte, tolerance time.Duration, secrets []string) bool {
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	for _, secret := range secrets {
+		expected := webhookMAC(secret, ts, body)
+		for _, sig := range sigs {
+			if secretsEqual(sig, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
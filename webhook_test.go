@@ -0,0 +1,170 @@
+package GoFlow
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("Signed Delivery", func(t *testing.T) {
+		received := make(chan *http.Request, 1)
+		receiver := httptest.NewServer(VerifyWebhook(time.Minute, "old", "secret")(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				r.Header.Set("X-Body", string(body))
+				received <- r
+			})))
+		defer receiver.Close()
+
+		d := NewWebhookDispatcher(WebhookOptions{Logger: discard})
+		d.Register(WebhookEndpoint{URL: receiver.URL, Secret: "secret", Events: []string{"user.created"}})
+		d.Register(WebhookEndpoint{URL: receiver.URL + "/other", Events: []string{"order.paid"}})
+
+		handler := Webhooks(d)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := EnqueueWebhook(r.Context(), "user.created", map[string]int{"id": 42}); err != nil {
+				t.Errorf("Expected the event queued, got %v", err)
+			}
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(MethodPost, "/users", nil))
+
+		select {
+		case r := <-received:
+			if r.URL.Path != "/" {
+				t.Errorf("Expected delivery to the subscribed endpoint, got %s", r.URL.Path)
+			}
+			if got := r.Header.Get("X-Body"); got != `{"id":42}` {
+				t.Errorf("Expected the JSON payload, got %q", got)
+			}
+			if r.Header.Get(WebhookEventHeader) != "user.created" || r.Header.Get(WebhookIDHeader) == "" {
+				t.Errorf("Expected event headers, got %v", r.Header)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("Expected the event delivered")
+		}
+		if err := d.Shutdown(context.Background()); err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+		if err := d.Enqueue(WebhookEvent{Type: "user.created"}); err != ErrWebhookClosed {
+			t.Errorf("Expected ErrWebhookClosed after Shutdown, got %v", err)
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		handler := VerifyWebhook(time.Minute, "secret")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		body := `{"id":1}`
+		tests := []struct {
+			name      string
+			signature string
+			code      int
+		}{
+			{"Valid", SignWebhook("secret", time.Now(), []byte(body)), http.StatusOK},
+			{"Wrong Secret", SignWebhook("other", time.Now(), []byte(body)), http.StatusUnauthorized},
+			{"Expired", SignWebhook("secret", time.Now().Add(-time.Hour), []byte(body)), http.StatusUnauthorized},
+			{"Missing", "", http.StatusUnauthorized},
+		}
+		for _, tt := range tests {
+			r := httptest.NewRequest(MethodPost, "/hooks", strings.NewReader(body))
+			r.Header.Set(WebhookSignatureHeader, tt.signature)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Errorf("%s: expected %d, got %d", tt.name, tt.code, w.Code)
+			}
+		}
+	})
+
+	t.Run("Verify Body Limit", func(t *testing.T) {
+		handler := VerifyWebhookWithOptions(VerifyWebhookOptions{
+			Tolerance:   time.Minute,
+			Secrets:     []string{"secret"},
+			MaxBodySize: 16,
+		})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		body := strings.Repeat("x", 17)
+		r := httptest.NewRequest(MethodPost, "/hooks", strings.NewReader(body))
+		r.Header.Set(WebhookSignatureHeader, SignWebhook("secret", time.Now(), []byte(body)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+		}
+	})
+
+	t.Run("Retry and Dead Letter", func(t *testing.T) {
+		var calls atomic.Int32
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/gone") {
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+			if calls.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer receiver.Close()
+
+		var mu sync.Mutex
+		var dead []WebhookDelivery
+		d := NewWebhookDispatcher(WebhookOptions{
+			BaseDelay: time.Millisecond,
+			Logger:    discard,
+			DeadLetter: func(delivery WebhookDelivery, err error) {
+				mu.Lock()
+				dead = append(dead, delivery)
+				mu.Unlock()
+			},
+		})
+		d.Register(WebhookEndpoint{URL: receiver.URL})
+		d.Register(WebhookEndpoint{URL: receiver.URL + "/gone"})
+		d.Enqueue(WebhookEvent{Type: "ping"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := d.Shutdown(ctx); err != nil {
+			t.Fatalf("Expected pending deliveries drained, got %v", err)
+		}
+		if n := calls.Load(); n != 3 {
+			t.Errorf("Expected success on the third attempt, got %d attempts", n)
+		}
+		if len(dead) != 1 || !strings.HasSuffix(dead[0].Endpoint.URL, "/gone") || dead[0].Attempts != 1 {
+			t.Errorf("Expected only the 410 delivery dead-lettered after one attempt, got %+v", dead)
+		}
+	})
+
+	t.Run("Shutdown Deadline", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer receiver.Close()
+
+		var dead atomic.Int32
+		d := NewWebhookDispatcher(WebhookOptions{
+			BaseDelay:  time.Hour,
+			Logger:     discard,
+			DeadLetter: func(WebhookDelivery, error) { dead.Add(1) },
+		})
+		d.Register(WebhookEndpoint{URL: receiver.URL})
+		d.Enqueue(WebhookEvent{Type: "ping"})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if err := d.Shutdown(ctx); err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded, got %v", err)
+		}
+		for deadline := time.Now().Add(time.Second); dead.Load() == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if dead.Load() != 1 {
+			t.Errorf("Expected the waiting retry dead-lettered, got %d", dead.Load())
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+// isWebSocketUpgrade reports whether r asks to switch the connection to the
+// WebSocket protocol. Middleware that buffers or times out responses passes
+// such requests through, since the connection outlives the request.
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		headerHasToken(r.Header, "Connection", "upgrade")
+}
+
+// headerHasToken reports whether the comma-separated header key contains token
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, v := range h.Values(key) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// webSocketProxy tunnels upgraded connections to the upstream chosen by
+// rewrite. The tunnel is torn down when either side closes or the request
+// context is canceled, e.g. by Server.Shutdown.
+type webSocketProxy struct {
+	rewrite        func(*httputil.ProxyRequest)
+	transport      http.RoundTripper
+	modifyResponse func(*http.Response) error
+	errorHandler   func(http.ResponseWriter, *http.Request, error)
+}
+
+func (p *webSocketProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	out := r.Clone(ctx)
+	out.RequestURI = ""
+	out.Close = false
+	for _, h := range []string{"Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding",
+		"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto"} {
+		out.Header.Del(h)
+	}
+	// Of the hop-by-hop headers only the upgrade itself is forwarded;
+	// Sec-WebSocket-* headers, including the subprotocols, pass as they are
+	out.Header.Set("Connection", "Upgrade")
+	out.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+	p.rewrite(&httputil.ProxyRequest{In: r, Out: out})
+	switch out.URL.Scheme {
+	case "ws":
+		out.URL.Scheme = "http"
+	case "wss":
+		out.URL.Scheme = "https"
+	}
+
+	transport := p.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		p.errorHandler(w, r, err)
+		return
+	}
+	if p.modifyResponse != nil {
+		if err := p.modifyResponse(resp); err != nil {
+			resp.Body.Close()
+			p.errorHandler(w, r, err)
+			return
+		}
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// The upstream declined the upgrade; relay its answer
+		defer resp.Body.Close()
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	backConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		p.errorHandler(w, r, errors.New("GoFlow: upstream connection can't be upgraded"))
+		return
+	}
+	defer backConn.Close()
+	if !strings.EqualFold(resp.Header.Get("Upgrade"), r.Header.Get("Upgrade")) {
+		p.errorHandler(w, r, fmt.Errorf("GoFlow: upstream switched to protocol %q", resp.Header.Get("Upgrade")))
+		return
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		p.errorHandler(w, r, fmt.Errorf("GoFlow: can't hijack connection: %w", err))
+		return
+	}
+	defer conn.Close()
+	// The server's read and write timeouts don't apply to the tunnel
+	conn.SetDeadline(time.Time{})
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	resp.Header = w.Header()
+	resp.Body = nil
+	if err := resp.Write(brw); err != nil {
+		return
+	}
+	if err := brw.Flush(); err != nil {
+		return
+	}
+
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(backConn, brw.Reader)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(conn, backConn)
+		errc <- err
+	}()
+
+	// Closing both connections on return stops the other copy
+	select {
+	case <-errc:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,143 @@
+package GoFlow
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WellKnownPrefix is where WellKnown registers RFC 8615 well-known URIs
+const WellKnownPrefix = "/.well-known/"
+
+// WellKnown serves h at /.well-known/<name> for GET and HEAD, hidden from
+// the OpenAPI document, e.g. mux.WellKnown("change-password", redirect)
+func (m *Mux) WellKnown(name string, h http.Handler) *Route {
+	name = strings.Trim(name, "/")
+	if name == "" {
+		panic("GoFlow: WellKnown requires a name")
+	}
+	return m.Handle(WellKnownPrefix+name, h, MethodGet, MethodHead).Hidden()
+}
+
+// SecurityTxt is the content of an RFC 9116 security.txt file, telling
+// researchers how to report vulnerabilities
+type SecurityTxt struct {
+	// Contact lists mailto:, tel: or https: URIs for reports; required
+	Contact []string
+
+	// Expires is when the file should no longer be trusted. Defaults to a
+	// year after each request.
+	Expires time.Time
+
+	Encryption         []string
+	Acknowledgments    []string
+	PreferredLanguages []string
+	Canonical          []string
+	Policy             []string
+	Hiring             []string
+}
+
+// String renders the file
+func (s SecurityTxt) String() string {
+	expires := s.Expires
+	if expires.IsZero() {
+		expires = time.Now().AddDate(1, 0, 0).Truncate(24 * time.Hour)
+	}
+
+	var b strings.Builder
+	field := func(name string, values []string) {
+		for _, v := range values {
+			b.WriteString(name + ": " + v + "\n")
+		}
+	}
+	field("Contact", s.Contact)
+	field("Expires", []string{expires.UTC().Format(time.RFC3339)})
+	field("Encryption", s.Encryption)
+	field("Acknowledgments", s.Acknowledgments)
+	if len(s.PreferredLanguages) > 0 {
+		field("Preferred-Languages", []string{strings.Join(s.PreferredLanguages, ", ")})
+	}
+	field("Canonical", s.Canonical)
+	field("Policy", s.Policy)
+	field("Hiring", s.Hiring)
+	return b.String()
+}
+
+// HandleSecurityTxt serves s at /.well-known/security.txt
+func (m *Mux) HandleSecurityTxt(s SecurityTxt) *Route {
+	if len(s.Contact) == 0 {
+		panic("GoFlow: SecurityTxt requires a Contact")
+	}
+	return m.WellKnown("security.txt", textHandler(s.String))
+}
+
+// RobotsTxt is the content of a robots.txt file
+type RobotsTxt struct {
+	Groups []RobotsGroup
+
+	// Sitemaps lists absolute sitemap URLs
+	Sitemaps []string
+}
+
+// RobotsGroup is a set of rules for some crawlers
+type RobotsGroup struct {
+	// UserAgents the rules apply to. Defaults to "*".
+	UserAgents []string
+
+	Allow    []string
+	Disallow []string
+}
+
+// String renders the file. Without groups, every crawler is allowed
+// everywhere.
+func (rt RobotsTxt) String() string {
+	groups := rt.Groups
+	if len(groups) == 0 {
+		groups = []RobotsGroup{{}}
+	}
+
+	var b strings.Builder
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		agents := g.UserAgents
+		if len(agents) == 0 {
+			agents = []string{"*"}
+		}
+		for _, agent := range agents {
+			b.WriteString("User-agent: " + agent + "\n")
+		}
+		for _, path := range g.Allow {
+			b.WriteString("Allow: " + path + "\n")
+		}
+		for _, path := range g.Disallow {
+			b.WriteString("Disallow: " + path + "\n")
+		}
+		if len(g.Allow) == 0 && len(g.Disallow) == 0 {
+			b.WriteString("Disallow:\n")
+		}
+	}
+	if len(rt.Sitemaps) > 0 {
+		b.WriteString("\n")
+		for _, sitemap := range rt.Sitemaps {
+			b.WriteString("Sitemap: " + sitemap + "\n")
+		}
+	}
+	return b.String()
+}
+
+// HandleRobotsTxt serves rt at /robots.txt, hidden from the OpenAPI
+// document
+func (m *Mux) HandleRobotsTxt(rt RobotsTxt) *Route {
+	return m.Handle("/robots.txt", textHandler(rt.String), MethodGet, MethodHead).Hidden()
+}
+
+func textHandler(render func() string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if r.Method != MethodHead {
+			w.Write([]byte(render()))
+		}
+	})
+}
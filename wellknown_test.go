@@ -0,0 +1,79 @@
+package GoFlow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWellKnown(t *testing.T) {
+	mux := New()
+	mux.HandleSecurityTxt(SecurityTxt{
+		Contact:            []string{"mailto:security@example.com", "https://example.com/security"},
+		Expires:            time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		PreferredLanguages: []string{"en", "de"},
+		Policy:             []string{"https://example.com/disclosure"},
+	})
+	mux.HandleRobotsTxt(RobotsTxt{
+		Groups: []RobotsGroup{
+			{Disallow: []string{"/admin", "/api/"}},
+			{UserAgents: []string{"GPTBot"}, Disallow: []string{"/"}},
+		},
+		Sitemaps: []string{"https://example.com/sitemap.xml"},
+	})
+	mux.WellKnown("/change-password", http.RedirectHandler("/account/password", http.StatusFound))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, path, nil))
+		return w
+	}
+
+	t.Run("Security Txt", func(t *testing.T) {
+		w := get("/.well-known/security.txt")
+		expected := "Contact: mailto:security@example.com\n" +
+			"Contact: https://example.com/security\n" +
+			"Expires: 2027-01-01T00:00:00Z\n" +
+			"Preferred-Languages: en, de\n" +
+			"Policy: https://example.com/disclosure\n"
+		if w.Body.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, w.Body.String())
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+			t.Errorf("Expected text/plain, got %q", ct)
+		}
+	})
+
+	t.Run("Default Expiry", func(t *testing.T) {
+		body := SecurityTxt{Contact: []string{"mailto:security@example.com"}}.String()
+		if !strings.Contains(body, "Expires: "+time.Now().AddDate(1, 0, 0).UTC().Format("2006-")) {
+			t.Errorf("Expected an expiry a year ahead, got %q", body)
+		}
+	})
+
+	t.Run("Robots Txt", func(t *testing.T) {
+		expected := "User-agent: *\nDisallow: /admin\nDisallow: /api/\n\n" +
+			"User-agent: GPTBot\nDisallow: /\n\n" +
+			"Sitemap: https://example.com/sitemap.xml\n"
+		if got := get("/robots.txt").Body.String(); got != expected {
+			t.Errorf("Expected %q, got %q", expected, got)
+		}
+		if got := (RobotsTxt{}).String(); got != "User-agent: *\nDisallow:\n" {
+			t.Errorf("Expected everything allowed by default, got %q", got)
+		}
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		w := get("/.well-known/change-password")
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/account/password" {
+			t.Errorf("Expected a redirect to the password page, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		for _, rt := range mux.Routes() {
+			if !rt.Doc().Hidden {
+				t.Errorf("Expected %s hidden from the API document", rt.Pattern())
+			}
+		}
+	})
+}
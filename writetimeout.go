@@ -0,0 +1,173 @@
+package GoFlow
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// StatusWriteTimeout is the status reported to OnRequestDone hooks and
+// the request log for responses aborted by Route.WriteTimeout. It is never
+// sent: the client got the original status and a truncated body.
+const StatusWriteTimeout = 599
+
+// ErrWriteTimeout is returned by writes to a response that exceeded its
+// Route.WriteTimeout, and is the cause of the request context's
+// cancellation
+var ErrWriteTimeout = errors.New("GoFlow: response write timed out")
+
+type writeTimeoutKey struct{}
+
+// WriteTimeout limits how long the route may take to send its response,
+// counted from the first write, so a slow-reading client or an endless
+// stream can't hold a worker. Once exceeded, writes fail with
+// ErrWriteTimeout and the request context is canceled. Unlike Timeout, the
+// response isn't buffered, so it suits streaming and downloads. Zero
+// disables the limit.
+func (rt *Route) WriteTimeout(d time.Duration) *Route {
+	rt.Set(writeTimeoutKey{}, d)
+	rt.compile()
+	return rt
+}
+
+// WriteTimeout sets Route.WriteTimeout for routes registered afterwards on
+// this mux or group
+func (m *Mux) WriteTimeout(d time.Duration) {
+	m.Set(writeTimeoutKey{}, d)
+}
+
+// writeTimeout enforces a route's WriteTimeout around its whole chain
+func writeTimeout(d time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebSocketUpgrade(r) {
+			// An upgraded connection outlives any response deadline
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		holder, ok := r.Context().Value(logHolderKey{}).(*logHolder)
+		if !ok {
+			holder = &logHolder{}
+			r = r.WithContext(context.WithValue(r.Context(), logHolderKey{}, holder))
+		}
+		ctx, cancel := context.WithCancelCause(r.Context())
+		defer cancel(nil)
+		r = r.WithContext(ctx)
+
+		dw := &deadlineWriter{
+			ResponseWriter: w,
+			rc:             http.NewResponseController(w),
+			limit:          d,
+			serverDeadline: serverWriteDeadline(r),
+			timeout: func() {
+				holder.writeTimedOut = true
+				debugNote(ctx, "write timeout: response exceeded %v", d)
+				cancel(ErrWriteTimeout)
+			},
+		}
+		defer dw.disarm()
+		next.ServeHTTP(wrapWriter(dw), r)
+	})
+}
+
+// deadlineWriter fails writes once the response has been written for
+// longer than limit. The connection's write deadline unblocks writes
+// stalled on a client that stopped reading.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc      *http.ResponseController
+	limit   time.Duration
+	timeout func()
+	// serverDeadline is the one the server's WriteTimeout set, if any
+	serverDeadline time.Time
+
+	deadline time.Time
+	once     sync.Once
+}
+
+// arm starts the clock at the first write and reports whether the
+// deadline has passed
+func (w *deadlineWriter) arm() bool {
+	if w.deadline.IsZero() {
+		w.deadline = time.Now().Add(w.limit)
+		// Writers without deadline support rely on the clock check alone,
+		// and the server's own deadline still applies when it's earlier
+		deadline := w.deadline
+		if !w.serverDeadline.IsZero() && w.serverDeadline.Before(deadline) {
+			deadline = w.serverDeadline
+		}
+		w.rc.SetWriteDeadline(deadline)
+		return true
+	}
+	if time.Now().After(w.deadline) {
+		w.once.Do(w.timeout)
+		return false
+	}
+	return true
+}
+
+// check reports a write error caused by the deadline
+func (w *deadlineWriter) check(err error) error {
+	if err != nil && (errors.Is(err, os.ErrDeadlineExceeded) || time.Now().After(w.deadline)) {
+		w.once.Do(w.timeout)
+		return ErrWriteTimeout
+	}
+	return err
+}
+
+// disarm restores the server's deadline, or lifts the deadline when the
+// server has none so it doesn't carry over to the next request on the
+// connection
+func (w *deadlineWriter) disarm() {
+	if !w.deadline.IsZero() {
+		w.rc.SetWriteDeadline(w.serverDeadline)
+	}
+}
+
+// serverWriteDeadline approximates the write deadline the server's
+// WriteTimeout set when it read the request headers
+func serverWriteDeadline(r *http.Request) time.Time {
+	if srv, ok := r.Context().Value(http.ServerContextKey).(*http.Server); ok && srv.WriteTimeout > 0 {
+		return time.Now().Add(srv.WriteTimeout)
+	}
+	return time.Time{}
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	w.arm()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	if !w.arm() {
+		return 0, ErrWriteTimeout
+	}
+	n, err := w.ResponseWriter.Write(b)
+	return n, w.check(err)
+}
+
+func (w *deadlineWriter) Flush() {
+	if w.arm() {
+		w.check(http.NewResponseController(w.ResponseWriter).Flush())
+	}
+}
+
+func (w *deadlineWriter) ReadFrom(src io.Reader) (int64, error) {
+	if !w.arm() {
+		return 0, ErrWriteTimeout
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		return n, w.check(err)
+	}
+	return io.Copy(writerOnly{w}, src)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *deadlineWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
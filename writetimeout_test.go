@@ -0,0 +1,125 @@
+package GoFlow
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteTimeout(t *testing.T) {
+	var status int
+	mux := New()
+	mux.OnRequestDone(func(info RequestInfo) { status = info.Status })
+
+	var writeErr, cause error
+	var chunks int
+	mux.Handle("/stream", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for chunks = 0; chunks < 100; chunks++ {
+			if _, writeErr = w.Write([]byte("tick\n")); writeErr != nil {
+				break
+			}
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		cause = context.Cause(r.Context())
+	}), MethodGet).WriteTimeout(50 * time.Millisecond)
+
+	mux.Handle("/slow-start", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(60 * time.Millisecond)
+		w.Write([]byte("done"))
+	}), MethodGet).WriteTimeout(50 * time.Millisecond)
+
+	t.Run("Endless Stream", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/stream", nil))
+		if writeErr != ErrWriteTimeout || cause != ErrWriteTimeout {
+			t.Errorf("Expected ErrWriteTimeout from writes and the context, got %v and %v", writeErr, cause)
+		}
+		if chunks >= 20 {
+			t.Errorf("Expected the stream cut short, wrote %d chunks", chunks)
+		}
+		if status != StatusWriteTimeout || w.Code != http.StatusOK {
+			t.Errorf("Expected status %d logged and 200 sent, got %d and %d", StatusWriteTimeout, status, w.Code)
+		}
+	})
+
+	t.Run("Handler Time Not Counted", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(MethodGet, "/slow-start", nil))
+		if w.Body.String() != "done" || status != http.StatusOK {
+			t.Errorf("Expected the response sent, got %q with status %d", w.Body.String(), status)
+		}
+	})
+
+	t.Run("Slow Reader", func(t *testing.T) {
+		finished := make(chan error, 1)
+		mux := New()
+		mux.Handle("/download", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chunk := bytes.Repeat([]byte("x"), 1<<20)
+			for i := 0; i < 512; i++ {
+				if _, err := w.Write(chunk); err != nil {
+					finished <- err
+					return
+				}
+			}
+			finished <- nil
+		}), MethodGet).WriteTimeout(100 * time.Millisecond)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		// A client that never reads the response
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET /download HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+		select {
+		case err := <-finished:
+			if err != ErrWriteTimeout {
+				t.Errorf("Expected ErrWriteTimeout, got %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the blocked write to be aborted")
+		}
+	})
+	t.Run("Server Deadline Kept", func(t *testing.T) {
+		finished := make(chan error, 1)
+		mux := New()
+		mux.Handle("/download", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			chunk := bytes.Repeat([]byte("x"), 1<<20)
+			for i := 0; i < 512; i++ {
+				if _, err := w.Write(chunk); err != nil {
+					finished <- err
+					return
+				}
+			}
+			finished <- nil
+		}), MethodGet).WriteTimeout(time.Hour)
+		server := httptest.NewUnstartedServer(mux)
+		server.Config.WriteTimeout = 100 * time.Millisecond
+		server.Start()
+		defer server.Close()
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		conn.Write([]byte("GET /download HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+		select {
+		case err := <-finished:
+			if err == nil {
+				t.Error("Expected the server's WriteTimeout to abort the write")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected the route's longer limit not to lift the server's WriteTimeout")
+		}
+	})
+}
@@ -0,0 +1,468 @@
+package GoFlow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes the subset of YAML used by configuration files into
+// map[string]any, []any and scalar values: block mappings and sequences,
+// flow sequences and mappings, quoted and plain scalars, literal (|) and
+// folded (>) blocks, and comments. Anchors, tags and multiple documents
+// are not supported.
+func parseYAML(src []byte) (any, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n") {
+		p.lines = append(p.lines, yamlLine{num: i + 1, raw: raw})
+	}
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	v, err := p.parseBlock(p.lines[p.pos].indent())
+	if err != nil {
+		return nil, err
+	}
+	if p.skipBlank(); p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected content")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	num int
+	raw string
+}
+
+func (l yamlLine) indent() int {
+	return len(l.raw) - len(strings.TrimLeft(l.raw, " "))
+}
+
+// text returns the line without indentation and trailing comment
+func (l yamlLine) text() string {
+	return strings.TrimSpace(stripComment(l.raw))
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	line := len(p.lines)
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].num
+	}
+	return fmt.Errorf("GoFlow: yaml line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) {
+		if t := p.lines[p.pos].text(); t != "" && t != "---" {
+			return
+		}
+		p.pos++
+	}
+}
+
+// parseBlock parses the mapping or sequence whose entries start at indent
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if t := p.lines[p.pos].text(); t == "-" || strings.HasPrefix(t, "- ") {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	seq := []any{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent() < indent {
+			break
+		}
+		t := line.text()
+		if t != "-" && !strings.HasPrefix(t, "- ") {
+			if line.indent() == indent {
+				// The next key of a mapping whose sequence shares its indentation
+				break
+			}
+			return nil, p.errorf("expected a sequence entry")
+		}
+		if line.indent() > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(t, "-"))
+		switch {
+		case rest == "":
+			p.pos++
+			v, err := p.parseNested(indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		case rest[0] == '{' || rest[0] == '[':
+			// "- {a: 1}" is a flow collection, even though it contains ": "
+			v, err := p.parseValue(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		case isMappingEntry(rest) || rest == "-" || strings.HasPrefix(rest, "- "):
+			// "- key: value" and "- - item" start a block indented past the dash
+			col := indent + 1
+			for line.raw[col] == ' ' {
+				col++
+			}
+			p.lines[p.pos].raw = strings.Repeat(" ", col) + line.raw[col:]
+			v, err := p.parseBlock(col)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		default:
+			v, err := p.parseValue(rest, indent)
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+		}
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.skipBlank(); p.pos < len(p.lines); p.skipBlank() {
+		line := p.lines[p.pos]
+		if line.indent() < indent {
+			break
+		}
+		t := line.text()
+		if line.indent() > indent || !isMappingEntry(t) {
+			return nil, p.errorf("expected a mapping entry")
+		}
+
+		key, rest := splitMappingEntry(t)
+		key, err := unquoteKey(key)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		if rest == "" {
+			p.pos++
+			// A sequence may sit at the same indentation as its key
+			nested := indent
+			if p.skipBlank(); p.pos < len(p.lines) {
+				if nt := p.lines[p.pos].text(); p.lines[p.pos].indent() == indent && (nt == "-" || strings.HasPrefix(nt, "- ")) {
+					nested = indent - 1
+				}
+			}
+			v, err := p.parseNested(nested)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			continue
+		}
+		v, err := p.parseValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// parseNested parses the block indented past parent, or null if there is none
+func (p *yamlParser) parseNested(parent int) (any, error) {
+	if p.skipBlank(); p.pos >= len(p.lines) || p.lines[p.pos].indent() <= parent {
+		return nil, nil
+	}
+	return p.parseBlock(p.lines[p.pos].indent())
+}
+
+// parseValue parses the inline value of the current line and advances past it
+func (p *yamlParser) parseValue(s string, indent int) (any, error) {
+	if s == "|" || s == ">" || s == "|-" || s == ">-" {
+		p.pos++
+		return p.parseBlockScalar(s, indent), nil
+	}
+	p.pos++
+	if s[0] == '[' || s[0] == '{' {
+		f := &yamlFlow{s: s}
+		v, err := f.parse()
+		if f.skipSpace(); err == nil && f.i < len(f.s) {
+			err = fmt.Errorf("unexpected %q", f.s[f.i:])
+		}
+		if err != nil {
+			p.pos--
+			return nil, p.errorf("%v", err)
+		}
+		return v, nil
+	}
+	v, err := parseScalar(s)
+	if err != nil {
+		p.pos--
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseBlockScalar(style string, indent int) string {
+	var lines []string
+	blockIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line.raw) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		if line.indent() <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = line.indent()
+		}
+		lines = append(lines, line.raw[min(blockIndent, line.indent()):])
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var s string
+	if style[0] == '|' {
+		s = strings.Join(lines, "\n")
+	} else {
+		var b strings.Builder
+		for i, l := range lines {
+			switch {
+			case l == "":
+				b.WriteByte('\n')
+			case i > 0 && lines[i-1] != "":
+				b.WriteByte(' ')
+			}
+			b.WriteString(l)
+		}
+		s = b.String()
+	}
+	if !strings.HasSuffix(style, "-") && s != "" {
+		s += "\n"
+	}
+	return s
+}
+
+// yamlFlow parses flow collections such as [GET, POST] or {a: 1}
+type yamlFlow struct {
+	s string
+	i int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) parse() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+	switch f.s[f.i] {
+	case '[':
+		f.i++
+		seq := []any{}
+		for {
+			if f.skipSpace(); f.i < len(f.s) && f.s[f.i] == ']' {
+				f.i++
+				return seq, nil
+			}
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			seq = append(seq, v)
+			if err := f.separator(']'); err != nil {
+				return nil, err
+			}
+		}
+	case '{':
+		f.i++
+		m := map[string]any{}
+		for {
+			if f.skipSpace(); f.i < len(f.s) && f.s[f.i] == '}' {
+				f.i++
+				return m, nil
+			}
+			k, err := f.scalar(":")
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			if f.i >= len(f.s) || f.s[f.i] != ':' {
+				return nil, fmt.Errorf("expected ':' after key %q", key)
+			}
+			f.i++
+			v, err := f.parse()
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+			if err := f.separator('}'); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f.scalar(",]}")
+}
+
+// separator consumes a comma, leaving a closing bracket for the caller
+func (f *yamlFlow) separator(end byte) error {
+	f.skipSpace()
+	switch {
+	case f.i < len(f.s) && f.s[f.i] == ',':
+		f.i++
+		return nil
+	case f.i < len(f.s) && f.s[f.i] == end:
+		return nil
+	}
+	return fmt.Errorf("expected ',' or %q", end)
+}
+
+func (f *yamlFlow) scalar(stops string) (any, error) {
+	f.skipSpace()
+	start := f.i
+	if f.i < len(f.s) && (f.s[f.i] == '"' || f.s[f.i] == '\'') {
+		end, err := quotedEnd(f.s[f.i:])
+		if err != nil {
+			return nil, err
+		}
+		f.i += end
+	} else {
+		for f.i < len(f.s) && !strings.ContainsRune(stops, rune(f.s[f.i])) {
+			f.i++
+		}
+	}
+	return parseScalar(strings.TrimSpace(f.s[start:f.i]))
+}
+
+// parseScalar converts a plain or quoted scalar to a string, bool, number or nil
+func parseScalar(s string) (any, error) {
+	if s == "" {
+		return nil, nil
+	}
+	switch s[0] {
+	case '"', '\'':
+		end, err := quotedEnd(s)
+		if err != nil {
+			return nil, err
+		}
+		if end != len(s) {
+			return nil, fmt.Errorf("unexpected %q after quoted string", s[end:])
+		}
+		if s[0] == '\'' {
+			return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+		}
+		return strconv.Unquote(s)
+	}
+
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if strings.ContainsAny(s, "0123456789") {
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n, nil
+		}
+	}
+	return s, nil
+}
+
+// quotedEnd returns the length of the quoted string at the start of s
+func quotedEnd(s string) (int, error) {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string")
+}
+
+func isMappingEntry(s string) bool {
+	key, _ := splitMappingEntry(s)
+	return key != ""
+}
+
+// splitMappingEntry splits "key: value" at the first colon that is followed
+// by a space or ends the line and isn't inside a quoted key
+func splitMappingEntry(s string) (key, value string) {
+	start := 0
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end, err := quotedEnd(s)
+		if err != nil {
+			return "", ""
+		}
+		start = end
+	}
+	for i := start; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		}
+	}
+	return "", ""
+}
+
+func unquoteKey(key string) (string, error) {
+	v, err := parseScalar(key)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return key, nil
+}
+
+// stripComment removes a trailing # comment outside quoted strings
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || strings.IndexByte("[{,:-", line[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}